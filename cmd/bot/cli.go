@@ -0,0 +1,138 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/korjavin/whatsfordinner/pkg/models"
+	"github.com/korjavin/whatsfordinner/pkg/recipes"
+	"github.com/korjavin/whatsfordinner/pkg/storage"
+)
+
+// cliUsage explains the subcommands that work on the data directory instead of running the bot
+const cliUsage = `Usage:
+  whatsfordinner                              run the bot
+  whatsfordinner export [-chat ID] [-format zip|json|md|jsonld] [-o FILE]
+  whatsfordinner import [-chat ID -owner USER_ID -username NAME] FILE
+
+Without -chat, export and import work on the base catalog shared by all chats.
+Stop the bot first: the database can only be opened by one process.`
+
+// runCLI runs a subcommand given on the command line
+func runCLI(args []string) error {
+	switch args[0] {
+	case "export":
+		return runExport(args[1:])
+	case "import":
+		return runImport(args[1:])
+	case "help", "-h", "-help", "--help":
+		fmt.Println(cliUsage)
+		return nil
+	default:
+		return fmt.Errorf("unknown command %q\n\n%s", args[0], cliUsage)
+	}
+}
+
+// runExport writes a chat's recipe book or the base catalog to a file
+func runExport(args []string) error {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	chatID := flags.Int64("chat", 0, "chat whose recipe book to export; the base catalog if not set")
+	format := flags.String("format", recipes.FormatZip, "one of "+strings.Join(recipes.Formats, ", "))
+	output := flags.String("o", "", "file to write; the format's default name if not set")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	store, err := storage.New(filepath.Join(".", "data"))
+	if err != nil {
+		return fmt.Errorf("failed to open storage: %w", err)
+	}
+	defer store.Close()
+
+	recipeService := recipes.New(store)
+	var dishes []models.Dish
+	if *chatID != 0 {
+		dishes, err = recipeService.Dishes(*chatID)
+	} else {
+		dishes, err = recipeService.Catalog()
+	}
+	if err != nil {
+		return err
+	}
+
+	data, fileName, err := recipes.Export(dishes, *format)
+	if err != nil {
+		return err
+	}
+
+	if *output == "" {
+		*output = fileName
+	}
+	if err := os.WriteFile(*output, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", *output, err)
+	}
+
+	fmt.Fprintf(os.Stderr, "Exported %d recipes to %s\n", len(dishes), *output)
+	return nil
+}
+
+// runImport reads recipes from a file, or from stdin, into a chat's recipe book or the base catalog
+func runImport(args []string) error {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	chatID := flags.Int64("chat", 0, "chat whose recipe book to import into; the base catalog if not set")
+	owner := flags.String("owner", "", "Telegram user ID of the family member who may edit the imported recipes")
+	username := flags.String("username", "", "username of that family member")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return fmt.Errorf("which file should be imported?\n\n%s", cliUsage)
+	}
+	if *chatID != 0 && *owner == "" {
+		return fmt.Errorf("-owner is needed to import into a chat's recipe book, as only the owner may edit a recipe")
+	}
+
+	fileName := flags.Arg(0)
+	var data []byte
+	var err error
+	if fileName == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(fileName)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", fileName, err)
+	}
+
+	dishes, err := recipes.ParseCollection(data, fileName)
+	if err != nil {
+		return err
+	}
+
+	store, err := storage.New(filepath.Join(".", "data"))
+	if err != nil {
+		return fmt.Errorf("failed to open storage: %w", err)
+	}
+	defer store.Close()
+
+	recipeService := recipes.New(store)
+	var result recipes.ImportResult
+	if *chatID != 0 {
+		result, err = recipeService.Import(*chatID, *owner, *username, dishes)
+	} else {
+		result, err = recipeService.ImportToCatalog(dishes)
+	}
+	if err != nil {
+		return fmt.Errorf("import stopped after %d recipes: %w", len(result.Added), err)
+	}
+
+	fmt.Fprintf(os.Stderr, "Imported %d recipes, skipped %d duplicates\n", len(result.Added), len(result.Duplicates))
+	for _, name := range result.Duplicates {
+		fmt.Fprintf(os.Stderr, "  duplicate: %s\n", name)
+	}
+	return nil
+}
@@ -0,0 +1,202 @@
+package attendance
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/korjavin/whatsfordinner/pkg/logger"
+	"github.com/korjavin/whatsfordinner/pkg/models"
+	"github.com/korjavin/whatsfordinner/pkg/storage"
+)
+
+var (
+	// ErrNotAsked is returned for answers when the family wasn't asked who's eating at home today
+	ErrNotAsked = errors.New("nobody was asked who's eating at home")
+	// ErrClosed is returned when the answers were already counted
+	ErrClosed = errors.New("attendance is already closed")
+)
+
+// Service asks the family who's eating at home before a meal poll and keeps their answers
+type Service struct {
+	store  *storage.Store
+	logger *logger.Logger
+}
+
+// New creates a new attendance service
+func New(store *storage.Store) *Service {
+	return &Service{
+		store:  store,
+		logger: logger.New("attendance"),
+	}
+}
+
+// Start records that the family was asked who's eating at home for a meal, in the message with the given ID
+// The poll starts at the deadline; the answers count until they expire
+func (s *Service) Start(channelID int64, meal string, messageID int, deadline, expiresAt time.Time) (*models.Attendance, error) {
+	attendance := &models.Attendance{
+		Meal:      meal,
+		MessageID: messageID,
+		Attending: make(map[string]string),
+		Away:      make(map[string]string),
+		AskedAt:   time.Now(),
+		Deadline:  deadline,
+		ExpiresAt: expiresAt,
+	}
+
+	err := s.update(channelID, func(channelState *models.ChannelState) error {
+		channelState.Attendance = attendance
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Asked channel %d who's eating at home for %s until %s", channelID, meal, deadline.Format(time.RFC3339))
+	return attendance, nil
+}
+
+// Get returns the channel's current attendance, or nil if nobody was asked today
+func (s *Service) Get(channelID int64) *models.Attendance {
+	var channelState models.ChannelState
+	if err := s.store.Get(fmt.Sprintf("channel:%d", channelID), &channelState); err != nil {
+		return nil
+	}
+	return Current(channelState, time.Now())
+}
+
+// Answer records whether a member is eating at home; members can change their answer until the cutoff
+func (s *Service) Answer(channelID int64, userID, username string, home bool) (*models.Attendance, error) {
+	var attendance *models.Attendance
+	err := s.update(channelID, func(channelState *models.ChannelState) error {
+		attendance = Current(*channelState, time.Now())
+		if attendance == nil {
+			return ErrNotAsked
+		}
+
+		delete(attendance.Attending, userID)
+		delete(attendance.Away, userID)
+		if home {
+			attendance.Attending[userID] = username
+		} else {
+			attendance.Away[userID] = username
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("User %s of channel %d is eating at home: %v", userID, channelID, home)
+	return attendance, nil
+}
+
+// Join counts a voter as eating at home, since they voted on the meal
+// Voters only join while anyone said they're eating at home, so the quorum keeps being taken of the attendees
+func (s *Service) Join(channelID int64, userID, username string) error {
+	return s.update(channelID, func(channelState *models.ChannelState) error {
+		attendance := Current(*channelState, time.Now())
+		if attendance == nil || len(attendance.Attending) == 0 {
+			return nil
+		}
+		if _, ok := attendance.Attending[userID]; ok {
+			return nil
+		}
+
+		delete(attendance.Away, userID)
+		attendance.Attending[userID] = username
+		return nil
+	})
+}
+
+// Close counts the answers so the poll can start
+func (s *Service) Close(channelID int64) (*models.Attendance, error) {
+	var attendance *models.Attendance
+	err := s.update(channelID, func(channelState *models.ChannelState) error {
+		attendance = Current(*channelState, time.Now())
+		if attendance == nil {
+			return ErrNotAsked
+		}
+		if !attendance.ClosedAt.IsZero() {
+			return ErrClosed
+		}
+
+		attendance.ClosedAt = time.Now()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Closed attendance of channel %d: %d at home, %d away", channelID, len(attendance.Attending), len(attendance.Away))
+	return attendance, nil
+}
+
+// update loads the channel state, applies a change and saves it
+func (s *Service) update(channelID int64, change func(channelState *models.ChannelState) error) error {
+	channelKey := fmt.Sprintf("channel:%d", channelID)
+
+	var channelState models.ChannelState
+	if err := s.store.Get(channelKey, &channelState); err != nil {
+		return fmt.Errorf("failed to get channel state: %w", err)
+	}
+
+	if err := change(&channelState); err != nil {
+		return err
+	}
+
+	if err := s.store.Set(channelKey, channelState); err != nil {
+		return fmt.Errorf("failed to save channel state: %w", err)
+	}
+	return nil
+}
+
+// Current returns a channel's attendance if its answers still count, or nil
+func Current(channelState models.ChannelState, now time.Time) *models.Attendance {
+	attendance := channelState.Attendance
+	if attendance == nil || !now.Before(attendance.ExpiresAt) {
+		return nil
+	}
+	return attendance
+}
+
+// IsOpen reports whether the family is still answering who's eating at home, before the poll starts
+func IsOpen(attendance *models.Attendance) bool {
+	return attendance != nil && attendance.ClosedAt.IsZero()
+}
+
+// Eligible returns how many members the quorum of a vote is taken of: the attendees, or when nobody said
+// they're eating at home, the family members who didn't say they're away
+func Eligible(attendance *models.Attendance, memberCount int) int {
+	if attendance == nil {
+		return memberCount
+	}
+	if len(attendance.Attending) > 0 {
+		return len(attendance.Attending)
+	}
+
+	eligible := memberCount - len(attendance.Away)
+	if eligible < 1 {
+		eligible = 1
+	}
+	return eligible
+}
+
+// IsAway reports whether a member said they aren't eating at home
+func IsAway(attendance *models.Attendance, userID string) bool {
+	if attendance == nil {
+		return false
+	}
+	_, away := attendance.Away[userID]
+	return away
+}
+
+// NobodyHome reports whether everyone answered they're away, so there's nobody to cook for
+func NobodyHome(attendance *models.Attendance, memberCount int) bool {
+	return attendance != nil && memberCount > 0 && len(attendance.Attending) == 0 && len(attendance.Away) >= memberCount
+}
+
+// Everyone reports whether every family member answered
+func Everyone(attendance *models.Attendance, memberCount int) bool {
+	return attendance != nil && memberCount > 0 && len(attendance.Attending)+len(attendance.Away) >= memberCount
+}
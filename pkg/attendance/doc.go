@@ -0,0 +1,6 @@
+// Package attendance provides the "Eating at home tonight?" step before a meal poll.
+// The answers are kept in the channel state until the channel's cutoff. While anyone said they're eating at home,
+// the vote quorum is taken of the attendees, and voting counts as eating at home; otherwise it's taken of the
+// family members who didn't say they're away. Members who are away can't be picked to cook,
+// and dinners are scaled to the number of attendees.
+package attendance
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strconv"
 	"strings"
 
 	"github.com/joho/godotenv"
@@ -12,7 +13,16 @@ import (
 // Config holds all configuration for the application
 type Config struct {
 	// Telegram Bot configuration
-	BotToken string
+	BotToken       string
+	TelegramAPIURL string // The Bot API to talk to; empty for Telegram's own
+
+	// Webhook configuration; without a webhook URL the bot fetches its updates instead
+	WebhookURL        string
+	WebhookListen     string
+	WebhookSecret     string
+	WebhookCert       string
+	WebhookKey        string
+	WebhookSelfSigned bool
 
 	// OpenAI configuration
 	OpenAIAPIBase string
@@ -21,6 +31,14 @@ type Config struct {
 
 	// Application configuration
 	Cuisines []string
+
+	// Access configuration
+	AllowedChats []int64 // Chats the bot answers in; empty for every chat
+	RateLimit    int     // Updates a member may send per minute
+
+	// Update processing configuration
+	Workers     int // Updates handled at the same time, each of a different chat
+	UpdateQueue int // Updates waiting for a worker before the bot stops fetching more
 }
 
 // LoadFromEnv loads configuration from environment variables
@@ -39,6 +57,14 @@ func LoadFromEnv() (*Config, error) {
 		return nil, fmt.Errorf("BOT_TOKEN environment variable is required")
 	}
 	cfg.BotToken = botToken
+	cfg.TelegramAPIURL = os.Getenv("TELEGRAM_API_URL")
+
+	cfg.WebhookURL = os.Getenv("WEBHOOK_URL")
+	cfg.WebhookListen = getEnvWithDefault("WEBHOOK_LISTEN", ":8080")
+	cfg.WebhookSecret = os.Getenv("WEBHOOK_SECRET")
+	cfg.WebhookCert = os.Getenv("WEBHOOK_CERT")
+	cfg.WebhookKey = os.Getenv("WEBHOOK_KEY")
+	cfg.WebhookSelfSigned = os.Getenv("WEBHOOK_SELF_SIGNED") == "true"
 
 	openAIAPIKey := os.Getenv("OPENAI_API_KEY")
 	if openAIAPIKey == "" {
@@ -54,6 +80,37 @@ func LoadFromEnv() (*Config, error) {
 	cuisinesStr := getEnvWithDefault("CUISINES", "European,Russian,Italian")
 	cfg.Cuisines = strings.Split(cuisinesStr, ",")
 
+	// Parse the chats the bot answers in
+	for _, chat := range strings.Split(os.Getenv("ALLOWED_CHATS"), ",") {
+		chat = strings.TrimSpace(chat)
+		if chat == "" {
+			continue
+		}
+		chatID, err := strconv.ParseInt(chat, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("ALLOWED_CHATS has an invalid chat ID %q: %w", chat, err)
+		}
+		cfg.AllowedChats = append(cfg.AllowedChats, chatID)
+	}
+
+	rateLimit, err := strconv.Atoi(getEnvWithDefault("RATE_LIMIT", "30"))
+	if err != nil || rateLimit < 1 {
+		return nil, fmt.Errorf("RATE_LIMIT must be a positive number of updates per minute")
+	}
+	cfg.RateLimit = rateLimit
+
+	workers, err := strconv.Atoi(getEnvWithDefault("WORKERS", "8"))
+	if err != nil || workers < 1 {
+		return nil, fmt.Errorf("WORKERS must be a positive number")
+	}
+	cfg.Workers = workers
+
+	updateQueue, err := strconv.Atoi(getEnvWithDefault("UPDATE_QUEUE", "256"))
+	if err != nil || updateQueue < 1 {
+		return nil, fmt.Errorf("UPDATE_QUEUE must be a positive number")
+	}
+	cfg.UpdateQueue = updateQueue
+
 	// Log configuration with sensitive data redacted
 	logCfg := *cfg
 	if len(logCfg.BotToken) > 8 {
@@ -62,6 +119,9 @@ func LoadFromEnv() (*Config, error) {
 	if len(logCfg.OpenAIAPIKey) > 8 {
 		logCfg.OpenAIAPIKey = logCfg.OpenAIAPIKey[:8] + "...REDACTED..."
 	}
+	if logCfg.WebhookSecret != "" {
+		logCfg.WebhookSecret = "REDACTED"
+	}
 	log.Printf("Configuration loaded: %+v", logCfg)
 	return cfg, nil
 }
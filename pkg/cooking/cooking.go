@@ -0,0 +1,201 @@
+package cooking
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/korjavin/whatsfordinner/pkg/logger"
+	"github.com/korjavin/whatsfordinner/pkg/models"
+	"github.com/korjavin/whatsfordinner/pkg/storage"
+)
+
+var (
+	// ErrNoSession is returned when a channel isn't cooking step by step
+	ErrNoSession = errors.New("no cooking session")
+	// ErrStaleSession is returned for buttons of a dinner that's no longer being cooked
+	ErrStaleSession = errors.New("cooking session is for another dinner")
+)
+
+// timerPrefix starts the store keys of cooking timers
+const timerPrefix = "cooking_timer:"
+
+// Service provides step-by-step cooking sessions and their timers
+type Service struct {
+	store  *storage.Store
+	logger *logger.Logger
+}
+
+// New creates a new cooking service
+func New(store *storage.Store) *Service {
+	return &Service{
+		store:  store,
+		logger: logger.New("cooking"),
+	}
+}
+
+// sessionKey returns the store key of a channel's cooking session; a channel cooks one dinner at a time
+func sessionKey(channelID int64) string {
+	return fmt.Sprintf("cooking:%d", channelID)
+}
+
+// Start starts a cooking session at the first step of a dinner, shown in the given message
+// A session for an earlier dinner is replaced
+func (s *Service) Start(dinner *models.Dinner, cookUsername string, messageID int) (*models.CookingSession, error) {
+	session := &models.CookingSession{
+		ChannelID:    dinner.ChannelID,
+		DinnerID:     dinner.ID,
+		Cook:         dinner.Cook,
+		CookUsername: cookUsername,
+		MessageID:    messageID,
+		StartedAt:    time.Now(),
+		LastUpdated:  time.Now(),
+	}
+
+	if err := s.store.Set(sessionKey(dinner.ChannelID), session); err != nil {
+		return nil, fmt.Errorf("failed to save cooking session: %w", err)
+	}
+
+	s.logger.Info("Started cooking %s step by step in channel %d", dinner.Dish.Name, dinner.ChannelID)
+	return session, nil
+}
+
+// Get returns the cooking session of a channel
+func (s *Service) Get(channelID int64) (*models.CookingSession, error) {
+	var session models.CookingSession
+	if err := s.store.Get(sessionKey(channelID), &session); err != nil {
+		return nil, ErrNoSession
+	}
+	return &session, nil
+}
+
+// Move moves a channel's session by delta steps, staying within the dinner's steps
+// Buttons of an earlier dinner return ErrStaleSession
+func (s *Service) Move(channelID int64, dinnerID string, delta, steps int) (*models.CookingSession, error) {
+	session, err := s.Get(channelID)
+	if err != nil {
+		return nil, err
+	}
+	if session.DinnerID != dinnerID {
+		return nil, ErrStaleSession
+	}
+
+	session.Step += delta
+	if session.Step >= steps {
+		session.Step = steps - 1
+	}
+	if session.Step < 0 {
+		session.Step = 0
+	}
+	session.LastUpdated = time.Now()
+
+	if err := s.store.Set(sessionKey(channelID), session); err != nil {
+		return nil, fmt.Errorf("failed to save cooking session: %w", err)
+	}
+
+	return session, nil
+}
+
+// End ends a channel's cooking session and cancels its timers
+// It returns the session that ended, so its message can be updated
+func (s *Service) End(channelID int64) (*models.CookingSession, error) {
+	session, err := s.Get(channelID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.store.Delete(sessionKey(channelID)); err != nil {
+		return nil, fmt.Errorf("failed to delete cooking session: %w", err)
+	}
+	s.CancelTimers(channelID)
+
+	s.logger.Info("Ended cooking session in channel %d", channelID)
+	return session, nil
+}
+
+// StartTimer starts a timer for a step of the session's dinner
+func (s *Service) StartTimer(session *models.CookingSession, step int, duration StepDuration) (*models.CookingTimer, error) {
+	now := time.Now()
+	timer := &models.CookingTimer{
+		ID:           fmt.Sprintf("%s%d:%d", timerPrefix, session.ChannelID, now.UnixNano()),
+		ChannelID:    session.ChannelID,
+		DinnerID:     session.DinnerID,
+		Cook:         session.Cook,
+		CookUsername: session.CookUsername,
+		Step:         step,
+		Label:        duration.Label,
+		StartedAt:    now,
+		FiresAt:      now.Add(duration.Duration),
+	}
+
+	if err := s.store.Set(timer.ID, timer); err != nil {
+		return nil, fmt.Errorf("failed to save timer: %w", err)
+	}
+
+	s.logger.Info("Started a %s timer for step %d in channel %d", duration.Label, step+1, session.ChannelID)
+	return timer, nil
+}
+
+// Timers returns the timers running in a channel, the one that fires first first
+func (s *Service) Timers(channelID int64) ([]models.CookingTimer, error) {
+	return s.listTimers(fmt.Sprintf("%s%d:", timerPrefix, channelID))
+}
+
+// DueTimers returns the timers of all channels that have fired by now
+func (s *Service) DueTimers(now time.Time) ([]models.CookingTimer, error) {
+	timers, err := s.listTimers(timerPrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	var due []models.CookingTimer
+	for _, timer := range timers {
+		if !timer.FiresAt.After(now) {
+			due = append(due, timer)
+		}
+	}
+	return due, nil
+}
+
+// RemoveTimer removes a timer once the cook has been pinged
+func (s *Service) RemoveTimer(timerID string) error {
+	return s.store.Delete(timerID)
+}
+
+// CancelTimers removes all timers of a channel
+func (s *Service) CancelTimers(channelID int64) {
+	timers, err := s.Timers(channelID)
+	if err != nil {
+		s.logger.Error("Failed to list timers of channel %d: %v", channelID, err)
+		return
+	}
+	for _, timer := range timers {
+		if err := s.store.Delete(timer.ID); err != nil {
+			s.logger.Error("Failed to delete timer %s: %v", timer.ID, err)
+		}
+	}
+}
+
+// listTimers returns the timers whose keys start with prefix, sorted by when they fire
+func (s *Service) listTimers(prefix string) ([]models.CookingTimer, error) {
+	keys, err := s.store.List(prefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list timers: %w", err)
+	}
+
+	timers := make([]models.CookingTimer, 0, len(keys))
+	for _, key := range keys {
+		var timer models.CookingTimer
+		if err := s.store.Get(key, &timer); err != nil {
+			s.logger.Error("Failed to get timer %s: %v", key, err)
+			continue
+		}
+		timers = append(timers, timer)
+	}
+
+	sort.Slice(timers, func(i, j int) bool {
+		return timers[i].FiresAt.Before(timers[j].FiresAt)
+	})
+	return timers, nil
+}
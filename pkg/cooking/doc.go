@@ -0,0 +1,5 @@
+// Package cooking provides guided step-by-step cooking sessions.
+// A session walks the cook through a dinner's steps one message at a time, and durations
+// mentioned in a step ("simmer 20 minutes") can be started as timers. Sessions and timers
+// are kept in storage, so they survive restarts; the scheduler pings the cook when a timer fires.
+package cooking
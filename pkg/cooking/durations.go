@@ -0,0 +1,80 @@
+package cooking
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxTimersPerStep is how many timers are offered for one step
+const maxTimersPerStep = 3
+
+// maxTimer is the longest timer offered; longer waits like "marinate 24 hours" aren't kitchen timers
+const maxTimer = 12 * time.Hour
+
+// StepDuration is a duration mentioned in a step, e.g. "simmer for 20 minutes"
+type StepDuration struct {
+	Label    string        // The duration as the step says it, e.g. "20-25 minutes"
+	Duration time.Duration // How long the timer runs; the shorter end of a range, to check on the dish in time
+}
+
+// durationRe matches "20 minutes", "20-25 min", "1.5 hours", "1 to 2 hrs", "30 сек" and "20 минут"
+var durationRe = regexp.MustCompile(`(?i)((\d+(?:[.,]\d+)?)(?:\s*(?:-|–|—|to|or|до|или)\s*\d+(?:[.,]\d+)?)?\s*(seconds?|secs?|minutes?|mins?|hours?|hrs?|h|секунд[уы]?|сек|минут[уы]?|мин|час(?:а|ов)?|ч))(?:[^\p{L}]|$)`)
+
+// Durations finds the durations mentioned in a step, in the order they appear
+func Durations(step string) []StepDuration {
+	var durations []StepDuration
+	seen := make(map[time.Duration]bool)
+
+	for _, match := range durationRe.FindAllStringSubmatch(step, -1) {
+		amount, err := strconv.ParseFloat(strings.Replace(match[2], ",", ".", 1), 64)
+		if err != nil || amount <= 0 {
+			continue
+		}
+
+		duration := time.Duration(amount * float64(unitOf(match[3])))
+		if duration < 10*time.Second || duration > maxTimer || seen[duration] {
+			continue
+		}
+		seen[duration] = true
+
+		durations = append(durations, StepDuration{Label: match[1], Duration: duration})
+		if len(durations) == maxTimersPerStep {
+			break
+		}
+	}
+
+	return durations
+}
+
+// unitOf returns the length of a duration unit as written in a step
+func unitOf(unit string) time.Duration {
+	unit = strings.ToLower(unit)
+	switch {
+	case strings.HasPrefix(unit, "s"), strings.HasPrefix(unit, "сек"):
+		return time.Second
+	case strings.HasPrefix(unit, "h"), strings.HasPrefix(unit, "ч"):
+		return time.Hour
+	default:
+		return time.Minute
+	}
+}
+
+// FormatDuration formats a timer's length for a button, e.g. "20 min", "1 h 30 min" or "45 s"
+func FormatDuration(duration time.Duration) string {
+	if duration < time.Minute {
+		return strconv.Itoa(int(duration.Seconds())) + " s"
+	}
+
+	hours := int(duration.Hours())
+	minutes := int(duration.Minutes()) % 60
+	switch {
+	case hours == 0:
+		return strconv.Itoa(minutes) + " min"
+	case minutes == 0:
+		return strconv.Itoa(hours) + " h"
+	default:
+		return strconv.Itoa(hours) + " h " + strconv.Itoa(minutes) + " min"
+	}
+}
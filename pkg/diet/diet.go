@@ -0,0 +1,202 @@
+package diet
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/korjavin/whatsfordinner/pkg/logger"
+	"github.com/korjavin/whatsfordinner/pkg/models"
+	"github.com/korjavin/whatsfordinner/pkg/openai"
+	"github.com/korjavin/whatsfordinner/pkg/storage"
+)
+
+// Conflict is an ingredient of a dish that a family member can't or won't eat
+type Conflict struct {
+	Username   string
+	Ingredient string
+	Rule       string // The restriction or allergy that rules the ingredient out
+	Allergy    bool   // Allergies exclude a dish, other restrictions only warn about it
+}
+
+// String formats the conflict for chat messages, e.g. "shrimp – @anna is allergic to shellfish"
+func (c Conflict) String() string {
+	if c.Allergy {
+		return fmt.Sprintf("%s – @%s is allergic to %s", c.Ingredient, c.Username, c.Rule)
+	}
+	return fmt.Sprintf("%s – @%s is %s", c.Ingredient, c.Username, c.Rule)
+}
+
+// Service provides diet profile functionality
+type Service struct {
+	store  *storage.Store
+	logger *logger.Logger
+}
+
+// New creates a new diet service
+func New(store *storage.Store) *Service {
+	return &Service{
+		store:  store,
+		logger: logger.New("diet"),
+	}
+}
+
+// GetProfile returns a family member's profile, or an empty one if they haven't set it up
+func (s *Service) GetProfile(channelID int64, userID string) (*models.DietProfile, error) {
+	var profile models.DietProfile
+	err := s.store.Get(fmt.Sprintf("diet:%d:%s", channelID, userID), &profile)
+	if err != nil {
+		return &models.DietProfile{ChannelID: channelID, UserID: userID}, nil
+	}
+	return &profile, nil
+}
+
+// ListProfiles returns the profiles of a channel's family members, sorted by username
+// Profiles without any restrictions or allergies are left out
+func (s *Service) ListProfiles(channelID int64) ([]models.DietProfile, error) {
+	keys, err := s.store.List(fmt.Sprintf("diet:%d:", channelID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list diet profiles: %w", err)
+	}
+
+	profiles := make([]models.DietProfile, 0, len(keys))
+	for _, key := range keys {
+		var profile models.DietProfile
+		if err := s.store.Get(key, &profile); err != nil {
+			s.logger.Error("Failed to get diet profile %s: %v", key, err)
+			continue
+		}
+		if len(profile.Restrictions) == 0 && len(profile.Allergies) == 0 {
+			continue
+		}
+		profiles = append(profiles, profile)
+	}
+
+	sort.Slice(profiles, func(i, j int) bool {
+		return strings.ToLower(profiles[i].Username) < strings.ToLower(profiles[j].Username)
+	})
+
+	return profiles, nil
+}
+
+// updateProfile applies a change to a family member's profile and saves it
+func (s *Service) updateProfile(channelID int64, userID, username string, apply func(profile *models.DietProfile)) (*models.DietProfile, error) {
+	profile, err := s.GetProfile(channelID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	apply(profile)
+	profile.Username = username
+	profile.LastUpdated = time.Now()
+
+	if err := s.store.Set(fmt.Sprintf("diet:%d:%s", channelID, userID), profile); err != nil {
+		return nil, fmt.Errorf("failed to save diet profile: %w", err)
+	}
+
+	return profile, nil
+}
+
+// SetRestrictions replaces a family member's dietary restrictions, e.g. "vegetarian", "lactose intolerant"
+func (s *Service) SetRestrictions(channelID int64, userID, username string, restrictions []string) (*models.DietProfile, error) {
+	s.logger.Info("Setting restrictions of %s in channel %d to %v", username, channelID, restrictions)
+	return s.updateProfile(channelID, userID, username, func(profile *models.DietProfile) {
+		profile.Restrictions = normalizeAll(restrictions, NormalizeRestriction)
+	})
+}
+
+// SetAllergies replaces a family member's allergies, e.g. "nuts", "shrimp"
+func (s *Service) SetAllergies(channelID int64, userID, username string, allergies []string) (*models.DietProfile, error) {
+	s.logger.Info("Setting allergies of %s in channel %d to %v", username, channelID, allergies)
+	return s.updateProfile(channelID, userID, username, func(profile *models.DietProfile) {
+		profile.Allergies = normalizeAll(allergies, NormalizeAllergy)
+	})
+}
+
+// Apply adds the family members' restrictions and allergies to LLM preferences
+// Restrictions are attributed, e.g. "anna: vegetarian", so the LLM can suggest something everyone can eat
+func (s *Service) Apply(channelID int64, prefs openai.Preferences) openai.Preferences {
+	profiles, err := s.ListProfiles(channelID)
+	if err != nil {
+		s.logger.Error("Failed to get diet profiles for channel %d: %v", channelID, err)
+		return prefs
+	}
+
+	rules := append([]string{}, prefs.DietaryRules...)
+	allergies := append([]string{}, prefs.Allergies...)
+	for _, profile := range profiles {
+		if len(profile.Restrictions) > 0 {
+			rules = append(rules, fmt.Sprintf("%s: %s", profile.Username, strings.Join(profile.Restrictions, ", ")))
+		}
+		allergies = append(allergies, profile.Allergies...)
+	}
+
+	prefs.DietaryRules = rules
+	prefs.Allergies = normalizeAll(allergies, strings.TrimSpace)
+	return prefs
+}
+
+// Check returns the conflicts between a dish's ingredients and the family members' profiles
+func Check(dish models.Dish, profiles []models.DietProfile) []Conflict {
+	var conflicts []Conflict
+	for _, profile := range profiles {
+		for _, allergy := range profile.Allergies {
+			r := allergyRule(allergy)
+			for _, ingredient := range dish.Ingredients {
+				if r.rulesOut(ingredient) {
+					conflicts = append(conflicts, Conflict{Username: profile.Username, Ingredient: ingredient, Rule: allergy, Allergy: true})
+					break
+				}
+			}
+		}
+
+		for _, restriction := range profile.Restrictions {
+			r, ok := restrictionRules[restriction]
+			if !ok {
+				continue
+			}
+			for _, ingredient := range dish.Ingredients {
+				if r.rulesOut(ingredient) {
+					conflicts = append(conflicts, Conflict{Username: profile.Username, Ingredient: ingredient, Rule: restriction})
+					break
+				}
+			}
+		}
+	}
+	return conflicts
+}
+
+// CheckDish returns the conflicts between a dish and the profiles of a channel's family members
+func (s *Service) CheckDish(channelID int64, dish models.Dish) ([]Conflict, error) {
+	profiles, err := s.ListProfiles(channelID)
+	if err != nil {
+		return nil, err
+	}
+	return Check(dish, profiles), nil
+}
+
+// HasAllergen reports whether any of the conflicts is an allergy
+func HasAllergen(conflicts []Conflict) bool {
+	for _, conflict := range conflicts {
+		if conflict.Allergy {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeAll normalizes names, dropping empty ones and duplicates
+func normalizeAll(names []string, normalize func(string) string) []string {
+	seen := make(map[string]bool)
+	result := make([]string, 0, len(names))
+	for _, name := range names {
+		name = normalize(name)
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		result = append(result, name)
+	}
+	return result
+}
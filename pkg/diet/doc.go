@@ -0,0 +1,5 @@
+// Package diet provides dietary restriction and allergy profiles for family members.
+// Profiles are kept per channel and per user. Known restrictions such as "vegetarian" or "gluten-free"
+// and allergies are checked against dish ingredients through the ingredient taxonomy:
+// allergens exclude a dish outright, other restrictions produce warnings.
+package diet
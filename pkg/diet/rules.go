@@ -0,0 +1,188 @@
+package diet
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/korjavin/whatsfordinner/pkg/ingredients"
+	"github.com/korjavin/whatsfordinner/pkg/quantity"
+)
+
+// rule describes which ingredients a restriction or an allergy rules out
+type rule struct {
+	Categories []string // Taxonomy categories or groups that are ruled out
+	Words      []string // Ingredient names or words in them that are ruled out, e.g. "pork" also catches "pork chop"
+}
+
+// Restrictions the bot can check dishes against
+const (
+	Vegetarian  = "vegetarian"
+	Vegan       = "vegan"
+	Pescatarian = "pescatarian"
+	LactoseFree = "lactose-free"
+	GlutenFree  = "gluten-free"
+	Halal       = "halal"
+)
+
+var (
+	meatWords   = []string{"meat", "beef", "pork", "chicken", "lamb", "turkey", "bacon", "ham", "sausage", "veal", "duck", "mince", "gelatin"}
+	fishWords   = []string{"fish", "salmon", "tuna", "cod", "shrimp", "prawn", "anchovy", "crab", "squid", "mussel", "oyster"}
+	dairyWords  = []string{"milk", "cream", "butter", "cheese", "yogurt", "kefir", "ghee", "whey"}
+	glutenWords = []string{
+		"flour", "bread", "pasta", "spaghetti", "noodle", "couscous", "lasagna sheet", "tortilla", "pita",
+		"wheat", "barley", "rye", "semolina", "breadcrumb", "bulgur",
+	}
+)
+
+// restrictionRules lists the restrictions the bot knows how to check
+var restrictionRules = map[string]rule{
+	Vegetarian:  {Categories: []string{ingredients.CategoryMeat, ingredients.CategoryFish}, Words: append(append([]string{}, meatWords...), fishWords...)},
+	Vegan:       {Categories: []string{ingredients.CategoryMeat, ingredients.CategoryFish, ingredients.CategoryDairy, ingredients.CategoryEggs}, Words: append(append(append([]string{"egg", "honey"}, meatWords...), fishWords...), dairyWords...)},
+	Pescatarian: {Categories: []string{ingredients.CategoryMeat}, Words: meatWords},
+	LactoseFree: {Categories: []string{ingredients.CategoryDairy}, Words: dairyWords},
+	GlutenFree:  {Words: glutenWords},
+	Halal:       {Words: []string{"pork", "bacon", "ham", "lard", "wine", "beer", "gelatin"}},
+}
+
+// restrictionAliases maps the ways people name restrictions to the known restrictions
+var restrictionAliases = map[string]string{
+	"veggie": Vegetarian, "no meat": Vegetarian, "вегетарианец": Vegetarian, "вегетарианка": Vegetarian, "вегетарианство": Vegetarian,
+	"веган": Vegan, "plant-based": Vegan, "plant based": Vegan,
+	"pescetarian": Pescatarian, "пескетарианец": Pescatarian,
+	"lactose intolerant": LactoseFree, "lactose-intolerant": LactoseFree, "lactose free": LactoseFree,
+	"no dairy": LactoseFree, "dairy-free": LactoseFree, "dairy free": LactoseFree, "без лактозы": LactoseFree,
+	"gluten free": GlutenFree, "no gluten": GlutenFree, "celiac": GlutenFree, "coeliac": GlutenFree, "без глютена": GlutenFree,
+	"no pork": Halal, "халяль": Halal,
+}
+
+// allergyRules lists allergies that cover more than a single ingredient
+var allergyRules = map[string]rule{
+	"nuts":      {Categories: []string{ingredients.CategoryNuts}, Words: []string{"nut", "almond", "walnut", "hazelnut", "cashew", "pistachio", "pecan", "peanut"}},
+	"peanut":    {Words: []string{"peanut"}},
+	"shellfish": {Words: []string{"shrimp", "prawn", "crab", "lobster", "mussel", "oyster", "scallop", "squid", "clam"}},
+	"fish":      {Categories: []string{ingredients.CategoryFish}, Words: fishWords},
+	"egg":       {Categories: []string{ingredients.CategoryEggs}, Words: []string{"egg", "mayonnaise"}},
+	"milk":      {Categories: []string{ingredients.CategoryDairy}, Words: dairyWords},
+	"gluten":    {Words: glutenWords},
+	"soy":       {Words: []string{"soy", "tofu", "edamame"}},
+	"sesame":    {Words: []string{"sesame", "tahini"}},
+}
+
+// allergyAliases maps the ways people name allergies to the allergies above
+var allergyAliases = map[string]string{
+	"nut": "nuts", "tree nuts": "nuts", "tree nut": "nuts", "орехи": "nuts",
+	"peanuts": "peanut", "арахис": "peanut",
+	"seafood": "shellfish", "shrimps": "shellfish", "морепродукты": "shellfish",
+	"рыба": "fish",
+	"eggs": "egg", "яйца": "egg",
+	"dairy": "milk", "lactose": "milk", "молоко": "milk",
+	"wheat": "gluten", "глютен": "gluten",
+	"soya": "soy", "соя": "soy",
+}
+
+// NormalizeRestriction returns the known name of a restriction, e.g. "lactose intolerant" -> "lactose-free"
+// Unknown restrictions are returned lowercased; they're passed on to the LLM but can't be checked
+func NormalizeRestriction(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if alias, ok := restrictionAliases[name]; ok {
+		return alias
+	}
+	return name
+}
+
+// IsKnownRestriction reports whether dishes can be checked against a restriction
+func IsKnownRestriction(name string) bool {
+	_, ok := restrictionRules[NormalizeRestriction(name)]
+	return ok
+}
+
+// NormalizeAllergy returns the name of an allergy, e.g. "peanuts" -> "peanut"
+// Allergies that aren't a known group are treated as a single canonical ingredient
+func NormalizeAllergy(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if alias, ok := allergyAliases[name]; ok {
+		return alias
+	}
+	if _, ok := allergyRules[name]; ok {
+		return name
+	}
+	return ingredients.Canonicalize(name)
+}
+
+// allergyRule returns the rule for an allergy, falling back to the ingredient itself
+func allergyRule(allergy string) rule {
+	if r, ok := allergyRules[allergy]; ok {
+		return r
+	}
+	return rule{Words: []string{allergy}}
+}
+
+// plantPrefixes are words that turn an animal product into a plant-based one, e.g. "peanut butter", "coconut milk"
+var plantPrefixes = map[string]bool{
+	"peanut": true, "coconut": true, "almond": true, "soy": true, "oat": true, "rice": true, "cocoa": true, "vegan": true,
+}
+
+// rulesOut reports whether a rule rules out an ingredient
+// Both the taxonomy category and the words of the name are checked, so "chicken stock" is caught
+// even though it's canonicalized to "broth"
+func (r rule) rulesOut(ingredient string) bool {
+	name, _, _ := quantity.ParseIngredient(ingredient)
+	words := splitWords(strings.ToLower(name))
+	candidates := [][]string{words}
+
+	// The canonical name and its category drop qualifiers, so "rice noodles" would become "noodle"
+	// and "oat milk" would be dairy; only fall back to them when there's no plant-based qualifier to lose
+	plantBased := false
+	for _, word := range words {
+		plantBased = plantBased || plantPrefixes[word]
+	}
+	if !plantBased {
+		category := ingredients.CategoryOf(ingredient)
+		for _, ruledOut := range r.Categories {
+			if ingredients.IsA(category, ruledOut) {
+				return true
+			}
+		}
+		candidates = append(candidates, splitWords(ingredients.Canonicalize(name)))
+	}
+
+	for _, candidate := range candidates {
+		for _, phrase := range r.Words {
+			if containsPhrase(candidate, strings.Fields(phrase)) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// splitWords splits a name into words, dropping punctuation and digits
+func splitWords(name string) []string {
+	return strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && r != '-'
+	})
+}
+
+// containsPhrase reports whether words contain a phrase, allowing a plural last word
+// A phrase right after a plant-based prefix doesn't count
+func containsPhrase(words, phrase []string) bool {
+	for start := 0; start+len(phrase) <= len(words); start++ {
+		if start > 0 && plantPrefixes[words[start-1]] {
+			continue
+		}
+
+		matched := true
+		for i, want := range phrase {
+			got := words[start+i]
+			if got != want && got != want+"s" && got != want+"es" {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,46 @@
+package diet
+
+import "testing"
+
+func TestRulesOut(t *testing.T) {
+	tests := []struct {
+		rule       string // A restriction, or an allergy if it isn't one
+		ingredient string
+		want       bool
+	}{
+		{Vegan, "200 ml almond milk", false},
+		{Vegan, "oat milk", false},
+		{Vegan, "soy milk", false},
+		{Vegan, "coconut cream", false},
+		{Vegan, "milk", true},
+		{Vegan, "2 eggs", true},
+		{Vegan, "chicken stock", true},
+		{LactoseFree, "oat milk", false},
+		{LactoseFree, "almond milk 1 l", false},
+		{LactoseFree, "peanut butter", false},
+		{LactoseFree, "butter", true},
+		{LactoseFree, "parmesan", true},
+		{Vegetarian, "bacon", true},
+		{Vegetarian, "tofu", false},
+		{GlutenFree, "rice noodles", false},
+		{GlutenFree, "egg noodles", true},
+		{"milk", "soy milk", false},
+		{"milk", "oat milk", false},
+		{"milk", "whole milk", true},
+		{"nuts", "almond milk", true},
+		{"soy", "soy milk", true},
+		{"peanut", "peanut butter", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.rule+"/"+tt.ingredient, func(t *testing.T) {
+			r, ok := restrictionRules[tt.rule]
+			if !ok {
+				r = allergyRule(NormalizeAllergy(tt.rule))
+			}
+			if got := r.rulesOut(tt.ingredient); got != tt.want {
+				t.Fatalf("%s rules out %q = %v, want %v", tt.rule, tt.ingredient, got, tt.want)
+			}
+		})
+	}
+}
@@ -2,54 +2,82 @@ package dinner
 
 import (
 	"fmt"
-	"math/rand"
+	"strings"
 	"time"
 
+	"github.com/korjavin/whatsfordinner/pkg/diet"
 	"github.com/korjavin/whatsfordinner/pkg/fridge"
 	"github.com/korjavin/whatsfordinner/pkg/logger"
 	"github.com/korjavin/whatsfordinner/pkg/models"
+	"github.com/korjavin/whatsfordinner/pkg/nutrition"
 	"github.com/korjavin/whatsfordinner/pkg/openai"
+	"github.com/korjavin/whatsfordinner/pkg/recipes"
 	"github.com/korjavin/whatsfordinner/pkg/storage"
 )
 
+// historyWindow is how far back past dinners are considered for ranking
+const historyWindow = 90 * 24 * time.Hour
+
 // Service provides dinner planning functionality
 type Service struct {
-	store         *storage.Store
-	fridgeService *fridge.Service
-	openaiClient  *openai.Client
-	logger        *logger.Logger
+	store            *storage.Store
+	fridgeService    *fridge.Service
+	dietService      *diet.Service
+	recipeService    *recipes.Service
+	nutritionService *nutrition.Service
+	openaiClient     *openai.Client
+	ranker           *Ranker
+	logger           *logger.Logger
 }
 
 // New creates a new dinner service
-func New(store *storage.Store, fridgeService *fridge.Service, openaiClient *openai.Client) *Service {
+func New(store *storage.Store, fridgeService *fridge.Service, dietService *diet.Service, recipeService *recipes.Service, nutritionService *nutrition.Service, openaiClient *openai.Client) *Service {
 	return &Service{
-		store:         store,
-		fridgeService: fridgeService,
-		openaiClient:  openaiClient,
-		logger:        logger.New(""),
+		store:            store,
+		fridgeService:    fridgeService,
+		dietService:      dietService,
+		recipeService:    recipeService,
+		nutritionService: nutritionService,
+		openaiClient:     openaiClient,
+		ranker:           NewRanker(DefaultRankingWeights, nil),
+		logger:           logger.New(""),
 	}
 }
 
-// GetDishes returns a list of all available dishes
-func (s *Service) GetDishes() ([]models.Dish, error) {
-	// Get dishes from the database
-	dishKeys, err := s.store.List("dish:")
+// GetDishes returns the dishes a channel can cook: its family recipe book followed by the shared base catalog
+// A family recipe replaces a catalog dish of the same name
+func (s *Service) GetDishes(channelID int64) ([]models.Dish, error) {
+	dishes, err := s.recipeService.Dishes(channelID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list dishes: %w", err)
-	}
-
-	// If we have dishes in the database, return them
-	if len(dishKeys) > 0 {
-		dishes := make([]models.Dish, 0, len(dishKeys))
-		for _, key := range dishKeys {
-			var dish models.Dish
-			err := s.store.Get(key, &dish)
-			if err != nil {
-				s.logger.Error("Failed to get dish %s: %v", key, err)
-				continue
-			}
+		// The base catalog is still worth suggesting from
+		s.logger.Error("Failed to get the recipe book of channel %d: %v", channelID, err)
+	}
+
+	inBook := make(map[string]bool, len(dishes))
+	for _, dish := range dishes {
+		inBook[strings.ToLower(dish.Name)] = true
+	}
+
+	catalog, err := s.getCatalog()
+	if err != nil {
+		return nil, err
+	}
+	for _, dish := range catalog {
+		if !inBook[strings.ToLower(dish.Name)] {
 			dishes = append(dishes, dish)
 		}
+	}
+
+	return dishes, nil
+}
+
+// getCatalog returns the shared base catalog of dishes, creating it on first use
+func (s *Service) getCatalog() ([]models.Dish, error) {
+	dishes, err := s.recipeService.Catalog()
+	if err != nil {
+		return nil, err
+	}
+	if len(dishes) > 0 {
 		return dishes, nil
 	}
 
@@ -65,47 +93,23 @@ func (s *Service) GetDishes() ([]models.Dish, error) {
 		{"Beef Stroganoff", "Russian"},
 	}
 
-	dishes := make([]models.Dish, 0, len(defaultDishes))
+	dishes = make([]models.Dish, 0, len(defaultDishes))
 	for _, defaultDish := range defaultDishes {
 		// Get dish info from OpenAI
-		dishInfo, err := s.openaiClient.GetDishInfo(defaultDish.Name, defaultDish.Cuisine)
+		dishInfo, err := s.openaiClient.GetDishInfo(defaultDish.Name, openai.Preferences{}, defaultDish.Cuisine)
 		if err != nil {
 			s.logger.Error("Failed to get dish info for %s: %v", defaultDish.Name, err)
 			continue
 		}
 
-		// Extract ingredients and instructions
-		ingredients, _ := dishInfo["ingredients"].([]interface{})
-		instructions, _ := dishInfo["instructions"].([]interface{})
-
-		// Convert to string slices
-		ingredientStrs := make([]string, 0, len(ingredients))
-		for _, ingredient := range ingredients {
-			if ingStr, ok := ingredient.(string); ok {
-				ingredientStrs = append(ingredientStrs, ingStr)
-			}
-		}
-
-		instructionStrs := make([]string, 0, len(instructions))
-		for _, instruction := range instructions {
-			if instStr, ok := instruction.(string); ok {
-				instructionStrs = append(instructionStrs, instStr)
-			}
-		}
-
-		// Create dish
-		dish := models.Dish{
-			Name:         defaultDish.Name,
-			Cuisine:      defaultDish.Cuisine,
-			Ingredients:  ingredientStrs,
-			Instructions: instructionStrs,
-		}
+		// Create dish, keeping our name and cuisine so the catalog keys stay stable
+		dish := DishFromInfo(dishInfo)
+		dish.Name = defaultDish.Name
+		dish.Cuisine = defaultDish.Cuisine
 
 		// Save dish to database
-		dishKey := fmt.Sprintf("dish:%s:%s", defaultDish.Cuisine, defaultDish.Name)
-		err = s.store.Set(dishKey, dish)
-		if err != nil {
-			s.logger.Error("Failed to save dish %s: %v", dishKey, err)
+		if err := s.recipeService.AddToCatalog(dish); err != nil {
+			s.logger.Error("Failed to save dish: %v", err)
 		}
 
 		dishes = append(dishes, dish)
@@ -114,9 +118,12 @@ func (s *Service) GetDishes() ([]models.Dish, error) {
 	return dishes, nil
 }
 
-// SuggestDishes suggests dishes based on available ingredients and cuisine preferences
-func (s *Service) SuggestDishes(channelID int64, cuisines []string, count int) ([]models.Dish, error) {
-	allDishes, err := s.GetDishes()
+// SuggestDishes suggests dishes from the family's recipe book and the base catalog,
+// based on available ingredients and cuisine preferences
+// Dishes are ranked by fridge coverage, recent repeats, past ratings and cuisine preference,
+// leaving out dishes with allergens from the family's diet profiles
+func (s *Service) SuggestDishes(channelID int64, cuisines []string, count int) ([]RankedDish, error) {
+	allDishes, err := s.GetDishes(channelID)
 	if err != nil {
 		return nil, err
 	}
@@ -142,63 +149,183 @@ func (s *Service) SuggestDishes(channelID int64, cuisines []string, count int) (
 		s.logger.Warn("No dishes found for cuisines %v, falling back to all dishes", cuisines)
 	}
 
-	// Get available ingredients
+	ranked, err := s.RankDishes(channelID, filteredDishes, cuisines)
+	if err != nil {
+		return nil, err
+	}
+
+	// Take the top N dishes
+	if len(ranked) > count {
+		ranked = ranked[:count]
+	}
+
+	return s.withNutrition(ranked), nil
+}
+
+// RankDishes ranks candidate dishes for a channel, best first
+// It's used both for catalog dishes and for dishes suggested by the LLM
+// Dishes with an allergen are left out, and dishes that break someone's restrictions come last, with warnings
+func (s *Service) RankDishes(channelID int64, dishes []models.Dish, cuisines []string) ([]RankedDish, error) {
 	ingredients, err := s.fridgeService.ListIngredients(channelID)
 	if err != nil {
 		return nil, err
 	}
 
-	// Create a map of available ingredients for faster lookup
-	availableIngredients := make(map[string]bool)
-	for _, ingredient := range ingredients {
-		availableIngredients[ingredient.Name] = true
+	history, err := s.GetHistory(channelID, time.Now().Add(-historyWindow))
+	if err != nil {
+		// Ranking still works without history, it just can't avoid repeats
+		s.logger.Error("Failed to get dinner history for channel %d: %v", channelID, err)
 	}
 
-	// Score dishes based on available ingredients
-	type scoredDish struct {
-		dish  models.Dish
-		score float64
+	ranked := s.ranker.Rank(dishes, RankingContext{
+		Fridge:   ingredients,
+		History:  history,
+		Cuisines: cuisines,
+		Now:      time.Now(),
+	})
+
+	ranked = s.applyDiets(channelID, ranked)
+
+	for _, rankedDish := range ranked {
+		s.logger.Debug("Ranked dish %s: score %.2f (%s)", rankedDish.Dish.Name, rankedDish.Score, rankedDish.Explanation(time.Now()))
 	}
 
-	var scoredDishes []scoredDish
-	for _, dish := range filteredDishes {
-		var matchCount int
-		for _, ingredient := range dish.Ingredients {
-			if availableIngredients[ingredient] {
-				matchCount++
-			}
+	return ranked, nil
+}
+
+// applyDiets checks ranked dishes against the family's diet profiles
+// Dishes with an allergen are dropped; dishes that only break a restriction keep their order after the others
+func (s *Service) applyDiets(channelID int64, ranked []RankedDish) []RankedDish {
+	profiles, err := s.dietService.ListProfiles(channelID)
+	if err != nil {
+		s.logger.Error("Failed to get diet profiles for channel %d: %v", channelID, err)
+		return ranked
+	}
+	if len(profiles) == 0 {
+		return ranked
+	}
+
+	safe := make([]RankedDish, 0, len(ranked))
+	var warned []RankedDish
+	for _, rankedDish := range ranked {
+		conflicts := diet.Check(rankedDish.Dish, profiles)
+		if diet.HasAllergen(conflicts) {
+			s.logger.Info("Leaving out %s for channel %d: %v", rankedDish.Dish.Name, channelID, conflicts)
+			continue
+		}
+		if len(conflicts) == 0 {
+			safe = append(safe, rankedDish)
+			continue
+		}
+		for _, conflict := range conflicts {
+			rankedDish.Warnings = append(rankedDish.Warnings, conflict.String())
 		}
+		warned = append(warned, rankedDish)
+	}
+
+	return append(safe, warned...)
+}
 
-		// Calculate score as percentage of matching ingredients
-		score := float64(matchCount) / float64(len(dish.Ingredients))
-		scoredDishes = append(scoredDishes, scoredDish{dish, score})
+// RankSuggestions ranks dinner options suggested by the LLM, best first
+// If ranking fails, the suggestions are returned in their original order
+func (s *Service) RankSuggestions(channelID int64, suggestions []map[string]interface{}, cuisines []string) []RankedDish {
+	dishes := make([]models.Dish, len(suggestions))
+	for i, suggestion := range suggestions {
+		dishes[i] = DishFromInfo(suggestion)
 	}
 
-	// Sort dishes by score (descending)
-	// Use a local random source instead of the deprecated rand.Seed
-	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
-	rng.Shuffle(len(scoredDishes), func(i, j int) {
-		scoredDishes[i], scoredDishes[j] = scoredDishes[j], scoredDishes[i]
-	})
+	ranked, err := s.RankDishes(channelID, dishes, cuisines)
+	if err != nil {
+		s.logger.Error("Failed to rank suggestions for channel %d: %v", channelID, err)
+		ranked = make([]RankedDish, len(dishes))
+		for i, dish := range dishes {
+			ranked[i] = RankedDish{Dish: dish}
+		}
+	}
 
-	// Take the top N dishes
-	result := make([]models.Dish, 0, count)
-	for i := 0; i < len(scoredDishes) && i < count; i++ {
-		result = append(result, scoredDishes[i].dish)
+	return s.withNutrition(ranked)
+}
+
+// withNutrition attaches the nutrients per serving to ranked dishes, for the poll message
+func (s *Service) withNutrition(ranked []RankedDish) []RankedDish {
+	for i := range ranked {
+		ranked[i].Dish = s.nutritionService.Annotate(ranked[i].Dish)
 	}
+	return ranked
+}
+
+// GetHistory returns the dinners of a channel that started after the given time
+func (s *Service) GetHistory(channelID int64, since time.Time) ([]models.Dinner, error) {
+	dinnerKeys, err := s.store.List(fmt.Sprintf("dinner:%d:", channelID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list dinners: %w", err)
+	}
+
+	dinners := make([]models.Dinner, 0, len(dinnerKeys))
+	for _, key := range dinnerKeys {
+		var dinner models.Dinner
+		err := s.store.Get(key, &dinner)
+		if err != nil {
+			s.logger.Error("Failed to get dinner %s: %v", key, err)
+			continue
+		}
+
+		if dinner.StartedAt.After(since) {
+			dinners = append(dinners, dinner)
+		}
+	}
+
+	return dinners, nil
+}
+
+// DishFromInfo builds a dish from the JSON returned by the LLM (GetDishInfo or SuggestDinnerOptions)
+func DishFromInfo(info map[string]interface{}) models.Dish {
+	name, _ := info["name"].(string)
+	cuisine, _ := info["cuisine"].(string)
+	description, _ := info["description"].(string)
+
+	ingredientsList, ok := info["ingredients_needed"].([]interface{})
+	if !ok {
+		// Try alternative key
+		ingredientsList, _ = info["ingredients"].([]interface{})
+	}
+	instructionsList, _ := info["instructions"].([]interface{})
+
+	servings, _ := info["servings"].(float64)
+
+	return models.Dish{
+		Name:         name,
+		Cuisine:      cuisine,
+		Description:  description,
+		Ingredients:  toStrings(ingredientsList),
+		Instructions: toStrings(instructionsList),
+		Servings:     int(servings),
+		Nutrition:    nutrition.FromInfo(info),
+	}
+}
 
-	return result, nil
+// toStrings converts a decoded JSON array to a string slice, skipping non-strings
+func toStrings(values []interface{}) []string {
+	result := make([]string, 0, len(values))
+	for _, value := range values {
+		if str, ok := value.(string); ok {
+			result = append(result, str)
+		}
+	}
+	return result
 }
 
-// CreateDinner creates a new dinner event
+// CreateDinner creates a new dinner event, cooked for as many people as the channel usually cooks for
+// The dish is saved with its nutrients per serving, for the cook's card and the weekly report
 func (s *Service) CreateDinner(channelID int64, dish models.Dish, cook string) (*models.Dinner, error) {
 	dinner := &models.Dinner{
 		ID:        fmt.Sprintf("dinner:%d:%d", channelID, time.Now().Unix()),
 		ChannelID: channelID,
-		Dish:      dish,
+		Dish:      s.nutritionService.Annotate(dish),
 		Cook:      cook,
 		StartedAt: time.Now(),
 		Ratings:   make(map[string]int),
+		Servings:  s.PortionsFor(channelID, dish),
 	}
 
 	err := s.store.Set(dinner.ID, dinner)
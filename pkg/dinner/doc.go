@@ -1,3 +1,8 @@
 // Package dinner provides functionality for dinner planning and suggestions.
-// It handles suggesting dishes based on available ingredients and cuisine preferences.
+// It handles suggesting dishes based on available ingredients and cuisine preferences,
+// drawing from the family's own recipe book and a base catalog shared by all channels.
+// Candidate dishes are ranked by fridge coverage, near-expiry ingredients, recent repeats,
+// past ratings and cuisine preference, and each pick comes with a short explanation.
+// Dinners are scaled to the number of people eating, which the family can adjust,
+// and dishes carry their estimated nutrients per serving.
 package dinner
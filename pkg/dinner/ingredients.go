@@ -1,47 +1,93 @@
 package dinner
 
 import (
+	"slices"
+	"sort"
 	"strings"
+
+	"github.com/korjavin/whatsfordinner/pkg/ingredients"
 )
 
 // CompareIngredients compares the ingredients needed for a dish with what's in the fridge
 // Returns a list of missing ingredients
 func CompareIngredients(neededIngredients []string, fridgeIngredients []string) []string {
-	// Convert all ingredients to lowercase for case-insensitive comparison
-	normalizedFridge := make(map[string]bool)
+	_, missing := MatchIngredients(neededIngredients, fridgeIngredients)
+	return missing
+}
+
+// MatchIngredients splits the ingredients needed for a dish into the ones found in the fridge and the missing ones
+// Matched entries are the fridge ingredient names, so callers can look them up in the fridge
+func MatchIngredients(neededIngredients []string, fridgeIngredients []string) (matched []string, missing []string) {
+	// Compare canonical names, so "Tomatoes" matches "помидоры"
+	normalizedFridge := make(map[string]string)
 	for _, ingredient := range fridgeIngredients {
-		normalizedFridge[normalizeIngredient(ingredient)] = true
+		normalized := normalizeIngredient(ingredient)
+		if normalized != "" {
+			normalizedFridge[normalized] = ingredient
+		}
 	}
-	
+
+	// Look for similar ingredients in a fixed order, the most specific name first, so the match is the same every time
+	names := make([]string, 0, len(normalizedFridge))
+	for name := range normalizedFridge {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		wordsI, wordsJ := len(strings.Fields(names[i])), len(strings.Fields(names[j]))
+		if wordsI != wordsJ {
+			return wordsI > wordsJ
+		}
+		return names[i] < names[j]
+	})
+
 	// Check which ingredients are missing
-	var missingIngredients []string
 	for _, ingredient := range neededIngredients {
 		normalized := normalizeIngredient(ingredient)
-		
-		// Check if the ingredient or a similar one is in the fridge
+		if normalized == "" {
+			continue
+		}
+
+		// Same canonical ingredient first
+		if original, ok := normalizedFridge[normalized]; ok {
+			matched = append(matched, original)
+			continue
+		}
+
+		// Then one whose name is made of whole words of the other, e.g. "chicken" for "chicken thigh fillet",
+		// but not "egg" for "eggplant"
 		found := false
-		for fridgeIngredient := range normalizedFridge {
-			if strings.Contains(fridgeIngredient, normalized) || strings.Contains(normalized, fridgeIngredient) {
+		for _, name := range names {
+			if containsWords(normalized, name) || containsWords(name, normalized) {
+				matched = append(matched, normalizedFridge[name])
 				found = true
 				break
 			}
 		}
-		
+
 		if !found {
-			missingIngredients = append(missingIngredients, ingredient)
+			missing = append(missing, ingredient)
+		}
+	}
+
+	return matched, missing
+}
+
+// containsWords reports whether the words of part appear in name one after another, as whole words
+func containsWords(name, part string) bool {
+	nameWords, partWords := strings.Fields(name), strings.Fields(part)
+	if len(partWords) == 0 {
+		return false
+	}
+	for i := 0; i+len(partWords) <= len(nameWords); i++ {
+		if slices.Equal(nameWords[i:i+len(partWords)], partWords) {
+			return true
 		}
 	}
-	
-	return missingIngredients
+	return false
 }
 
 // normalizeIngredient normalizes an ingredient name for comparison
+// Quantities like "200 g spaghetti" or "eggs (2)" are dropped and the name is canonicalized
 func normalizeIngredient(ingredient string) string {
-	// Remove quantity if present
-	if idx := strings.Index(ingredient, "("); idx > 0 {
-		ingredient = ingredient[:idx]
-	}
-	
-	// Convert to lowercase and trim spaces
-	return strings.ToLower(strings.TrimSpace(ingredient))
+	return ingredients.Canonicalize(ingredient)
 }
@@ -0,0 +1,68 @@
+package dinner
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMatchIngredients(t *testing.T) {
+	tests := []struct {
+		name        string
+		needed      []string
+		fridge      []string
+		wantMatched []string
+		wantMissing []string
+	}{
+		{
+			name:        "canonical names",
+			needed:      []string{"200 g Tomatoes", "eggs (2)"},
+			fridge:      []string{"tomato", "egg"},
+			wantMatched: []string{"tomato", "egg"},
+		},
+		{
+			name:        "whole words of a longer name",
+			needed:      []string{"chicken thigh fillet"},
+			fridge:      []string{"chicken"},
+			wantMatched: []string{"chicken"},
+		},
+		{
+			name:        "a longer name in the fridge",
+			needed:      []string{"cheese"},
+			fridge:      []string{"goat cheese"},
+			wantMatched: []string{"goat cheese"},
+		},
+		{
+			name:        "no matches inside words",
+			needed:      []string{"egg", "pea", "corn"},
+			fridge:      []string{"eggplant", "peanut", "chickpea", "pear", "peppercorn"},
+			wantMissing: []string{"egg", "pea", "corn"},
+		},
+		{
+			name:        "the most specific name wins",
+			needed:      []string{"smoked goat cheese"},
+			fridge:      []string{"cheese", "goat cheese", "smoked"},
+			wantMatched: []string{"goat cheese"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matched, missing := MatchIngredients(tt.needed, tt.fridge)
+			if !reflect.DeepEqual(matched, tt.wantMatched) || !reflect.DeepEqual(missing, tt.wantMissing) {
+				t.Fatalf("MatchIngredients = %v, %v; want %v, %v", matched, missing, tt.wantMatched, tt.wantMissing)
+			}
+		})
+	}
+}
+
+func TestMatchIngredientsIsStable(t *testing.T) {
+	needed := []string{"cheese"}
+	fridge := []string{"goat cheese", "blue cheese", "cream cheese", "feta cheese"}
+
+	first, _ := MatchIngredients(needed, fridge)
+	for i := 0; i < 50; i++ {
+		if matched, _ := MatchIngredients(needed, fridge); !reflect.DeepEqual(matched, first) {
+			t.Fatalf("matched %v, then %v", first, matched)
+		}
+	}
+}
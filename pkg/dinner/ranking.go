@@ -0,0 +1,209 @@
+package dinner
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/korjavin/whatsfordinner/pkg/fridge"
+	"github.com/korjavin/whatsfordinner/pkg/models"
+)
+
+// RepeatWindow is how long a cooked dish is penalized to avoid eating the same thing again
+const RepeatWindow = 14 * 24 * time.Hour
+
+// RankingWeights controls how much each signal contributes to a dish's score
+type RankingWeights struct {
+	Coverage   float64 // Share of the dish's ingredients that are in the fridge
+	Expiring   float64 // Bonus per fridge ingredient that should be used soon
+	Repeat     float64 // Penalty for a dish cooked just now, fading out over RepeatWindow
+	Rating     float64 // Bonus (or penalty) based on how the family rated the dish before
+	Cuisine    float64 // Bonus for the family's preferred cuisines
+	Randomness float64 // Maximum random jitter, so equal dishes don't always come in the same order
+}
+
+// DefaultRankingWeights are the weights used unless a caller overrides them
+var DefaultRankingWeights = RankingWeights{
+	Coverage:   1.0,
+	Expiring:   0.25,
+	Repeat:     0.6,
+	Rating:     0.4,
+	Cuisine:    0.3,
+	Randomness: 0.1,
+}
+
+// RankingContext holds everything the ranker needs to know about a family
+type RankingContext struct {
+	Fridge   []models.Ingredient
+	History  []models.Dinner
+	Cuisines []string
+	Now      time.Time
+}
+
+// RankedDish is a dish together with its score and the facts behind it
+type RankedDish struct {
+	Dish             models.Dish
+	Score            float64
+	MatchedCount     int       // Number of dish ingredients found in the fridge
+	TotalCount       int       // Number of ingredients the dish needs
+	Missing          []string  // Dish ingredients that aren't in the fridge
+	UsesSoon         []string  // Fridge ingredients the dish uses up before they expire
+	LastCooked       time.Time // When the family last had this dish, zero if never
+	AverageRating    float64   // Average past rating, zero if never rated
+	PreferredCuisine bool
+	Warnings         []string // Family members' restrictions the dish breaks, e.g. "chicken – @anna is vegetarian"
+}
+
+// Ranker scores and orders dishes for a family
+type Ranker struct {
+	weights RankingWeights
+	rng     *rand.Rand
+	mu      sync.Mutex // rand.Rand is not safe for concurrent use
+}
+
+// NewRanker creates a new ranker
+// Pass a fixed source to get a reproducible order, or nil to seed from the clock
+func NewRanker(weights RankingWeights, source rand.Source) *Ranker {
+	if source == nil {
+		source = rand.NewSource(time.Now().UnixNano())
+	}
+	return &Ranker{
+		weights: weights,
+		rng:     rand.New(source),
+	}
+}
+
+// Rank scores the dishes and returns them best first
+func (r *Ranker) Rank(dishes []models.Dish, ctx RankingContext) []RankedDish {
+	if ctx.Now.IsZero() {
+		ctx.Now = time.Now()
+	}
+
+	fridgeNames := make([]string, 0, len(ctx.Fridge))
+	expiring := make(map[string]bool)
+	for _, ingredient := range ctx.Fridge {
+		fridgeNames = append(fridgeNames, ingredient.Name)
+		if fridge.IsExpiringSoon(ingredient, ctx.Now, fridge.UseSoonWindow) {
+			expiring[ingredient.Name] = true
+		}
+	}
+
+	ranked := make([]RankedDish, 0, len(dishes))
+	for _, dish := range dishes {
+		rankedDish := RankedDish{Dish: dish}
+
+		// Fridge coverage, using the same matching as the missing-ingredient check
+		matched, missing := MatchIngredients(dish.Ingredients, fridgeNames)
+		rankedDish.MatchedCount = len(matched)
+		rankedDish.TotalCount = len(matched) + len(missing)
+		rankedDish.Missing = missing
+		if rankedDish.TotalCount > 0 {
+			rankedDish.Score += r.weights.Coverage * float64(rankedDish.MatchedCount) / float64(rankedDish.TotalCount)
+		}
+
+		// Near-expiry ingredients the dish would use up
+		seen := make(map[string]bool)
+		for _, name := range matched {
+			if expiring[name] && !seen[name] {
+				seen[name] = true
+				rankedDish.UsesSoon = append(rankedDish.UsesSoon, name)
+			}
+		}
+		rankedDish.Score += r.weights.Expiring * float64(len(rankedDish.UsesSoon))
+
+		// Recent repeats and past ratings
+		var ratingSum float64
+		var ratingCount int
+		for _, past := range ctx.History {
+			if !strings.EqualFold(past.Dish.Name, dish.Name) {
+				continue
+			}
+			if past.StartedAt.After(rankedDish.LastCooked) {
+				rankedDish.LastCooked = past.StartedAt
+			}
+			if past.AverageRating > 0 {
+				ratingSum += past.AverageRating
+				ratingCount++
+			}
+		}
+
+		if !rankedDish.LastCooked.IsZero() {
+			age := ctx.Now.Sub(rankedDish.LastCooked)
+			if age < RepeatWindow {
+				rankedDish.Score -= r.weights.Repeat * (1 - float64(age)/float64(RepeatWindow))
+			}
+		}
+
+		if ratingCount > 0 {
+			rankedDish.AverageRating = ratingSum / float64(ratingCount)
+			// Map 1..5 stars to -1..1 so mediocre dishes are pushed down
+			rankedDish.Score += r.weights.Rating * (rankedDish.AverageRating - 3) / 2
+		}
+
+		// Cuisine preference
+		for _, cuisine := range ctx.Cuisines {
+			if strings.EqualFold(strings.TrimSpace(cuisine), strings.TrimSpace(dish.Cuisine)) {
+				rankedDish.PreferredCuisine = true
+				rankedDish.Score += r.weights.Cuisine
+				break
+			}
+		}
+
+		// Controlled randomness
+		r.mu.Lock()
+		rankedDish.Score += r.weights.Randomness * r.rng.Float64()
+		r.mu.Unlock()
+
+		ranked = append(ranked, rankedDish)
+	}
+
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return ranked[i].Score > ranked[j].Score
+	})
+
+	return ranked
+}
+
+// Reasons returns short, human-readable reasons why the dish was picked
+func (r RankedDish) Reasons(now time.Time) []string {
+	var reasons []string
+
+	if r.TotalCount > 0 {
+		reasons = append(reasons, fmt.Sprintf("uses %d/%d of what you have", r.MatchedCount, r.TotalCount))
+	}
+
+	if len(r.UsesSoon) > 0 {
+		reasons = append(reasons, "uses up "+strings.Join(r.UsesSoon, ", "))
+	}
+
+	if r.AverageRating > 0 {
+		reasons = append(reasons, fmt.Sprintf("rated %.1f⭐", r.AverageRating))
+	}
+
+	if !r.LastCooked.IsZero() {
+		days := int(math.Floor(now.Sub(r.LastCooked).Hours() / 24))
+		switch {
+		case days < 1:
+			reasons = append(reasons, "cooked today")
+		case days == 1:
+			reasons = append(reasons, "cooked yesterday")
+		default:
+			reasons = append(reasons, fmt.Sprintf("last cooked %d days ago", days))
+		}
+	}
+
+	for _, warning := range r.Warnings {
+		reasons = append(reasons, "⚠️ "+warning)
+	}
+
+	return reasons
+}
+
+// Explanation joins the reasons into one line for poll messages
+func (r RankedDish) Explanation(now time.Time) string {
+	return strings.Join(r.Reasons(now), " · ")
+}
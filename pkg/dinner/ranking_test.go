@@ -0,0 +1,135 @@
+package dinner
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/korjavin/whatsfordinner/pkg/models"
+)
+
+func TestRankScores(t *testing.T) {
+	now := time.Date(2025, 10, 20, 18, 0, 0, 0, time.UTC)
+	weights := DefaultRankingWeights
+	weights.Randomness = 0
+	ranker := NewRanker(weights, nil)
+
+	pasta := models.Dish{Name: "Pasta", Cuisine: "Italian", Ingredients: []string{"200 g tomatoes", "pasta"}}
+	rice := models.Dish{Name: "Rice", Ingredients: []string{"rice"}}
+	ranked := ranker.Rank([]models.Dish{rice, pasta}, RankingContext{
+		Fridge:   []models.Ingredient{{Name: "tomato", ExpiresAt: now.Add(time.Hour)}},
+		History:  []models.Dinner{{Dish: models.Dish{Name: "pasta"}, StartedAt: now.Add(-RepeatWindow / 2), AverageRating: 5}},
+		Cuisines: []string{" italian "},
+		Now:      now,
+	})
+
+	if len(ranked) != 2 || ranked[0].Dish.Name != "Pasta" {
+		t.Fatalf("ranked %v, want Pasta first", ranked)
+	}
+
+	// Half of the ingredients, one expiring, cooked half a repeat window ago, rated 5, a preferred cuisine
+	want := weights.Coverage*0.5 + weights.Expiring - weights.Repeat*0.5 + weights.Rating + weights.Cuisine
+	top := ranked[0]
+	if math.Abs(top.Score-want) > 1e-9 {
+		t.Errorf("score = %v, want %v", top.Score, want)
+	}
+	if top.MatchedCount != 1 || top.TotalCount != 2 || len(top.Missing) != 1 || top.Missing[0] != "pasta" {
+		t.Errorf("coverage = %d/%d missing %v, want 1/2 missing [pasta]", top.MatchedCount, top.TotalCount, top.Missing)
+	}
+	if len(top.UsesSoon) != 1 || top.UsesSoon[0] != "tomato" || !top.PreferredCuisine || top.AverageRating != 5 {
+		t.Errorf("facts = %+v", top)
+	}
+	if ranked[1].Score != 0 {
+		t.Errorf("score of a dish with nothing going for it = %v, want 0", ranked[1].Score)
+	}
+}
+
+func TestRankRepeatPenaltyFades(t *testing.T) {
+	now := time.Date(2025, 10, 20, 18, 0, 0, 0, time.UTC)
+	weights := RankingWeights{Repeat: 1}
+	ranker := NewRanker(weights, nil)
+	dish := models.Dish{Name: "Soup"}
+
+	tests := []struct {
+		age  time.Duration
+		want float64
+	}{
+		{0, -1},
+		{RepeatWindow / 4, -0.75},
+		{RepeatWindow, 0},
+		{2 * RepeatWindow, 0},
+	}
+	for _, tt := range tests {
+		ranked := ranker.Rank([]models.Dish{dish}, RankingContext{
+			History: []models.Dinner{{Dish: dish, StartedAt: now.Add(-tt.age)}},
+			Now:     now,
+		})
+		if math.Abs(ranked[0].Score-tt.want) > 1e-9 {
+			t.Errorf("cooked %s ago: score = %v, want %v", tt.age, ranked[0].Score, tt.want)
+		}
+	}
+}
+
+func TestRankSeededRandomness(t *testing.T) {
+	weights := RankingWeights{Randomness: 0.1}
+	dishes := []models.Dish{{Name: "A"}, {Name: "B"}, {Name: "C"}, {Name: "D"}}
+	ctx := RankingContext{Now: time.Date(2025, 10, 20, 18, 0, 0, 0, time.UTC)}
+
+	first := NewRanker(weights, rand.NewSource(42)).Rank(dishes, ctx)
+	second := NewRanker(weights, rand.NewSource(42)).Rank(dishes, ctx)
+	for i := range first {
+		if first[i].Dish.Name != second[i].Dish.Name || first[i].Score != second[i].Score {
+			t.Fatalf("the same seed ranked %v, then %v", first, second)
+		}
+		if first[i].Score < 0 || first[i].Score >= weights.Randomness {
+			t.Errorf("jitter %v is outside [0, %v)", first[i].Score, weights.Randomness)
+		}
+	}
+}
+
+func TestExplanation(t *testing.T) {
+	now := time.Date(2025, 10, 20, 18, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		dish RankedDish
+		want string
+	}{
+		{
+			name: "every reason",
+			dish: RankedDish{
+				MatchedCount:  2,
+				TotalCount:    3,
+				UsesSoon:      []string{"milk", "spinach"},
+				AverageRating: 4.5,
+				LastCooked:    now.Add(-3 * 24 * time.Hour),
+				Warnings:      []string{"cheese – @anna is vegan"},
+			},
+			want: "uses 2/3 of what you have · uses up milk, spinach · rated 4.5⭐ · last cooked 3 days ago · ⚠️ cheese – @anna is vegan",
+		},
+		{
+			name: "cooked yesterday",
+			dish: RankedDish{LastCooked: now.Add(-30 * time.Hour)},
+			want: "cooked yesterday",
+		},
+		{
+			name: "cooked today",
+			dish: RankedDish{LastCooked: now.Add(-time.Hour)},
+			want: "cooked today",
+		},
+		{
+			name: "nothing to say",
+			dish: RankedDish{},
+			want: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.dish.Explanation(now); got != tt.want {
+				t.Fatalf("Explanation = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package dinner
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/korjavin/whatsfordinner/pkg/attendance"
+	"github.com/korjavin/whatsfordinner/pkg/models"
+	"github.com/korjavin/whatsfordinner/pkg/quantity"
+)
+
+// DefaultServings is how many people a recipe is assumed to be for when it doesn't say
+const DefaultServings = 4
+
+// MaxServings is the most people a dinner can be scaled to
+const MaxServings = 20
+
+// ScaleDish returns the dish with its ingredient amounts scaled from its own servings to the given number
+func ScaleDish(dish models.Dish, servings int) models.Dish {
+	base := dish.Servings
+	if base <= 0 {
+		base = DefaultServings
+	}
+	if servings <= 0 || servings == base {
+		return dish
+	}
+
+	factor := float64(servings) / float64(base)
+	scaled := dish
+	scaled.Ingredients = make([]string, len(dish.Ingredients))
+	for i, ingredient := range dish.Ingredients {
+		scaled.Ingredients[i] = quantity.ScaleIngredient(ingredient, factor)
+	}
+	scaled.Servings = servings
+
+	return scaled
+}
+
+// Scaled returns a dinner's dish scaled to the number of people the cook is cooking for
+func Scaled(dinner *models.Dinner) models.Dish {
+	return ScaleDish(dinner.Dish, dinner.Servings)
+}
+
+// PortionsFor returns how many people a channel cooks a dish for: the people eating at home tonight,
+// the portions the family picked last time, the number of family members, or the dish's own servings
+// The people eating at home are counted the way the poll's quorum counts them, so the bot never gets a portion
+func (s *Service) PortionsFor(channelID int64, dish models.Dish) int {
+	var channelState models.ChannelState
+	if err := s.store.Get(fmt.Sprintf("channel:%d", channelID), &channelState); err == nil {
+		if current := attendance.Current(channelState, time.Now()); current != nil {
+			if len(current.Attending) > 0 || (len(current.Away) > 0 && channelState.MemberCount > len(current.Away)) {
+				return min(attendance.Eligible(current, channelState.MemberCount), MaxServings)
+			}
+		}
+		if channelState.Portions > 0 {
+			return channelState.Portions
+		}
+		if channelState.MemberCount > 0 {
+			return min(channelState.MemberCount, MaxServings)
+		}
+	}
+
+	if dish.Servings > 0 {
+		return dish.Servings
+	}
+	return DefaultServings
+}
+
+// ScaleForChannel scales a dish to the number of people the channel usually cooks for
+func (s *Service) ScaleForChannel(channelID int64, dish models.Dish) models.Dish {
+	return ScaleDish(dish, s.PortionsFor(channelID, dish))
+}
+
+// SetServings changes how many people a dinner is cooked for and remembers it for the channel's next dinners
+func (s *Service) SetServings(dinnerID string, servings int) (*models.Dinner, error) {
+	if servings < 1 || servings > MaxServings {
+		return nil, fmt.Errorf("servings must be between 1 and %d", MaxServings)
+	}
+
+	var dinner models.Dinner
+	if err := s.store.Get(dinnerID, &dinner); err != nil {
+		return nil, fmt.Errorf("failed to get dinner: %w", err)
+	}
+
+	dinner.Servings = servings
+	if err := s.store.Set(dinnerID, dinner); err != nil {
+		return nil, fmt.Errorf("failed to save dinner: %w", err)
+	}
+
+	// Keep the channel's copy of the dinner in sync and remember the portions
+	channelKey := fmt.Sprintf("channel:%d", dinner.ChannelID)
+	var channelState models.ChannelState
+	if err := s.store.Get(channelKey, &channelState); err == nil {
+		if channelState.CurrentDinner != nil && channelState.CurrentDinner.ID == dinnerID {
+			channelState.CurrentDinner.Servings = servings
+		}
+		channelState.Portions = servings
+		channelState.LastActivity = time.Now()
+		if err := s.store.Set(channelKey, channelState); err != nil {
+			s.logger.Error("Failed to remember portions for channel %d: %v", dinner.ChannelID, err)
+		}
+	}
+
+	s.logger.Info("Cooking %s for %d in channel %d", dinner.Dish.Name, servings, dinner.ChannelID)
+	return &dinner, nil
+}
@@ -1,3 +1,4 @@
 // Package fridge provides functionality for managing the fridge inventory.
 // It handles adding, removing, and listing ingredients, as well as extracting ingredients from photos.
+// Quantities are stored in structured form so cooking a dish decrements them instead of dropping whole items.
 package fridge
@@ -0,0 +1,148 @@
+package fridge
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/korjavin/whatsfordinner/pkg/ingredients"
+	"github.com/korjavin/whatsfordinner/pkg/models"
+)
+
+// UseSoonWindow is how close to its expiry an ingredient has to be to count as "use soon"
+const UseSoonWindow = 48 * time.Hour
+
+// shelfLives holds the default shelf life per category once an item is in the fridge
+// Categories not listed here (e.g. pantry staples) don't expire by default
+var shelfLives = map[string]time.Duration{
+	ingredients.CategoryDairy:      7 * 24 * time.Hour,
+	ingredients.CategoryMeat:       3 * 24 * time.Hour,
+	ingredients.CategoryFish:       2 * 24 * time.Hour,
+	ingredients.CategoryGreens:     4 * 24 * time.Hour,
+	ingredients.CategoryVegetables: 7 * 24 * time.Hour,
+	ingredients.CategoryFruit:      7 * 24 * time.Hour,
+	ingredients.CategoryBakery:     4 * 24 * time.Hour,
+	ingredients.CategoryEggs:       21 * 24 * time.Hour,
+}
+
+// DefaultShelfLife returns the default shelf life for a category, or zero if it doesn't expire
+func DefaultShelfLife(category string) time.Duration {
+	return shelfLives[category]
+}
+
+// ExpiryDate returns when an ingredient expires: its best-before date if set,
+// otherwise the time it was added plus the default shelf life of its category
+// The second return value is false if the ingredient doesn't expire
+func ExpiryDate(ingredient models.Ingredient) (time.Time, bool) {
+	if !ingredient.ExpiresAt.IsZero() {
+		return ingredient.ExpiresAt, true
+	}
+
+	category := ingredient.Category
+	if category == "" {
+		category = ingredients.CategoryOf(ingredient.Name)
+	}
+
+	shelfLife := DefaultShelfLife(category)
+	if shelfLife == 0 || ingredient.AddedAt.IsZero() {
+		return time.Time{}, false
+	}
+
+	return ingredient.AddedAt.Add(shelfLife), true
+}
+
+// IsExpiringSoon reports whether an ingredient expires within the given window (or has already expired)
+func IsExpiringSoon(ingredient models.Ingredient, now time.Time, within time.Duration) bool {
+	expiresAt, ok := ExpiryDate(ingredient)
+	return ok && expiresAt.Before(now.Add(within))
+}
+
+// ExpiringSoon returns the ingredients that expire within the given window, soonest first
+// Already expired ingredients are included
+func (s *Service) ExpiringSoon(channelID int64, within time.Duration) ([]models.Ingredient, error) {
+	items, err := s.ListIngredients(channelID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	expiring := make([]models.Ingredient, 0)
+	for _, ingredient := range items {
+		if IsExpiringSoon(ingredient, now, within) {
+			expiring = append(expiring, ingredient)
+		}
+	}
+
+	sort.Slice(expiring, func(i, j int) bool {
+		expiresI, _ := ExpiryDate(expiring[i])
+		expiresJ, _ := ExpiryDate(expiring[j])
+		return expiresI.Before(expiresJ)
+	})
+
+	return expiring, nil
+}
+
+// SetBestBefore sets an explicit best-before date for an ingredient in the fridge
+func (s *Service) SetBestBefore(channelID int64, name string, bestBefore time.Time) error {
+	fridge, err := s.GetFridge(channelID)
+	if err != nil {
+		return err
+	}
+
+	key, ok := findIngredient(fridge, name)
+	if !ok {
+		return fmt.Errorf("ingredient not found: %s", name)
+	}
+
+	ingredient := fridge.Ingredients[key]
+	ingredient.ExpiresAt = bestBefore
+	fridge.Ingredients[key] = ingredient
+	fridge.LastUpdated = time.Now()
+
+	s.logger.Info("Set best-before date of %s in fridge %d to %s", key, channelID, bestBefore.Format("2006-01-02"))
+	return s.store.Set(fridge.ID, fridge)
+}
+
+// ParseBestBefore parses a best-before date as typed by a user
+// Supported forms: "2025-10-20", "20.10", "20.10.2025", "today", "tomorrow", "3d"
+// Dates resolve to the end of that day
+func ParseBestBefore(s string, now time.Time) (time.Time, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	endOfDay := func(t time.Time) time.Time {
+		return time.Date(t.Year(), t.Month(), t.Day(), 23, 59, 59, 0, t.Location())
+	}
+
+	switch s {
+	case "today", "сегодня":
+		return endOfDay(now), nil
+	case "tomorrow", "завтра":
+		return endOfDay(now.AddDate(0, 0, 1)), nil
+	}
+
+	if days, found := strings.CutSuffix(s, "d"); found {
+		if n, err := strconv.Atoi(days); err == nil && n >= 0 {
+			return endOfDay(now.AddDate(0, 0, n)), nil
+		}
+	}
+
+	for _, layout := range []string{"2006-01-02", "02.01.2006", "2.1.2006"} {
+		if t, err := time.ParseInLocation(layout, s, now.Location()); err == nil {
+			return endOfDay(t), nil
+		}
+	}
+
+	// Day and month only: assume the next occurrence of that date
+	for _, layout := range []string{"02.01", "2.1"} {
+		if t, err := time.ParseInLocation(layout, s, now.Location()); err == nil {
+			date := time.Date(now.Year(), t.Month(), t.Day(), 0, 0, 0, 0, now.Location())
+			if endOfDay(date).Before(now) {
+				date = date.AddDate(1, 0, 0)
+			}
+			return endOfDay(date), nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("invalid date: %q", s)
+}
@@ -0,0 +1,128 @@
+package fridge
+
+import (
+	"testing"
+	"time"
+
+	"github.com/korjavin/whatsfordinner/pkg/ingredients"
+	"github.com/korjavin/whatsfordinner/pkg/models"
+)
+
+func TestExpiryDate(t *testing.T) {
+	added := time.Date(2025, 10, 1, 12, 0, 0, 0, time.UTC)
+	bestBefore := time.Date(2025, 10, 3, 23, 59, 59, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		ingredient models.Ingredient
+		want       time.Time
+		wantOK     bool
+	}{
+		{"best-before date wins", models.Ingredient{Name: "milk", Category: ingredients.CategoryDairy, AddedAt: added, ExpiresAt: bestBefore}, bestBefore, true},
+		{"default shelf life of the category", models.Ingredient{Name: "milk", Category: ingredients.CategoryDairy, AddedAt: added}, added.Add(7 * 24 * time.Hour), true},
+		{"category looked up from the name", models.Ingredient{Name: "chicken", AddedAt: added}, added.Add(3 * 24 * time.Hour), true},
+		{"pantry staples don't expire", models.Ingredient{Name: "rice", Category: ingredients.CategoryGrains, AddedAt: added}, time.Time{}, false},
+		{"unknown when added", models.Ingredient{Name: "milk", Category: ingredients.CategoryDairy}, time.Time{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := ExpiryDate(tt.ingredient)
+			if ok != tt.wantOK || !got.Equal(tt.want) {
+				t.Fatalf("ExpiryDate = %v, %v; want %v, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestIsExpiringSoon(t *testing.T) {
+	now := time.Date(2025, 10, 10, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		expiresAt time.Time
+		want      bool
+	}{
+		{"already expired", now.Add(-time.Hour), true},
+		{"within the window", now.Add(47 * time.Hour), true},
+		{"after the window", now.Add(49 * time.Hour), false},
+		{"doesn't expire", time.Time{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ingredient := models.Ingredient{Name: "rice", Category: ingredients.CategoryGrains, ExpiresAt: tt.expiresAt}
+			if got := IsExpiringSoon(ingredient, now, UseSoonWindow); got != tt.want {
+				t.Fatalf("IsExpiringSoon = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseBestBefore(t *testing.T) {
+	now := time.Date(2025, 10, 20, 15, 0, 0, 0, time.UTC)
+	endOf := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, 23, 59, 59, 0, time.UTC)
+	}
+
+	tests := []struct {
+		in      string
+		want    time.Time
+		wantErr bool
+	}{
+		{"today", endOf(2025, 10, 20), false},
+		{"Tomorrow", endOf(2025, 10, 21), false},
+		{"завтра", endOf(2025, 10, 21), false},
+		{"3d", endOf(2025, 10, 23), false},
+		{"2025-11-02", endOf(2025, 11, 2), false},
+		{"02.11.2025", endOf(2025, 11, 2), false},
+		{"2.11.2025", endOf(2025, 11, 2), false},
+		{"25.10", endOf(2025, 10, 25), false},
+		{"20.10", endOf(2025, 10, 20), false},
+		{"1.3", endOf(2026, 3, 1), false},
+		{"15.10", endOf(2026, 10, 15), false},
+		{"soon", time.Time{}, true},
+		{"-2d", time.Time{}, true},
+		{"31.02.2025", time.Time{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseBestBefore(tt.in, now)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseBestBefore(%q) error = %v, want error %v", tt.in, err, tt.wantErr)
+			}
+			if !got.Equal(tt.want) {
+				t.Fatalf("ParseBestBefore(%q) = %v, want %v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAddIngredientKeepsOlderBatchDates(t *testing.T) {
+	s := newTestService(t)
+	if err := s.AddIngredient(1, "milk", "1 l"); err != nil {
+		t.Fatalf("AddIngredient: %v", err)
+	}
+	fridge, err := s.GetFridge(1)
+	if err != nil {
+		t.Fatalf("GetFridge: %v", err)
+	}
+	older := fridge.Ingredients["milk"]
+	older.AddedAt = older.AddedAt.Add(-6 * 24 * time.Hour)
+	fridge.Ingredients["milk"] = older
+	if err := s.store.Set(fridge.ID, fridge); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+
+	if err := s.AddIngredient(1, "milk", "1 l"); err != nil {
+		t.Fatalf("AddIngredient: %v", err)
+	}
+	expiring, err := s.ExpiringSoon(1, UseSoonWindow)
+	if err != nil {
+		t.Fatalf("ExpiringSoon: %v", err)
+	}
+	if len(expiring) != 1 || expiring[0].Name != "milk" || expiring[0].Quantity != "2 l" {
+		t.Fatalf("expiring = %+v, want the merged milk to keep the older batch's shelf life", expiring)
+	}
+}
@@ -2,10 +2,13 @@ package fridge
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
+	"github.com/korjavin/whatsfordinner/pkg/ingredients"
 	"github.com/korjavin/whatsfordinner/pkg/logger"
 	"github.com/korjavin/whatsfordinner/pkg/models"
+	"github.com/korjavin/whatsfordinner/pkg/quantity"
 	"github.com/korjavin/whatsfordinner/pkg/storage"
 )
 
@@ -43,12 +46,35 @@ func (s *Service) GetFridge(channelID int64) (*models.Fridge, error) {
 		}
 	}
 
+	// Fridges saved before names were canonicalized may hold "Tomatoes" and "tomato" side by side
+	if canonicalizeKeys(&fridge) {
+		s.logger.Info("Merged ingredient names in fridge %d into canonical names", channelID)
+		if err := s.store.Set(fridgeKey, fridge); err != nil {
+			return nil, fmt.Errorf("failed to save fridge: %w", err)
+		}
+	}
+
 	return &fridge, nil
 }
 
 // AddIngredient adds an ingredient to the fridge
-func (s *Service) AddIngredient(channelID int64, name, quantity string) error {
-	s.logger.Info("Adding ingredient to fridge %d: %s (quantity: %s)", channelID, name, quantity)
+// If no quantity is given, it's looked for in the name itself (e.g. "milk 1 l")
+// The name is stored in its canonical form, so "Tomatoes" and "помидоры" are the same item
+// Adding an ingredient that's already in the fridge adds up compatible amounts
+func (s *Service) AddIngredient(channelID int64, name, quantityStr string) error {
+	if quantityStr == "" {
+		if parsedName, parsed, ok := quantity.ParseIngredient(name); ok {
+			name = parsedName
+			quantityStr = parsed.String()
+		}
+	}
+
+	name = ingredients.Canonicalize(name)
+	if name == "" {
+		return fmt.Errorf("empty ingredient name")
+	}
+
+	s.logger.Info("Adding ingredient to fridge %d: %s (quantity: %s)", channelID, name, quantityStr)
 
 	fridge, err := s.GetFridge(channelID)
 	if err != nil {
@@ -56,12 +82,13 @@ func (s *Service) AddIngredient(channelID int64, name, quantity string) error {
 		return err
 	}
 
-	fridge.Ingredients[name] = models.Ingredient{
-		Name:     name,
-		Quantity: quantity,
-		AddedAt:  time.Now(),
+	ingredient := newIngredient(name, quantityStr)
+	if existing, ok := fridge.Ingredients[name]; ok {
+		ingredient = mergeIngredients(existing, ingredient)
 	}
 
+	fridge.Ingredients[name] = ingredient
+
 	fridge.LastUpdated = time.Now()
 
 	err = s.store.Set(fridge.ID, fridge)
@@ -81,7 +108,9 @@ func (s *Service) RemoveIngredient(channelID int64, name string) error {
 		return err
 	}
 
-	delete(fridge.Ingredients, name)
+	if key, ok := findIngredient(fridge, name); ok {
+		delete(fridge.Ingredients, key)
+	}
 	fridge.LastUpdated = time.Now()
 
 	return s.store.Set(fridge.ID, fridge)
@@ -111,7 +140,7 @@ func (s *Service) HasIngredients(channelID int64, ingredientNames []string) (boo
 
 	missing := make([]string, 0)
 	for _, name := range ingredientNames {
-		if _, ok := fridge.Ingredients[name]; !ok {
+		if _, ok := findIngredient(fridge, name); !ok {
 			missing = append(missing, name)
 		}
 	}
@@ -134,18 +163,18 @@ func (s *Service) ResetFridge(channelID int64) error {
 }
 
 // UpdateIngredients updates multiple ingredients at once
-func (s *Service) UpdateIngredients(channelID int64, ingredients map[string]string) error {
+func (s *Service) UpdateIngredients(channelID int64, items map[string]string) error {
 	fridge, err := s.GetFridge(channelID)
 	if err != nil {
 		return err
 	}
 
-	for name, quantity := range ingredients {
-		fridge.Ingredients[name] = models.Ingredient{
-			Name:     name,
-			Quantity: quantity,
-			AddedAt:  time.Now(),
+	for name, quantityStr := range items {
+		name = ingredients.Canonicalize(name)
+		if name == "" {
+			continue
 		}
+		fridge.Ingredients[name] = newIngredient(name, quantityStr)
 	}
 
 	fridge.LastUpdated = time.Now()
@@ -161,10 +190,191 @@ func (s *Service) RemoveIngredients(channelID int64, ingredientNames []string) e
 	}
 
 	for _, name := range ingredientNames {
-		delete(fridge.Ingredients, name)
+		if key, ok := findIngredient(fridge, name); ok {
+			delete(fridge.Ingredients, key)
+		}
 	}
 
 	fridge.LastUpdated = time.Now()
 
 	return s.store.Set(fridge.ID, fridge)
 }
+
+// ConsumeIngredients decrements the fridge by the ingredients used for a dish
+// Each entry is an ingredient line such as "200 g spaghetti" or "eggs (2)"
+// Items that are used up are removed; if either side has no comparable amount, e.g. "2 tomatoes"
+// from "500 g" of them, there's no way to tell what's left, so the item is left as it is and
+// returned among the unchanged ones for the family to check
+func (s *Service) ConsumeIngredients(channelID int64, used []string) (unchanged []string, err error) {
+	fridge, err := s.GetFridge(channelID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, line := range used {
+		name, needed, hasAmount := quantity.ParseIngredient(line)
+
+		key, ok := findIngredient(fridge, name)
+		if !ok {
+			s.logger.Debug("Ingredient %s is not in fridge %d, nothing to consume", name, channelID)
+			continue
+		}
+
+		ingredient := fridge.Ingredients[key]
+		available, known := IngredientQuantity(ingredient)
+		if !hasAmount || !known || !available.Compatible(needed) {
+			s.logger.Info("Leaving %s in fridge %d as it is (amounts not comparable)", key, channelID)
+			unchanged = append(unchanged, key)
+			continue
+		}
+
+		remaining, err := available.Sub(needed)
+		if err != nil {
+			s.logger.Error("Failed to subtract %s from %s: %v", needed, available, err)
+			continue
+		}
+
+		if remaining.IsEmpty() {
+			s.logger.Info("Ingredient %s is used up in fridge %d", key, channelID)
+			delete(fridge.Ingredients, key)
+			continue
+		}
+
+		setQuantity(&ingredient, remaining)
+		fridge.Ingredients[key] = ingredient
+		s.logger.Info("Ingredient %s in fridge %d decreased to %s", key, channelID, ingredient.Quantity)
+	}
+
+	fridge.LastUpdated = time.Now()
+
+	if err := s.store.Set(fridge.ID, fridge); err != nil {
+		return nil, err
+	}
+	return unchanged, nil
+}
+
+// IngredientQuantity returns the structured quantity of an ingredient
+// Older records only have the free-form string, so it's parsed on the fly
+func IngredientQuantity(ingredient models.Ingredient) (quantity.Quantity, bool) {
+	if ingredient.Unit != "" {
+		return quantity.New(ingredient.Amount, quantity.Unit(ingredient.Unit)), true
+	}
+
+	if ingredient.Quantity == "" {
+		return quantity.Quantity{}, false
+	}
+
+	parsed, err := quantity.Parse(ingredient.Quantity)
+	if err != nil {
+		return quantity.Quantity{}, false
+	}
+	return parsed, true
+}
+
+// newIngredient creates an ingredient, parsing the quantity into a structured amount when possible
+func newIngredient(name, quantityStr string) models.Ingredient {
+	ingredient := models.Ingredient{
+		Name:     name,
+		Quantity: quantityStr,
+		Category: ingredients.CategoryOf(name),
+		AddedAt:  time.Now(),
+	}
+
+	if quantityStr != "" {
+		if parsed, err := quantity.Parse(quantityStr); err == nil {
+			setQuantity(&ingredient, parsed)
+		}
+	}
+
+	return ingredient
+}
+
+// setQuantity stores a structured quantity on an ingredient and refreshes its display string
+func setQuantity(ingredient *models.Ingredient, q quantity.Quantity) {
+	q = q.Normalize()
+	ingredient.Amount = q.Amount
+	ingredient.Unit = string(q.Unit)
+	ingredient.Quantity = q.String()
+}
+
+// mergeIngredients combines a newly added batch with the one already in the fridge
+// Compatible amounts are added up; otherwise the existing stock is kept, with the new batch
+// noted next to it when they can't be added, e.g. "1 kg + 3 pcs"
+func mergeIngredients(existing, added models.Ingredient) models.Ingredient {
+	// Keep the older batch's dates, since it expires first: its best-before date, and when it was added
+	// for the default shelf life
+	added.ExpiresAt = existing.ExpiresAt
+	if !existing.AddedAt.IsZero() {
+		added.AddedAt = existing.AddedAt
+	}
+	existingQuantity, existingKnown := IngredientQuantity(existing)
+	addedQuantity, addedKnown := IngredientQuantity(added)
+
+	switch {
+	case existingKnown && addedKnown:
+		if total, err := existingQuantity.Add(addedQuantity); err == nil {
+			setQuantity(&added, total)
+			return added
+		}
+		added.Amount = existing.Amount
+		added.Unit = existing.Unit
+		added.Quantity = existing.Quantity + " + " + added.Quantity
+	case added.Quantity == "":
+		added.Amount = existing.Amount
+		added.Unit = existing.Unit
+		added.Quantity = existing.Quantity
+	case existing.Quantity != "":
+		added.Amount = existing.Amount
+		added.Unit = existing.Unit
+		added.Quantity = existing.Quantity + " + " + added.Quantity
+	}
+	return added
+}
+
+// canonicalizeKeys renames fridge items to their canonical names, merging duplicates
+// It reports whether anything changed
+func canonicalizeKeys(fridge *models.Fridge) bool {
+	keys := make([]string, 0, len(fridge.Ingredients))
+	for key := range fridge.Ingredients {
+		keys = append(keys, key)
+	}
+
+	changed := false
+	for _, key := range keys {
+		canonical := ingredients.Canonicalize(key)
+		if canonical == "" || canonical == key {
+			continue
+		}
+
+		ingredient := fridge.Ingredients[key]
+		delete(fridge.Ingredients, key)
+		ingredient.Name = canonical
+		if existing, ok := fridge.Ingredients[canonical]; ok {
+			ingredient = mergeIngredients(existing, ingredient)
+		}
+		fridge.Ingredients[canonical] = ingredient
+		changed = true
+	}
+	return changed
+}
+
+// findIngredient finds the fridge key for an ingredient name
+// Names are compared by their canonical form, so "Tomatoes" finds "tomato"
+func findIngredient(fridge *models.Fridge, name string) (string, bool) {
+	if _, ok := fridge.Ingredients[name]; ok {
+		return name, true
+	}
+
+	canonical := ingredients.Canonicalize(name)
+	if _, ok := fridge.Ingredients[canonical]; ok {
+		return canonical, true
+	}
+
+	for key := range fridge.Ingredients {
+		if strings.EqualFold(strings.TrimSpace(key), strings.TrimSpace(name)) || ingredients.Canonicalize(key) == canonical {
+			return key, true
+		}
+	}
+
+	return "", false
+}
@@ -0,0 +1,125 @@
+package fridge
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/korjavin/whatsfordinner/pkg/storage"
+)
+
+// newTestService creates a fridge service on a fresh store
+func newTestService(t *testing.T) *Service {
+	t.Helper()
+	store, err := storage.New(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to open store: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return New(store)
+}
+
+func TestConsumeIngredients(t *testing.T) {
+	tests := []struct {
+		name          string
+		stock         map[string]string // Name -> quantity in the fridge
+		used          []string
+		wantQuantity  map[string]string // Name -> quantity left; missing names are used up
+		wantUnchanged []string
+	}{
+		{
+			name:         "decrements compatible amounts",
+			stock:        map[string]string{"tomato": "500 g", "milk": "1 l"},
+			used:         []string{"200 g tomatoes", "milk 250 ml"},
+			wantQuantity: map[string]string{"tomato": "300 g", "milk": "750 ml"},
+		},
+		{
+			name:         "removes what's used up",
+			stock:        map[string]string{"egg": "2"},
+			used:         []string{"3 eggs"},
+			wantQuantity: map[string]string{},
+		},
+		{
+			name:          "leaves amounts that can't be compared",
+			stock:         map[string]string{"tomato": "500 g"},
+			used:          []string{"2 tomatoes"},
+			wantQuantity:  map[string]string{"tomato": "500 g"},
+			wantUnchanged: []string{"tomato"},
+		},
+		{
+			name:          "leaves items used without an amount",
+			stock:         map[string]string{"salt": "1 kg"},
+			used:          []string{"salt"},
+			wantQuantity:  map[string]string{"salt": "1 kg"},
+			wantUnchanged: []string{"salt"},
+		},
+		{
+			name:         "ignores what isn't in the fridge",
+			stock:        map[string]string{"milk": "1 l"},
+			used:         []string{"200 g flour"},
+			wantQuantity: map[string]string{"milk": "1 l"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestService(t)
+			for name, quantity := range tt.stock {
+				if err := s.AddIngredient(1, name, quantity); err != nil {
+					t.Fatalf("AddIngredient: %v", err)
+				}
+			}
+
+			unchanged, err := s.ConsumeIngredients(1, tt.used)
+			if err != nil {
+				t.Fatalf("ConsumeIngredients: %v", err)
+			}
+			if !reflect.DeepEqual(unchanged, tt.wantUnchanged) {
+				t.Errorf("unchanged = %v, want %v", unchanged, tt.wantUnchanged)
+			}
+
+			fridge, err := s.GetFridge(1)
+			if err != nil {
+				t.Fatalf("GetFridge: %v", err)
+			}
+			got := make(map[string]string)
+			for name, ingredient := range fridge.Ingredients {
+				got[name] = ingredient.Quantity
+			}
+			if !reflect.DeepEqual(got, tt.wantQuantity) {
+				t.Errorf("fridge = %v, want %v", got, tt.wantQuantity)
+			}
+		})
+	}
+}
+
+func TestAddIngredientMerges(t *testing.T) {
+	tests := []struct {
+		name  string
+		added []string // Quantities of milk added one after another
+		want  string
+	}{
+		{"adds compatible amounts", []string{"1 l", "500 ml"}, "1.5 l"},
+		{"keeps the stock when the amounts can't be added", []string{"1 kg", "3"}, "1 kg + 3 pcs"},
+		{"keeps the stock when no amount is added", []string{"1 l", ""}, "1 l"},
+		{"keeps an unparsed stock", []string{"a bottle", "1 l"}, "a bottle + 1 l"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestService(t)
+			for _, quantity := range tt.added {
+				if err := s.AddIngredient(1, "milk", quantity); err != nil {
+					t.Fatalf("AddIngredient: %v", err)
+				}
+			}
+
+			fridge, err := s.GetFridge(1)
+			if err != nil {
+				t.Fatalf("GetFridge: %v", err)
+			}
+			if got := fridge.Ingredients["milk"].Quantity; got != tt.want {
+				t.Fatalf("milk = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
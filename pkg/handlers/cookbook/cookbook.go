@@ -0,0 +1,299 @@
+package cookbook
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/korjavin/whatsfordinner/pkg/handlers"
+	"github.com/korjavin/whatsfordinner/pkg/logger"
+	"github.com/korjavin/whatsfordinner/pkg/models"
+	"github.com/korjavin/whatsfordinner/pkg/recipes"
+	"github.com/korjavin/whatsfordinner/pkg/state"
+	"github.com/korjavin/whatsfordinner/pkg/telegram"
+)
+
+// handler handles the family recipe book
+type handler struct {
+	*handlers.Deps
+	log *logger.Logger
+}
+
+// Register routes the recipe book commands and buttons, and the recipe being typed
+func Register(router *telegram.Router, deps *handlers.Deps) {
+	h := &handler{Deps: deps, log: logger.New("cookbook")}
+
+	router.Command("recipe", h.handleRecipe)
+	router.Command("export_recipes", h.handleExportRecipes)
+	router.Command("import_recipes", h.handleImportRecipes)
+	router.Callback("recipe_cancel", h.handleRecipeCancel)
+
+	deps.Conversations.Handle(state.StateEditingRecipe, h.handleRecipeText)
+}
+
+// handleRecipe handles /recipe
+func (h *handler) handleRecipe(ctx *telegram.Context) {
+	message := ctx.Message
+	// Manage the family recipe book, e.g. "/recipe add Grandma's Borscht" or "/recipe show borscht"
+	chatID := ctx.ChatID
+	userID := ctx.MemberID()
+	username := ctx.Username
+
+	subcommand, name, _ := strings.Cut(strings.TrimSpace(message.CommandArguments()), " ")
+	name = strings.TrimSpace(name)
+
+	switch strings.ToLower(subcommand) {
+	case "", "list":
+		book, err := h.Recipes.List(chatID)
+		if err != nil {
+			h.log.Error("Failed to list recipes: %v", err)
+			h.Bot.SendMessage(chatID, "😢 Sorry, I couldn't retrieve the recipe book right now. Please try again later.")
+			return
+		}
+		h.Bot.SendMessage(chatID, formatRecipeBook(book)+"\n"+recipeUsage)
+	case "show":
+		recipe, err := h.Recipes.Get(chatID, name)
+		if err != nil {
+			h.Bot.SendMessage(chatID, fmt.Sprintf("📖 There's no %q in your recipe book. Use /recipe to see what's there.", name))
+			return
+		}
+		h.Bot.SendMessage(chatID, formatRecipe(recipe))
+	case "add", "edit":
+		if name == "" {
+			h.Bot.SendMessage(chatID, recipeUsage)
+			return
+		}
+
+		mode := strings.ToLower(subcommand)
+		prompt := "🌍 Which cuisine is it? For example: Italian. Send - to skip."
+		if mode == "add" {
+			if _, err := h.Recipes.Get(chatID, name); err == nil {
+				h.Bot.SendMessage(chatID, fmt.Sprintf("📖 %s is already in your recipe book. Use /recipe edit %s to change it.", name, name))
+				return
+			}
+		} else {
+			recipe, err := h.Recipes.Get(chatID, name)
+			if err != nil {
+				h.Bot.SendMessage(chatID, fmt.Sprintf("📖 There's no %q in your recipe book. Use /recipe add %s to add it.", name, name))
+				return
+			}
+			if recipe.OwnerID != userID {
+				h.Bot.SendMessage(chatID, fmt.Sprintf("🔒 Only @%s can change %s.", recipe.OwnerUsername, recipe.Dish.Name))
+				return
+			}
+			name = recipe.Dish.Name
+			prompt = fmt.Sprintf("🌍 Which cuisine is it? It's %s now; send - to keep it.", recipe.Dish.Cuisine)
+		}
+
+		// The recipe is typed over the next messages of the member who started it
+		h.State.ClearState(chatID, ctx.UserID)
+		h.State.SetState(chatID, ctx.UserID, state.StateEditingRecipe)
+		h.State.SetData(chatID, ctx.UserID, "recipe_mode", mode)
+		h.State.SetData(chatID, ctx.UserID, "recipe_name", name)
+		h.State.SetData(chatID, ctx.UserID, "recipe_step", "cuisine")
+
+		h.Bot.SendMessageWithKeyboard(chatID, fmt.Sprintf("📖 @%s, let's write down %s.\n\n%s", username, name, prompt), recipeCancelKeyboard())
+	case "import":
+		// A link or pasted HTML, given after the command or in the message replied to
+		source := name
+		if source == "" && message.ReplyToMessage != nil {
+			source = strings.TrimSpace(message.ReplyToMessage.Text)
+		}
+		if source == "" {
+			h.Bot.SendMessage(chatID, "🔗 Send a link to the recipe, for example: /recipe import https://example.com/borscht")
+			return
+		}
+
+		h.Bot.SendMessage(chatID, "🔍 Reading the recipe... This might take a moment.")
+
+		var dish models.Dish
+		var err error
+		if strings.HasPrefix(source, "<") {
+			dish, err = h.RecipeImporter.FromHTML(source)
+		} else {
+			dish, err = h.RecipeImporter.FromURL(strings.Fields(source)[0])
+		}
+		if err != nil {
+			h.log.Error("Failed to import recipe: %v", err)
+			if errors.Is(err, recipes.ErrNoRecipe) {
+				h.Bot.SendMessage(chatID, "😢 I couldn't find a recipe on that page.")
+			} else {
+				h.Bot.SendMessage(chatID, fmt.Sprintf("😢 I couldn't import the recipe: %v.", err))
+			}
+			return
+		}
+
+		recipe, err := h.Recipes.Add(chatID, userID, username, dish)
+		if err != nil {
+			if errors.Is(err, recipes.ErrExists) {
+				h.Bot.SendMessage(chatID, fmt.Sprintf("📖 %s is already in your recipe book. Delete it first to import it again.", dish.Name))
+				return
+			}
+			h.log.Error("Failed to save imported recipe: %v", err)
+			h.Bot.SendMessage(chatID, "😢 Sorry, I couldn't save the recipe. Please try again later.")
+			return
+		}
+
+		h.Bot.SendMessage(chatID, "✅ Imported to your recipe book!\n\n"+formatRecipe(recipe))
+	case "delete", "remove":
+		recipe, err := h.Recipes.Delete(chatID, userID, name)
+		if err != nil {
+			switch {
+			case errors.Is(err, recipes.ErrNotFound):
+				h.Bot.SendMessage(chatID, fmt.Sprintf("📖 There's no %q in your recipe book.", name))
+			case errors.Is(err, recipes.ErrNotOwner):
+				h.Bot.SendMessage(chatID, "🔒 Only the family member who added a recipe can delete it.")
+			default:
+				h.log.Error("Failed to delete recipe: %v", err)
+				h.Bot.SendMessage(chatID, "😢 Sorry, I couldn't delete the recipe. Please try again later.")
+			}
+			return
+		}
+		h.Bot.SendMessage(chatID, fmt.Sprintf("🗑️ %s is no longer in your recipe book.", recipe.Dish.Name))
+	default:
+		h.Bot.SendMessage(chatID, recipeUsage)
+	}
+}
+
+// handleExportRecipes handles /export_recipes
+func (h *handler) handleExportRecipes(ctx *telegram.Context) {
+	message := ctx.Message
+	// Send the family recipe book as a file, e.g. "/export_recipes md"; a zip of every format by default
+	chatID := ctx.ChatID
+
+	dishes, err := h.Recipes.Dishes(chatID)
+	if err != nil {
+		h.log.Error("Failed to get recipes: %v", err)
+		h.Bot.SendMessage(chatID, "😢 Sorry, I couldn't retrieve the recipe book right now. Please try again later.")
+		return
+	}
+	if len(dishes) == 0 {
+		h.Bot.SendMessage(chatID, "📖 Your recipe book is empty, there's nothing to export yet. Add recipes with /recipe add.")
+		return
+	}
+
+	data, fileName, err := recipes.Export(dishes, strings.TrimSpace(message.CommandArguments()))
+	if err != nil {
+		h.Bot.SendMessage(chatID, fmt.Sprintf("😢 %v.", err))
+		return
+	}
+
+	if _, err := h.Bot.SendDocument(chatID, fileName, data, fmt.Sprintf("📖 %d recipes from your family recipe book", len(dishes))); err != nil {
+		h.log.Error("Failed to send recipe export: %v", err)
+		h.Bot.SendMessage(chatID, "😢 Sorry, I couldn't send the file. Please try again later.")
+	}
+}
+
+// handleImportRecipes handles /import_recipes
+func (h *handler) handleImportRecipes(ctx *telegram.Context) {
+	message := ctx.Message
+	// Import recipes from a file or text exported by /export_recipes or another app, sent in reply to it
+	chatID := ctx.ChatID
+	userID := ctx.MemberID()
+	username := ctx.Username
+
+	reply := message.ReplyToMessage
+	var data []byte
+	var fileName string
+	switch {
+	case reply != nil && reply.Document != nil:
+		var err error
+		data, err = h.Bot.DownloadFile(reply.Document.FileID)
+		if err != nil {
+			h.log.Error("Failed to download recipe file: %v", err)
+			h.Bot.SendMessage(chatID, "😢 Sorry, I couldn't download that file. Please try again.")
+			return
+		}
+		fileName = reply.Document.FileName
+	case reply != nil && reply.Text != "":
+		data = []byte(reply.Text)
+	default:
+		h.Bot.SendMessage(chatID, fmt.Sprintf("📥 Reply with /import_recipes to a recipe file (%s) or to a message with recipes in it.", strings.Join(recipes.Formats, ", ")))
+		return
+	}
+
+	dishes, err := recipes.ParseCollection(data, fileName)
+	if err != nil {
+		h.log.Error("Failed to parse recipes: %v", err)
+		h.Bot.SendMessage(chatID, fmt.Sprintf("😢 I couldn't read the recipes: %v.", err))
+		return
+	}
+	if len(dishes) == 0 {
+		h.Bot.SendMessage(chatID, "😢 I couldn't find any recipes in there.")
+		return
+	}
+
+	result, err := h.Recipes.Import(chatID, userID, username, dishes)
+	if err != nil {
+		h.log.Error("Failed to import recipes: %v", err)
+		h.Bot.SendMessage(chatID, fmt.Sprintf("😢 The import stopped halfway: %v. %d recipes were added.", err, len(result.Added)))
+		return
+	}
+
+	h.Bot.SendMessage(chatID, formatImportResult(result))
+}
+
+// handleRecipeCancel handles "Cancel" while typing a recipe
+func (h *handler) handleRecipeCancel(ctx *telegram.Context) {
+	callback := ctx.Callback
+	chatID := ctx.ChatID
+
+	if h.State.GetState(chatID, ctx.UserID) != state.StateEditingRecipe {
+		ctx.Answer("Only the member writing the recipe can cancel it.")
+		return
+	}
+	h.State.ClearState(chatID, ctx.UserID)
+
+	ctx.Answer("Recipe cancelled.")
+
+	editMsg := tgbotapi.NewEditMessageText(chatID, callback.Message.MessageID, "📖 Recipe cancelled, nothing was saved.")
+	editMsg.ReplyMarkup = &tgbotapi.InlineKeyboardMarkup{}
+	h.Bot.Send(editMsg)
+}
+
+// handleRecipeText handles the next part of a recipe for /recipe add or edit, from the member who started it
+func (h *handler) handleRecipeText(ctx *telegram.Context) {
+	chatID := ctx.ChatID
+	userID := ctx.UserID
+	text := ctx.Message.Text
+
+	step, _ := h.State.GetData(chatID, userID, "recipe_step")
+	switch step {
+	case "cuisine":
+		h.State.SetData(chatID, userID, "recipe_cuisine", strings.TrimSpace(text))
+		h.State.SetData(chatID, userID, "recipe_step", "ingredients")
+		h.Bot.SendMessageWithKeyboard(chatID, "🥕 Now the ingredients, one per line or separated by commas, e.g. 500 g beef, 2 onions. In an edit, send - to keep them.", recipeCancelKeyboard())
+	case "ingredients":
+		mode, _ := h.State.GetData(chatID, userID, "recipe_mode")
+		if strings.TrimSpace(text) != "-" || mode != "edit" {
+			if len(recipes.ParseIngredients(text)) == 0 {
+				h.Bot.SendMessageWithKeyboard(chatID, "🥕 I need at least one ingredient. Please list them, separated by commas.", recipeCancelKeyboard())
+				return
+			}
+		}
+		h.State.SetData(chatID, userID, "recipe_ingredients", text)
+		h.State.SetData(chatID, userID, "recipe_step", "instructions")
+		h.Bot.SendMessageWithKeyboard(chatID, "📝 And finally the steps, one per line. In an edit, send - to keep them.", recipeCancelKeyboard())
+	case "instructions":
+		mode, _ := h.State.GetData(chatID, userID, "recipe_mode")
+		name, _ := h.State.GetData(chatID, userID, "recipe_name")
+		cuisine, _ := h.State.GetData(chatID, userID, "recipe_cuisine")
+		ingredientsText, _ := h.State.GetData(chatID, userID, "recipe_ingredients")
+		h.State.ClearState(chatID, userID)
+
+		recipe, err := saveRecipe(h.Recipes, chatID, ctx.MemberID(), ctx.Username, mode, models.Dish{
+			Name:         name,
+			Cuisine:      cuisine,
+			Ingredients:  recipes.ParseIngredients(ingredientsText),
+			Instructions: recipes.ParseSteps(text),
+		})
+		if err != nil {
+			h.log.Error("Failed to save recipe %s: %v", name, err)
+			h.Bot.SendMessage(chatID, fmt.Sprintf("😢 I couldn't save the recipe: %v.", err))
+			return
+		}
+
+		h.Bot.SendMessage(chatID, "✅ Saved to your recipe book!\n\n"+formatRecipe(recipe))
+	}
+}
@@ -0,0 +1,3 @@
+// Package cookbook provides the family recipe book: adding and editing recipes step by step,
+// and exporting and importing them.
+package cookbook
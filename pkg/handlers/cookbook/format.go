@@ -0,0 +1,130 @@
+package cookbook
+
+import (
+	"fmt"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/korjavin/whatsfordinner/pkg/models"
+	"github.com/korjavin/whatsfordinner/pkg/recipes"
+)
+
+// recipeUsage explains how to manage the recipe book
+const recipeUsage = `✏️ Manage it with:
+/recipe add Grandma's Borscht
+/recipe edit Grandma's Borscht
+/recipe show Grandma's Borscht
+/recipe import https://example.com/borscht
+/recipe delete Grandma's Borscht`
+
+// formatRecipeBook lists the recipes in a channel's book for the /recipe message
+func formatRecipeBook(book []models.Recipe) string {
+	if len(book) == 0 {
+		return "📖 Your recipe book is empty. Add your family's favourites and I'll suggest them too!\n"
+	}
+
+	text := "📖 *Family recipe book:*\n"
+	for _, recipe := range book {
+		cuisine := ""
+		if recipe.Dish.Cuisine != "" {
+			cuisine = fmt.Sprintf(" (%s)", recipe.Dish.Cuisine)
+		}
+		text += fmt.Sprintf("• %s%s – by @%s\n", recipe.Dish.Name, cuisine, recipe.OwnerUsername)
+	}
+	return text
+}
+
+// formatRecipe formats a recipe with its ingredients and steps
+func formatRecipe(recipe *models.Recipe) string {
+	text := fmt.Sprintf("📖 *%s*\n", recipe.Dish.Name)
+	if recipe.Dish.Cuisine != "" {
+		text += fmt.Sprintf("🌍 %s\n", recipe.Dish.Cuisine)
+	}
+	if recipe.Dish.Servings > 0 {
+		text += fmt.Sprintf("🍽️ Serves %d\n", recipe.Dish.Servings)
+	}
+	minutes := recipe.Dish.TotalMinutes
+	if minutes == 0 {
+		minutes = recipe.Dish.PrepMinutes + recipe.Dish.CookMinutes
+	}
+	if minutes > 0 {
+		text += fmt.Sprintf("⏱️ %d min\n", minutes)
+	}
+	text += fmt.Sprintf("👤 Added by @%s", recipe.OwnerUsername)
+	if recipe.UpdatedBy != "" && recipe.UpdatedBy != recipe.OwnerUsername {
+		text += fmt.Sprintf(", last edited by @%s", recipe.UpdatedBy)
+	}
+	text += "\n"
+
+	if len(recipe.Dish.Ingredients) > 0 {
+		text += "\n*Ingredients:*\n"
+		for _, ingredient := range recipe.Dish.Ingredients {
+			text += fmt.Sprintf("• %s\n", ingredient)
+		}
+	}
+
+	if len(recipe.Dish.Instructions) > 0 {
+		text += "\n*Instructions:*\n"
+		for i, instruction := range recipe.Dish.Instructions {
+			text += fmt.Sprintf("%d. %s\n", i+1, instruction)
+		}
+	}
+
+	if recipe.Dish.SourceURL != "" {
+		text += fmt.Sprintf("\n🔗 %s\n", recipe.Dish.SourceURL)
+	}
+
+	return text
+}
+
+// formatImportResult tells which imported recipes were added and which were already known
+func formatImportResult(result recipes.ImportResult) string {
+	text := fmt.Sprintf("📥 Added %d recipes to your recipe book", len(result.Added))
+	if len(result.Added) > 0 {
+		text += ": " + strings.Join(result.Added, ", ")
+	}
+	text += ".\n"
+	if len(result.Duplicates) > 0 {
+		text += fmt.Sprintf("Skipped %d I already know: %s.\n", len(result.Duplicates), strings.Join(result.Duplicates, ", "))
+	}
+	return text
+}
+
+// recipeCancelKeyboard returns the button that stops typing a recipe
+func recipeCancelKeyboard() tgbotapi.InlineKeyboardMarkup {
+	return tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Cancel", "recipe_cancel"),
+		),
+	)
+}
+
+// saveRecipe adds or updates a recipe typed in the /recipe dialog
+// In an edit, "-" for the cuisine and empty ingredients or steps keep what the recipe had
+func saveRecipe(recipeService *recipes.Service, chatID int64, userID, username, mode string, dish models.Dish) (*models.Recipe, error) {
+	keepCuisine := dish.Cuisine == "-"
+	if keepCuisine {
+		dish.Cuisine = ""
+	}
+
+	if mode != "edit" {
+		return recipeService.Add(chatID, userID, username, dish)
+	}
+
+	existing, err := recipeService.Get(chatID, dish.Name)
+	if err != nil {
+		return nil, err
+	}
+	if keepCuisine {
+		dish.Cuisine = existing.Dish.Cuisine
+	}
+	if len(dish.Ingredients) == 0 {
+		dish.Ingredients = existing.Dish.Ingredients
+	}
+	if len(dish.Instructions) == 0 {
+		dish.Instructions = existing.Dish.Instructions
+	}
+	dish.Description = existing.Dish.Description
+
+	return recipeService.Update(chatID, userID, username, dish)
+}
@@ -0,0 +1,5 @@
+// Package handlers holds what the bot's feature handlers share: the services they use and the routing of
+// the messages members type outside of commands, by the conversation they're in.
+// Each feature has its own package below it (pantry, voting, kitchen, groceries, planning, preferences,
+// cookbook and family), which registers its commands, buttons and conversations with the router.
+package handlers
@@ -0,0 +1,3 @@
+// Package family provides the bot's family commands: the welcome of /start, nutrition reports,
+// the cook rotation and the family's stats.
+package family
@@ -0,0 +1,331 @@
+package family
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/korjavin/whatsfordinner/pkg/handlers"
+	"github.com/korjavin/whatsfordinner/pkg/logger"
+	"github.com/korjavin/whatsfordinner/pkg/models"
+	"github.com/korjavin/whatsfordinner/pkg/telegram"
+)
+
+// handler handles the family commands: the welcome, nutrition reports, the cook rotation and the stats
+type handler struct {
+	*handlers.Deps
+	log *logger.Logger
+}
+
+// Register routes the family commands
+func Register(router *telegram.Router, deps *handlers.Deps) {
+	h := &handler{Deps: deps, log: logger.New("family")}
+
+	router.Command("start", h.handleStart)
+	router.Command("nutrition", h.handleNutrition)
+	router.Command("rotation", h.handleRotation)
+	router.Command("stats", h.handleStats)
+}
+
+// handleStart handles /start
+func (h *handler) handleStart(ctx *telegram.Context) {
+	message := ctx.Message
+	welcomeMsg := h.Messages.GenerateWelcomeMessage()
+	h.Bot.SendMessage(message.Chat.ID, welcomeMsg)
+}
+
+// handleNutrition handles /nutrition
+func (h *handler) handleNutrition(ctx *telegram.Context) {
+	message := ctx.Message
+	// Show roughly what the family ate with the dinners of the last week, per person
+	chatID := ctx.ChatID
+
+	switch strings.ToLower(strings.TrimSpace(message.CommandArguments())) {
+	case "", "week":
+	default:
+		h.Bot.SendMessage(chatID, "🔥 Use /nutrition week to see what you ate with the dinners of the last 7 days.")
+		return
+	}
+
+	to := time.Now()
+	from := to.AddDate(0, 0, -7)
+	history, err := h.Dinner.GetHistory(chatID, from)
+	if err != nil {
+		h.log.Error("Failed to get dinner history: %v", err)
+		h.Bot.SendMessage(chatID, "😢 Sorry, I couldn't put the report together right now. Please try again later.")
+		return
+	}
+
+	report := h.Nutrition.Summarize(history, from, to)
+	if len(report.Dinners) == 0 {
+		h.Bot.SendMessage(chatID, "🔥 No dinners were served in the last 7 days. Once the cook marks dinner as ready, it shows up here.")
+		return
+	}
+
+	channelSettings, err := h.Settings.Get(chatID)
+	if err != nil {
+		h.log.Error("Failed to get settings: %v", err)
+	}
+	h.Bot.SendMessage(chatID, formatNutritionReport(report, channelSettings.Location))
+}
+
+// handleRotation handles /rotation
+func (h *handler) handleRotation(ctx *telegram.Context) {
+	message := ctx.Message
+	// Show whose turn it is to cook, or leave or rejoin the rotation
+	chatID := ctx.ChatID
+	userID := ctx.MemberID()
+	username := ctx.Username
+
+	switch strings.ToLower(strings.TrimSpace(message.CommandArguments())) {
+	case "":
+	case "leave":
+		if err := h.Rotation.SetPaused(chatID, userID, username, true); err != nil {
+			h.log.Error("Failed to leave the cook rotation: %v", err)
+			h.Bot.SendMessage(chatID, "😢 Sorry, I couldn't update the rotation right now. Please try again later.")
+			return
+		}
+		h.Bot.SendMessage(chatID, fmt.Sprintf("⏸ @%s is taking a break from cooking. Use /rotation join to come back.", username))
+		return
+	case "join":
+		if err := h.Rotation.SetPaused(chatID, userID, username, false); err != nil {
+			h.log.Error("Failed to join the cook rotation: %v", err)
+			h.Bot.SendMessage(chatID, "😢 Sorry, I couldn't update the rotation right now. Please try again later.")
+			return
+		}
+		h.Bot.SendMessage(chatID, fmt.Sprintf("🔄 @%s is in the cook rotation.", username))
+		return
+	default:
+		h.Bot.SendMessage(chatID, "🔄 Use /rotation to see whose turn it is to cook, /rotation leave to take a break and /rotation join to come back.")
+		return
+	}
+
+	members, err := h.Rotation.Members(chatID)
+	if err != nil {
+		h.log.Error("Failed to get rotation members: %v", err)
+		h.Bot.SendMessage(chatID, "😢 Sorry, I couldn't get the rotation right now. Please try again later.")
+		return
+	}
+	turns, err := h.Rotation.Order(chatID, time.Now())
+	if err != nil {
+		h.log.Error("Failed to get the cook rotation: %v", err)
+		h.Bot.SendMessage(chatID, "😢 Sorry, I couldn't get the rotation right now. Please try again later.")
+		return
+	}
+	if len(members) == 0 {
+		h.Bot.SendMessage(chatID, "🔄 Nobody is in the cook rotation yet. Everyone who votes in a dinner poll or volunteers to cook joins it.")
+		return
+	}
+
+	channelSettings, err := h.Settings.Get(chatID)
+	if err != nil {
+		h.log.Error("Failed to get settings: %v", err)
+	}
+	h.Bot.SendMessage(chatID, formatRotation(turns, members, channelSettings.Location))
+}
+
+// handleStats handles /stats
+func (h *handler) handleStats(ctx *telegram.Context) {
+	// Show family leaderboards
+	chatID := ctx.ChatID
+
+	// Get statistics
+	stats, err := h.Stats.GetStatistics(chatID)
+	if err != nil {
+		h.log.Error("Failed to get statistics: %v", err)
+		h.Bot.SendMessage(chatID, "😢 Sorry, I couldn't retrieve the statistics right now. Please try again later.")
+		return
+	}
+
+	// Check if we have any statistics
+	if len(stats.CookStats) == 0 && len(stats.HelperStats) == 0 && len(stats.SuggesterStats) == 0 {
+		h.Bot.SendMessage(chatID, "📊 No statistics available yet. Start cooking and rating meals to build up your family leaderboards!")
+		return
+	}
+
+	// Create a formatted message with statistics
+	msgText := "🏆 *Family Leaderboards*\n\n"
+
+	// Add cook statistics
+	if len(stats.CookStats) > 0 {
+		msgText += "👨‍🍳 *Top Cooks*\n"
+
+		// Convert map to slice for sorting
+		cooks := make([]models.CookStat, 0, len(stats.CookStats))
+		for _, cookStat := range stats.CookStats {
+			cooks = append(cooks, cookStat)
+		}
+
+		// Sort by average rating (descending)
+		sort.Slice(cooks, func(i, j int) bool {
+			return cooks[i].AvgRating > cooks[j].AvgRating
+		})
+
+		// Take the top 3 cooks
+		limit := 3
+		if len(cooks) < limit {
+			limit = len(cooks)
+		}
+
+		for i := 0; i < limit; i++ {
+			cook := cooks[i]
+			// Use username if available, otherwise try to get a friendly name
+			displayName := cook.Username
+			if displayName == "" {
+				// Try to convert user ID to integer for Telegram API
+				userIDInt, err := strconv.ParseInt(cook.UserID, 10, 64)
+				if err == nil {
+					// Try to get chat member info
+					member, err := h.Bot.GetChatMember(chatID, userIDInt)
+					if err == nil && member.User != nil {
+						// Use username if available, otherwise use first name
+						if member.User.UserName != "" {
+							displayName = "@" + member.User.UserName
+							// Update the stored username for future use
+							h.Stats.UpdateCookStats(chatID, cook.UserID, member.User.UserName, 0)
+						} else if member.User.FirstName != "" {
+							displayName = member.User.FirstName
+							// Update the stored username for future use
+							h.Stats.UpdateCookStats(chatID, cook.UserID, member.User.FirstName, 0)
+						}
+					}
+				}
+
+				// If we still don't have a display name, use the user ID
+				if displayName == "" {
+					displayName = fmt.Sprintf("User %s", cook.UserID)
+				}
+			}
+			msgText += fmt.Sprintf("%d. %s - %.1f stars (%d meals)\n", i+1, displayName, cook.AvgRating, cook.CookCount)
+		}
+		msgText += "\n"
+	}
+
+	// Add helper statistics
+	if len(stats.HelperStats) > 0 {
+		msgText += "🛒 *Top Shoppers*\n"
+
+		// Convert map to slice for sorting
+		helpers := make([]models.HelperStat, 0, len(stats.HelperStats))
+		for _, helperStat := range stats.HelperStats {
+			helpers = append(helpers, helperStat)
+		}
+
+		// Sort by shopping count (descending)
+		sort.Slice(helpers, func(i, j int) bool {
+			return helpers[i].ShoppingCount > helpers[j].ShoppingCount
+		})
+
+		// Take the top 3 helpers
+		limit := 3
+		if len(helpers) < limit {
+			limit = len(helpers)
+		}
+
+		for i := 0; i < limit; i++ {
+			helper := helpers[i]
+			// Use username if available, otherwise try to get a friendly name
+			displayName := helper.Username
+			if displayName == "" {
+				// Try to convert user ID to integer for Telegram API
+				userIDInt, err := strconv.ParseInt(helper.UserID, 10, 64)
+				if err == nil {
+					// Try to get chat member info
+					member, err := h.Bot.GetChatMember(chatID, userIDInt)
+					if err == nil && member.User != nil {
+						// Use username if available, otherwise use first name
+						if member.User.UserName != "" {
+							displayName = "@" + member.User.UserName
+							// Update the stored username for future use
+							h.Stats.UpdateHelperStats(chatID, helper.UserID, member.User.UserName)
+						} else if member.User.FirstName != "" {
+							displayName = member.User.FirstName
+							// Update the stored username for future use
+							h.Stats.UpdateHelperStats(chatID, helper.UserID, member.User.FirstName)
+						}
+					}
+				}
+
+				// If we still don't have a display name, use the user ID
+				if displayName == "" {
+					displayName = fmt.Sprintf("User %s", helper.UserID)
+				}
+			}
+			msgText += fmt.Sprintf("%d. %s - %d shopping trips\n", i+1, displayName, helper.ShoppingCount)
+		}
+		msgText += "\n"
+	}
+
+	// Add suggester statistics
+	if len(stats.SuggesterStats) > 0 {
+		msgText += "💡 *Top Suggesters*\n"
+
+		// Convert map to slice for sorting
+		suggesters := make([]models.SuggesterStat, 0, len(stats.SuggesterStats))
+		for _, suggesterStat := range stats.SuggesterStats {
+			suggesters = append(suggesters, suggesterStat)
+		}
+
+		// Sort by acceptance rate (descending)
+		sort.Slice(suggesters, func(i, j int) bool {
+			// Calculate acceptance rates
+			rateI := 0.0
+			if suggesterStat := suggesters[i]; suggesterStat.SuggestionCount > 0 {
+				rateI = float64(suggesterStat.AcceptedCount) / float64(suggesterStat.SuggestionCount)
+			}
+
+			rateJ := 0.0
+			if suggesterStat := suggesters[j]; suggesterStat.SuggestionCount > 0 {
+				rateJ = float64(suggesterStat.AcceptedCount) / float64(suggesterStat.SuggestionCount)
+			}
+
+			return rateI > rateJ
+		})
+
+		// Take the top 3 suggesters
+		limit := 3
+		if len(suggesters) < limit {
+			limit = len(suggesters)
+		}
+
+		for i := 0; i < limit; i++ {
+			suggester := suggesters[i]
+			rate := 0.0
+			if suggester.SuggestionCount > 0 {
+				rate = float64(suggester.AcceptedCount) / float64(suggester.SuggestionCount) * 100
+			}
+			// Use username if available, otherwise try to get a friendly name
+			displayName := suggester.Username
+			if displayName == "" {
+				// Try to convert user ID to integer for Telegram API
+				userIDInt, err := strconv.ParseInt(suggester.UserID, 10, 64)
+				if err == nil {
+					// Try to get chat member info
+					member, err := h.Bot.GetChatMember(chatID, userIDInt)
+					if err == nil && member.User != nil {
+						// Use username if available, otherwise use first name
+						if member.User.UserName != "" {
+							displayName = "@" + member.User.UserName
+							// Update the stored username for future use
+							h.Stats.UpdateSuggesterStats(chatID, suggester.UserID, member.User.UserName, false)
+						} else if member.User.FirstName != "" {
+							displayName = member.User.FirstName
+							// Update the stored username for future use
+							h.Stats.UpdateSuggesterStats(chatID, suggester.UserID, member.User.FirstName, false)
+						}
+					}
+				}
+
+				// If we still don't have a display name, use the user ID
+				if displayName == "" {
+					displayName = fmt.Sprintf("User %s", suggester.UserID)
+				}
+			}
+			msgText += fmt.Sprintf("%d. %s - %.1f%% acceptance (%d/%d)\n", i+1, displayName, rate, suggester.AcceptedCount, suggester.SuggestionCount)
+		}
+	}
+
+	h.Bot.SendMessage(chatID, msgText)
+}
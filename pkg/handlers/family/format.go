@@ -0,0 +1,66 @@
+package family
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/korjavin/whatsfordinner/pkg/models"
+	"github.com/korjavin/whatsfordinner/pkg/nutrition"
+	"github.com/korjavin/whatsfordinner/pkg/rotation"
+)
+
+// formatNutritionReport formats what the family ate with each dinner of a report, per person
+func formatNutritionReport(report nutrition.Report, location *time.Location) string {
+	text := fmt.Sprintf("🔥 *Nutrition, %s – %s*\nPer person, for one serving of each dinner:\n\n", report.From.In(location).Format("Jan 2"), report.To.In(location).Format("Jan 2"))
+	for _, dinnerNutrition := range report.Dinners {
+		text += fmt.Sprintf("%s – %s\n", dinnerNutrition.Dinner.FinishedAt.In(location).Format("Mon"), dinnerNutrition.Dinner.Dish.Name)
+		if dinnerNutrition.Nutrition != nil {
+			text += fmt.Sprintf("   %s\n", nutrition.Format(dinnerNutrition.Nutrition))
+		} else {
+			text += "   no estimate\n"
+		}
+	}
+
+	if report.Counted > 0 {
+		average := report.Average()
+		total := report.Total
+		text += fmt.Sprintf("\n*Total:* %s\n", nutrition.Format(&total))
+		text += fmt.Sprintf("*Average dinner:* %s\n", nutrition.Format(&average))
+	}
+	if report.Counted < len(report.Dinners) {
+		text += fmt.Sprintf("\n%d dinners have no estimate and aren't counted.\n", len(report.Dinners)-report.Counted)
+	}
+	text += "\nThese are rough estimates from typical nutrient values; ≈ marks an estimate by the AI."
+
+	return text
+}
+
+// formatRotation formats whose turn it is to cook, next cook first, and who is taking a break
+func formatRotation(turns []rotation.Turn, members []models.RotationMember, location *time.Location) string {
+	text := fmt.Sprintf("🔄 *Cook rotation* (last %d days)\n\n", int(rotation.Window.Hours()/24))
+	for i, turn := range turns {
+		last := "never cooked"
+		if !turn.LastCooked.IsZero() {
+			last = "last cooked " + turn.LastCooked.In(location).Format("Jan 2")
+		}
+		text += fmt.Sprintf("%d. @%s – %d dinners, %s", i+1, turn.Member.Username, turn.Cooked, last)
+		if i == 0 {
+			text += " 👈 next up"
+		}
+		text += "\n"
+	}
+
+	var paused []string
+	for _, member := range members {
+		if member.Paused {
+			paused = append(paused, "@"+member.Username)
+		}
+	}
+	if len(paused) > 0 {
+		text += fmt.Sprintf("\n⏸ Taking a break: %s\n", strings.Join(paused, ", "))
+	}
+
+	text += "\n/rotation leave takes you out of the rotation, /rotation join puts you back."
+	return text
+}
@@ -0,0 +1,3 @@
+// Package groceries provides the shopping list: /shopping, claiming the shopping and marking it done.
+// The list and its buttons are also sent by the kitchen and the week plan.
+package groceries
@@ -0,0 +1,41 @@
+package groceries
+
+import (
+	"fmt"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/korjavin/whatsfordinner/pkg/models"
+)
+
+// FormatList formats the items of a shopping list, one per line
+func FormatList(list *models.ShoppingList) string {
+	text := ""
+	for _, item := range list.Items {
+		line := "• " + item.Name
+		if item.Quantity != "" {
+			line += fmt.Sprintf(" (%s)", item.Quantity)
+		}
+		if item.ForDish != "" {
+			line += fmt.Sprintf(" – for %s", item.ForDish)
+		}
+		text += line + "\n"
+	}
+	return text
+}
+
+// Keyboard returns "I will buy" while nobody has volunteered and "Shopping done" after that
+func Keyboard(list *models.ShoppingList) tgbotapi.InlineKeyboardMarkup {
+	if list.BuyerID == "" {
+		return tgbotapi.NewInlineKeyboardMarkup(
+			tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData("🛒 I will buy", "shopping_claim"),
+			),
+		)
+	}
+
+	return tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("✅ Shopping done", "shopping_done"),
+		),
+	)
+}
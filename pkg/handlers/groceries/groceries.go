@@ -0,0 +1,138 @@
+package groceries
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/korjavin/whatsfordinner/pkg/handlers"
+	"github.com/korjavin/whatsfordinner/pkg/logger"
+	"github.com/korjavin/whatsfordinner/pkg/shopping"
+	"github.com/korjavin/whatsfordinner/pkg/telegram"
+)
+
+// handler handles the shopping list commands and buttons
+type handler struct {
+	*handlers.Deps
+	log *logger.Logger
+}
+
+// Register routes the shopping list commands and buttons
+func Register(router *telegram.Router, deps *handlers.Deps) {
+	h := &handler{Deps: deps, log: logger.New("groceries")}
+
+	router.Command("shopping", h.handleShopping)
+	router.Callback("shopping_claim", h.handleShoppingClaim)
+	router.Callback("shopping_done", h.handleShoppingDone)
+}
+
+// handleShopping handles /shopping
+func (h *handler) handleShopping(ctx *telegram.Context) {
+	message := ctx.Message
+	// Show the shopping list, adding any items given with the command, e.g. "/shopping milk, 6 eggs"
+	chatID := ctx.ChatID
+
+	if args := message.CommandArguments(); args != "" {
+		items := strings.FieldsFunc(args, func(r rune) bool {
+			return r == ',' || r == '\n' || r == ';'
+		})
+		err := h.Shopping.AddItems(chatID, "", items)
+		if err != nil {
+			h.log.Error("Failed to add shopping items: %v", err)
+			h.Bot.SendMessage(chatID, "😢 Sorry, I couldn't update the shopping list. Please try again later.")
+			return
+		}
+	}
+
+	shoppingList, err := h.Shopping.GetList(chatID)
+	if err != nil {
+		h.log.Error("Failed to get shopping list: %v", err)
+		h.Bot.SendMessage(chatID, "😢 Sorry, I couldn't retrieve the shopping list right now. Please try again later.")
+		return
+	}
+
+	if len(shoppingList.Items) == 0 {
+		h.Bot.SendMessage(chatID, "🛒 The shopping list is empty! Add items with /shopping milk, 6 eggs")
+		return
+	}
+
+	msgText := "🛒 *Shopping list:*\n" + FormatList(shoppingList)
+	if shoppingList.BuyerName != "" {
+		msgText += fmt.Sprintf("\n@%s is going to buy it.", shoppingList.BuyerName)
+	}
+
+	h.Bot.SendMessageWithKeyboard(chatID, msgText, Keyboard(shoppingList))
+}
+
+// handleShoppingClaim handles "I will buy" on the shopping list
+func (h *handler) handleShoppingClaim(ctx *telegram.Context) {
+	callback := ctx.Callback
+	chatID := ctx.ChatID
+	userID := ctx.MemberID()
+	username := ctx.Username
+
+	shoppingList, err := h.Shopping.Claim(chatID, userID, username)
+	if err != nil {
+		switch {
+		case errors.Is(err, shopping.ErrEmptyList):
+			ctx.Answer("The shopping list is empty, nothing to buy!")
+		case errors.Is(err, shopping.ErrAlreadyClaimed):
+			ctx.Answer("Someone is already on it!")
+		default:
+			ctx.Fail("Failed to claim shopping list: %v", err)
+		}
+		return
+	}
+
+	// Answer the callback
+	ctx.Answer("Thanks for going shopping!")
+
+	// Replace the message with who is buying what
+	msgText := fmt.Sprintf("🛒 @%s is going to buy:\n", username) + FormatList(shoppingList)
+	msgText += "\nPress the button below once shopping is done."
+	editMsg := tgbotapi.NewEditMessageText(chatID, callback.Message.MessageID, msgText)
+	keyboard := Keyboard(shoppingList)
+	editMsg.ReplyMarkup = &keyboard
+	h.Bot.Send(editMsg)
+}
+
+// handleShoppingDone handles "Shopping done" on the shopping list
+func (h *handler) handleShoppingDone(ctx *telegram.Context) {
+	callback := ctx.Callback
+	chatID := ctx.ChatID
+	userID := ctx.MemberID()
+	username := ctx.Username
+
+	bought, err := h.Shopping.Complete(chatID, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, shopping.ErrEmptyList):
+			ctx.Answer("The shopping list is already empty.")
+		case errors.Is(err, shopping.ErrNotBuyer):
+			ctx.Answer("Only the person who went shopping can mark it as done.")
+		default:
+			ctx.Fail("Failed to complete shopping: %v", err)
+		}
+		return
+	}
+
+	// Credit the buyer
+	err = h.Stats.UpdateHelperStats(chatID, userID, username)
+	if err != nil {
+		h.log.Error("Failed to update helper stats: %v", err)
+		// Continue anyway
+	}
+
+	// Answer the callback
+	ctx.Answer("Thanks for shopping!")
+
+	// Edit the message to remove the buttons
+	itemNames := make([]string, len(bought))
+	for i, item := range bought {
+		itemNames[i] = item.Name
+	}
+	editMsg := tgbotapi.NewEditMessageText(chatID, callback.Message.MessageID, fmt.Sprintf("✅ @%s bought %s. Everything is in the fridge now!", username, strings.Join(itemNames, ", ")))
+	editMsg.ReplyMarkup = &tgbotapi.InlineKeyboardMarkup{}
+	h.Bot.Send(editMsg)
+}
@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"errors"
+
+	"github.com/korjavin/whatsfordinner/pkg/attendance"
+	"github.com/korjavin/whatsfordinner/pkg/cooking"
+	"github.com/korjavin/whatsfordinner/pkg/diet"
+	"github.com/korjavin/whatsfordinner/pkg/dinner"
+	"github.com/korjavin/whatsfordinner/pkg/fridge"
+	"github.com/korjavin/whatsfordinner/pkg/messages"
+	"github.com/korjavin/whatsfordinner/pkg/nutrition"
+	"github.com/korjavin/whatsfordinner/pkg/openai"
+	"github.com/korjavin/whatsfordinner/pkg/planner"
+	"github.com/korjavin/whatsfordinner/pkg/poll"
+	"github.com/korjavin/whatsfordinner/pkg/recipes"
+	"github.com/korjavin/whatsfordinner/pkg/rotation"
+	"github.com/korjavin/whatsfordinner/pkg/scheduler"
+	"github.com/korjavin/whatsfordinner/pkg/settings"
+	"github.com/korjavin/whatsfordinner/pkg/shopping"
+	"github.com/korjavin/whatsfordinner/pkg/state"
+	"github.com/korjavin/whatsfordinner/pkg/stats"
+	"github.com/korjavin/whatsfordinner/pkg/storage"
+	"github.com/korjavin/whatsfordinner/pkg/suggest"
+	"github.com/korjavin/whatsfordinner/pkg/telegram"
+	"github.com/korjavin/whatsfordinner/pkg/workflow"
+)
+
+// Deps are the services the feature handlers use
+type Deps struct {
+	Store          *storage.Store
+	Bot            *telegram.Bot
+	OpenAI         *openai.Client
+	Fridge         *fridge.Service
+	Diet           *diet.Service
+	Recipes        *recipes.Service
+	RecipeImporter *recipes.Importer
+	Nutrition      *nutrition.Service
+	Cooking        *cooking.Service
+	Rotation       *rotation.Service
+	Attendance     *attendance.Service
+	Workflow       *workflow.Service
+	Dinner         *dinner.Service
+	Poll           *poll.Service
+	Messages       *messages.Service
+	State          *state.Manager
+	Suggest        *suggest.Service
+	Stats          *stats.Service
+	Shopping       *shopping.Service
+	Settings       *settings.Service
+	Planner        *planner.Service
+	Scheduler      *scheduler.Service
+
+	// Conversations routes what members type outside of commands; features add their conversations to it
+	Conversations *Conversations
+}
+
+// Conversations routes the messages members send outside of commands, e.g. a recipe being typed,
+// to the handler of the conversation the member is in
+type Conversations struct {
+	states   *state.Manager
+	text     map[state.State]telegram.Handler
+	photos   map[state.State]telegram.Handler
+	fallback telegram.Handler
+}
+
+// NewConversations creates the routing of members' messages by their conversation state
+func NewConversations(states *state.Manager) *Conversations {
+	return &Conversations{
+		states: states,
+		text:   make(map[state.State]telegram.Handler),
+		photos: make(map[state.State]telegram.Handler),
+	}
+}
+
+// Handle routes the text of members in a conversation state to a handler
+func (c *Conversations) Handle(conversationState state.State, handler telegram.Handler) {
+	c.text[conversationState] = handler
+}
+
+// HandlePhotos routes the photos of members in a conversation state to a handler
+func (c *Conversations) HandlePhotos(conversationState state.State, handler telegram.Handler) {
+	c.photos[conversationState] = handler
+}
+
+// Fallback routes the text and photos that no conversation handles, e.g. of members who aren't in one
+func (c *Conversations) Fallback(handler telegram.Handler) {
+	c.fallback = handler
+}
+
+// Route handles a message with the handler of the conversation its sender is in
+// Messages with neither text nor a photo, e.g. stickers, are ignored
+func (c *Conversations) Route(ctx *telegram.Context) {
+	if ctx.Message.From == nil {
+		return
+	}
+
+	var handlers map[state.State]telegram.Handler
+	switch {
+	case len(ctx.Message.Photo) > 0:
+		handlers = c.photos
+	case ctx.Message.Text != "":
+		handlers = c.text
+	default:
+		return
+	}
+
+	handler, ok := handlers[c.states.GetState(ctx.ChatID, ctx.UserID)]
+	if !ok {
+		handler = c.fallback
+	}
+	if handler != nil {
+		handler(ctx)
+	}
+}
+
+// Allowed reports whether the dinner's workflow allows a member's action, given the error of checking its guard
+// An action the workflow turns down, e.g. rating a dinner that isn't served, is answered with refused;
+// a dinner without a workflow is turned down the same way, since every dinner starts one
+func Allowed(ctx *telegram.Context, err error, refused string) bool {
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, workflow.ErrWrongState), errors.Is(err, workflow.ErrIllegalTransition), errors.Is(err, workflow.ErrNoWorkflow):
+		ctx.Logger.Info("Turned down %s: %v", ctx.Route, err)
+		ctx.Answer(refused)
+		return false
+	default:
+		ctx.Fail("Failed to check the dinner's workflow: %v", err)
+		return false
+	}
+}
@@ -0,0 +1,3 @@
+// Package kitchen provides cooking dinner: volunteering to cook, the recipe card with its portions,
+// going through the steps with timers, serving dinner and rating it.
+package kitchen
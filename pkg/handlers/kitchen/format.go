@@ -0,0 +1,119 @@
+package kitchen
+
+import (
+	"fmt"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/korjavin/whatsfordinner/pkg/cooking"
+	"github.com/korjavin/whatsfordinner/pkg/dinner"
+	"github.com/korjavin/whatsfordinner/pkg/models"
+	"github.com/korjavin/whatsfordinner/pkg/nutrition"
+)
+
+// formatCookingStep formats one step of step-by-step cooking, with the timers that are running
+func formatCookingStep(dish models.Dish, step int, timers []models.CookingTimer, location *time.Location) string {
+	text := fmt.Sprintf("👩‍🍳 %s – step %d of %d\n\n%s\n", dish.Name, step+1, len(dish.Instructions), dish.Instructions[step])
+
+	if len(timers) > 0 {
+		text += "\n⏲️ Timers:\n"
+		for _, timer := range timers {
+			text += fmt.Sprintf("• %s for step %d – rings at %s\n", timer.Label, timer.Step+1, timer.FiresAt.In(location).Format("15:04"))
+		}
+	}
+
+	return text
+}
+
+// cookingStepKeyboard creates the buttons under a step: its timers, "Back" and "Next step",
+// or "Dinner is ready" on the last step
+func cookingStepKeyboard(dinnerEvent *models.Dinner, step int) tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+
+	var timerRow []tgbotapi.InlineKeyboardButton
+	for i, duration := range cooking.Durations(dinnerEvent.Dish.Instructions[step]) {
+		label := "⏲️ " + cooking.FormatDuration(duration.Duration)
+		timerRow = append(timerRow, tgbotapi.NewInlineKeyboardButtonData(label, fmt.Sprintf("cook_timer:%d:%d:%s", step, i, dinnerEvent.ID)))
+	}
+	if len(timerRow) > 0 {
+		rows = append(rows, timerRow)
+	}
+
+	var navigation []tgbotapi.InlineKeyboardButton
+	if step > 0 {
+		navigation = append(navigation, tgbotapi.NewInlineKeyboardButtonData("◀ Back", "cook_back:"+dinnerEvent.ID))
+	}
+	if step < len(dinnerEvent.Dish.Instructions)-1 {
+		navigation = append(navigation, tgbotapi.NewInlineKeyboardButtonData("Next step ▶", "cook_next:"+dinnerEvent.ID))
+	} else {
+		navigation = append(navigation, tgbotapi.NewInlineKeyboardButtonData("🍽️ Dinner is ready!", "dinner_ready:"+dinnerEvent.ID))
+	}
+	rows = append(rows, navigation)
+
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// portionOptions are the numbers of people offered by "Adjust portions"
+var portionOptions = []int{1, 2, 3, 4, 5, 6, 8, 10}
+
+// cookingInstructions formats the cook's instructions with the amounts scaled to the number of people
+func cookingInstructions(dinnerEvent *models.Dinner) string {
+	dish := dinner.Scaled(dinnerEvent)
+	text := fmt.Sprintf("🍳 *Cooking Instructions for %s*\n", dish.Name)
+	if dinnerEvent.Servings > 0 {
+		text += fmt.Sprintf("👥 For %d people\n", dinnerEvent.Servings)
+	}
+	if dish.Nutrition != nil {
+		text += fmt.Sprintf("🔥 %s per serving\n", nutrition.Format(dish.Nutrition))
+	}
+	text += "\n"
+
+	if len(dish.Ingredients) > 0 {
+		text += "*Ingredients:*\n"
+		for _, ingredient := range dish.Ingredients {
+			text += fmt.Sprintf("• %s\n", ingredient)
+		}
+		text += "\n"
+	}
+
+	if len(dish.Instructions) > 0 {
+		text += "*Instructions:*\n"
+		for i, instruction := range dish.Instructions {
+			text += fmt.Sprintf("%d. %s\n", i+1, instruction)
+		}
+	}
+
+	return text
+}
+
+// cookingKeyboard creates the buttons under the cook's instructions
+func cookingKeyboard(dinnerID string) tgbotapi.InlineKeyboardMarkup {
+	return tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("🍽️ Dinner is ready!", "dinner_ready:"+dinnerID),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("👥 Adjust portions", "portions:"+dinnerID),
+		),
+	)
+}
+
+// portionsKeyboard lets the family pick how many people the dinner is for
+func portionsKeyboard(dinnerID string, current int) tgbotapi.InlineKeyboardMarkup {
+	var row []tgbotapi.InlineKeyboardButton
+	for _, n := range portionOptions {
+		label := fmt.Sprintf("%d", n)
+		if n == current {
+			label = "✅ " + label
+		}
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData(label, fmt.Sprintf("portions_set:%d:%s", n, dinnerID)))
+	}
+
+	return tgbotapi.NewInlineKeyboardMarkup(
+		row[:len(row)/2],
+		row[len(row)/2:],
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("⬅️ Back", "portions_set:0:"+dinnerID),
+		),
+	)
+}
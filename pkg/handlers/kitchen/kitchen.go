@@ -0,0 +1,647 @@
+package kitchen
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/korjavin/whatsfordinner/pkg/attendance"
+	"github.com/korjavin/whatsfordinner/pkg/cooking"
+	"github.com/korjavin/whatsfordinner/pkg/dinner"
+	"github.com/korjavin/whatsfordinner/pkg/handlers"
+	"github.com/korjavin/whatsfordinner/pkg/handlers/groceries"
+	"github.com/korjavin/whatsfordinner/pkg/logger"
+	"github.com/korjavin/whatsfordinner/pkg/models"
+	"github.com/korjavin/whatsfordinner/pkg/openai"
+	"github.com/korjavin/whatsfordinner/pkg/planner"
+	"github.com/korjavin/whatsfordinner/pkg/poll"
+	"github.com/korjavin/whatsfordinner/pkg/telegram"
+	"github.com/korjavin/whatsfordinner/pkg/workflow"
+)
+
+// handler handles the cook: volunteering, cooking step by step, portions, serving and rating dinner
+type handler struct {
+	*handlers.Deps
+	log *logger.Logger
+}
+
+// Register routes the cook's buttons, from volunteering to rating dinner
+func Register(router *telegram.Router, deps *handlers.Deps) {
+	h := &handler{Deps: deps, log: logger.New("kitchen")}
+
+	router.CallbackPrefix("volunteer:", h.handleVolunteer)
+	router.CallbackPrefix("cook_start:", h.handleCookStart)
+	router.CallbackPrefix("no_dinner:", h.handleNoDinner)
+	router.CallbackPrefix("portions:", h.handlePortions)
+	router.CallbackPrefix("portions_set:", h.handlePortionsSet)
+	router.CallbackPrefix("cook_next:", h.handleCookNext)
+	router.CallbackPrefix("cook_back:", h.handleCookBack)
+	router.CallbackPrefix("cook_timer:", h.handleCookTimer)
+	router.CallbackPrefix("dinner_ready:", h.handleDinnerReady)
+	router.CallbackPrefix("rate:", h.handleRate)
+}
+
+// advanceWorkflow moves the channel's dinner workflow to a state and reports whether its guard allowed it;
+// a move it turns down is answered with refused
+func (h *handler) advanceWorkflow(ctx *telegram.Context, to workflow.State, reason, refused string) bool {
+	_, err := h.Workflow.Advance(ctx.ChatID, to, reason)
+	return handlers.Allowed(ctx, err, refused)
+}
+
+// startCooking starts the dinner of a vote with its cook: the recipe card, the steps and the shopping list
+func (h *handler) startCooking(chatID int64, vote *models.VoteState, userID, username string) {
+	pollID := vote.PollID
+
+	// Use the family's own recipe if the dish is in their book
+	var dish models.Dish
+	if recipe, err := h.Recipes.Get(chatID, vote.WinningDish); err == nil {
+		dish = recipe.Dish
+	} else {
+		// Get dish information from OpenAI, with a recipe that suits everyone's diet
+		channelSettings, err := h.Settings.Get(chatID)
+		if err != nil {
+			h.log.Error("Failed to get settings: %v", err)
+		}
+		prefs := h.Diet.Apply(chatID, openai.Preferences{
+			DietaryRules: channelSettings.DietaryRules,
+			Language:     channelSettings.LanguageName(),
+		})
+		dishInfo, err := h.OpenAI.GetDishInfo(vote.WinningDish, prefs)
+		if err != nil {
+			h.log.Error("Failed to get dish info: %v", err)
+			h.Bot.SendMessage(chatID, fmt.Sprintf("😢 Sorry, I couldn't find cooking instructions for %s. @%s, you're on your own for this one!", vote.WinningDish, username))
+			return
+		}
+
+		dish = dinner.DishFromInfo(dishInfo)
+		if dish.Name == "" {
+			dish.Name = vote.WinningDish // Fallback to the winning dish name
+		}
+	}
+	dishName := dish.Name
+
+	// Create a dinner event
+	dinnerEvent, err := h.Dinner.CreateDinner(chatID, dish, userID)
+	if err != nil {
+		h.log.Error("Failed to create dinner event: %v", err)
+		h.Bot.SendMessage(chatID, fmt.Sprintf("😢 Sorry, something went wrong while starting dinner. @%s, happy cooking anyway!", username))
+		return
+	}
+	if err := h.Workflow.Link(chatID, "", dinnerEvent.ID); err != nil {
+		h.log.Error("Failed to link dinner to its workflow: %v", err)
+	}
+
+	// Update cook statistics with the username
+	err = h.Stats.UpdateCookStats(chatID, userID, username, 0) // No rating yet
+	if err != nil {
+		h.log.Error("Failed to update cook stats: %v", err)
+		// Continue anyway
+	}
+
+	// Update suggester statistics if this was a user-suggested dish
+	// We would need to check if the dish was suggested by a user and update their stats
+	// For now, we'll just update the cook's stats when the dinner is rated
+
+	// Send cooking instructions, with the amounts scaled to the number of people
+	h.Bot.SendMessageWithKeyboard(chatID, cookingInstructions(dinnerEvent), cookingKeyboard(dinnerEvent.ID))
+
+	// Walk the cook through the steps one at a time, with timers for the waits
+	if len(dinnerEvent.Dish.Instructions) > 0 {
+		channelSettings, _ := h.Settings.Get(chatID)
+		stepMsg, err := h.Bot.SendMessageWithKeyboard(chatID, formatCookingStep(dinnerEvent.Dish, 0, nil, channelSettings.Location), cookingStepKeyboard(dinnerEvent, 0))
+		if err != nil {
+			h.log.Error("Failed to send the first cooking step: %v", err)
+		} else if _, err := h.Cooking.Start(dinnerEvent, username, stepMsg.MessageID); err != nil {
+			h.log.Error("Failed to start cooking session: %v", err)
+		}
+	}
+
+	// Planned dinners were already shopped for when the week plan was saved
+	if planner.IsPlanPoll(pollID) {
+		return
+	}
+
+	// Put whatever the fridge is missing on the shopping list and ask for a volunteer to buy it
+	missingIngredients, err := h.Shopping.PlanForDish(chatID, dinner.Scaled(dinnerEvent))
+	if err != nil {
+		h.log.Error("Failed to plan shopping for %s: %v", dishName, err)
+		return
+	}
+
+	if len(missingIngredients) > 0 {
+		shoppingList, err := h.Shopping.GetList(chatID)
+		if err != nil {
+			h.log.Error("Failed to get shopping list: %v", err)
+			return
+		}
+
+		msgText := fmt.Sprintf("🛒 *Missing for %s:*\n", dishName)
+		for _, ingredient := range missingIngredients {
+			msgText += fmt.Sprintf("• %s\n", ingredient)
+		}
+		msgText += "\nWho can buy them?"
+
+		h.Bot.SendMessageWithKeyboard(chatID, msgText, groceries.Keyboard(shoppingList))
+	}
+}
+
+// handleVolunteer handles volunteer for cooking
+// Volunteers are gathered for the channel's volunteer window, then the scheduler picks the cook;
+// without a window the first volunteer cooks
+func (h *handler) handleVolunteer(ctx *telegram.Context) {
+	callback := ctx.Callback
+	chatID := ctx.ChatID
+	userID := ctx.MemberID()
+	username := ctx.Username
+
+	pollID := ctx.Payload.String()
+
+	// Only members eating at home can cook
+	if attendance.IsAway(h.Attendance.Get(chatID), userID) {
+		ctx.Answer("You said you're not eating at home tonight. Press 🏠 I'm in first if you are.")
+		return
+	}
+
+	// Volunteers are only taken while the family is waiting for a cook of this vote's dish
+	if !handlers.Allowed(ctx, h.Workflow.RequireVote(chatID, pollID, workflow.AwaitingCook), "The cook for tonight has already been decided.") {
+		return
+	}
+
+	// Add the volunteer
+	vote, err := h.Poll.AddCookVolunteer(chatID, pollID, userID, username)
+	if err != nil {
+		switch {
+		case errors.Is(err, poll.ErrCookDecided):
+			ctx.Answer("The cook for tonight has already been decided.")
+		case errors.Is(err, poll.ErrNotWinningVoter):
+			ctx.Answer("Only those who voted for the winning dish can volunteer.")
+		default:
+			ctx.Fail("Failed to add cook volunteer: %v", err)
+		}
+		return
+	}
+
+	if err := h.Rotation.Seen(chatID, userID, username); err != nil {
+		h.log.Error("Failed to add %s to the cook rotation: %v", username, err)
+	}
+
+	channelSettings, _ := h.Settings.Get(chatID)
+	if channelSettings.VolunteerWindow > 0 {
+		pickAt := vote.FirstVolunteerAt.Add(channelSettings.VolunteerWindow).In(channelSettings.Location)
+		ctx.Answer(fmt.Sprintf("Thanks for volunteering! I'll pick the cook at %s.", pickAt.Format("15:04")))
+
+		// Show who volunteered so far, keeping the buttons for the others
+		msgText := fmt.Sprintf("Who wants to cook *%s* tonight? Press the button below to volunteer!\n\n🙋 Volunteers: @%s\n🎲 I'll pick the cook at %s.", vote.WinningDish, strings.Join(poll.VolunteerNames(vote), ", @"), pickAt.Format("15:04"))
+		if callback.Message.ReplyMarkup != nil {
+			h.Bot.EditMessage(chatID, callback.Message.MessageID, msgText, *callback.Message.ReplyMarkup)
+		}
+		return
+	}
+
+	// The first volunteer cooks
+	err = h.Poll.SelectCook(chatID, pollID, userID)
+	if err != nil {
+		if errors.Is(err, poll.ErrCookDecided) {
+			ctx.Answer("The cook for tonight has already been decided.")
+			return
+		}
+		ctx.Fail("Failed to select cook: %v", err)
+		return
+	}
+	if !h.advanceWorkflow(ctx, workflow.Cooking, fmt.Sprintf("@%s volunteered", username), "Dinner is already being cooked.") {
+		return
+	}
+
+	// Answer the callback
+	ctx.Answer("Thanks for volunteering to cook!")
+
+	// Edit the message to remove the buttons
+	editMsg := tgbotapi.NewEditMessageText(chatID, callback.Message.MessageID, fmt.Sprintf("@%s has volunteered to cook %s tonight!", username, vote.WinningDish))
+	editMsg.ReplyMarkup = &tgbotapi.InlineKeyboardMarkup{}
+	h.Bot.Send(editMsg)
+
+	h.startCooking(chatID, vote, userID, username)
+}
+
+// handleCookStart handles "Start cooking" of the cook picked from the volunteers
+func (h *handler) handleCookStart(ctx *telegram.Context) {
+	callback := ctx.Callback
+	chatID := ctx.ChatID
+	userID := ctx.MemberID()
+	pollID := ctx.Payload.String()
+
+	// The picked cook starts while the family waits for them, and only once
+	if !handlers.Allowed(ctx, h.Workflow.RequireVote(chatID, pollID, workflow.AwaitingCook), "Dinner is already being cooked.") {
+		return
+	}
+
+	vote, err := h.Poll.GetVote(chatID, pollID)
+	if err != nil {
+		ctx.Fail("Failed to get vote: %v", err)
+		return
+	}
+
+	if vote.SelectedCook != userID {
+		ctx.Answer(fmt.Sprintf("Only @%s can start cooking tonight.", vote.VolunteerNames[vote.SelectedCook]))
+		return
+	}
+
+	// Start the dinner only once, however often the button is pressed
+	username := vote.VolunteerNames[userID]
+	if !h.advanceWorkflow(ctx, workflow.Cooking, fmt.Sprintf("@%s started cooking", username), "Dinner is already being cooked.") {
+		return
+	}
+
+	ctx.Answer("Happy cooking!")
+
+	// Remove the button
+	h.Bot.EditMessage(chatID, callback.Message.MessageID, fmt.Sprintf("👩‍🍳 @%s is cooking %s tonight!", username, vote.WinningDish))
+
+	h.startCooking(chatID, vote, userID, username)
+}
+
+// handleNoDinner handles "No dinner today" on the request for a cook
+func (h *handler) handleNoDinner(ctx *telegram.Context) {
+	callback := ctx.Callback
+	chatID := ctx.ChatID
+	username := ctx.Username
+	pollID := ctx.Payload.String()
+
+	// Dinner can be called off while the family waits for a cook of this vote's dish
+	if !handlers.Allowed(ctx, h.Workflow.RequireVote(chatID, pollID, workflow.AwaitingCook), "The cook for tonight has already been decided.") {
+		return
+	}
+
+	vote, err := h.Poll.CancelDinner(chatID, pollID)
+	if err != nil {
+		if errors.Is(err, poll.ErrCookDecided) {
+			ctx.Answer("The cook for tonight has already been decided.")
+			return
+		}
+		ctx.Fail("Failed to call off dinner: %v", err)
+		return
+	}
+
+	if _, err := h.Workflow.Advance(chatID, workflow.Cancelled, fmt.Sprintf("@%s called dinner off", username)); err != nil {
+		h.log.Error("Failed to cancel the workflow of channel %d: %v", chatID, err)
+	}
+
+	ctx.Answer("Dinner is off for today.")
+	h.Bot.EditMessage(chatID, callback.Message.MessageID, fmt.Sprintf("🙅 @%s called dinner off, no %s today.", username, vote.WinningDish))
+}
+
+// handlePortions handles "Adjust portions" on the cook's instructions
+// The format is "portions:dinner:{channelID}:{timestamp}"
+func (h *handler) handlePortions(ctx *telegram.Context) {
+	callback := ctx.Callback
+	chatID := ctx.ChatID
+	dinnerID := ctx.Payload.String()
+
+	var dinnerEvent models.Dinner
+	if err := h.Store.Get(dinnerID, &dinnerEvent); err != nil {
+		ctx.Fail("Failed to get dinner event: %v", err)
+		return
+	}
+	if !dinnerEvent.FinishedAt.IsZero() {
+		ctx.Answer("This dinner is already served.")
+		return
+	}
+
+	ctx.Answer("How many people are eating?")
+	editMsg := tgbotapi.NewEditMessageReplyMarkup(chatID, callback.Message.MessageID, portionsKeyboard(dinnerID, dinnerEvent.Servings))
+	h.Bot.Send(editMsg)
+}
+
+// handlePortionsSet handles a number of people picked under "Adjust portions"
+// The format is "portions_set:{servings}:dinner:{channelID}:{timestamp}", 0 goes back without a change
+func (h *handler) handlePortionsSet(ctx *telegram.Context) {
+	callback := ctx.Callback
+	chatID := ctx.ChatID
+
+	fields, err := ctx.Payload.Split(2)
+	if err != nil {
+		ctx.Fail("Invalid callback data: %v", err)
+		return
+	}
+	servings, err := strconv.Atoi(fields[0])
+	if err != nil {
+		ctx.Fail("Invalid servings in callback data: %s", callback.Data)
+		return
+	}
+	dinnerID := fields[1]
+
+	if servings == 0 {
+		ctx.Answer("")
+		h.Bot.Send(tgbotapi.NewEditMessageReplyMarkup(chatID, callback.Message.MessageID, cookingKeyboard(dinnerID)))
+		return
+	}
+
+	dinnerEvent, err := h.Dinner.SetServings(dinnerID, servings)
+	if err != nil {
+		ctx.Fail("Failed to set servings: %v", err)
+		return
+	}
+
+	ctx.Answer(fmt.Sprintf("Cooking for %d", servings))
+	editMsg := tgbotapi.NewEditMessageText(chatID, callback.Message.MessageID, cookingInstructions(dinnerEvent))
+	keyboard := cookingKeyboard(dinnerID)
+	editMsg.ReplyMarkup = &keyboard
+	h.Bot.Send(editMsg)
+}
+
+// moveCookingStep turns the cook's step message by delta steps
+// The format is "cook_next:dinner:{channelID}:{timestamp}", and the same for "cook_back"
+func (h *handler) moveCookingStep(ctx *telegram.Context, delta int) {
+	callback := ctx.Callback
+	chatID := ctx.ChatID
+	userID := ctx.MemberID()
+	dinnerID := ctx.Payload.String()
+
+	var dinnerEvent models.Dinner
+	if err := h.Store.Get(dinnerID, &dinnerEvent); err != nil {
+		ctx.Fail("Failed to get dinner event: %v", err)
+		return
+	}
+	if dinnerEvent.Cook != userID {
+		ctx.Answer("Only the cook can go through the steps.")
+		return
+	}
+
+	session, err := h.Cooking.Move(chatID, dinnerID, delta, len(dinnerEvent.Dish.Instructions))
+	if err != nil {
+		if !errors.Is(err, cooking.ErrNoSession) && !errors.Is(err, cooking.ErrStaleSession) {
+			h.log.Error("Failed to move cooking session: %v", err)
+		}
+		ctx.Answer("This cooking session is over.")
+		h.Bot.EditMessageKeyboard(chatID, callback.Message.MessageID, tgbotapi.InlineKeyboardMarkup{InlineKeyboard: [][]tgbotapi.InlineKeyboardButton{}})
+		return
+	}
+
+	timers, err := h.Cooking.Timers(chatID)
+	if err != nil {
+		h.log.Error("Failed to get timers: %v", err)
+	}
+
+	channelSettings, _ := h.Settings.Get(chatID)
+	ctx.Answer("")
+	h.Bot.EditMessage(chatID, callback.Message.MessageID, formatCookingStep(dinnerEvent.Dish, session.Step, timers, channelSettings.Location), cookingStepKeyboard(&dinnerEvent, session.Step))
+}
+
+// handleCookNext handles "Next step" in step-by-step cooking
+func (h *handler) handleCookNext(ctx *telegram.Context) {
+	h.moveCookingStep(ctx, 1)
+}
+
+// handleCookBack handles "Back" in step-by-step cooking
+func (h *handler) handleCookBack(ctx *telegram.Context) {
+	h.moveCookingStep(ctx, -1)
+}
+
+// handleCookTimer handles a timer button in step-by-step cooking
+// The format is "cook_timer:{step}:{duration index}:dinner:{channelID}:{timestamp}"
+func (h *handler) handleCookTimer(ctx *telegram.Context) {
+	callback := ctx.Callback
+	chatID := ctx.ChatID
+	userID := ctx.MemberID()
+
+	fields, err := ctx.Payload.Split(3)
+	if err != nil {
+		ctx.Fail("Invalid callback data: %v", err)
+		return
+	}
+	step, err := strconv.Atoi(fields[0])
+	if err != nil {
+		ctx.Fail("Invalid step in callback data: %s", callback.Data)
+		return
+	}
+	index, err := strconv.Atoi(fields[1])
+	if err != nil {
+		ctx.Fail("Invalid timer in callback data: %s", callback.Data)
+		return
+	}
+	dinnerID := fields[2]
+
+	var dinnerEvent models.Dinner
+	if err := h.Store.Get(dinnerID, &dinnerEvent); err != nil {
+		ctx.Fail("Failed to get dinner event: %v", err)
+		return
+	}
+	if dinnerEvent.Cook != userID {
+		ctx.Answer("Only the cook can start timers.")
+		return
+	}
+
+	session, err := h.Cooking.Get(chatID)
+	if err != nil || session.DinnerID != dinnerID {
+		ctx.Answer("This cooking session is over.")
+		return
+	}
+
+	if step >= len(dinnerEvent.Dish.Instructions) {
+		ctx.Fail("Invalid step in callback data: %s", callback.Data)
+		return
+	}
+	durations := cooking.Durations(dinnerEvent.Dish.Instructions[step])
+	if index < 0 || index >= len(durations) {
+		ctx.Fail("Invalid timer in callback data: %s", callback.Data)
+		return
+	}
+
+	if _, err := h.Cooking.StartTimer(session, step, durations[index]); err != nil {
+		ctx.Fail("Failed to start timer: %v", err)
+		return
+	}
+	ctx.Answer(fmt.Sprintf("⏲️ Timer set for %s. I'll ping you!", cooking.FormatDuration(durations[index].Duration)))
+
+	// Show the running timer under the step
+	timers, err := h.Cooking.Timers(chatID)
+	if err != nil {
+		h.log.Error("Failed to get timers: %v", err)
+	}
+	channelSettings, _ := h.Settings.Get(chatID)
+	h.Bot.EditMessage(chatID, callback.Message.MessageID, formatCookingStep(dinnerEvent.Dish, session.Step, timers, channelSettings.Location), cookingStepKeyboard(&dinnerEvent, session.Step))
+}
+
+// handleDinnerReady handles dinner ready callback
+func (h *handler) handleDinnerReady(ctx *telegram.Context) {
+	callback := ctx.Callback
+	chatID := ctx.ChatID
+	userID := ctx.MemberID()
+	username := ctx.Username
+
+	// The format is "dinner_ready:dinner:{channelID}:{timestamp}"
+	dinnerID := ctx.Payload.String()
+
+	// Get the dinner event
+	h.log.Info("Looking up dinner with ID: %s", dinnerID)
+	var dinnerEvent models.Dinner
+	err := h.Store.Get(dinnerID, &dinnerEvent)
+	if err != nil {
+		ctx.Fail("Failed to get dinner event: %v", err)
+		return
+	}
+	h.log.Info("Successfully found dinner: %s cooked by %s", dinnerEvent.Dish.Name, dinnerEvent.Cook)
+
+	// Check if the user is the cook
+	if dinnerEvent.Cook != userID {
+		ctx.Answer("Only the cook can mark dinner as ready.")
+		return
+	}
+
+	// Serve the dinner only once, and only while it's cooking
+	_, err = h.Workflow.AdvanceDinner(chatID, dinnerID, workflow.Served, fmt.Sprintf("@%s said dinner is ready", username))
+	if !handlers.Allowed(ctx, err, "This dinner was already served or called off.") {
+		return
+	}
+
+	// Mark the dinner as finished
+	err = h.Dinner.FinishDinner(chatID)
+	if err != nil {
+		ctx.Fail("Failed to finish dinner: %v", err)
+		return
+	}
+
+	// Answer the callback
+	ctx.Answer("Dinner is ready!")
+
+	// Edit the message to remove the buttons
+	editMsg := tgbotapi.NewEditMessageText(chatID, callback.Message.MessageID, callback.Message.Text+"\n\n✅ Dinner is ready!")
+	editMsg.ReplyMarkup = &tgbotapi.InlineKeyboardMarkup{}
+	h.Bot.Send(editMsg)
+
+	// Stop cooking step by step; the timers aren't needed anymore
+	session, err := h.Cooking.End(chatID)
+	if err != nil && !errors.Is(err, cooking.ErrNoSession) {
+		h.log.Error("Failed to end cooking session: %v", err)
+	}
+	if session != nil && session.MessageID != callback.Message.MessageID {
+		h.Bot.EditMessage(chatID, session.MessageID, fmt.Sprintf("👩‍🍳 %s is cooked.\n\n✅ Dinner is ready!", dinnerEvent.Dish.Name))
+	}
+
+	// Send a message to the chat
+	h.Bot.SendMessage(chatID, fmt.Sprintf("🍽️ *Dinner is ready!* @%s has prepared %s. Enjoy your meal!", username, dinnerEvent.Dish.Name))
+
+	// Add rating buttons
+	h.log.Info("Creating rating buttons for dinner ID: %s", dinnerID)
+
+	// Create callback data for each rating
+	rate1 := fmt.Sprintf("rate:%s:1", dinnerID)
+	rate2 := fmt.Sprintf("rate:%s:2", dinnerID)
+	rate3 := fmt.Sprintf("rate:%s:3", dinnerID)
+	rate4 := fmt.Sprintf("rate:%s:4", dinnerID)
+	rate5 := fmt.Sprintf("rate:%s:5", dinnerID)
+
+	h.log.Info("Rating callback example: %s", rate3) // Log one example for debugging
+
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("⭐", rate1),
+			tgbotapi.NewInlineKeyboardButtonData("⭐⭐", rate2),
+			tgbotapi.NewInlineKeyboardButtonData("⭐⭐⭐", rate3),
+			tgbotapi.NewInlineKeyboardButtonData("⭐⭐⭐⭐", rate4),
+			tgbotapi.NewInlineKeyboardButtonData("⭐⭐⭐⭐⭐", rate5),
+		),
+	)
+
+	h.Bot.SendMessageWithKeyboard(chatID, "How would you rate tonight's dinner? Your feedback helps improve future suggestions!", keyboard)
+
+	_, err = h.Workflow.AdvanceDinner(chatID, dinnerID, workflow.Rating, "asked for ratings")
+	if err != nil {
+		h.log.Error("Failed to start rating dinner in its workflow: %v", err)
+	}
+}
+
+// handleRate handles dinner rating callback
+func (h *handler) handleRate(ctx *telegram.Context) {
+	callback := ctx.Callback
+	chatID := ctx.ChatID
+	userID := ctx.MemberID()
+	username := ctx.Username
+
+	// The format is "rate:dinner:{channelID}:{timestamp}:{rating}"
+	dinnerID, ratingField, err := ctx.Payload.SplitLast()
+	if err != nil {
+		ctx.Fail("Invalid callback data: %v", err)
+		return
+	}
+
+	rating, err := strconv.Atoi(ratingField)
+	if err != nil || rating < 1 || rating > 5 {
+		h.log.Error("Invalid rating: %s", ratingField)
+		ctx.Answer("Invalid rating. Please try again.")
+		return
+	}
+
+	h.log.Info("Looking up dinner with ID: %s for rating: %d", dinnerID, rating)
+
+	// Get the dinner event
+	var dinnerEvent models.Dinner
+	err = h.Store.Get(dinnerID, &dinnerEvent)
+	if err != nil {
+		ctx.Fail("Failed to get dinner event: %v", err)
+		return
+	}
+
+	// Ratings are taken once the dinner was served, until its rating closes
+	if err := h.Workflow.RequireDinner(chatID, dinnerID, workflow.Rating); err != nil {
+		refused := "Rating this dinner is closed."
+		if current, _ := h.Workflow.ForDinner(chatID, dinnerID); workflow.IsUnfinished(current) {
+			refused = "This dinner isn't ready yet, you can rate it once it's served."
+		}
+		handlers.Allowed(ctx, err, refused)
+		return
+	}
+
+	// Add the rating
+	err = h.Dinner.RateDinner(dinnerID, userID, rating)
+	if err != nil {
+		ctx.Fail("Failed to rate dinner: %v", err)
+		return
+	}
+
+	// Update cook statistics
+	cookID := dinnerEvent.Cook
+
+	// Try to get the cook's username from the chat members
+	cookUsername := ""
+	// First check if the current user is the cook
+	if cookID == userID {
+		cookUsername = username
+	}
+
+	// Update cook statistics
+	err = h.Stats.UpdateCookStats(chatID, cookID, cookUsername, float64(rating))
+	if err != nil {
+		h.log.Error("Failed to update cook stats: %v", err)
+		// Continue anyway
+	}
+
+	// Answer the callback
+	ctx.Answer(fmt.Sprintf("Thanks for rating %d stars!", rating))
+
+	// Edit the message to remove the buttons
+	editMsg := tgbotapi.NewEditMessageText(chatID, callback.Message.MessageID, fmt.Sprintf("Thanks for your feedback! @%s rated tonight's dinner %d stars.", username, rating))
+	editMsg.ReplyMarkup = &tgbotapi.InlineKeyboardMarkup{}
+	h.Bot.Send(editMsg)
+
+	// Update the fridge by removing used ingredients
+	if len(dinnerEvent.Dish.Ingredients) > 0 {
+		// Ask if they want to update the fridge
+		h.log.Info("Creating update fridge buttons for dinner ID: %s", dinnerID)
+
+		// Create callback data for update fridge
+		updateFridgeCallback := fmt.Sprintf("update_fridge:%s", dinnerID)
+		h.log.Info("Update fridge callback: %s", updateFridgeCallback)
+
+		keyboard := tgbotapi.NewInlineKeyboardMarkup(
+			tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData("Yes, update fridge", updateFridgeCallback),
+				tgbotapi.NewInlineKeyboardButtonData("No, keep as is", "skip_update_fridge"),
+			),
+		)
+
+		h.Bot.SendMessageWithKeyboard(chatID, "Would you like to update your fridge by subtracting the ingredients used for this dinner?", keyboard)
+	}
+}
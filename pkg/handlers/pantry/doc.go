@@ -0,0 +1,4 @@
+// Package pantry provides the fridge: listing, adding and syncing ingredients, their expiry dates,
+// and the ingredients members type or photograph while adding them. It also takes a single word
+// typed outside of any conversation as an ingredient.
+package pantry
@@ -0,0 +1,28 @@
+package pantry
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/korjavin/whatsfordinner/pkg/fridge"
+	"github.com/korjavin/whatsfordinner/pkg/models"
+)
+
+// formatIngredientLine formats a fridge item for display, flagging items that should be used soon
+func formatIngredientLine(ingredient models.Ingredient) string {
+	line := "• " + ingredient.Name
+	if ingredient.Quantity != "" {
+		line += fmt.Sprintf(" (%s)", ingredient.Quantity)
+	}
+
+	now := time.Now()
+	if expiresAt, ok := fridge.ExpiryDate(ingredient); ok {
+		if expiresAt.Before(now) {
+			line += " ⚠️ expired"
+		} else if fridge.IsExpiringSoon(ingredient, now, fridge.UseSoonWindow) {
+			line += fmt.Sprintf(" ⏳ use by %s", expiresAt.Format("Jan 2"))
+		}
+	}
+
+	return line + "\n"
+}
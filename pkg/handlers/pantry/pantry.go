@@ -0,0 +1,660 @@
+package pantry
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/korjavin/whatsfordinner/pkg/dinner"
+	"github.com/korjavin/whatsfordinner/pkg/fridge"
+	"github.com/korjavin/whatsfordinner/pkg/handlers"
+	"github.com/korjavin/whatsfordinner/pkg/logger"
+	"github.com/korjavin/whatsfordinner/pkg/models"
+	"github.com/korjavin/whatsfordinner/pkg/state"
+	"github.com/korjavin/whatsfordinner/pkg/telegram"
+)
+
+// handler handles the fridge commands and buttons, and the ingredients members type or photograph
+type handler struct {
+	*handlers.Deps
+	log *logger.Logger
+}
+
+// Register routes the fridge commands and buttons, and the ingredients typed or photographed
+func Register(router *telegram.Router, deps *handlers.Deps) {
+	h := &handler{Deps: deps, log: logger.New("pantry")}
+
+	router.Command("fridge", h.handleFridge)
+	router.Command("sync_fridge", h.handleSyncFridge)
+	router.Command("show_fridge", h.handleShowFridge)
+	router.Command("add_photo", h.handleAddPhoto)
+	router.Command("add", h.handleAdd)
+	router.Command("expires", h.handleExpires)
+	router.Callback("done_adding", h.handleDoneAdding)
+	router.Callback("add_more", h.handleAddMore)
+	router.Callback("show_fridge", h.handleShowFridgeButton)
+	router.Callback("done_adding_photos", h.handleDoneAddingPhotos)
+	router.Callback("cancel_adding_photos", h.handleCancelAddingPhotos)
+	router.CallbackPrefix("update_fridge:", h.handleUpdateFridge)
+	router.Callback("skip_update_fridge", h.handleSkipUpdateFridge)
+
+	deps.Conversations.Handle(state.StateAddingIngredients, h.handleIngredientsText)
+	deps.Conversations.HandlePhotos(state.StateAddingIngredients, h.handleIngredientsPhoto)
+	deps.Conversations.HandlePhotos(state.StateAddingPhotos, h.handleIngredientsPhoto)
+	deps.Conversations.Fallback(h.handleMessage)
+}
+
+// handleFridge handles /fridge
+func (h *handler) handleFridge(ctx *telegram.Context) {
+	// Show current ingredients
+	chatID := ctx.ChatID
+
+	ingredients, err := h.Fridge.ListIngredients(chatID)
+	if err != nil {
+		h.log.Error("Failed to list ingredients: %v", err)
+		h.Bot.SendMessage(chatID, "😢 Sorry, I couldn't retrieve your fridge contents right now. Please try again later.")
+		return
+	}
+
+	if len(ingredients) == 0 {
+		h.Bot.SendMessage(chatID, "Your fridge is empty! Add ingredients with /sync_fridge or by sending a photo with /add_photo.")
+		return
+	}
+
+	// Create a formatted message with all ingredients
+	msgText := "🧊 Here's what's in your fridge:\n\n"
+
+	// Sort ingredients alphabetically
+	sort.Slice(ingredients, func(i, j int) bool {
+		return ingredients[i].Name < ingredients[j].Name
+	})
+
+	for _, ingredient := range ingredients {
+		msgText += formatIngredientLine(ingredient)
+	}
+
+	h.Bot.SendMessage(chatID, msgText)
+}
+
+// handleSyncFridge handles /sync_fridge
+func (h *handler) handleSyncFridge(ctx *telegram.Context) {
+	// Reset the fridge
+	chatID := ctx.ChatID
+
+	err := h.Fridge.ResetFridge(chatID)
+	if err != nil {
+		h.log.Error("Failed to reset fridge: %v", err)
+		errorMsg := h.Messages.GenerateErrorMessage("reset fridge")
+		h.Bot.SendMessage(chatID, errorMsg)
+		return
+	}
+
+	// Set the chat state to adding ingredients
+	h.State.SetState(chatID, ctx.UserID, state.StateAddingIngredients)
+
+	h.Bot.SendMessage(chatID, "🧹 Fridge reset! Now, please send me a list of ingredients you have. You can send multiple messages, and I'll add all the ingredients to your fridge.")
+}
+
+// handleShowFridge handles /show_fridge
+func (h *handler) handleShowFridge(ctx *telegram.Context) {
+	// This is an alias for the /fridge command
+	// Show current ingredients
+	chatID := ctx.ChatID
+
+	ingredients, err := h.Fridge.ListIngredients(chatID)
+	if err != nil {
+		h.log.Error("Failed to list ingredients: %v", err)
+		h.Bot.SendMessage(chatID, "😢 Sorry, I couldn't retrieve your fridge contents right now. Please try again later.")
+		return
+	}
+
+	if len(ingredients) == 0 {
+		h.Bot.SendMessage(chatID, "Your fridge is empty! Add ingredients with /sync_fridge or by sending a photo with /add_photo.")
+		return
+	}
+
+	// Create a formatted message with all ingredients
+	msgText := "🧊 Here's what's in your fridge:\n\n"
+
+	// Sort ingredients alphabetically
+	sort.Slice(ingredients, func(i, j int) bool {
+		return ingredients[i].Name < ingredients[j].Name
+	})
+
+	for _, ingredient := range ingredients {
+		msgText += formatIngredientLine(ingredient)
+	}
+
+	h.Bot.SendMessage(chatID, msgText)
+}
+
+// handleAddPhoto handles /add_photo
+func (h *handler) handleAddPhoto(ctx *telegram.Context) {
+	message := ctx.Message
+	chatID := ctx.ChatID
+
+	// Set the chat state to adding photos
+	h.State.SetState(chatID, ctx.UserID, state.StateAddingPhotos)
+
+	// If the message already has a photo, process it
+	if message.Photo != nil && len(message.Photo) > 0 {
+		// Get the largest photo (last in the array)
+		photo := message.Photo[len(message.Photo)-1]
+
+		// Send a processing message
+		processingMsg, _ := h.Bot.SendMessage(chatID, "🔍 Processing your photo... This might take a moment.")
+
+		// Get the file URL
+		photoURL, err := h.Bot.GetFileURL(photo.FileID)
+		if err != nil {
+			h.log.Error("Failed to get photo URL: %v", err)
+			h.Bot.SendMessage(chatID, "😢 Sorry, I couldn't process your photo. Please try again.")
+			return
+		}
+
+		// Extract ingredients from the photo
+		ingredients, err := h.OpenAI.ExtractIngredientsFromPhoto(photoURL)
+		if err != nil {
+			h.log.Error("Failed to extract ingredients from photo: %v", err)
+			h.Bot.SendMessage(chatID, "😢 Sorry, I couldn't identify any ingredients in your photo. Please try again with a clearer photo.")
+			return
+		}
+
+		if len(ingredients) == 0 {
+			h.Bot.SendMessage(chatID, "I couldn't identify any ingredients in your photo. Please try again with a clearer photo.")
+			return
+		}
+
+		// Add ingredients to the fridge
+		for _, ingredient := range ingredients {
+			err := h.Fridge.AddIngredient(chatID, ingredient, "")
+			if err != nil {
+				h.log.Error("Failed to add ingredient %s: %v", ingredient, err)
+			}
+		}
+
+		// Edit the processing message to show the results
+		h.Bot.EditMessage(chatID, processingMsg.MessageID, fmt.Sprintf("✅ I found %d ingredients in your photo: %s", len(ingredients), strings.Join(ingredients, ", ")))
+
+		// Ask if they want to add more photos
+		keyboard := tgbotapi.NewInlineKeyboardMarkup(
+			tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData("Done adding photos", "done_adding_photos"),
+			),
+		)
+
+		msg := tgbotapi.NewMessage(chatID, "Send more photos of your fridge or pantry, and I'll extract ingredients from them. Press 'Done' when you're finished.")
+		msg.ReplyMarkup = keyboard
+		h.Bot.Send(msg)
+	} else {
+		// No photo in the command, instruct the user to send photos
+		keyboard := tgbotapi.NewInlineKeyboardMarkup(
+			tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData("Cancel", "cancel_adding_photos"),
+			),
+		)
+
+		msg := tgbotapi.NewMessage(chatID, "📷 Please send photos of your fridge or pantry, and I'll extract ingredients from them. Send as many photos as you need, and I'll process each one. Press 'Cancel' if you want to stop.")
+		msg.ReplyMarkup = keyboard
+		h.Bot.Send(msg)
+	}
+}
+
+// handleAdd handles /add
+func (h *handler) handleAdd(ctx *telegram.Context) {
+	message := ctx.Message
+	// Extract ingredients from text and add them to the fridge
+	chatID := ctx.ChatID
+
+	// Check if there's text in the command
+	args := message.CommandArguments()
+	if args == "" {
+		// No text provided, ask for it
+		h.Bot.SendMessage(chatID, "🍎 Please provide a list of ingredients to add to your fridge. For example: /add eggs, milk, bread")
+		return
+	}
+
+	// Send a processing message
+	processingMsg, _ := h.Bot.SendMessage(chatID, "🔍 Processing your ingredients... This might take a moment.")
+
+	// Parse ingredients from the text
+	ingredients, err := h.OpenAI.ParseIngredientsFromText(args)
+	if err != nil {
+		h.log.Error("Failed to parse ingredients: %v", err)
+		h.Bot.EditMessage(chatID, processingMsg.MessageID, "😢 Sorry, I couldn't understand the ingredients. Please try again with a clearer list.")
+		return
+	}
+
+	if len(ingredients) == 0 {
+		h.Bot.EditMessage(chatID, processingMsg.MessageID, "I couldn't find any ingredients in your message. Please try again with a list of ingredients.")
+		return
+	}
+
+	// Add ingredients to the fridge
+	for _, ingredient := range ingredients {
+		err := h.Fridge.AddIngredient(chatID, ingredient, "")
+		if err != nil {
+			h.log.Error("Failed to add ingredient %s: %v", ingredient, err)
+		}
+	}
+
+	// Edit the processing message to show the results
+	h.Bot.EditMessage(chatID, processingMsg.MessageID, fmt.Sprintf("✅ Added %d ingredients to your fridge: %s", len(ingredients), strings.Join(ingredients, ", ")))
+
+	// Show the updated fridge
+	ingredientList, err := h.Fridge.ListIngredients(chatID)
+	if err != nil {
+		h.log.Error("Failed to list ingredients: %v", err)
+		return
+	}
+
+	if len(ingredientList) == 0 {
+		h.Bot.SendMessage(chatID, "Your fridge is still empty. Try adding ingredients with text or better photos.")
+		return
+	}
+
+	// Create a formatted message with all ingredients
+	msgText := "🧊 Here's what's in your fridge now:\n\n"
+
+	// Sort ingredients alphabetically
+	sort.Slice(ingredientList, func(i, j int) bool {
+		return ingredientList[i].Name < ingredientList[j].Name
+	})
+
+	for _, ingredient := range ingredientList {
+		msgText += formatIngredientLine(ingredient)
+	}
+
+	h.Bot.SendMessage(chatID, msgText)
+}
+
+// handleExpires handles /expires
+func (h *handler) handleExpires(ctx *telegram.Context) {
+	message := ctx.Message
+	// Set a best-before date for a fridge item, e.g. "/expires milk 20.10"
+	chatID := ctx.ChatID
+
+	args := strings.Fields(message.CommandArguments())
+	if len(args) < 2 {
+		h.Bot.SendMessage(chatID, "📅 Tell me what expires when, for example: /expires milk 20.10 or /expires chicken tomorrow")
+		return
+	}
+
+	name := strings.Join(args[:len(args)-1], " ")
+	bestBefore, err := fridge.ParseBestBefore(args[len(args)-1], time.Now())
+	if err != nil {
+		h.Bot.SendMessage(chatID, "😢 I couldn't understand that date. Try 2025-10-20, 20.10, tomorrow or 3d.")
+		return
+	}
+
+	err = h.Fridge.SetBestBefore(chatID, name, bestBefore)
+	if err != nil {
+		h.log.Error("Failed to set best-before date: %v", err)
+		h.Bot.SendMessage(chatID, fmt.Sprintf("😢 I couldn't find %s in your fridge. Check /fridge for the exact name.", name))
+		return
+	}
+
+	h.Bot.SendMessage(chatID, fmt.Sprintf("✅ Got it! %s is best before %s.", name, bestBefore.Format("Mon, Jan 2")))
+}
+
+// handleDoneAdding handles "Done adding ingredients"
+func (h *handler) handleDoneAdding(ctx *telegram.Context) {
+	callback := ctx.Callback
+	chatID := ctx.ChatID
+
+	// Clear the state
+	h.State.ClearState(chatID, ctx.UserID)
+
+	// Answer the callback
+	ctx.Answer("Thanks! Your fridge is now updated.")
+
+	// Edit the message to remove the buttons
+	editMsg := tgbotapi.NewEditMessageText(chatID, callback.Message.MessageID, "✅ Fridge update complete! Use /fridge to see your ingredients or /dinner to get dinner suggestions.")
+	editMsg.ReplyMarkup = &tgbotapi.InlineKeyboardMarkup{}
+	h.Bot.Send(editMsg)
+}
+
+// handleAddMore handles "Add more" while adding ingredients
+func (h *handler) handleAddMore(ctx *telegram.Context) {
+	callback := ctx.Callback
+	chatID := ctx.ChatID
+
+	// Keep the state as is
+
+	// Answer the callback
+	ctx.Answer("Please send more ingredients!")
+
+	// Edit the message to remove the buttons
+	editMsg := tgbotapi.NewEditMessageText(chatID, callback.Message.MessageID, "Please send more ingredients. I'll add them to your fridge.")
+	editMsg.ReplyMarkup = &tgbotapi.InlineKeyboardMarkup{}
+	h.Bot.Send(editMsg)
+}
+
+// handleShowFridgeButton handles a button showing the fridge
+func (h *handler) handleShowFridgeButton(ctx *telegram.Context) {
+	callback := ctx.Callback
+	chatID := ctx.ChatID
+
+	// Answer the callback
+	ctx.Answer("Here's what's in your fridge!")
+
+	// Edit the message to remove the buttons
+	editMsg := tgbotapi.NewEditMessageText(chatID, callback.Message.MessageID, "Here's what's in your fridge:")
+	editMsg.ReplyMarkup = &tgbotapi.InlineKeyboardMarkup{}
+	h.Bot.Send(editMsg)
+
+	// Show fridge contents
+	ingredients, err := h.Fridge.ListIngredients(chatID)
+	if err != nil {
+		h.log.Error("Failed to list ingredients: %v", err)
+		h.Bot.SendMessage(chatID, "😢 Sorry, I couldn't retrieve your fridge contents right now. Please try again later.")
+		return
+	}
+
+	if len(ingredients) == 0 {
+		h.Bot.SendMessage(chatID, "Your fridge is empty! Add ingredients with /sync_fridge or by sending a photo with /add_photo.")
+		return
+	}
+
+	// Create a formatted message with all ingredients
+	msgText := "🧊 Here's what's in your fridge:\n\n"
+
+	// Sort ingredients alphabetically
+	sort.Slice(ingredients, func(i, j int) bool {
+		return ingredients[i].Name < ingredients[j].Name
+	})
+
+	for _, ingredient := range ingredients {
+		msgText += formatIngredientLine(ingredient)
+	}
+
+	h.Bot.SendMessage(chatID, msgText)
+}
+
+// handleDoneAddingPhotos handles "Done adding photos"
+func (h *handler) handleDoneAddingPhotos(ctx *telegram.Context) {
+	callback := ctx.Callback
+	chatID := ctx.ChatID
+
+	// Clear the state
+	h.State.ClearState(chatID, ctx.UserID)
+
+	// Answer the callback
+	ctx.Answer("Thanks! Your fridge is now updated with ingredients from your photos.")
+
+	// Edit the message to remove the buttons
+	editMsg := tgbotapi.NewEditMessageText(chatID, callback.Message.MessageID, "✅ Photo processing complete! I've added all the ingredients I found to your fridge.")
+	editMsg.ReplyMarkup = &tgbotapi.InlineKeyboardMarkup{}
+	h.Bot.Send(editMsg)
+
+	// Show fridge contents
+	ingredients, err := h.Fridge.ListIngredients(chatID)
+	if err != nil {
+		h.log.Error("Failed to list ingredients: %v", err)
+		return
+	}
+
+	if len(ingredients) == 0 {
+		h.Bot.SendMessage(chatID, "Your fridge is still empty. Try adding ingredients with text or better photos.")
+		return
+	}
+
+	// Create a formatted message with all ingredients
+	msgText := "🧊 Here's what's in your fridge:\n\n"
+
+	// Sort ingredients alphabetically
+	sort.Slice(ingredients, func(i, j int) bool {
+		return ingredients[i].Name < ingredients[j].Name
+	})
+
+	for _, ingredient := range ingredients {
+		msgText += formatIngredientLine(ingredient)
+	}
+
+	h.Bot.SendMessage(chatID, msgText)
+
+	// Suggest next steps
+	h.Bot.SendMessage(chatID, "You can now use /dinner to get dinner suggestions based on your ingredients!")
+}
+
+// handleCancelAddingPhotos handles "Cancel" while adding photos
+func (h *handler) handleCancelAddingPhotos(ctx *telegram.Context) {
+	callback := ctx.Callback
+	chatID := ctx.ChatID
+
+	// Clear the state
+	h.State.ClearState(chatID, ctx.UserID)
+
+	// Answer the callback
+	ctx.Answer("Photo adding cancelled.")
+
+	// Edit the message to remove the buttons
+	editMsg := tgbotapi.NewEditMessageText(chatID, callback.Message.MessageID, "Photo adding cancelled. You can use /fridge to see your current ingredients or /dinner to get dinner suggestions.")
+	editMsg.ReplyMarkup = &tgbotapi.InlineKeyboardMarkup{}
+	h.Bot.Send(editMsg)
+}
+
+// handleUpdateFridge handles "Yes, update fridge" after a dinner was rated
+func (h *handler) handleUpdateFridge(ctx *telegram.Context) {
+	callback := ctx.Callback
+	chatID := ctx.ChatID
+
+	// The format is "update_fridge:dinner:{channelID}:{timestamp}"
+	dinnerID := ctx.Payload.String()
+	h.log.Info("Looking up dinner with ID: %s for fridge update", dinnerID)
+
+	// Get the dinner event
+	var dinnerEvent models.Dinner
+	err := h.Store.Get(dinnerID, &dinnerEvent)
+	if err != nil {
+		ctx.Fail("Failed to get dinner event: %v", err)
+		return
+	}
+
+	// Subtract the amounts used for the number of people cooked for from the fridge
+	usedIngredients := dinner.Scaled(&dinnerEvent).Ingredients
+	unchanged, err := h.Fridge.ConsumeIngredients(chatID, usedIngredients)
+	if err != nil {
+		ctx.Fail("Failed to consume ingredients: %v", err)
+		return
+	}
+
+	// Update the dinner with the used ingredients
+	err = h.Dinner.UpdateUsedIngredients(dinnerID, usedIngredients)
+	if err != nil {
+		h.log.Error("Failed to update used ingredients: %v", err)
+		// Continue anyway
+	}
+
+	// Answer the callback
+	ctx.Answer("Fridge updated!")
+
+	// Edit the message to remove the buttons
+	text := "✅ Your fridge has been updated with the amounts used for this dinner."
+	if len(unchanged) > 0 {
+		text += fmt.Sprintf("\n\n⚠️ I couldn't tell how much of %s was used, so I left them as they were. Please check them with /fridge.", strings.Join(unchanged, ", "))
+	}
+	editMsg := tgbotapi.NewEditMessageText(chatID, callback.Message.MessageID, text)
+	editMsg.ReplyMarkup = &tgbotapi.InlineKeyboardMarkup{}
+	h.Bot.Send(editMsg)
+
+	// Show the updated fridge
+	ingredients, err := h.Fridge.ListIngredients(chatID)
+	if err != nil {
+		h.log.Error("Failed to list ingredients: %v", err)
+		return
+	}
+
+	if len(ingredients) == 0 {
+		h.Bot.SendMessage(chatID, "Your fridge is now empty! You might want to add more ingredients with /sync_fridge or /add_photo.")
+		return
+	}
+
+	// Create a formatted message with all ingredients
+	msgText := "🧊 Here's what's left in your fridge:\n\n"
+
+	// Sort ingredients alphabetically
+	sort.Slice(ingredients, func(i, j int) bool {
+		return ingredients[i].Name < ingredients[j].Name
+	})
+
+	for _, ingredient := range ingredients {
+		msgText += formatIngredientLine(ingredient)
+	}
+
+	h.Bot.SendMessage(chatID, msgText)
+}
+
+// handleSkipUpdateFridge handles skip update fridge callback
+func (h *handler) handleSkipUpdateFridge(ctx *telegram.Context) {
+	callback := ctx.Callback
+	chatID := ctx.ChatID
+
+	// Answer the callback
+	ctx.Answer("Fridge not updated.")
+
+	// Edit the message to remove the buttons
+	editMsg := tgbotapi.NewEditMessageText(chatID, callback.Message.MessageID, "Fridge not updated. Your ingredients remain the same.")
+	editMsg.ReplyMarkup = &tgbotapi.InlineKeyboardMarkup{}
+	h.Bot.Send(editMsg)
+}
+
+// handleIngredientsText handles a list of ingredients typed while adding ingredients
+func (h *handler) handleIngredientsText(ctx *telegram.Context) {
+	chatID := ctx.ChatID
+
+	// Parse ingredients from the text
+	ingredients, err := h.OpenAI.ParseIngredientsFromText(ctx.Message.Text)
+	if err != nil {
+		h.log.Error("Failed to parse ingredients: %v", err)
+		h.Bot.SendMessage(chatID, "😢 Sorry, I couldn't understand the ingredients. Please try again with a clearer list.")
+		return
+	}
+
+	if len(ingredients) == 0 {
+		h.Bot.SendMessage(chatID, "I couldn't find any ingredients in your message. Please try again with a list of ingredients.")
+		return
+	}
+
+	// Add ingredients to the fridge
+	for _, ingredient := range ingredients {
+		err := h.Fridge.AddIngredient(chatID, ingredient, "")
+		if err != nil {
+			h.log.Error("Failed to add ingredient %s: %v", ingredient, err)
+		}
+	}
+
+	// Confirm the ingredients were added
+	h.Bot.SendMessage(chatID, fmt.Sprintf("✅ Added %d ingredients to your fridge: %s", len(ingredients), strings.Join(ingredients, ", ")))
+
+	// Ask if they want to add more
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Done adding ingredients", "done_adding"),
+			tgbotapi.NewInlineKeyboardButtonData("Add more", "add_more"),
+		),
+	)
+
+	msg := tgbotapi.NewMessage(chatID, "Would you like to add more ingredients or are you done?")
+	msg.ReplyMarkup = keyboard
+	h.Bot.Send(msg)
+}
+
+// handleIngredientsPhoto handles a photo sent while adding ingredients or photos
+func (h *handler) handleIngredientsPhoto(ctx *telegram.Context) {
+	chatID := ctx.ChatID
+	chatState := h.State.GetState(chatID, ctx.UserID)
+
+	// Get the largest photo (last in the array)
+	photo := ctx.Message.Photo[len(ctx.Message.Photo)-1]
+
+	// Send a processing message
+	processingMsg, _ := h.Bot.SendMessage(chatID, "🔍 Processing your photo... This might take a moment.")
+
+	// Get the file URL
+	photoURL, err := h.Bot.GetFileURL(photo.FileID)
+	if err != nil {
+		h.log.Error("Failed to get photo URL: %v", err)
+		h.Bot.SendMessage(chatID, "😢 Sorry, I couldn't process your photo. Please try again.")
+		return
+	}
+
+	// Extract ingredients from the photo
+	ingredients, err := h.OpenAI.ExtractIngredientsFromPhoto(photoURL)
+	if err != nil {
+		h.log.Error("Failed to extract ingredients from photo: %v", err)
+		h.Bot.SendMessage(chatID, "😢 Sorry, I couldn't identify any ingredients in your photo. Please try again with a clearer photo.")
+		return
+	}
+
+	if len(ingredients) == 0 {
+		h.Bot.SendMessage(chatID, "I couldn't identify any ingredients in your photo. Please try again with a clearer photo.")
+		return
+	}
+
+	// Add ingredients to the fridge
+	for _, ingredient := range ingredients {
+		err := h.Fridge.AddIngredient(chatID, ingredient, "")
+		if err != nil {
+			h.log.Error("Failed to add ingredient %s: %v", ingredient, err)
+		}
+	}
+
+	// Edit the processing message to show the results
+	h.Bot.EditMessage(chatID, processingMsg.MessageID, fmt.Sprintf("✅ I found %d ingredients in your photo: %s", len(ingredients), strings.Join(ingredients, ", ")))
+
+	// Different buttons based on the state
+	var keyboard tgbotapi.InlineKeyboardMarkup
+	var promptText string
+
+	if chatState == state.StateAddingIngredients {
+		// For text-based ingredient adding
+		keyboard = tgbotapi.NewInlineKeyboardMarkup(
+			tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData("Done adding ingredients", "done_adding"),
+				tgbotapi.NewInlineKeyboardButtonData("Add more", "add_more"),
+			),
+		)
+		promptText = "Would you like to add more ingredients or are you done?"
+	} else {
+		// For photo-based ingredient adding
+		keyboard = tgbotapi.NewInlineKeyboardMarkup(
+			tgbotapi.NewInlineKeyboardRow(
+				tgbotapi.NewInlineKeyboardButtonData("Done adding photos", "done_adding_photos"),
+			),
+		)
+		promptText = "Send more photos of your fridge or pantry, and I'll extract ingredients from them. Press 'Done' when you're finished."
+	}
+
+	msg := tgbotapi.NewMessage(chatID, promptText)
+	msg.ReplyMarkup = keyboard
+	h.Bot.Send(msg)
+}
+
+// handleMessage handles the text and photos outside of a conversation
+// A single word is taken as an ingredient for the fridge
+func (h *handler) handleMessage(ctx *telegram.Context) {
+	chatID := ctx.ChatID
+
+	if len(ctx.Message.Photo) > 0 {
+		// Suggest using /add_photo command
+		h.Bot.SendMessage(chatID, "I see you sent a photo! If you want me to extract ingredients from it, please use the /add_photo command.")
+		return
+	}
+
+	// Regular ingredient adding (single ingredient)
+	// Check if it looks like an ingredient
+	text := ctx.Message.Text
+	if !strings.Contains(text, " ") && len(text) < 30 {
+		err := h.Fridge.AddIngredient(chatID, text, "")
+		if err != nil {
+			h.log.Error("Failed to add ingredient: %v", err)
+			h.Bot.SendMessage(chatID, fmt.Sprintf("😢 Sorry, I couldn't add %s to your fridge.", text))
+			return
+		}
+
+		h.Bot.SendMessage(chatID, fmt.Sprintf("✅ Added %s to your fridge!", text))
+	}
+}
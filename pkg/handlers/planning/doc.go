@@ -0,0 +1,3 @@
+// Package planning provides the meal schedule (/schedule and /skip) and the week plan of /week,
+// with the family's votes to keep or swap each day's dish.
+package planning
@@ -0,0 +1,110 @@
+package planning
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/korjavin/whatsfordinner/pkg/models"
+	"github.com/korjavin/whatsfordinner/pkg/planner"
+	"github.com/korjavin/whatsfordinner/pkg/scheduler"
+)
+
+// formatWeekPlan formats a week plan with the approvals of each day
+func formatWeekPlan(plan *models.WeekPlan) string {
+	text := "📅 *Dinners this week:*\n\n"
+	for _, day := range plan.Days {
+		dayName := day.Date
+		if date, err := time.Parse(planner.DateLayout, day.Date); err == nil {
+			dayName = date.Format("Mon, Jan 2")
+		}
+
+		text += fmt.Sprintf("• %s – %s", dayName, day.Dish.Name)
+		if day.Dish.Cuisine != "" {
+			text += fmt.Sprintf(" (%s)", day.Dish.Cuisine)
+		}
+		if len(day.Approvals) > 0 {
+			text += fmt.Sprintf(" 👍%d", len(day.Approvals))
+		}
+		text += "\n"
+	}
+
+	if !plan.Confirmed {
+		text += "\nVote 👍 to keep a dinner or 🔄 to swap it, then save the plan."
+	}
+
+	return text
+}
+
+// weekPlanKeyboard returns the keep and swap buttons of each day and the save and discard buttons
+func weekPlanKeyboard(plan *models.WeekPlan) tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for _, day := range plan.Days {
+		dayName := day.Date
+		if date, err := time.Parse(planner.DateLayout, day.Date); err == nil {
+			dayName = date.Format("Mon")
+		}
+
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("👍 "+dayName, "week_keep:"+day.Date),
+			tgbotapi.NewInlineKeyboardButtonData("🔄 "+dayName, "week_swap:"+day.Date),
+		))
+	}
+
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("🗑 Discard", "week_discard"),
+		tgbotapi.NewInlineKeyboardButtonData("✅ Save plan", "week_save"),
+	))
+
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// hasUpcomingDays reports whether a week plan still has days from today on
+func hasUpcomingDays(plan *models.WeekPlan, today time.Time) bool {
+	for _, day := range plan.Days {
+		if day.Date >= today.Format(planner.DateLayout) {
+			return true
+		}
+	}
+	return false
+}
+
+// scheduleUsage explains how to edit the schedule
+const scheduleUsage = `✏️ Change it with:
+/schedule add Mon-Fri 16:30
+/schedule add weekends 12:00 brunch
+/schedule add Fri skip
+/schedule remove 2
+/schedule clear
+/skip tomorrow, /schedule unskip tomorrow`
+
+// formatSchedule formats a channel's schedule rules and the coming days for the /schedule message
+func formatSchedule(schedule models.Schedule, plan []scheduler.DayPlan) string {
+	text := "📅 *Schedule*\n\n"
+	if len(schedule.Rules) == 0 {
+		text += "Dinner poll every day at the dinner time from /settings.\n"
+	}
+	for i, rule := range schedule.Rules {
+		text += fmt.Sprintf("%d. %s\n", i+1, scheduler.FormatRule(rule))
+	}
+
+	text += "\n*Coming days:*\n"
+	for _, day := range plan {
+		var meals []string
+		for _, meal := range day.Meals {
+			meals = append(meals, fmt.Sprintf("%s %s", meal.At, meal.Name))
+		}
+
+		switch {
+		case day.Skipped:
+			text += fmt.Sprintf("• %s – skipped\n", day.Date.Format("Mon, Jan 2"))
+		case len(meals) == 0:
+			text += fmt.Sprintf("• %s – no polls\n", day.Date.Format("Mon, Jan 2"))
+		default:
+			text += fmt.Sprintf("• %s – %s\n", day.Date.Format("Mon, Jan 2"), strings.Join(meals, ", "))
+		}
+	}
+
+	return text
+}
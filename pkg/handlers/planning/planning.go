@@ -0,0 +1,311 @@
+package planning
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/korjavin/whatsfordinner/pkg/handlers"
+	"github.com/korjavin/whatsfordinner/pkg/handlers/groceries"
+	"github.com/korjavin/whatsfordinner/pkg/logger"
+	"github.com/korjavin/whatsfordinner/pkg/models"
+	"github.com/korjavin/whatsfordinner/pkg/openai"
+	"github.com/korjavin/whatsfordinner/pkg/planner"
+	"github.com/korjavin/whatsfordinner/pkg/scheduler"
+	"github.com/korjavin/whatsfordinner/pkg/settings"
+	"github.com/korjavin/whatsfordinner/pkg/telegram"
+)
+
+// handler handles the meal schedule and the week plan
+type handler struct {
+	*handlers.Deps
+	log *logger.Logger
+}
+
+// Register routes the schedule and week plan commands and buttons
+func Register(router *telegram.Router, deps *handlers.Deps) {
+	h := &handler{Deps: deps, log: logger.New("planning")}
+
+	router.Command("schedule", h.handleSchedule)
+	router.Command("skip", h.handleSkip)
+	router.Command("week", h.handleWeek)
+	router.CallbackPrefix("week_keep:", h.handleWeekKeep)
+	router.CallbackPrefix("week_swap:", h.handleWeekSwap)
+	router.Callback("week_save", h.handleWeekSave)
+	router.Callback("week_discard", h.handleWeekDiscard)
+}
+
+// handleSchedule handles /schedule
+func (h *handler) handleSchedule(ctx *telegram.Context) {
+	message := ctx.Message
+	// Show or edit when meal polls start, e.g. "/schedule add Mon-Fri 16:30" or "/schedule remove 2"
+	chatID := ctx.ChatID
+
+	subcommand, rest, _ := strings.Cut(strings.TrimSpace(message.CommandArguments()), " ")
+	rest = strings.TrimSpace(rest)
+
+	var err error
+	switch strings.ToLower(subcommand) {
+	case "":
+		// Just show the schedule
+	case "add":
+		var rule models.ScheduleRule
+		rule, err = scheduler.ParseRule(rest)
+		if err != nil {
+			h.Bot.SendMessage(chatID, fmt.Sprintf("😢 I couldn't understand that rule: %v.\n\n%s", err, scheduleUsage))
+			return
+		}
+		err = h.Scheduler.AddRule(chatID, rule)
+	case "remove", "delete":
+		number, convErr := strconv.Atoi(rest)
+		if convErr != nil {
+			h.Bot.SendMessage(chatID, "🔢 Tell me the number of the rule to remove, for example: /schedule remove 2")
+			return
+		}
+		_, err = h.Scheduler.RemoveRule(chatID, number-1)
+	case "clear":
+		err = h.Scheduler.ClearRules(chatID)
+	case "unskip":
+		var channelSettings settings.Settings
+		channelSettings, err = h.Settings.Get(chatID)
+		if err != nil {
+			break
+		}
+		var day time.Time
+		day, err = scheduler.ParseSkipDate(rest, channelSettings.Now())
+		if err != nil {
+			h.Bot.SendMessage(chatID, "😢 I couldn't understand that date. Try tomorrow, friday, 24.12 or 2025-12-24.")
+			return
+		}
+		err = h.Scheduler.UnskipDay(chatID, day)
+	default:
+		h.Bot.SendMessage(chatID, scheduleUsage)
+		return
+	}
+	if err != nil {
+		h.log.Error("Failed to update schedule: %v", err)
+		h.Bot.SendMessage(chatID, fmt.Sprintf("😢 That didn't work: %v.", err))
+		return
+	}
+
+	schedule, err := h.Scheduler.GetSchedule(chatID)
+	if err != nil {
+		h.log.Error("Failed to get schedule: %v", err)
+		h.Bot.SendMessage(chatID, "😢 Sorry, I couldn't retrieve the schedule right now. Please try again later.")
+		return
+	}
+
+	plan, err := h.Scheduler.Plan(chatID, 7)
+	if err != nil {
+		h.log.Error("Failed to plan schedule: %v", err)
+		h.Bot.SendMessage(chatID, "😢 Sorry, I couldn't retrieve the schedule right now. Please try again later.")
+		return
+	}
+
+	h.Bot.SendMessage(chatID, formatSchedule(schedule, plan)+"\n"+scheduleUsage)
+}
+
+// handleSkip handles /skip
+func (h *handler) handleSkip(ctx *telegram.Context) {
+	message := ctx.Message
+	// Skip the meal polls on one day, e.g. "/skip tomorrow" when the family eats out
+	chatID := ctx.ChatID
+
+	args := strings.TrimSpace(message.CommandArguments())
+	if args == "" {
+		h.Bot.SendMessage(chatID, "📅 Tell me which day to skip, for example: /skip tomorrow, /skip friday or /skip 24.12")
+		return
+	}
+
+	channelSettings, err := h.Settings.Get(chatID)
+	if err != nil {
+		h.log.Error("Failed to get settings: %v", err)
+		h.Bot.SendMessage(chatID, "😢 Sorry, I couldn't update the schedule right now. Please try again later.")
+		return
+	}
+
+	day, err := scheduler.ParseSkipDate(args, channelSettings.Now())
+	if err != nil {
+		h.Bot.SendMessage(chatID, "😢 I couldn't understand that date. Try tomorrow, friday, 24.12 or 2025-12-24.")
+		return
+	}
+
+	if err := h.Scheduler.SkipDay(chatID, day); err != nil {
+		h.log.Error("Failed to skip day: %v", err)
+		h.Bot.SendMessage(chatID, "😢 Sorry, I couldn't update the schedule right now. Please try again later.")
+		return
+	}
+
+	h.Bot.SendMessage(chatID, fmt.Sprintf("✅ No polls on %s. Changed your mind? /schedule unskip %s", day.Format("Mon, Jan 2"), day.Format("2006-01-02")))
+}
+
+// handleWeek handles /week
+func (h *handler) handleWeek(ctx *telegram.Context) {
+	message := ctx.Message
+	// Plan dinners for the coming week, or show the current plan; "/week new" plans again, "/week discard" drops the plan
+	chatID := ctx.ChatID
+
+	channelSettings, err := h.Settings.Get(chatID)
+	if err != nil {
+		h.log.Error("Failed to get settings for channel %d: %v", chatID, err)
+	}
+
+	switch strings.ToLower(strings.TrimSpace(message.CommandArguments())) {
+	case "discard", "cancel":
+		if err := h.Planner.Discard(chatID); err != nil {
+			h.log.Error("Failed to discard week plan: %v", err)
+		}
+		h.Bot.SendMessage(chatID, "🗑 The week plan is gone. I'll start a poll every day again.")
+		return
+	case "":
+		// Show the current plan if it still has days ahead
+		if plan, err := h.Planner.GetPlan(chatID); err == nil && hasUpcomingDays(plan, channelSettings.Today()) {
+			if plan.Confirmed {
+				h.Bot.SendMessage(chatID, formatWeekPlan(plan)+"\nPlan again with /week new, or drop the plan with /week discard.")
+			} else {
+				h.Bot.SendMessageWithKeyboard(chatID, formatWeekPlan(plan), weekPlanKeyboard(plan))
+			}
+			return
+		}
+	case "new":
+		// Plan again below
+	default:
+		h.Bot.SendMessage(chatID, "📅 Use /week to plan the coming week, /week new to plan again or /week discard to drop the plan.")
+		return
+	}
+
+	// Plan the days that have a dinner in the schedule, starting tomorrow
+	days, err := h.Scheduler.Plan(chatID, 8)
+	if err != nil {
+		h.log.Error("Failed to get schedule: %v", err)
+		h.Bot.SendMessage(chatID, "😢 Sorry, I couldn't plan the week right now. Please try again later.")
+		return
+	}
+
+	var dates []time.Time
+	for _, day := range days[1:] {
+		for _, meal := range day.Meals {
+			if meal.Name == scheduler.DefaultMeal {
+				dates = append(dates, day.Date)
+				break
+			}
+		}
+	}
+	if len(dates) == 0 {
+		h.Bot.SendMessage(chatID, "📅 There are no dinners in your /schedule for the coming week.")
+		return
+	}
+
+	prefs := h.Diet.Apply(chatID, openai.Preferences{
+		Cuisines:     channelSettings.Cuisines,
+		DietaryRules: channelSettings.DietaryRules,
+		Language:     channelSettings.LanguageName(),
+	})
+
+	processingMsg, _ := h.Bot.SendMessage(chatID, "🧐 Planning dinners for the week... This might take a moment.")
+
+	plan, err := h.Planner.Propose(chatID, dates, prefs)
+	if err != nil {
+		h.log.Error("Failed to propose week plan: %v", err)
+		h.Bot.EditMessage(chatID, processingMsg.MessageID, "😢 Sorry, I couldn't plan the week right now. Please try again later.")
+		return
+	}
+
+	editMsg := tgbotapi.NewEditMessageText(chatID, processingMsg.MessageID, formatWeekPlan(plan))
+	keyboard := weekPlanKeyboard(plan)
+	editMsg.ReplyMarkup = &keyboard
+	h.Bot.Send(editMsg)
+}
+
+// handleWeekKeep handles a vote to keep a day's dish in the week plan
+func (h *handler) handleWeekKeep(ctx *telegram.Context) {
+	callback := ctx.Callback
+	chatID := ctx.ChatID
+	userID := ctx.MemberID()
+	date := ctx.Payload.String()
+
+	plan, err := h.Planner.Approve(chatID, date, userID)
+	if err != nil {
+		h.log.Error("Failed to approve planned dinner: %v", err)
+		ctx.Answer("This plan is out of date. Use /week to see the current one.")
+		return
+	}
+
+	ctx.Answer("👍 Noted!")
+	editMsg := tgbotapi.NewEditMessageText(chatID, callback.Message.MessageID, formatWeekPlan(plan))
+	keyboard := weekPlanKeyboard(plan)
+	editMsg.ReplyMarkup = &keyboard
+	h.Bot.Send(editMsg)
+}
+
+// handleWeekSwap handles swapping a day's dish in the week plan for another suggestion
+func (h *handler) handleWeekSwap(ctx *telegram.Context) {
+	callback := ctx.Callback
+	chatID := ctx.ChatID
+	date := ctx.Payload.String()
+
+	plan, err := h.Planner.Swap(chatID, date)
+	if err != nil {
+		if errors.Is(err, planner.ErrNoAlternatives) {
+			ctx.Answer("I'm out of ideas for this day. Try /week new for a fresh plan.")
+			return
+		}
+		h.log.Error("Failed to swap planned dinner: %v", err)
+		ctx.Answer("This plan is out of date. Use /week to see the current one.")
+		return
+	}
+
+	ctx.Answer("🔄 Swapped!")
+	editMsg := tgbotapi.NewEditMessageText(chatID, callback.Message.MessageID, formatWeekPlan(plan))
+	keyboard := weekPlanKeyboard(plan)
+	editMsg.ReplyMarkup = &keyboard
+	h.Bot.Send(editMsg)
+}
+
+// handleWeekSave handles saving the week plan, which also fills the shopping list for the week
+func (h *handler) handleWeekSave(ctx *telegram.Context) {
+	callback := ctx.Callback
+	chatID := ctx.ChatID
+
+	plan, missingCount, err := h.Planner.Confirm(chatID)
+	if err != nil {
+		ctx.Fail("Failed to confirm week plan: %v", err)
+		return
+	}
+
+	ctx.Answer("✅ Plan saved!")
+	editMsg := tgbotapi.NewEditMessageText(chatID, callback.Message.MessageID, formatWeekPlan(plan)+"\n✅ Saved! I'll announce each day's dinner instead of starting a poll.")
+	editMsg.ReplyMarkup = &tgbotapi.InlineKeyboardMarkup{}
+	h.Bot.Send(editMsg)
+
+	if missingCount == 0 {
+		h.Bot.SendMessage(chatID, "🧺 You already have everything for this week!")
+		return
+	}
+
+	shoppingList, err := h.Shopping.GetList(chatID)
+	if err != nil {
+		h.log.Error("Failed to get shopping list: %v", err)
+		return
+	}
+
+	h.Bot.SendMessageWithKeyboard(chatID, "🛒 *Shopping list for the week:*\n"+groceries.FormatList(shoppingList)+"\nWho can buy them?", groceries.Keyboard(shoppingList))
+}
+
+// handleWeekDiscard handles dropping a proposed week plan
+func (h *handler) handleWeekDiscard(ctx *telegram.Context) {
+	callback := ctx.Callback
+	chatID := ctx.ChatID
+
+	if err := h.Planner.Discard(chatID); err != nil {
+		h.log.Error("Failed to discard week plan: %v", err)
+	}
+
+	ctx.Answer("Plan dropped")
+	editMsg := tgbotapi.NewEditMessageText(chatID, callback.Message.MessageID, "🗑 The week plan was dropped. I'll start a poll every day as usual.")
+	editMsg.ReplyMarkup = &tgbotapi.InlineKeyboardMarkup{}
+	h.Bot.Send(editMsg)
+}
@@ -0,0 +1,2 @@
+// Package preferences provides the family's preferences: the /settings wizard, diets and allergies.
+package preferences
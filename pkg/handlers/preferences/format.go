@@ -0,0 +1,267 @@
+package preferences
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/korjavin/whatsfordinner/pkg/models"
+	"github.com/korjavin/whatsfordinner/pkg/poll"
+	"github.com/korjavin/whatsfordinner/pkg/settings"
+)
+
+// settingField describes one setting in the /settings wizard
+type settingField struct {
+	Key     string
+	Label   string
+	Prompt  string
+	Presets [][2]string // Button text and value
+}
+
+// settingFields lists the settings in the order they're shown in the wizard
+var settingFields = []settingField{
+	{"cuisines", "🍝 Cuisines", "🍝 Which cuisines do you like? Type them separated by commas, e.g. Italian, Georgian, Japanese", nil},
+	{"dinner_time", "🕒 Dinner poll", "🕒 When should I start the dinner poll? Pick a time or type one, e.g. 16:30", [][2]string{
+		{"16:00", "16:00"}, {"17:00", "17:00"}, {"18:00", "18:00"}, {"19:00", "19:00"},
+	}},
+	{"cutoff_time", "🌙 Cutoff", "🌙 When should I close unfinished polls and dinners? Pick a time or type one", [][2]string{
+		{"20:00", "20:00"}, {"21:00", "21:00"}, {"22:00", "22:00"},
+	}},
+	{"timezone", "🌍 Timezone", "🌍 Which timezone are you in? Pick one or type its name, e.g. Asia/Tbilisi", [][2]string{
+		{"London", "Europe/London"}, {"Berlin", "Europe/Berlin"}, {"Moscow", "Europe/Moscow"},
+		{"New York", "America/New_York"}, {"UTC", "UTC"},
+	}},
+	{"vote_threshold", "🗳 Vote threshold", "🗳 How many of those eating at home must vote before the poll closes? Pick one or type e.g. 60%", [][2]string{
+		{"50%", "0.5"}, {"2/3", "2/3"}, {"75%", "0.75"}, {"Everyone", "1"},
+	}},
+	{"voting", "🗳 Voting", "🗳 How should the family vote on dinner?", [][2]string{
+		{"Single choice", poll.ModePlurality}, {"Approval", poll.ModeApproval}, {"Ranked", poll.ModeRanked},
+	}},
+	{"rsvp", "🏠 RSVP", "🏠 How many minutes should I ask who's eating at home before the poll starts?", [][2]string{
+		{"Don't ask", "0"}, {"10 min", "10"}, {"15 min", "15"}, {"30 min", "30"},
+	}},
+	{"min_votes", "🔢 Min votes", "🔢 How many votes should close the poll at least, however few are at home?", [][2]string{
+		{"1", "1"}, {"2", "2"}, {"3", "3"}, {"4", "4"},
+	}},
+	{"cook_timeout", "⏱ Cook timeout", "⏱ How many minutes should I wait for a cook volunteer?", [][2]string{
+		{"10 min", "10"}, {"15 min", "15"}, {"30 min", "30"}, {"60 min", "60"},
+	}},
+	{"volunteer_window", "🙋 Volunteer window", "🙋 How many minutes should I gather volunteers before picking the cook?", [][2]string{
+		{"First one cooks", "0"}, {"2 min", "2"}, {"5 min", "5"}, {"10 min", "10"},
+	}},
+	{"cook_pick", "🎲 Cook pick", "🎲 How should I pick the cook when several people volunteer?", [][2]string{
+		{"Fair", settings.CookPickFair}, {"Random", settings.CookPickRandom},
+	}},
+	{"cook_retries", "🔁 Cook retries", "🔁 How many times should I ask again when nobody volunteers to cook?", [][2]string{
+		{"Never", "0"}, {"Once", "1"}, {"Twice", "2"}, {"3 times", "3"},
+	}},
+	{"no_cook", "🙅 Nobody cooks", "🙅 What should happen when still nobody volunteers to cook?", [][2]string{
+		{"No dinner today", settings.NoCookNoDinner}, {"By rotation", settings.NoCookAssign}, {"New poll", settings.NoCookNewPoll},
+	}},
+	{"language", "🗣 Language", "🗣 Which language should dish suggestions be in?", [][2]string{
+		{"English", "en"}, {"Русский", "ru"},
+	}},
+	{"diet", "🥗 Diet", "🥗 Any dietary rules for the whole family? Type them separated by commas, e.g. vegetarian, no nuts", [][2]string{
+		{"None", "none"},
+	}},
+}
+
+// findSettingField looks up a setting of the wizard by its key
+func findSettingField(key string) (settingField, bool) {
+	for _, field := range settingFields {
+		if field.Key == key {
+			return field, true
+		}
+	}
+	return settingField{}, false
+}
+
+// applySetting changes one setting from a preset or a typed value
+func applySetting(settingsService *settings.Service, chatID int64, key, value string) error {
+	switch key {
+	case "cuisines":
+		return settingsService.SetCuisines(chatID, settings.ParseList(value))
+	case "dinner_time":
+		return settingsService.SetDinnerTime(chatID, value)
+	case "cutoff_time":
+		return settingsService.SetCutoffTime(chatID, value)
+	case "timezone":
+		return settingsService.SetTimezone(chatID, value)
+	case "vote_threshold":
+		threshold, err := settings.ParseThreshold(value)
+		if err != nil {
+			return err
+		}
+		return settingsService.SetVoteThreshold(chatID, threshold)
+	case "voting":
+		return settingsService.SetVotingMode(chatID, value)
+	case "rsvp":
+		deadline, err := settings.ParseTimeout(value)
+		if err != nil {
+			return err
+		}
+		return settingsService.SetRSVPDeadline(chatID, deadline)
+	case "min_votes":
+		minVotes, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil {
+			return fmt.Errorf("invalid number of votes: %q", value)
+		}
+		return settingsService.SetMinVotes(chatID, minVotes)
+	case "cook_timeout":
+		timeout, err := settings.ParseTimeout(value)
+		if err != nil {
+			return err
+		}
+		return settingsService.SetCookTimeout(chatID, timeout)
+	case "volunteer_window":
+		window, err := settings.ParseTimeout(value)
+		if err != nil {
+			return err
+		}
+		return settingsService.SetVolunteerWindow(chatID, window)
+	case "cook_pick":
+		return settingsService.SetCookPick(chatID, value)
+	case "cook_retries":
+		retries, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil {
+			return fmt.Errorf("invalid number of retries: %q", value)
+		}
+		return settingsService.SetCookRetries(chatID, retries)
+	case "no_cook":
+		return settingsService.SetNoCookAction(chatID, value)
+	case "language":
+		return settingsService.SetLanguage(chatID, value)
+	case "diet":
+		return settingsService.SetDietaryRules(chatID, settings.ParseList(value))
+	default:
+		return fmt.Errorf("unknown setting: %s", key)
+	}
+}
+
+// formatSettings formats a channel's settings for the /settings message
+func formatSettings(channelSettings settings.Settings) string {
+	timezone := channelSettings.Location.String()
+	if channelSettings.Location == time.Local {
+		timezone = "server time"
+	}
+
+	diet := "none"
+	if len(channelSettings.DietaryRules) > 0 {
+		diet = strings.Join(channelSettings.DietaryRules, ", ")
+	}
+
+	volunteerWindow := "first one cooks"
+	if channelSettings.VolunteerWindow > 0 {
+		volunteerWindow = fmt.Sprintf("%d min", int(channelSettings.VolunteerWindow.Minutes()))
+	}
+
+	rsvp := "don't ask"
+	if channelSettings.RSVPDeadline > 0 {
+		rsvp = fmt.Sprintf("%d min before the poll", int(channelSettings.RSVPDeadline.Minutes()))
+	}
+
+	noCook := "no dinner today"
+	switch channelSettings.NoCookAction {
+	case settings.NoCookNewPoll:
+		noCook = "new poll"
+	case settings.NoCookAssign:
+		noCook = "assign by rotation"
+	}
+
+	voting := "single choice"
+	switch channelSettings.VotingMode {
+	case poll.ModeApproval:
+		voting = "approval"
+	case poll.ModeRanked:
+		voting = "ranked choice"
+	}
+
+	text := "⚙️ *Settings*\n\n"
+	text += fmt.Sprintf("🍝 Cuisines: %s\n", strings.Join(channelSettings.Cuisines, ", "))
+	text += fmt.Sprintf("🕒 Dinner poll: %s\n", channelSettings.DinnerTime)
+	text += fmt.Sprintf("🌙 Cutoff: %s\n", channelSettings.CutoffTime)
+	text += fmt.Sprintf("🌍 Timezone: %s\n", timezone)
+	text += fmt.Sprintf("🗳 Vote threshold: %.0f%%\n", channelSettings.VoteThreshold*100)
+	text += fmt.Sprintf("🗳 Voting: %s\n", voting)
+	text += fmt.Sprintf("🏠 RSVP: %s\n", rsvp)
+	text += fmt.Sprintf("🔢 Min votes: %d\n", channelSettings.MinVotes)
+	text += fmt.Sprintf("⏱ Cook timeout: %d min\n", int(channelSettings.CookTimeout.Minutes()))
+	text += fmt.Sprintf("🙋 Volunteer window: %s\n", volunteerWindow)
+	text += fmt.Sprintf("🎲 Cook pick: %s\n", channelSettings.CookPick)
+	text += fmt.Sprintf("🔁 Cook retries: %d\n", channelSettings.CookRetries)
+	text += fmt.Sprintf("🙅 Nobody cooks: %s\n", noCook)
+	text += fmt.Sprintf("🗣 Language: %s\n", channelSettings.LanguageName())
+	text += fmt.Sprintf("🥗 Diet: %s\n", diet)
+	return text
+}
+
+// dietUsage explains how to set diets and allergies
+const dietUsage = `✏️ Set your own with:
+/diet vegetarian, lactose-free
+/allergy nuts, shrimp
+Use "none" to clear them, e.g. /diet none`
+
+// formatDietProfiles formats the family members' diets and allergies for the /diet message
+func formatDietProfiles(profiles []models.DietProfile) string {
+	if len(profiles) == 0 {
+		return "🥗 Nobody has dietary restrictions or allergies yet.\n"
+	}
+
+	text := "🥗 *Family diets:*\n"
+	for _, profile := range profiles {
+		var parts []string
+		if len(profile.Restrictions) > 0 {
+			parts = append(parts, strings.Join(profile.Restrictions, ", "))
+		}
+		if len(profile.Allergies) > 0 {
+			parts = append(parts, "allergic to "+strings.Join(profile.Allergies, ", "))
+		}
+		text += fmt.Sprintf("• @%s: %s\n", profile.Username, strings.Join(parts, "; "))
+	}
+	return text
+}
+
+// settingsKeyboard returns the main keyboard of the /settings wizard, two settings per row
+func settingsKeyboard() tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for i := 0; i < len(settingFields); i += 2 {
+		row := tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData(settingFields[i].Label, "settings:"+settingFields[i].Key),
+		)
+		if i+1 < len(settingFields) {
+			row = append(row, tgbotapi.NewInlineKeyboardButtonData(settingFields[i+1].Label, "settings:"+settingFields[i+1].Key))
+		}
+		rows = append(rows, row)
+	}
+
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("↩️ Reset", "settings:reset"),
+		tgbotapi.NewInlineKeyboardButtonData("✅ Done", "settings:done"),
+	))
+
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// settingOptionsKeyboard returns the presets of a setting and a back button
+func settingOptionsKeyboard(field settingField) tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	var row []tgbotapi.InlineKeyboardButton
+	for _, preset := range field.Presets {
+		row = append(row, tgbotapi.NewInlineKeyboardButtonData(preset[0], fmt.Sprintf("settings_set:%s:%s", field.Key, preset[1])))
+		if len(row) == 3 {
+			rows = append(rows, row)
+			row = nil
+		}
+	}
+	if len(row) > 0 {
+		rows = append(rows, row)
+	}
+
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("⬅️ Back", "settings:back"),
+	))
+
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
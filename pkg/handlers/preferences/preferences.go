@@ -0,0 +1,227 @@
+package preferences
+
+import (
+	"fmt"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/korjavin/whatsfordinner/pkg/diet"
+	"github.com/korjavin/whatsfordinner/pkg/handlers"
+	"github.com/korjavin/whatsfordinner/pkg/logger"
+	"github.com/korjavin/whatsfordinner/pkg/settings"
+	"github.com/korjavin/whatsfordinner/pkg/state"
+	"github.com/korjavin/whatsfordinner/pkg/telegram"
+)
+
+// handler handles the /settings wizard, diets and allergies
+type handler struct {
+	*handlers.Deps
+	log *logger.Logger
+}
+
+// Register routes /settings, /diet and /allergy, and the values typed in the wizard
+func Register(router *telegram.Router, deps *handlers.Deps) {
+	h := &handler{Deps: deps, log: logger.New("preferences")}
+
+	router.Command("settings", h.handleSettings)
+	router.Command("diet", h.handleDiet)
+	router.Command("allergy", h.handleAllergy)
+	router.CallbackPrefix("settings:", h.handleSettingsButton)
+	router.CallbackPrefix("settings_set:", h.handleSettingsSet)
+
+	deps.Conversations.Handle(state.StateEditingSettings, h.handleSettingValue)
+}
+
+// handleSettings handles /settings
+func (h *handler) handleSettings(ctx *telegram.Context) {
+	// Show the channel's settings with buttons to change them
+	chatID := ctx.ChatID
+
+	channelSettings, err := h.Settings.Get(chatID)
+	if err != nil {
+		h.log.Error("Failed to get settings: %v", err)
+		h.Bot.SendMessage(chatID, "😢 Sorry, I couldn't retrieve the settings right now. Please try again later.")
+		return
+	}
+
+	h.Bot.SendMessageWithKeyboard(chatID, formatSettings(channelSettings), settingsKeyboard())
+}
+
+// handleDiet handles /diet
+func (h *handler) handleDiet(ctx *telegram.Context) {
+	message := ctx.Message
+	// Set your own dietary restrictions, e.g. "/diet vegetarian, lactose-free", or show everyone's
+	chatID := ctx.ChatID
+	userID := ctx.MemberID()
+	username := ctx.Username
+
+	args := strings.TrimSpace(message.CommandArguments())
+	if args == "" {
+		profiles, err := h.Diet.ListProfiles(chatID)
+		if err != nil {
+			h.log.Error("Failed to list diet profiles: %v", err)
+			h.Bot.SendMessage(chatID, "😢 Sorry, I couldn't retrieve the diets right now. Please try again later.")
+			return
+		}
+		h.Bot.SendMessage(chatID, formatDietProfiles(profiles)+"\n"+dietUsage)
+		return
+	}
+
+	profile, err := h.Diet.SetRestrictions(chatID, userID, username, settings.ParseList(args))
+	if err != nil {
+		h.log.Error("Failed to set restrictions: %v", err)
+		h.Bot.SendMessage(chatID, "😢 Sorry, I couldn't save your diet. Please try again later.")
+		return
+	}
+
+	if len(profile.Restrictions) == 0 {
+		h.Bot.SendMessage(chatID, fmt.Sprintf("✅ @%s, you have no dietary restrictions now.", username))
+		return
+	}
+
+	msgText := fmt.Sprintf("✅ @%s, I'll keep in mind that you're %s.", username, strings.Join(profile.Restrictions, ", "))
+	for _, restriction := range profile.Restrictions {
+		if !diet.IsKnownRestriction(restriction) {
+			msgText += fmt.Sprintf("\nI'll pass \"%s\" on when suggesting dishes, but I can't check dishes for it myself.", restriction)
+		}
+	}
+	h.Bot.SendMessage(chatID, msgText)
+}
+
+// handleAllergy handles /allergy
+func (h *handler) handleAllergy(ctx *telegram.Context) {
+	message := ctx.Message
+	// Set your own allergies, e.g. "/allergy nuts, shrimp"; dishes with them are never suggested
+	chatID := ctx.ChatID
+	userID := ctx.MemberID()
+	username := ctx.Username
+
+	args := strings.TrimSpace(message.CommandArguments())
+	if args == "" {
+		h.Bot.SendMessage(chatID, dietUsage)
+		return
+	}
+
+	profile, err := h.Diet.SetAllergies(chatID, userID, username, settings.ParseList(args))
+	if err != nil {
+		h.log.Error("Failed to set allergies: %v", err)
+		h.Bot.SendMessage(chatID, "😢 Sorry, I couldn't save your allergies. Please try again later.")
+		return
+	}
+
+	if len(profile.Allergies) == 0 {
+		h.Bot.SendMessage(chatID, fmt.Sprintf("✅ @%s, you have no allergies now.", username))
+		return
+	}
+
+	h.Bot.SendMessage(chatID, fmt.Sprintf("✅ @%s, I'll never suggest dishes with %s.", username, strings.Join(profile.Allergies, ", ")))
+}
+
+// handleSettingsButton handles the /settings wizard: pick a setting, go back, reset or finish
+func (h *handler) handleSettingsButton(ctx *telegram.Context) {
+	callback := ctx.Callback
+	chatID := ctx.ChatID
+	action := ctx.Payload.String()
+
+	switch action {
+	case "done":
+		h.State.ClearState(chatID, ctx.UserID)
+		ctx.Answer("Settings saved!")
+		editMsg := tgbotapi.NewEditMessageText(chatID, callback.Message.MessageID, "✅ Settings saved. Use /settings to change them again.")
+		editMsg.ReplyMarkup = &tgbotapi.InlineKeyboardMarkup{}
+		h.Bot.Send(editMsg)
+		return
+	case "reset":
+		if err := h.Settings.Reset(chatID); err != nil {
+			ctx.Fail("Failed to reset settings: %v", err)
+			return
+		}
+		ctx.Answer("Settings reset to defaults.")
+	case "back":
+		h.State.ClearState(chatID, ctx.UserID)
+		ctx.Answer("")
+	default:
+		field, ok := findSettingField(action)
+		if !ok {
+			ctx.Fail("Unknown setting in callback data: %s", callback.Data)
+			return
+		}
+
+		// Wait for a typed value, or a tap on one of the presets
+		h.State.SetState(chatID, ctx.UserID, state.StateEditingSettings)
+		h.State.SetData(chatID, ctx.UserID, "setting", field.Key)
+		ctx.Answer("")
+
+		editMsg := tgbotapi.NewEditMessageText(chatID, callback.Message.MessageID, field.Prompt)
+		keyboard := settingOptionsKeyboard(field)
+		editMsg.ReplyMarkup = &keyboard
+		h.Bot.Send(editMsg)
+		return
+	}
+
+	// Show the summary again
+	channelSettings, err := h.Settings.Get(chatID)
+	if err != nil {
+		h.log.Error("Failed to get settings: %v", err)
+		return
+	}
+	editMsg := tgbotapi.NewEditMessageText(chatID, callback.Message.MessageID, formatSettings(channelSettings))
+	keyboard := settingsKeyboard()
+	editMsg.ReplyMarkup = &keyboard
+	h.Bot.Send(editMsg)
+}
+
+// handleSettingsSet handles a preset value picked in the /settings wizard
+// The format is "settings_set:{field}:{value}"
+func (h *handler) handleSettingsSet(ctx *telegram.Context) {
+	callback := ctx.Callback
+	chatID := ctx.ChatID
+
+	fields, err := ctx.Payload.Split(2)
+	if err != nil {
+		ctx.Fail("Invalid callback data: %v", err)
+		return
+	}
+
+	h.State.ClearState(chatID, ctx.UserID)
+	h.State.ClearData(chatID, ctx.UserID, "setting")
+
+	if err := applySetting(h.Settings, chatID, fields[0], fields[1]); err != nil {
+		ctx.Fail("Failed to apply setting %s: %v", fields[0], err)
+		return
+	}
+
+	ctx.Answer("Saved!")
+
+	channelSettings, err := h.Settings.Get(chatID)
+	if err != nil {
+		h.log.Error("Failed to get settings: %v", err)
+		return
+	}
+	editMsg := tgbotapi.NewEditMessageText(chatID, callback.Message.MessageID, formatSettings(channelSettings))
+	keyboard := settingsKeyboard()
+	editMsg.ReplyMarkup = &keyboard
+	h.Bot.Send(editMsg)
+}
+
+// handleSettingValue handles a value typed for the setting picked in the /settings wizard
+func (h *handler) handleSettingValue(ctx *telegram.Context) {
+	chatID := ctx.ChatID
+
+	field, _ := h.State.GetData(chatID, ctx.UserID, "setting")
+	h.State.ClearState(chatID, ctx.UserID)
+	h.State.ClearData(chatID, ctx.UserID, "setting")
+
+	if err := applySetting(h.Settings, chatID, field, ctx.Message.Text); err != nil {
+		h.log.Error("Failed to apply setting %s: %v", field, err)
+		h.Bot.SendMessage(chatID, fmt.Sprintf("😢 That didn't work: %v. Open /settings to try again.", err))
+		return
+	}
+
+	channelSettings, err := h.Settings.Get(chatID)
+	if err != nil {
+		h.log.Error("Failed to get settings: %v", err)
+		return
+	}
+	h.Bot.SendMessageWithKeyboard(chatID, "✅ Saved!\n\n"+formatSettings(channelSettings), settingsKeyboard())
+}
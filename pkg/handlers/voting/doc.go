@@ -0,0 +1,3 @@
+// Package voting provides choosing tonight's dish: /dinner and /suggest, who's eating at home,
+// the votes in Telegram polls and ranked votes, and closing the vote once enough members voted.
+package voting
@@ -0,0 +1,15 @@
+package voting
+
+import (
+	"fmt"
+	"strings"
+)
+
+// formatRanking formats a member's ranking of the dishes, e.g. "1. Borscht, 2. Pasta"
+func formatRanking(ranking []string) string {
+	parts := make([]string, len(ranking))
+	for i, option := range ranking {
+		parts[i] = fmt.Sprintf("%d. %s", i+1, option)
+	}
+	return strings.Join(parts, ", ")
+}
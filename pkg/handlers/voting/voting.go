@@ -0,0 +1,638 @@
+package voting
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/korjavin/whatsfordinner/pkg/attendance"
+	"github.com/korjavin/whatsfordinner/pkg/dinner"
+	"github.com/korjavin/whatsfordinner/pkg/fridge"
+	"github.com/korjavin/whatsfordinner/pkg/handlers"
+	"github.com/korjavin/whatsfordinner/pkg/logger"
+	"github.com/korjavin/whatsfordinner/pkg/models"
+	"github.com/korjavin/whatsfordinner/pkg/nutrition"
+	"github.com/korjavin/whatsfordinner/pkg/openai"
+	"github.com/korjavin/whatsfordinner/pkg/poll"
+	"github.com/korjavin/whatsfordinner/pkg/scheduler"
+	"github.com/korjavin/whatsfordinner/pkg/state"
+	"github.com/korjavin/whatsfordinner/pkg/telegram"
+	"github.com/korjavin/whatsfordinner/pkg/workflow"
+)
+
+// handler handles the dinner poll: suggestions, who's eating at home, votes and rankings
+type handler struct {
+	*handlers.Deps
+	log *logger.Logger
+}
+
+// Register routes /dinner and /suggest, the votes and who's eating at home
+func Register(router *telegram.Router, deps *handlers.Deps) {
+	h := &handler{Deps: deps, log: logger.New("voting")}
+
+	router.Command("dinner", h.handleDinner)
+	router.Command("suggest", h.handleSuggest)
+	router.CallbackPrefix("rsvp:", h.handleRsvp)
+	router.CallbackPrefix("rank:", h.handleRank)
+	router.CallbackPrefix("rank_done:", h.handleRankDone)
+	router.CallbackPrefix("rank_reset:", h.handleRankReset)
+	router.PollAnswer(h.handlePollAnswer)
+
+	deps.Conversations.Handle(state.StateSuggestingDish, h.handleSuggestingDish)
+}
+
+// handleDinner handles /dinner
+func (h *handler) handleDinner(ctx *telegram.Context) {
+	// Start dinner suggestion flow
+	chatID := ctx.ChatID
+
+	// Get ingredients from the fridge
+	ingredients, err := h.Fridge.ListIngredients(chatID)
+	if err != nil {
+		h.log.Error("Failed to list ingredients: %v", err)
+		errorMsg := h.Messages.GenerateErrorMessage("retrieve fridge contents")
+		h.Bot.SendMessage(chatID, errorMsg)
+		return
+	}
+
+	if len(ingredients) == 0 {
+		h.Bot.SendMessage(chatID, "😢 Your fridge is empty! Please add some ingredients with /sync_fridge or /add_photo before I can suggest dinner options.")
+		return
+	}
+
+	// Extract ingredient names, noting the ones that should be used up soon
+	ingredientNames := make([]string, len(ingredients))
+	var useSoon []string
+	now := time.Now()
+	for i, ingredient := range ingredients {
+		ingredientNames[i] = ingredient.Name
+		if fridge.IsExpiringSoon(ingredient, now, fridge.UseSoonWindow) {
+			useSoon = append(useSoon, ingredient.Name)
+		}
+	}
+
+	// Start the dinner's workflow over, unless the family is still being asked who's eating at home
+	if _, err := h.Workflow.Begin(chatID, "/dinner"); err != nil {
+		ctx.Fail("Failed to begin the dinner workflow: %v", err)
+		return
+	}
+
+	// Send a processing message
+	processingMsg, _ := h.Bot.SendMessage(chatID, "🧐 Thinking about dinner options based on your ingredients... This might take a moment.")
+
+	// Get user suggestions
+	userSuggestions, err := h.Suggest.GetUnusedSuggestions(chatID)
+	if err != nil {
+		h.log.Error("Failed to get user suggestions: %v", err)
+		// Continue without user suggestions
+		userSuggestions = []*models.SuggestedDish{}
+	}
+
+	// Determine how many AI suggestions to get
+	aiSuggestionCount := 4
+	if len(userSuggestions) > 0 {
+		// If we have user suggestions, get fewer AI suggestions
+		aiSuggestionCount = 4 - len(userSuggestions)
+		if aiSuggestionCount < 2 {
+			aiSuggestionCount = 2 // Always get at least 2 AI suggestions
+		}
+	}
+
+	// Get dinner suggestions from OpenAI, following the channel's settings and the family's diets
+	channelSettings, err := h.Settings.Get(chatID)
+	if err != nil {
+		h.log.Error("Failed to get settings: %v", err)
+	}
+	prefs := h.Diet.Apply(chatID, openai.Preferences{
+		Cuisines:     channelSettings.Cuisines,
+		DietaryRules: channelSettings.DietaryRules,
+		Language:     channelSettings.LanguageName(),
+	})
+	aiSuggestions, err := h.OpenAI.SuggestDinnerOptions(ingredientNames, useSoon, prefs, aiSuggestionCount)
+	if err != nil {
+		h.log.Error("Failed to get dinner suggestions: %v", err)
+
+		// If we have user suggestions, continue with those
+		if len(userSuggestions) == 0 {
+			h.Bot.EditMessage(chatID, processingMsg.MessageID, "😢 Sorry, I couldn't come up with dinner suggestions right now. Please try again later.")
+			return
+		}
+
+		// Continue with just user suggestions
+		aiSuggestions = []map[string]interface{}{}
+	}
+
+	// Rank the AI suggestions, leaving out dishes with allergens
+	rankedDishes := h.Dinner.RankSuggestions(chatID, aiSuggestions, channelSettings.Cuisines)
+
+	// Combine AI and user suggestions
+	if len(rankedDishes) == 0 && len(userSuggestions) == 0 {
+		h.Bot.EditMessage(chatID, processingMsg.MessageID, "😢 I couldn't find any suitable dishes based on your fridge contents. Try adding more ingredients with /fridge or suggest your own dishes with /suggest.")
+		return
+	}
+
+	// Calculate total number of suggestions
+	totalSuggestions := len(rankedDishes) + len(userSuggestions)
+
+	// Create options for the poll
+	options := make([]string, totalSuggestions)
+	dishNames := make([]string, totalSuggestions)
+
+	// Create a detailed message with suggestions
+	detailedMsg := "🍲 Here are some dinner suggestions based on your ingredients:\n\n"
+
+	// Add user suggestions first
+	for i, suggestion := range userSuggestions {
+		options[i] = suggestion.Name
+		dishNames[i] = fmt.Sprintf("%s (%s) - suggested by @%s", suggestion.Name, suggestion.Cuisine, suggestion.Username)
+
+		detailedMsg += fmt.Sprintf("🍴 *%s* (%s)\n%s\n_Suggested by @%s_\n\n", suggestion.Name, suggestion.Cuisine, suggestion.Description, suggestion.Username)
+
+		// Mark the suggestion as used
+		err := h.Suggest.MarkAsUsed(suggestion.ID)
+		if err != nil {
+			h.log.Error("Failed to mark suggestion as used: %v", err)
+		}
+	}
+
+	// Add AI suggestions, best match first, with the reasons they were picked
+	for i, rankedDish := range rankedDishes {
+		name := rankedDish.Dish.Name
+		cuisine := rankedDish.Dish.Cuisine
+
+		// Add to options at the correct index (after user suggestions)
+		index := len(userSuggestions) + i
+		options[index] = name
+		dishNames[index] = fmt.Sprintf("%s (%s)", name, cuisine)
+
+		detailedMsg += fmt.Sprintf("🍴 *%s* (%s)\n%s\n", name, cuisine, rankedDish.Dish.Description)
+		if rankedDish.Dish.Nutrition != nil {
+			detailedMsg += fmt.Sprintf("🔥 %s per serving\n", nutrition.Format(rankedDish.Dish.Nutrition))
+		}
+		if explanation := rankedDish.Explanation(time.Now()); explanation != "" {
+			detailedMsg += fmt.Sprintf("_%s_\n", explanation)
+		}
+		detailedMsg += "\n"
+	}
+
+	// Edit the processing message to show the detailed suggestions
+	h.Bot.EditMessage(chatID, processingMsg.MessageID, detailedMsg)
+
+	// Start the vote in the channel's voting mode
+	vote, err := h.Scheduler.StartVote(chatID, "What should we cook tonight?", options)
+	if err != nil {
+		h.log.Error("Failed to start vote: %v", err)
+		errorMsg := h.Messages.GenerateErrorMessage("create poll")
+		h.Bot.SendMessage(chatID, errorMsg)
+		return
+	}
+
+	// Send a message with voting instructions
+	h.Bot.SendMessage(chatID, fmt.Sprintf("🗳 %s The poll is above.", scheduler.VotingInstructions(vote.Mode)))
+}
+
+// handleSuggest handles /suggest
+func (h *handler) handleSuggest(ctx *telegram.Context) {
+	message := ctx.Message
+	// Start dish suggestion flow
+	chatID := ctx.ChatID
+	userID := ctx.MemberID()
+	username := ctx.Username
+
+	// Check if there's a dish name in the command
+	args := message.CommandArguments()
+	if args != "" {
+		// User provided a dish name with the command
+		// Send a processing message
+		processingMsg, _ := h.Bot.SendMessage(chatID, fmt.Sprintf("🧐 Looking up information about '%s'... This might take a moment.", args))
+
+		// Get dish information from OpenAI
+		// Look the dish up as it is, so conflicts with the family's diets can be pointed out
+		dishInfo, err := h.OpenAI.GetDishInfo(args, openai.Preferences{})
+		if err != nil {
+			h.log.Error("Failed to get dish info: %v", err)
+			h.Bot.EditMessage(chatID, processingMsg.MessageID, fmt.Sprintf("😢 Sorry, I couldn't find information about '%s'. Please try again with a different dish.", args))
+			return
+		}
+
+		// Extract dish information
+		dishName, _ := dishInfo["name"].(string)
+		if dishName == "" {
+			dishName = args // Fallback to the user-provided name
+		}
+
+		cuisine, _ := dishInfo["cuisine"].(string)
+		description, _ := dishInfo["description"].(string)
+
+		// Get ingredients needed
+		var ingredientsNeeded []string
+		ingredientsList, ok := dishInfo["ingredients_needed"].([]interface{})
+		if !ok {
+			// Try alternative key
+			ingredientsList, ok = dishInfo["ingredients"].([]interface{})
+		}
+
+		if ok {
+			ingredientsNeeded = make([]string, len(ingredientsList))
+			for i, ing := range ingredientsList {
+				if ingStr, ok := ing.(string); ok {
+					ingredientsNeeded[i] = ingStr
+				}
+			}
+		}
+
+		// Get fridge ingredients
+		fridgeIngredients, err := h.Fridge.ListIngredients(chatID)
+		if err != nil {
+			h.log.Error("Failed to list ingredients: %v", err)
+			// Continue without fridge comparison
+			fridgeIngredients = nil
+		}
+
+		// Compare ingredients
+		var missingIngredients []string
+		if len(fridgeIngredients) > 0 && len(ingredientsNeeded) > 0 {
+			// Extract ingredient names from fridge
+			fridgeNames := make([]string, len(fridgeIngredients))
+			for i, ingredient := range fridgeIngredients {
+				fridgeNames[i] = ingredient.Name
+			}
+
+			// Compare ingredients
+			missingIngredients = dinner.CompareIngredients(ingredientsNeeded, fridgeNames)
+		}
+
+		// Add the suggestion
+		suggestion, err := h.Suggest.AddSuggestion(chatID, userID, username, dishName, cuisine, description)
+		if err != nil {
+			h.log.Error("Failed to add suggestion: %v", err)
+			h.Bot.EditMessage(chatID, processingMsg.MessageID, fmt.Sprintf("😢 Sorry, I couldn't save your suggestion for '%s'. Please try again later.", args))
+			return
+		}
+
+		// Create a detailed message about the dish
+		detailedMsg := fmt.Sprintf("✅ Thanks for suggesting *%s* (%s cuisine)!\n\n%s\n\n", suggestion.Name, suggestion.Cuisine, suggestion.Description)
+
+		// Add ingredients information
+		if len(ingredientsNeeded) > 0 {
+			detailedMsg += "*Ingredients needed:*\n"
+			for _, ingredient := range ingredientsNeeded {
+				detailedMsg += fmt.Sprintf("• %s\n", ingredient)
+			}
+			detailedMsg += "\n"
+		}
+
+		// Add missing ingredients information
+		if len(missingIngredients) > 0 {
+			detailedMsg += "*Missing from your fridge:*\n"
+			for _, ingredient := range missingIngredients {
+				detailedMsg += fmt.Sprintf("• %s\n", ingredient)
+			}
+			detailedMsg += "\n"
+		}
+
+		// Warn about family members who can't or won't eat the dish
+		conflicts, err := h.Diet.CheckDish(chatID, models.Dish{Name: dishName, Ingredients: ingredientsNeeded})
+		if err != nil {
+			h.log.Error("Failed to check dish against diets: %v", err)
+		}
+		if len(conflicts) > 0 {
+			detailedMsg += "⚠️ *Heads up:*\n"
+			for _, conflict := range conflicts {
+				detailedMsg += fmt.Sprintf("• %s\n", conflict)
+			}
+			detailedMsg += "\n"
+		}
+
+		// Check if there's an ongoing poll in the channel
+		currentVote, err := h.Poll.GetCurrentVote(chatID)
+		if err == nil && currentVote != nil && currentVote.EndedAt.IsZero() {
+			// There's an active poll, add the suggestion to it
+			h.log.Info("Adding suggestion '%s' to ongoing poll %s", suggestion.Name, currentVote.PollID)
+
+			// Create a new poll with the existing options plus the new suggestion
+			newOptions := append(currentVote.Options, suggestion.Name)
+
+			// Create a new poll with the updated options
+			newVote, err := h.Scheduler.StartVote(chatID, "What should we cook tonight?", newOptions)
+			if err != nil {
+				h.log.Error("Failed to create updated poll: %v", err)
+				detailedMsg += "Your suggestion will be included in future dinner polls."
+			} else {
+				// End the old poll in our database
+				_, winningOption, _ := h.Poll.GetVoteResults(chatID, currentVote.PollID)
+				h.Poll.EndVote(chatID, currentVote.PollID, winningOption)
+
+				// Stop the old poll from taking ballots
+				h.Scheduler.CloseBallot(chatID, currentVote)
+
+				// Send a message to indicate the old poll is no longer active
+				h.Bot.SendMessage(chatID, "⚠️ The previous dinner poll has been replaced with a new one that includes the latest suggestion.")
+
+				// Copy existing ballots to the new poll (for options that still exist)
+				if err := h.Poll.CarryOverBallots(chatID, currentVote, newVote); err != nil {
+					h.log.Error("Failed to save updated vote: %v", err)
+				}
+
+				// Inform users about the updated poll
+				h.Bot.SendMessage(chatID, fmt.Sprintf("🔄 The dinner poll has been updated with a new suggestion: *%s*. Please vote in the new poll above!", suggestion.Name))
+
+				detailedMsg += "Your suggestion has been added to the current dinner poll!"
+			}
+		} else {
+			// No active poll, just store the suggestion for future polls
+			detailedMsg += "Your suggestion will be included in future dinner polls."
+		}
+
+		// Edit the processing message with the detailed information
+		h.Bot.EditMessage(chatID, processingMsg.MessageID, detailedMsg)
+	} else {
+		// No dish name provided, ask for it
+		h.Bot.SendMessage(chatID, "🍴 You can suggest a dish for dinner! Please use the command like this: /suggest Lasagna")
+	}
+}
+
+// closeVoteIfDecided closes a vote once enough of the channel has voted, explains the result and asks for a cook
+func (h *handler) closeVoteIfDecided(channelID int64, pollID string) {
+	// Get the channel state to check the member count
+	channelKey := fmt.Sprintf("channel:%d", channelID)
+	var channelState models.ChannelState
+	err := h.Store.Get(channelKey, &channelState)
+	if err != nil {
+		h.log.Error("Failed to get channel state: %v", err)
+		return
+	}
+
+	// Always get the latest member count from Telegram API
+	h.log.Info("Attempting to get chat member count for channel %d", channelID)
+	chatMemberCount, err := h.Bot.GetChatMemberCount(channelID)
+	if err != nil {
+		h.log.Error("Failed to get chat member count: %v", err)
+		// Fallback to default value if API call fails
+		if channelState.MemberCount == 0 {
+			channelState.MemberCount = 3
+			h.log.Info("Using default member count: %d", channelState.MemberCount)
+		} else {
+			h.log.Info("Using existing member count: %d", channelState.MemberCount)
+		}
+	} else {
+		h.log.Info("Got chat member count from Telegram API: %d", chatMemberCount)
+		channelState.MemberCount = chatMemberCount - 1 // bot is not a family member
+		// Save the updated channel state
+		err = h.Store.Set(channelKey, channelState)
+		if err != nil {
+			h.log.Error("Failed to update channel state: %v", err)
+		}
+	}
+
+	// Check if we've reached the quorum of those eating at home to close the poll
+	channelSettings := h.Settings.Resolve(channelState)
+	eligible := attendance.Eligible(attendance.Current(channelState, time.Now()), channelState.MemberCount)
+	thresholdReached, winningOption, err := h.Poll.CheckVoteThreshold(channelID, pollID, eligible, channelSettings.VoteThreshold, channelSettings.MinVotes)
+	if err != nil {
+		h.log.Error("Failed to check vote threshold: %v", err)
+		return
+	}
+	if !thresholdReached {
+		return
+	}
+
+	result, err := h.Poll.GetResult(channelID, pollID)
+	if err != nil {
+		h.log.Error("Failed to get vote results: %v", err)
+		return
+	}
+
+	// End the vote
+	err = h.Poll.EndVote(channelID, pollID, winningOption)
+	if err != nil {
+		h.log.Error("Failed to end vote: %v", err)
+		return
+	}
+	if vote, err := h.Poll.GetVote(channelID, pollID); err == nil {
+		h.Scheduler.CloseBallot(channelID, vote)
+	}
+
+	// Send a message that the poll is closed, with how the winner was decided
+	h.Bot.SendMessage(channelID, "🎉 The poll has closed!\n\n"+result.Explain())
+
+	// Ask for cook volunteers; the scheduler picks one of them and follows up if nobody volunteers
+	err = h.Scheduler.AskForCook(channelID, pollID, fmt.Sprintf("Who wants to cook *%s* tonight? Press the button below to volunteer!", winningOption))
+	if err != nil {
+		h.log.Error("Failed to ask for a cook: %v", err)
+	}
+}
+
+// voting reports whether the channel's dinner workflow takes ballots for a vote: it's voting, and on that vote
+func (h *handler) voting(ctx *telegram.Context, channelID int64, pollID string) bool {
+	return handlers.Allowed(ctx, h.Workflow.RequireVote(channelID, pollID, workflow.Voting), "This vote has already closed.")
+}
+
+// voted records that a member voted: voters take turns cooking and are eating at home
+// Then the vote is closed if enough members voted
+func (h *handler) voted(channelID int64, pollID, userID, username string) {
+	if err := h.Rotation.Seen(channelID, userID, username); err != nil {
+		h.log.Error("Failed to add %s to the cook rotation: %v", username, err)
+	}
+	if err := h.Attendance.Join(channelID, userID, username); err != nil {
+		h.log.Error("Failed to count %s as eating at home: %v", username, err)
+	}
+
+	h.closeVoteIfDecided(channelID, pollID)
+}
+
+// handleRsvp handles the answers to "Eating at home tonight?"
+func (h *handler) handleRsvp(ctx *telegram.Context) {
+	callback := ctx.Callback
+	chatID := ctx.ChatID
+	userID := ctx.MemberID()
+	username := ctx.Username
+
+	home := ctx.Payload == "in"
+	current, err := h.Attendance.Answer(chatID, userID, username, home)
+	if err != nil {
+		if errors.Is(err, attendance.ErrNotAsked) {
+			ctx.Answer("This question has expired.")
+			return
+		}
+		ctx.Fail("Failed to record attendance: %v", err)
+		return
+	}
+
+	if home {
+		ctx.Answer("See you at the table!")
+	} else {
+		ctx.Answer("Thanks for letting us know!")
+	}
+
+	channelSettings, _ := h.Settings.Get(chatID)
+	h.Bot.EditMessage(chatID, callback.Message.MessageID, scheduler.AttendanceMessage(current, channelSettings.Location), scheduler.AttendanceKeyboard())
+
+	// Start the poll as soon as everyone answered
+	if attendance.IsOpen(current) {
+		chatMemberCount, err := h.Bot.GetChatMemberCount(chatID)
+		if err != nil {
+			h.log.Error("Failed to get chat member count: %v", err)
+			return
+		}
+		if attendance.Everyone(current, chatMemberCount-1) {
+			h.Scheduler.FinishAttendance(chatID)
+		}
+	}
+}
+
+// handleRank handles a dish tapped in a ranked vote, the next one in the member's order of preference
+func (h *handler) handleRank(ctx *telegram.Context) {
+	callback := ctx.Callback
+	chatID := ctx.ChatID
+	userID := ctx.MemberID()
+
+	// The format is "rank:{pollID}:{option index}"
+	fields, err := ctx.Payload.Split(2)
+	if err != nil {
+		ctx.Fail("Invalid callback data: %v", err)
+		return
+	}
+	pollID := fields[0]
+
+	if !h.voting(ctx, chatID, pollID) {
+		return
+	}
+
+	vote, err := h.Poll.GetVote(chatID, pollID)
+	if err != nil {
+		ctx.Fail("Failed to get vote: %v", err)
+		return
+	}
+	index, err := strconv.Atoi(fields[1])
+	if err != nil || index < 0 || index >= len(vote.Options) {
+		ctx.Fail("Invalid option index in callback data: %s", callback.Data)
+		return
+	}
+
+	ranking, done, err := h.Poll.RecordRanking(chatID, pollID, userID, vote.Options[index])
+	if err != nil {
+		if errors.Is(err, poll.ErrVoteEnded) {
+			ctx.Answer("This vote has already closed.")
+			return
+		}
+		ctx.Fail("Failed to record ranking: %v", err)
+		return
+	}
+
+	if !done {
+		ctx.Answer(fmt.Sprintf("Your ranking so far: %s. Tap the next dish, or ✅ Done.", formatRanking(ranking)))
+		return
+	}
+
+	ctx.Answer(fmt.Sprintf("Thanks for voting! Your ranking: %s", formatRanking(ranking)))
+	h.voted(chatID, pollID, userID, ctx.Username)
+}
+
+// handleRankDone handles "Done" of a member who ranked only the dishes they care about
+func (h *handler) handleRankDone(ctx *telegram.Context) {
+	chatID := ctx.ChatID
+	userID := ctx.MemberID()
+	pollID := ctx.Payload.String()
+
+	if !h.voting(ctx, chatID, pollID) {
+		return
+	}
+
+	ranking, err := h.Poll.FinishRanking(chatID, pollID, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, poll.ErrVoteEnded):
+			ctx.Answer("This vote has already closed.")
+		case errors.Is(err, poll.ErrEmptyRanking):
+			ctx.Answer("Tap at least one dish first, your favorite first.")
+		default:
+			ctx.Fail("Failed to finish ranking: %v", err)
+		}
+		return
+	}
+
+	ctx.Answer(fmt.Sprintf("Thanks for voting! Your ranking: %s", formatRanking(ranking)))
+	h.voted(chatID, pollID, userID, ctx.Username)
+}
+
+// handleRankReset handles "Start over" of a member who wants to change their ranking
+func (h *handler) handleRankReset(ctx *telegram.Context) {
+	chatID := ctx.ChatID
+	userID := ctx.MemberID()
+	pollID := ctx.Payload.String()
+
+	if !h.voting(ctx, chatID, pollID) {
+		return
+	}
+
+	if err := h.Poll.ResetRanking(chatID, pollID, userID); err != nil {
+		if errors.Is(err, poll.ErrVoteEnded) {
+			ctx.Answer("This vote has already closed.")
+			return
+		}
+		ctx.Fail("Failed to reset ranking: %v", err)
+		return
+	}
+
+	ctx.Answer("Your ranking is cleared. Tap the dishes again, your favorite first.")
+}
+
+// handlePollAnswer handles a vote in a Telegram poll
+func (h *handler) handlePollAnswer(ctx *telegram.Context) {
+	pollID := ctx.PollAnswer.PollID
+	userID := ctx.MemberID()
+
+	h.log.Info("Received poll answer for poll %s from user %s", pollID, userID)
+
+	// Find the channel of the poll; the mapping is kept in storage, so it survives restarts
+	channelID, err := h.Poll.FindChannelByPollID(pollID)
+	if err != nil {
+		h.log.Error("Could not find channel for poll %s: %v", pollID, err)
+		return
+	}
+
+	// Telegram can't be told a poll answer was turned down, so it's only logged
+	if !h.voting(ctx, channelID, pollID) {
+		return
+	}
+
+	vote, err := h.Poll.GetVote(channelID, pollID)
+	if err != nil {
+		h.log.Error("Failed to get vote: %v", err)
+		return
+	}
+
+	// Get the option texts
+	var options []string
+	for _, optionID := range ctx.PollAnswer.OptionIDs {
+		if optionID < 0 || int(optionID) >= len(vote.Options) {
+			h.log.Error("Invalid option ID: %d", optionID)
+			return
+		}
+		options = append(options, vote.Options[optionID])
+	}
+
+	// Record the vote; in approval polls members pick any number of dishes, and retracting them all withdraws the ballot
+	if poll.ModeOf(vote) == poll.ModeApproval {
+		err = h.Poll.RecordApproval(channelID, pollID, userID, options)
+	} else if len(options) > 0 {
+		err = h.Poll.RecordVote(channelID, pollID, userID, options[0])
+	} else {
+		return
+	}
+	if err != nil {
+		h.log.Error("Failed to record vote: %v", err)
+		return
+	}
+
+	h.voted(channelID, pollID, userID, ctx.Username)
+}
+
+// handleSuggestingDish handles text typed after /suggest without a dish
+// Suggestions are made with the command itself, so the member is reminded how
+func (h *handler) handleSuggestingDish(ctx *telegram.Context) {
+	h.State.ClearState(ctx.ChatID, ctx.UserID)
+	h.Bot.SendMessage(ctx.ChatID, "🍴 Please use the /suggest command followed by a dish name, like: /suggest Lasagna")
+}
@@ -0,0 +1,172 @@
+package ingredients
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/korjavin/whatsfordinner/pkg/quantity"
+)
+
+// descriptors are words that describe the state of an ingredient rather than what it is
+var descriptors = map[string]bool{
+	"fresh": true, "frozen": true, "chopped": true, "diced": true, "sliced": true, "grated": true,
+	"shredded": true, "peeled": true, "large": true, "small": true, "medium": true, "ripe": true,
+	"organic": true, "raw": true, "whole": true, "finely": true, "roughly": true, "some": true,
+	"a": true, "an": true, "of": true, "few": true, "handful": true,
+	"свежий": true, "свежая": true, "свежие": true, "замороженный": true, "замороженные": true,
+	"нарезанный": true, "крупный": true, "мелкий": true,
+}
+
+// index maps every known spelling to its catalog entry
+var index = buildIndex()
+
+// buildIndex builds the lookup index from the catalog
+// Spellings listed in the catalog win over singular forms derived from other entries
+func buildIndex() map[string]Entry {
+	result := make(map[string]Entry)
+	for _, entry := range catalog {
+		for _, name := range append([]string{entry.Name}, entry.Synonyms...) {
+			result[singularize(clean(name))] = entry
+		}
+	}
+	for _, entry := range catalog {
+		for _, name := range append([]string{entry.Name}, entry.Synonyms...) {
+			result[clean(name)] = entry
+		}
+	}
+	return result
+}
+
+// Lookup finds the catalog entry for an ingredient name
+// Quantities, descriptors like "fresh" and modifiers like "cherry" are ignored
+func Lookup(name string) (Entry, bool) {
+	key := clean(stripQuantity(name))
+	if key == "" {
+		return Entry{}, false
+	}
+
+	if entry, ok := lookupPhrase(key); ok {
+		return entry, true
+	}
+
+	// Drop leading modifiers one at a time: "cherry tomatoes" -> "tomatoes"
+	words := strings.Fields(key)
+	for i := 1; i < len(words); i++ {
+		if entry, ok := lookupPhrase(strings.Join(words[i:], " ")); ok {
+			return entry, true
+		}
+	}
+
+	return Entry{}, false
+}
+
+// Canonicalize returns the canonical name of an ingredient
+// Unknown ingredients are lowercased, cleaned up and made singular,
+// so "Chicken Wings" and "chicken wing" still end up as the same item
+func Canonicalize(name string) string {
+	if entry, ok := Lookup(name); ok {
+		return entry.Name
+	}
+	return singularize(clean(stripQuantity(name)))
+}
+
+// CategoryOf returns the taxonomy category of an ingredient
+func CategoryOf(name string) string {
+	if entry, ok := Lookup(name); ok {
+		return entry.Category
+	}
+	return CategoryOther
+}
+
+// Equivalent reports whether two ingredient names refer to the same canonical ingredient
+func Equivalent(a, b string) bool {
+	canonicalA := Canonicalize(a)
+	return canonicalA != "" && canonicalA == Canonicalize(b)
+}
+
+// lookupPhrase looks up a cleaned phrase as it is and in its singular form
+func lookupPhrase(phrase string) (Entry, bool) {
+	if entry, ok := index[phrase]; ok {
+		return entry, true
+	}
+	entry, ok := index[singularize(phrase)]
+	return entry, ok
+}
+
+// stripQuantity removes amounts and parenthesized notes, e.g. "cherry tomatoes (250g)"
+func stripQuantity(name string) string {
+	name, _, _ = quantity.ParseIngredient(name)
+	if idx := strings.Index(name, "("); idx > 0 {
+		name = name[:idx]
+	}
+	return name
+}
+
+// clean lowercases a name, removes punctuation and descriptor words and collapses spaces
+func clean(name string) string {
+	name = strings.ToLower(name)
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' {
+			return r
+		}
+		return ' '
+	}, name)
+	name = strings.ReplaceAll(name, "ё", "е")
+
+	words := strings.Fields(name)
+	kept := make([]string, 0, len(words))
+	for _, word := range words {
+		if !descriptors[word] {
+			kept = append(kept, word)
+		}
+	}
+
+	// If everything was a descriptor, keep the original words
+	if len(kept) == 0 {
+		kept = words
+	}
+
+	return strings.Join(kept, " ")
+}
+
+// singularize makes the last word of an English phrase singular
+// Russian words are left alone, their variants are listed in the catalog instead
+func singularize(phrase string) string {
+	words := strings.Fields(phrase)
+	if len(words) == 0 {
+		return phrase
+	}
+
+	last := words[len(words)-1]
+	if !isASCII(last) || len(last) <= 3 {
+		return phrase
+	}
+
+	switch {
+	case strings.HasSuffix(last, "ies"):
+		last = strings.TrimSuffix(last, "ies") + "y"
+	case strings.HasSuffix(last, "oes"),
+		strings.HasSuffix(last, "ches"),
+		strings.HasSuffix(last, "shes"),
+		strings.HasSuffix(last, "sses"),
+		strings.HasSuffix(last, "xes"):
+		last = strings.TrimSuffix(last, "es")
+	case strings.HasSuffix(last, "ss"), strings.HasSuffix(last, "us"):
+		// "swiss", "asparagus" are not plurals
+	case strings.HasSuffix(last, "s"):
+		last = strings.TrimSuffix(last, "s")
+	}
+
+	words[len(words)-1] = last
+	return strings.Join(words, " ")
+}
+
+// isASCII reports whether a word only contains ASCII characters
+func isASCII(word string) bool {
+	for _, r := range word {
+		if r > unicode.MaxASCII {
+			return false
+		}
+	}
+	return true
+}
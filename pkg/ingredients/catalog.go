@@ -0,0 +1,150 @@
+package ingredients
+
+// Entry is a canonical ingredient with the other names it's known by
+type Entry struct {
+	Name     string   // Canonical name: lowercase, singular, English
+	Category string   // Category from the taxonomy
+	Synonyms []string // Synonyms, irregular plurals and Russian variants
+}
+
+// catalog lists the ingredients families use most often
+// Regular English plurals don't need to be listed, they're handled by singularize
+var catalog = []Entry{
+	// Dairy
+	{"milk", CategoryDairy, []string{"whole milk", "skim milk", "молоко"}},
+	{"cream", CategoryDairy, []string{"heavy cream", "whipping cream", "double cream", "сливки"}},
+	{"sour cream", CategoryDairy, []string{"smetana", "сметана"}},
+	{"yogurt", CategoryDairy, []string{"yoghurt", "greek yogurt", "йогурт"}},
+	{"kefir", CategoryDairy, []string{"кефир"}},
+	{"butter", CategoryDairy, []string{"сливочное масло", "масло сливочное"}},
+	{"cheese", CategoryDairy, []string{"hard cheese", "cheddar", "gouda", "сыр"}},
+	{"mozzarella", CategoryDairy, []string{"моцарелла"}},
+	{"parmesan", CategoryDairy, []string{"parmigiano", "parmigiano reggiano", "пармезан"}},
+	{"cottage cheese", CategoryDairy, []string{"tvorog", "quark", "творог"}},
+	{"cream cheese", CategoryDairy, []string{"сливочный сыр"}},
+
+	// Eggs
+	{"egg", CategoryEggs, []string{"chicken egg", "яйцо", "яйца"}},
+
+	// Meat
+	{"chicken", CategoryMeat, []string{"whole chicken", "курица", "курицу"}},
+	{"chicken breast", CategoryMeat, []string{"chicken fillet", "chicken breast fillet", "куриная грудка", "куриное филе"}},
+	{"chicken thigh", CategoryMeat, []string{"куриные бедра", "куриное бедро"}},
+	{"beef", CategoryMeat, []string{"говядина"}},
+	{"ground beef", CategoryMeat, []string{"minced beef", "beef mince", "говяжий фарш"}},
+	{"minced meat", CategoryMeat, []string{"mince", "ground meat", "фарш"}},
+	{"pork", CategoryMeat, []string{"свинина"}},
+	{"lamb", CategoryMeat, []string{"баранина"}},
+	{"turkey", CategoryMeat, []string{"индейка"}},
+	{"bacon", CategoryMeat, []string{"бекон"}},
+	{"ham", CategoryMeat, []string{"ветчина"}},
+	{"sausage", CategoryMeat, []string{"колбаса", "сосиски", "сосиска"}},
+
+	// Fish
+	{"fish", CategoryFish, []string{"white fish", "рыба"}},
+	{"salmon", CategoryFish, []string{"лосось", "семга"}},
+	{"cod", CategoryFish, []string{"треска"}},
+	{"tuna", CategoryFish, []string{"тунец"}},
+	{"shrimp", CategoryFish, []string{"prawn", "креветки", "креветка"}},
+
+	// Greens
+	{"lettuce", CategoryGreens, []string{"салат", "листья салата"}},
+	{"spinach", CategoryGreens, []string{"шпинат"}},
+	{"parsley", CategoryGreens, []string{"петрушка"}},
+	{"dill", CategoryGreens, []string{"укроп"}},
+	{"basil", CategoryGreens, []string{"базилик"}},
+	{"cilantro", CategoryGreens, []string{"coriander leaves", "кинза"}},
+	{"arugula", CategoryGreens, []string{"rocket", "руккола"}},
+	{"green onion", CategoryGreens, []string{"spring onion", "scallion", "зеленый лук"}},
+
+	// Vegetables
+	{"tomato", CategoryVegetables, []string{"помидор", "помидоры", "томат", "томаты"}},
+	{"cucumber", CategoryVegetables, []string{"огурец", "огурцы"}},
+	{"potato", CategoryVegetables, []string{"картофель", "картошка"}},
+	{"onion", CategoryVegetables, []string{"yellow onion", "red onion", "лук", "репчатый лук"}},
+	{"garlic", CategoryVegetables, []string{"garlic clove", "чеснок"}},
+	{"carrot", CategoryVegetables, []string{"морковь", "морковка"}},
+	{"bell pepper", CategoryVegetables, []string{"peppers", "sweet pepper", "red pepper", "green pepper", "capsicum", "болгарский перец", "сладкий перец"}},
+	{"zucchini", CategoryVegetables, []string{"courgette", "кабачок", "цукини"}},
+	{"eggplant", CategoryVegetables, []string{"aubergine", "баклажан", "баклажаны"}},
+	{"cabbage", CategoryVegetables, []string{"white cabbage", "капуста"}},
+	{"broccoli", CategoryVegetables, []string{"брокколи"}},
+	{"cauliflower", CategoryVegetables, []string{"цветная капуста"}},
+	{"mushroom", CategoryVegetables, []string{"champignon", "грибы", "гриб", "шампиньоны"}},
+	{"beetroot", CategoryVegetables, []string{"beet", "свекла", "свёкла"}},
+	{"celery", CategoryVegetables, []string{"сельдерей"}},
+	{"corn", CategoryVegetables, []string{"sweetcorn", "кукуруза"}},
+	{"pumpkin", CategoryVegetables, []string{"тыква"}},
+
+	// Fruit
+	{"apple", CategoryFruit, []string{"яблоко", "яблоки"}},
+	{"banana", CategoryFruit, []string{"банан", "бананы"}},
+	{"orange", CategoryFruit, []string{"апельсин", "апельсины"}},
+	{"lemon", CategoryFruit, []string{"лимон"}},
+	{"lime", CategoryFruit, []string{"лайм"}},
+	{"strawberry", CategoryFruit, []string{"клубника"}},
+	{"berry", CategoryFruit, []string{"mixed berries", "ягоды"}},
+	{"grape", CategoryFruit, []string{"виноград"}},
+	{"pear", CategoryFruit, []string{"груша", "груши"}},
+	{"avocado", CategoryFruit, []string{"авокадо"}},
+
+	// Bakery
+	{"bread", CategoryBakery, []string{"white bread", "loaf", "хлеб", "батон"}},
+	{"tortilla", CategoryBakery, []string{"wrap", "тортилья"}},
+	{"pita", CategoryBakery, []string{"pita bread", "лаваш"}},
+
+	// Grains
+	{"pasta", CategoryGrains, []string{"макароны", "паста"}},
+	{"spaghetti", CategoryGrains, []string{"спагетти"}},
+	{"lasagna sheet", CategoryGrains, []string{"lasagne sheet", "lasagna noodle", "листы для лазаньи"}},
+	{"rice", CategoryGrains, []string{"white rice", "рис"}},
+	{"buckwheat", CategoryGrains, []string{"гречка", "гречневая крупа"}},
+	{"oats", CategoryGrains, []string{"oatmeal", "rolled oats", "овсянка", "овсяные хлопья"}},
+	{"flour", CategoryGrains, []string{"all-purpose flour", "wheat flour", "мука"}},
+	{"couscous", CategoryGrains, []string{"кускус"}},
+	{"noodle", CategoryGrains, []string{"лапша"}},
+
+	// Legumes
+	{"bean", CategoryLegumes, []string{"фасоль"}},
+	{"lentil", CategoryLegumes, []string{"чечевица"}},
+	{"chickpea", CategoryLegumes, []string{"garbanzo", "нут"}},
+	{"pea", CategoryLegumes, []string{"green pea", "горох", "горошек", "зеленый горошек"}},
+
+	// Nuts
+	{"walnut", CategoryNuts, []string{"грецкий орех", "грецкие орехи"}},
+	{"almond", CategoryNuts, []string{"миндаль"}},
+	{"peanut", CategoryNuts, []string{"арахис"}},
+	{"peanut butter", CategoryNuts, []string{"арахисовая паста"}},
+	{"hazelnut", CategoryNuts, []string{"фундук"}},
+
+	// Spices
+	{"salt", CategorySpices, []string{"sea salt", "соль"}},
+	{"black pepper", CategorySpices, []string{"pepper", "ground pepper", "черный перец"}},
+	{"paprika", CategorySpices, []string{"паприка"}},
+	{"bay leaf", CategorySpices, []string{"лавровый лист"}},
+	{"oregano", CategorySpices, []string{"орегано"}},
+	{"cinnamon", CategorySpices, []string{"корица"}},
+
+	// Condiments
+	{"tomato paste", CategoryCondiments, []string{"tomato puree", "томатная паста"}},
+	{"tomato sauce", CategoryCondiments, []string{"passata", "томатный соус"}},
+	{"ketchup", CategoryCondiments, []string{"кетчуп"}},
+	{"mayonnaise", CategoryCondiments, []string{"mayo", "майонез"}},
+	{"mustard", CategoryCondiments, []string{"горчица"}},
+	{"soy sauce", CategoryCondiments, []string{"соевый соус"}},
+	{"vinegar", CategoryCondiments, []string{"уксус"}},
+	{"broth", CategoryCondiments, []string{"stock", "бульон"}},
+
+	// Oils
+	{"olive oil", CategoryOils, []string{"extra virgin olive oil", "оливковое масло"}},
+	{"vegetable oil", CategoryOils, []string{"sunflower oil", "oil", "растительное масло", "подсолнечное масло"}},
+
+	// Sweets
+	{"sugar", CategorySweets, []string{"сахар"}},
+	{"honey", CategorySweets, []string{"мед", "мёд"}},
+	{"chocolate", CategorySweets, []string{"шоколад"}},
+
+	// Beverages
+	{"coconut milk", CategoryBeverages, []string{"кокосовое молоко"}},
+	{"wine", CategoryBeverages, []string{"white wine", "red wine", "вино"}},
+}
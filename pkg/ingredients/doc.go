@@ -0,0 +1,4 @@
+// Package ingredients provides the canonical ingredient catalog.
+// It maps plurals, synonyms and Russian/English variants of ingredient names to one canonical name,
+// and classifies ingredients into a small category taxonomy used for shelf lives and matching.
+package ingredients
@@ -0,0 +1,64 @@
+package ingredients
+
+// Categories of the ingredient taxonomy
+const (
+	CategoryDairy      = "dairy"
+	CategoryMeat       = "meat"
+	CategoryFish       = "fish"
+	CategoryEggs       = "eggs"
+	CategoryGreens     = "greens"
+	CategoryVegetables = "vegetables"
+	CategoryFruit      = "fruit"
+	CategoryBakery     = "bakery"
+	CategoryGrains     = "grains"
+	CategoryLegumes    = "legumes"
+	CategoryNuts       = "nuts"
+	CategorySpices     = "spices"
+	CategoryCondiments = "condiments"
+	CategoryOils       = "oils"
+	CategorySweets     = "sweets"
+	CategoryBeverages  = "beverages"
+	CategoryOther      = "other"
+)
+
+// Top-level groups of the taxonomy
+const (
+	GroupProduce        = "produce"
+	GroupAnimalProducts = "animal_products"
+	GroupPantry         = "pantry"
+)
+
+// categoryParents links each category to its group
+var categoryParents = map[string]string{
+	CategoryGreens:     GroupProduce,
+	CategoryVegetables: GroupProduce,
+	CategoryFruit:      GroupProduce,
+	CategoryDairy:      GroupAnimalProducts,
+	CategoryMeat:       GroupAnimalProducts,
+	CategoryFish:       GroupAnimalProducts,
+	CategoryEggs:       GroupAnimalProducts,
+	CategoryBakery:     GroupPantry,
+	CategoryGrains:     GroupPantry,
+	CategoryLegumes:    GroupPantry,
+	CategoryNuts:       GroupPantry,
+	CategorySpices:     GroupPantry,
+	CategoryCondiments: GroupPantry,
+	CategoryOils:       GroupPantry,
+	CategorySweets:     GroupPantry,
+	CategoryBeverages:  GroupPantry,
+}
+
+// Parent returns the group a category belongs to, or an empty string for unknown categories
+func Parent(category string) string {
+	return categoryParents[category]
+}
+
+// IsA reports whether a category is the given category or belongs to the given group
+func IsA(category, ancestor string) bool {
+	for current := category; current != ""; current = Parent(current) {
+		if current == ancestor {
+			return true
+		}
+	}
+	return false
+}
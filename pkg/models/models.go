@@ -6,13 +6,31 @@ import (
 
 // ChannelState represents the state of a Telegram channel
 type ChannelState struct {
-	ChannelID     int64      `json:"channel_id"`
-	FridgeID      string     `json:"fridge_id"`
-	CurrentDinner *Dinner    `json:"current_dinner,omitempty"`
-	CurrentVote   *VoteState `json:"current_vote,omitempty"`
-	LastActivity  time.Time  `json:"last_activity"`
-	Cuisines      []string   `json:"cuisines"`
-	MemberCount   int        `json:"member_count,omitempty"`
+	ChannelID     int64       `json:"channel_id"`
+	FridgeID      string      `json:"fridge_id"`
+	CurrentDinner *Dinner     `json:"current_dinner,omitempty"`
+	CurrentVote   *VoteState  `json:"current_vote,omitempty"`
+	Attendance    *Attendance `json:"attendance,omitempty"` // Who's eating at home for the current meal
+	LastActivity  time.Time   `json:"last_activity"`
+	Cuisines      []string    `json:"cuisines"`
+	MemberCount   int         `json:"member_count,omitempty"` // Family members, not counting the bot
+	Portions      int         `json:"portions,omitempty"`     // How many portions the family cooks, remembered from the last adjustment
+
+	// Per-channel settings changed with /settings; zero values, or nil where zero is a valid setting, mean the defaults in pkg/settings apply
+	DinnerTime         string   `json:"dinner_time,omitempty"`          // "HH:MM" when the daily dinner poll starts
+	CutoffTime         string   `json:"cutoff_time,omitempty"`          // "HH:MM" when unfinished dinners are closed
+	Timezone           string   `json:"timezone,omitempty"`             // IANA name, e.g. "Europe/Berlin"
+	VoteThreshold      float64  `json:"vote_threshold,omitempty"`       // Share of members that must vote to close a poll
+	VotingMode         string   `json:"voting_mode,omitempty"`          // "plurality", "approval" or "ranked"
+	CookTimeoutMinutes int      `json:"cook_timeout_minutes,omitempty"` // How long to wait for a cook volunteer
+	VolunteerWindow    *int     `json:"volunteer_window,omitempty"`     // Minutes to gather volunteers before picking; 0 means the first one cooks
+	CookPick           string   `json:"cook_pick,omitempty"`            // How the cook is picked from the volunteers: "random" or "fair"
+	CookRetries        *int     `json:"cook_retries,omitempty"`         // How many times to ask again when nobody volunteers
+	RSVPDeadline       *int     `json:"rsvp_deadline,omitempty"`        // Minutes to ask who's eating at home before the poll starts; 0 skips asking
+	MinVotes           int      `json:"min_votes,omitempty"`            // Fewest votes that close a poll, however few are at home
+	NoCookAction       string   `json:"no_cook_action,omitempty"`       // What happens when still nobody cooks: "no_dinner", "new_poll" or "assign"
+	Language           string   `json:"language,omitempty"`             // Language code for suggestions, e.g. "en"
+	DietaryRules       []string `json:"dietary_rules,omitempty"`        // Family-wide rules, e.g. "vegetarian", "no nuts"
 }
 
 // Fridge represents the ingredients available in a channel's fridge
@@ -25,17 +43,37 @@ type Fridge struct {
 
 // Ingredient represents a single ingredient in the fridge
 type Ingredient struct {
-	Name     string    `json:"name"`
-	Quantity string    `json:"quantity,omitempty"`
-	AddedAt  time.Time `json:"added_at"`
+	Name      string    `json:"name"`
+	Quantity  string    `json:"quantity,omitempty"` // Human-readable quantity, e.g. "1.5 kg"
+	Amount    float64   `json:"amount,omitempty"`   // Parsed amount, set together with Unit
+	Unit      string    `json:"unit,omitempty"`     // Parsed unit (see pkg/quantity), empty if unknown
+	Category  string    `json:"category,omitempty"` // Food category used for default shelf life
+	AddedAt   time.Time `json:"added_at"`
+	ExpiresAt time.Time `json:"expires_at,omitempty"` // Best-before date; zero means the category default applies
 }
 
 // Dish represents a dinner dish
 type Dish struct {
-	Name         string   `json:"name"`
-	Cuisine      string   `json:"cuisine"`
-	Ingredients  []string `json:"ingredients"`
-	Instructions []string `json:"instructions"`
+	Name         string     `json:"name"`
+	Cuisine      string     `json:"cuisine"`
+	Description  string     `json:"description,omitempty"`
+	Ingredients  []string   `json:"ingredients"`
+	Instructions []string   `json:"instructions"`
+	Servings     int        `json:"servings,omitempty"`      // How many people the ingredients are for; zero if unknown
+	PrepMinutes  int        `json:"prep_minutes,omitempty"`  // Preparation time
+	CookMinutes  int        `json:"cook_minutes,omitempty"`  // Cooking time
+	TotalMinutes int        `json:"total_minutes,omitempty"` // Total time, which may be more than prep and cook together
+	SourceURL    string     `json:"source_url,omitempty"`    // Page the recipe was imported from
+	Nutrition    *Nutrition `json:"nutrition,omitempty"`     // Estimated nutrients per serving; nil if not estimated yet
+}
+
+// Nutrition holds the estimated nutrients of one serving of a dish
+type Nutrition struct {
+	Calories float64 `json:"calories"`         // kcal
+	Protein  float64 `json:"protein"`          // Grams
+	Fat      float64 `json:"fat"`              // Grams
+	Carbs    float64 `json:"carbs"`            // Grams
+	Source   string  `json:"source,omitempty"` // "table" if computed from the nutrient table, "llm" if estimated by the LLM
 }
 
 // VoteState represents the state of a vote
@@ -43,12 +81,28 @@ type VoteState struct {
 	PollID         string            `json:"poll_id"`
 	MessageID      int               `json:"message_id"`
 	Options        []string          `json:"options"`
-	Votes          map[string]string `json:"votes"` // UserID -> Option
+	Mode           string            `json:"mode,omitempty"` // "plurality", "approval" or "ranked"; empty means plurality
+	Votes          map[string]string `json:"votes"`          // UserID -> Option
 	StartedAt      time.Time         `json:"started_at"`
 	EndedAt        time.Time         `json:"ended_at,omitempty"`
 	WinningDish    string            `json:"winning_dish,omitempty"`
 	CookVolunteers []string          `json:"cook_volunteers,omitempty"`
 	SelectedCook   string            `json:"selected_cook,omitempty"`
+
+	// Ballots of approval and ranked-choice votes, and who counted for the winner
+	Approvals    map[string][]string `json:"approvals,omitempty"`     // UserID -> Options they approve of
+	Rankings     map[string][]string `json:"rankings,omitempty"`      // UserID -> Options, most preferred first
+	RankingsDone map[string]bool     `json:"rankings_done,omitempty"` // UserIDs who finished ranking
+	Supporters   []string            `json:"supporters,omitempty"`    // UserIDs whose ballots counted for the winning dish
+
+	// Asking for a cook once the winner is known
+	CookRequestedAt      time.Time         `json:"cook_requested_at,omitempty"`       // When the family was last asked who cooks
+	CookRequestMessageID int               `json:"cook_request_message_id,omitempty"` // The message with the volunteer buttons
+	CookRetries          int               `json:"cook_retries,omitempty"`            // How many times the family was asked again
+	VolunteerNames       map[string]string `json:"volunteer_names,omitempty"`         // UserID -> Username of the volunteers
+	FirstVolunteerAt     time.Time         `json:"first_volunteer_at,omitempty"`      // Opens the volunteer window
+	Outcome              string            `json:"outcome,omitempty"`                 // "cooking" or "no_dinner" once decided
+	DecidedAt            time.Time         `json:"decided_at,omitempty"`
 }
 
 // Dinner represents a dinner event
@@ -62,6 +116,7 @@ type Dinner struct {
 	Ratings         map[string]int `json:"ratings,omitempty"` // UserID -> Rating (1-5)
 	AverageRating   float64        `json:"average_rating,omitempty"`
 	UsedIngredients []string       `json:"used_ingredients,omitempty"`
+	Servings        int            `json:"servings,omitempty"` // How many people the cook is cooking for; the dish is scaled to it
 }
 
 // Statistics represents the statistics for a channel
@@ -108,3 +163,147 @@ type SuggestedDish struct {
 	SuggestedAt time.Time `json:"suggested_at"`
 	UsedInPoll  bool      `json:"used_in_poll"`
 }
+
+// ShoppingList represents the things a channel needs to buy
+type ShoppingList struct {
+	ID          string         `json:"id"`
+	ChannelID   int64          `json:"channel_id"`
+	Items       []ShoppingItem `json:"items"`
+	BuyerID     string         `json:"buyer_id,omitempty"` // UserID of whoever volunteered to shop
+	BuyerName   string         `json:"buyer_name,omitempty"`
+	ClaimedAt   time.Time      `json:"claimed_at,omitempty"`
+	LastUpdated time.Time      `json:"last_updated"`
+}
+
+// ShoppingItem represents a single item on the shopping list
+type ShoppingItem struct {
+	Name     string    `json:"name"`               // Canonical ingredient name
+	Quantity string    `json:"quantity,omitempty"` // Amount needed, e.g. "500 g"
+	ForDish  string    `json:"for_dish,omitempty"` // Dish the item is needed for
+	AddedAt  time.Time `json:"added_at"`
+}
+
+// Schedule represents when the bot starts meal polls in a channel
+type Schedule struct {
+	ID          string         `json:"id"`
+	ChannelID   int64          `json:"channel_id"`
+	Rules       []ScheduleRule `json:"rules,omitempty"`      // No meal rules means a dinner poll every day at the channel's dinner time
+	SkipDates   []string       `json:"skip_dates,omitempty"` // One-off days without polls, "2006-01-02" in the channel's timezone
+	LastUpdated time.Time      `json:"last_updated"`
+}
+
+// ScheduleRule represents a recurring meal poll, or a recurring day without polls
+type ScheduleRule struct {
+	Days []time.Weekday `json:"days,omitempty"` // Days the rule applies to, empty means every day
+	Time string         `json:"time,omitempty"` // "HH:MM" when the poll starts, empty for skip rules
+	Meal string         `json:"meal,omitempty"` // e.g. "dinner", "brunch"
+	Skip bool           `json:"skip,omitempty"` // No polls at all on these days
+}
+
+// WeekPlan represents the dinners a channel planned for the coming week
+type WeekPlan struct {
+	ID           string       `json:"id"`
+	ChannelID    int64        `json:"channel_id"`
+	Days         []PlannedDay `json:"days"`
+	Alternatives []Dish       `json:"alternatives,omitempty"` // Unused suggestions, best first, for swapping days
+	Confirmed    bool         `json:"confirmed"`              // Saved by the family; the scheduler announces confirmed dinners
+	CreatedAt    time.Time    `json:"created_at"`
+	LastUpdated  time.Time    `json:"last_updated"`
+}
+
+// PlannedDay represents the dinner planned for one day of a week plan
+type PlannedDay struct {
+	Date      string   `json:"date"` // "2006-01-02" in the channel's timezone
+	Dish      Dish     `json:"dish"`
+	Approvals []string `json:"approvals,omitempty"` // UserIDs who voted to keep the dish
+	Announced bool     `json:"announced,omitempty"` // The scheduler has announced the dinner
+}
+
+// DietProfile represents the dietary restrictions and allergies of a family member
+type DietProfile struct {
+	ChannelID    int64     `json:"channel_id"`
+	UserID       string    `json:"user_id"`
+	Username     string    `json:"username"`
+	Restrictions []string  `json:"restrictions,omitempty"` // e.g. "vegetarian", "lactose-free"
+	Allergies    []string  `json:"allergies,omitempty"`    // e.g. "nuts", "shrimp"
+	LastUpdated  time.Time `json:"last_updated"`
+}
+
+// Recipe represents a dish in a channel's family recipe book
+type Recipe struct {
+	ID            string    `json:"id"`
+	ChannelID     int64     `json:"channel_id"`
+	Dish          Dish      `json:"dish"`
+	OwnerID       string    `json:"owner_id"` // The family member who added the recipe and may change it
+	OwnerUsername string    `json:"owner_username"`
+	UpdatedBy     string    `json:"updated_by,omitempty"` // Username of the last editor
+	CreatedAt     time.Time `json:"created_at"`
+	LastUpdated   time.Time `json:"last_updated"`
+}
+
+// CookingSession represents a cook going through the steps of a dinner one message at a time
+type CookingSession struct {
+	ChannelID    int64     `json:"channel_id"`
+	DinnerID     string    `json:"dinner_id"`
+	Cook         string    `json:"cook"` // UserID of the cook
+	CookUsername string    `json:"cook_username"`
+	MessageID    int       `json:"message_id"` // The message that shows the current step
+	Step         int       `json:"step"`       // Index of the current step
+	StartedAt    time.Time `json:"started_at"`
+	LastUpdated  time.Time `json:"last_updated"`
+}
+
+// CookingTimer represents a timer the cook started for a step
+type CookingTimer struct {
+	ID           string    `json:"id"`
+	ChannelID    int64     `json:"channel_id"`
+	DinnerID     string    `json:"dinner_id"`
+	Cook         string    `json:"cook"` // UserID of the cook to ping
+	CookUsername string    `json:"cook_username"`
+	Step         int       `json:"step"`  // Index of the step the timer is for
+	Label        string    `json:"label"` // The duration as the step says it, e.g. "20 minutes"
+	StartedAt    time.Time `json:"started_at"`
+	FiresAt      time.Time `json:"fires_at"`
+}
+
+// RotationMember represents a family member who takes turns cooking
+type RotationMember struct {
+	ChannelID int64     `json:"channel_id"`
+	UserID    string    `json:"user_id"`
+	Username  string    `json:"username"`
+	Paused    bool      `json:"paused,omitempty"` // Left the rotation, e.g. while away
+	JoinedAt  time.Time `json:"joined_at"`
+	LastSeen  time.Time `json:"last_seen"` // Last vote or volunteer
+}
+
+// Attendance records who's eating at home for a meal, asked before its poll starts
+type Attendance struct {
+	Meal      string            `json:"meal"`
+	MessageID int               `json:"message_id"`
+	Attending map[string]string `json:"attending"` // UserID -> username of those eating at home
+	Away      map[string]string `json:"away"`      // UserID -> username of those who aren't
+	AskedAt   time.Time         `json:"asked_at"`
+	Deadline  time.Time         `json:"deadline"`            // When the poll starts
+	ClosedAt  time.Time         `json:"closed_at,omitempty"` // When the answers were counted and the poll started
+	ExpiresAt time.Time         `json:"expires_at"`          // The answers count until the channel's cutoff
+}
+
+// Workflow is the lifecycle of one dinner in a channel, from the suggestions to the ratings
+type Workflow struct {
+	ID         string               `json:"id"`
+	ChannelID  int64                `json:"channel_id"`
+	State      string               `json:"state"`
+	StateSince time.Time            `json:"state_since"`         // When the workflow entered its state, for its timeout
+	PollID     string               `json:"poll_id,omitempty"`   // The vote on the dish, or the planned dinner
+	DinnerID   string               `json:"dinner_id,omitempty"` // The dinner being cooked
+	StartedAt  time.Time            `json:"started_at"`
+	History    []WorkflowTransition `json:"history"`
+}
+
+// WorkflowTransition records a step of a dinner workflow from one state to the next
+type WorkflowTransition struct {
+	From   string    `json:"from"`
+	To     string    `json:"to"`
+	At     time.Time `json:"at"`
+	Reason string    `json:"reason"` // Why it moved on, e.g. "poll started" or "timed out"
+}
@@ -0,0 +1,5 @@
+// Package nutrition estimates calories, protein, fat and carbs per serving of a dish.
+// Estimates are computed from a bundled nutrient table keyed by canonical ingredient,
+// with the LLM as a fallback for recipes the table doesn't cover, and summed up into
+// reports of what a family ate over a week.
+package nutrition
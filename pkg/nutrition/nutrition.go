@@ -0,0 +1,287 @@
+package nutrition
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/korjavin/whatsfordinner/pkg/ingredients"
+	"github.com/korjavin/whatsfordinner/pkg/logger"
+	"github.com/korjavin/whatsfordinner/pkg/models"
+	"github.com/korjavin/whatsfordinner/pkg/openai"
+	"github.com/korjavin/whatsfordinner/pkg/quantity"
+	"github.com/korjavin/whatsfordinner/pkg/storage"
+)
+
+// Sources of a nutrition estimate
+const (
+	SourceTable = "table"
+	SourceLLM   = "llm"
+)
+
+// defaultServings is how many people a recipe is assumed to be for when it doesn't say, as in the dinner package
+const defaultServings = 4
+
+// negligibleGrams is how much of an ingredient the table may not know and still give a fair estimate
+const negligibleGrams = 15
+
+// Service estimates the nutrients of dishes
+type Service struct {
+	store        *storage.Store
+	openaiClient *openai.Client
+	logger       *logger.Logger
+}
+
+// New creates a new nutrition service
+func New(store *storage.Store, openaiClient *openai.Client) *Service {
+	return &Service{
+		store:        store,
+		openaiClient: openaiClient,
+		logger:       logger.New("nutrition"),
+	}
+}
+
+// FromTable computes the nutrients of one serving of a dish from the nutrient table
+// ok is false if the table doesn't know enough of the ingredients, or their amounts, for a fair estimate
+func FromTable(dish models.Dish) (models.Nutrition, bool) {
+	var total models.Nutrition
+	if len(dish.Ingredients) == 0 {
+		return total, false
+	}
+
+	for _, line := range dish.Ingredients {
+		name, q, hasAmount := quantity.ParseIngredient(line)
+		entry, known := table[ingredients.Canonicalize(name)]
+
+		if !hasAmount {
+			// "salt to taste" or "parsley for garnish" don't change the numbers, "chicken" does
+			if isNegligible(line) {
+				continue
+			}
+			return total, false
+		}
+
+		grams, weighed := weigh(q, entry)
+		if !known || !weighed {
+			if weighed && grams <= negligibleGrams {
+				continue
+			}
+			return total, false
+		}
+
+		total.Calories += entry.calories * grams / 100
+		total.Protein += entry.protein * grams / 100
+		total.Fat += entry.fat * grams / 100
+		total.Carbs += entry.carbs * grams / 100
+	}
+
+	servings := dish.Servings
+	if servings <= 0 {
+		servings = defaultServings
+	}
+
+	return models.Nutrition{
+		Calories: math.Round(total.Calories / float64(servings)),
+		Protein:  math.Round(total.Protein / float64(servings)),
+		Fat:      math.Round(total.Fat / float64(servings)),
+		Carbs:    math.Round(total.Carbs / float64(servings)),
+		Source:   SourceTable,
+	}, true
+}
+
+// weigh returns how many grams a quantity of an ingredient is
+// Volumes use the ingredient's density and pieces its weight per piece; ok is false if that's not known
+func weigh(q quantity.Quantity, entry nutrients) (float64, bool) {
+	switch q.Unit.Dimension() {
+	case quantity.DimensionMass:
+		grams, err := q.Convert(quantity.Gram)
+		return grams.Amount, err == nil
+	case quantity.DimensionVolume:
+		milliliters, err := q.Convert(quantity.Milliliter)
+		density := entry.density
+		if density == 0 {
+			density = 1
+		}
+		return milliliters.Amount * density, err == nil
+	default:
+		return q.Amount * entry.piece, entry.piece > 0
+	}
+}
+
+// isNegligible reports whether an ingredient line without an amount can be left out of the estimate
+func isNegligible(line string) bool {
+	lower := strings.ToLower(line)
+	for _, phrase := range []string{"to taste", "for serving", "for garnish", "optional", "pinch"} {
+		if strings.Contains(lower, phrase) {
+			return true
+		}
+	}
+
+	category := ingredients.CategoryOf(line)
+	return category == ingredients.CategorySpices || category == ingredients.CategoryGreens
+}
+
+// Estimate returns the nutrients of one serving of a dish
+// They're computed from the nutrient table when it knows the ingredients; otherwise the dish's own
+// LLM estimate is used, or the LLM is asked, remembering its answer for the same recipe
+func (s *Service) Estimate(dish models.Dish) (*models.Nutrition, error) {
+	if nutrition, ok := FromTable(dish); ok {
+		return &nutrition, nil
+	}
+	if dish.Nutrition != nil {
+		return dish.Nutrition, nil
+	}
+
+	key := estimateKey(dish)
+	var cached models.Nutrition
+	if err := s.store.Get(key, &cached); err == nil {
+		return &cached, nil
+	}
+
+	servings := dish.Servings
+	if servings <= 0 {
+		servings = defaultServings
+	}
+	info, err := s.openaiClient.EstimateNutrition(dish.Name, dish.Ingredients, servings)
+	if err != nil {
+		return nil, fmt.Errorf("failed to estimate nutrition of %s: %w", dish.Name, err)
+	}
+
+	nutrition := FromInfo(info)
+	if nutrition == nil {
+		return nil, fmt.Errorf("no nutrition estimate for %s", dish.Name)
+	}
+	if err := s.store.Set(key, nutrition); err != nil {
+		s.logger.Error("Failed to cache nutrition of %s: %v", dish.Name, err)
+	}
+
+	s.logger.Info("Estimated nutrition of %s with the LLM", dish.Name)
+	return nutrition, nil
+}
+
+// Annotate returns the dish with its nutrients per serving attached
+// If they can't be estimated the dish is returned as it is
+func (s *Service) Annotate(dish models.Dish) models.Dish {
+	nutrition, err := s.Estimate(dish)
+	if err != nil {
+		s.logger.Error("Failed to estimate nutrition: %v", err)
+		return dish
+	}
+	dish.Nutrition = nutrition
+	return dish
+}
+
+// estimateKey returns the store key of an LLM estimate; a changed recipe gets a new estimate
+func estimateKey(dish models.Dish) string {
+	hash := fnv.New64a()
+	hash.Write([]byte(strings.ToLower(strings.TrimSpace(dish.Name))))
+	for _, ingredient := range dish.Ingredients {
+		hash.Write([]byte("\n" + ingredient))
+	}
+	hash.Write([]byte("\n" + strconv.Itoa(dish.Servings)))
+	return fmt.Sprintf("nutrition:%x", hash.Sum64())
+}
+
+// FromInfo reads nutrients per serving from the JSON returned by the LLM
+// It accepts the nutrition object itself or an answer that has it under "nutrition", and returns nil if there's none
+func FromInfo(info map[string]interface{}) *models.Nutrition {
+	if nested, ok := info["nutrition"].(map[string]interface{}); ok {
+		info = nested
+	}
+
+	calories, ok := info["calories"].(float64)
+	if !ok {
+		return nil
+	}
+	protein, _ := info["protein"].(float64)
+	fat, _ := info["fat"].(float64)
+	carbs, _ := info["carbs"].(float64)
+
+	return &models.Nutrition{
+		Calories: math.Round(calories),
+		Protein:  math.Round(protein),
+		Fat:      math.Round(fat),
+		Carbs:    math.Round(carbs),
+		Source:   SourceLLM,
+	}
+}
+
+// Format formats nutrients for a message, e.g. "520 kcal · protein 30 g · fat 20 g · carbs 45 g"
+// LLM estimates are marked with "≈", as they're rougher than the table
+func Format(nutrition *models.Nutrition) string {
+	if nutrition == nil {
+		return ""
+	}
+	text := fmt.Sprintf("%.0f kcal · protein %.0f g · fat %.0f g · carbs %.0f g", nutrition.Calories, nutrition.Protein, nutrition.Fat, nutrition.Carbs)
+	if nutrition.Source == SourceLLM {
+		text = "≈" + text
+	}
+	return text
+}
+
+// DinnerNutrition is a finished dinner with the nutrients of one serving
+type DinnerNutrition struct {
+	Dinner    models.Dinner
+	Nutrition *models.Nutrition // nil if it couldn't be estimated
+}
+
+// Report sums up what a family ate over a period, per person
+type Report struct {
+	From    time.Time
+	To      time.Time
+	Dinners []DinnerNutrition
+	Total   models.Nutrition // One serving of every dinner with an estimate
+	Counted int              // Dinners with an estimate
+}
+
+// Average returns the nutrients of an average dinner of the report
+func (r Report) Average() models.Nutrition {
+	if r.Counted == 0 {
+		return models.Nutrition{}
+	}
+	count := float64(r.Counted)
+	return models.Nutrition{
+		Calories: math.Round(r.Total.Calories / count),
+		Protein:  math.Round(r.Total.Protein / count),
+		Fat:      math.Round(r.Total.Fat / count),
+		Carbs:    math.Round(r.Total.Carbs / count),
+	}
+}
+
+// Summarize builds a report from the dinners finished between from and to, oldest first
+// Dinners saved without nutrients get an estimate now
+func (s *Service) Summarize(dinners []models.Dinner, from, to time.Time) Report {
+	report := Report{From: from, To: to}
+	for _, dinner := range dinners {
+		if dinner.FinishedAt.IsZero() || dinner.FinishedAt.Before(from) || dinner.FinishedAt.After(to) {
+			continue
+		}
+
+		nutrition := dinner.Dish.Nutrition
+		if nutrition == nil {
+			if estimate, err := s.Estimate(dinner.Dish); err == nil {
+				nutrition = estimate
+			} else {
+				s.logger.Error("Failed to estimate nutrition of dinner %s: %v", dinner.ID, err)
+			}
+		}
+
+		report.Dinners = append(report.Dinners, DinnerNutrition{Dinner: dinner, Nutrition: nutrition})
+		if nutrition != nil {
+			report.Total.Calories += nutrition.Calories
+			report.Total.Protein += nutrition.Protein
+			report.Total.Fat += nutrition.Fat
+			report.Total.Carbs += nutrition.Carbs
+			report.Counted++
+		}
+	}
+
+	sort.Slice(report.Dinners, func(i, j int) bool {
+		return report.Dinners[i].Dinner.FinishedAt.Before(report.Dinners[j].Dinner.FinishedAt)
+	})
+	return report
+}
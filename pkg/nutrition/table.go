@@ -0,0 +1,153 @@
+package nutrition
+
+// nutrients are the nutrients of 100 g of an ingredient, with what's needed to weigh it
+type nutrients struct {
+	calories float64 // kcal per 100 g
+	protein  float64 // Grams per 100 g
+	fat      float64 // Grams per 100 g
+	carbs    float64 // Grams per 100 g
+	piece    float64 // Grams of one piece, e.g. an egg; zero if the ingredient isn't counted in pieces
+	density  float64 // Grams per milliliter; zero means 1, like water
+}
+
+// table holds the nutrients of the canonical ingredients, as they go into the pot (raw, dry pasta and grains)
+// Values are rounded averages from public food composition tables; they're meant for estimates, not diets
+var table = map[string]nutrients{
+	// Dairy
+	"milk":           {61, 3.2, 3.3, 4.8, 0, 1.03},
+	"cream":          {340, 2.1, 36, 2.8, 0, 1},
+	"sour cream":     {198, 2.4, 19, 4.6, 0, 1},
+	"yogurt":         {61, 3.5, 3.3, 4.7, 0, 1.05},
+	"kefir":          {52, 3.3, 2.5, 4, 0, 1.03},
+	"butter":         {717, 0.9, 81, 0.1, 0, 0.91},
+	"cheese":         {402, 25, 33, 1.3, 0, 0},
+	"mozzarella":     {280, 28, 17, 3, 125, 0},
+	"parmesan":       {431, 38, 29, 4, 0, 0.4},
+	"cottage cheese": {98, 11, 4.3, 3.4, 0, 0},
+	"cream cheese":   {342, 6, 34, 4, 0, 0},
+
+	// Eggs
+	"egg": {143, 12.6, 9.5, 0.7, 50, 0},
+
+	// Meat
+	"chicken":        {215, 18.6, 15, 0, 1500, 0},
+	"chicken breast": {120, 22.5, 2.6, 0, 200, 0},
+	"chicken thigh":  {177, 19.7, 10.9, 0, 120, 0},
+	"beef":           {187, 20, 12, 0, 0, 0},
+	"ground beef":    {254, 17, 20, 0, 0, 0},
+	"minced meat":    {263, 17, 21, 0, 0, 0},
+	"pork":           {242, 17, 19, 0, 0, 0},
+	"lamb":           {282, 17, 23, 0, 0, 0},
+	"turkey":         {135, 20, 6, 0, 0, 0},
+	"bacon":          {458, 12, 45, 1.4, 25, 0},
+	"ham":            {145, 21, 6, 1.5, 30, 0},
+	"sausage":        {301, 12, 27, 2, 60, 0},
+
+	// Fish
+	"fish":   {90, 19, 1.5, 0, 0, 0},
+	"salmon": {208, 20, 13, 0, 150, 0},
+	"cod":    {82, 18, 0.7, 0, 150, 0},
+	"tuna":   {116, 26, 1, 0, 0, 0},
+	"shrimp": {85, 20, 0.5, 0.2, 15, 0},
+
+	// Greens
+	"lettuce":     {15, 1.4, 0.2, 2.9, 300, 0},
+	"spinach":     {23, 2.9, 0.4, 3.6, 0, 0.12},
+	"parsley":     {36, 3, 0.8, 6.3, 0, 0.25},
+	"dill":        {43, 3.5, 1.1, 7, 0, 0.25},
+	"basil":       {23, 3.2, 0.6, 2.7, 0, 0.1},
+	"cilantro":    {23, 2.1, 0.5, 3.7, 0, 0.1},
+	"arugula":     {25, 2.6, 0.7, 3.7, 0, 0.1},
+	"green onion": {32, 1.8, 0.2, 7.3, 15, 0.25},
+
+	// Vegetables
+	"tomato":      {18, 0.9, 0.2, 3.9, 120, 0},
+	"cucumber":    {15, 0.7, 0.1, 3.6, 200, 0},
+	"potato":      {77, 2, 0.1, 17, 170, 0},
+	"onion":       {40, 1.1, 0.1, 9.3, 110, 0.65},
+	"garlic":      {149, 6.4, 0.5, 33, 5, 0.6},
+	"carrot":      {41, 0.9, 0.2, 9.6, 60, 0.55},
+	"bell pepper": {31, 1, 0.3, 6, 150, 0},
+	"zucchini":    {17, 1.2, 0.3, 3.1, 200, 0},
+	"eggplant":    {25, 1, 0.2, 5.9, 300, 0},
+	"cabbage":     {25, 1.3, 0.1, 5.8, 900, 0.35},
+	"broccoli":    {34, 2.8, 0.4, 6.6, 300, 0.35},
+	"cauliflower": {25, 1.9, 0.3, 5, 600, 0.4},
+	"mushroom":    {22, 3.1, 0.3, 3.3, 20, 0.3},
+	"beetroot":    {43, 1.6, 0.2, 9.6, 150, 0},
+	"celery":      {16, 0.7, 0.2, 3, 40, 0},
+	"corn":        {86, 3.3, 1.4, 19, 150, 0.65},
+	"pumpkin":     {26, 1, 0.1, 6.5, 0, 0},
+
+	// Fruit
+	"apple":      {52, 0.3, 0.2, 14, 180, 0},
+	"banana":     {89, 1.1, 0.3, 23, 120, 0},
+	"orange":     {47, 0.9, 0.1, 12, 150, 0},
+	"lemon":      {29, 1.1, 0.3, 9.3, 100, 0},
+	"lime":       {30, 0.7, 0.2, 10.5, 60, 0},
+	"strawberry": {32, 0.7, 0.3, 7.7, 12, 0.6},
+	"berry":      {50, 0.7, 0.3, 12, 0, 0.6},
+	"grape":      {69, 0.7, 0.2, 18, 5, 0.6},
+	"pear":       {57, 0.4, 0.1, 15, 180, 0},
+	"avocado":    {160, 2, 15, 8.5, 150, 0},
+
+	// Bakery
+	"bread":    {265, 9, 3.2, 49, 30, 0},
+	"tortilla": {310, 8, 8, 52, 45, 0},
+	"pita":     {275, 9, 1.2, 56, 60, 0},
+
+	// Grains
+	"pasta":         {371, 13, 1.5, 75, 0, 0.45},
+	"spaghetti":     {371, 13, 1.5, 75, 0, 0},
+	"lasagna sheet": {371, 13, 1.5, 75, 20, 0},
+	"rice":          {360, 6.6, 0.6, 79, 0, 0.85},
+	"buckwheat":     {343, 13, 3.4, 72, 0, 0.8},
+	"oats":          {389, 17, 6.9, 66, 0, 0.4},
+	"flour":         {364, 10, 1, 76, 0, 0.53},
+	"couscous":      {376, 13, 0.6, 77, 0, 0.7},
+	"noodle":        {384, 14, 4.4, 71, 0, 0},
+
+	// Legumes, cooked or canned except lentils, which are mostly cooked from dry
+	"bean":     {127, 8.7, 0.5, 22.8, 0, 0.75},
+	"lentil":   {353, 25, 1.1, 60, 0, 0.8},
+	"chickpea": {164, 8.9, 2.6, 27, 0, 0.7},
+	"pea":      {81, 5.4, 0.4, 14, 0, 0.6},
+
+	// Nuts
+	"walnut":        {654, 15, 65, 14, 0, 0.45},
+	"almond":        {579, 21, 50, 22, 0, 0.6},
+	"peanut":        {567, 26, 49, 16, 0, 0.6},
+	"peanut butter": {588, 25, 50, 20, 0, 1.05},
+	"hazelnut":      {628, 15, 61, 17, 0, 0.55},
+
+	// Spices
+	"salt":         {0, 0, 0, 0, 0, 1.2},
+	"black pepper": {251, 10, 3.3, 64, 0, 0.45},
+	"paprika":      {282, 14, 13, 54, 0, 0.45},
+	"bay leaf":     {313, 7.6, 8.4, 75, 0.2, 0},
+	"oregano":      {265, 9, 4.3, 69, 0, 0.2},
+	"cinnamon":     {247, 4, 1.2, 81, 0, 0.55},
+
+	// Condiments
+	"tomato paste": {82, 4.3, 0.5, 19, 0, 1.1},
+	"tomato sauce": {24, 1.2, 0.2, 5, 0, 1.03},
+	"ketchup":      {112, 1.3, 0.2, 26, 0, 1.15},
+	"mayonnaise":   {680, 1, 75, 0.6, 0, 0.95},
+	"mustard":      {66, 4, 3.3, 5.8, 0, 1.05},
+	"soy sauce":    {53, 8, 0.6, 4.9, 0, 1.15},
+	"vinegar":      {18, 0, 0, 0, 0, 1},
+	"broth":        {5, 0.6, 0.2, 0.4, 0, 1},
+
+	// Oils
+	"olive oil":     {884, 0, 100, 0, 0, 0.92},
+	"vegetable oil": {884, 0, 100, 0, 0, 0.92},
+
+	// Sweets
+	"sugar":     {387, 0, 0, 100, 0, 0.85},
+	"honey":     {304, 0.3, 0, 82, 0, 1.42},
+	"chocolate": {546, 4.9, 31, 61, 0, 0},
+
+	// Beverages
+	"coconut milk": {230, 2.3, 24, 6, 0, 1},
+	"wine":         {83, 0.1, 0, 2.6, 0, 1},
+}
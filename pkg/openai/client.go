@@ -7,6 +7,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/korjavin/whatsfordinner/pkg/ingredients"
 	"github.com/korjavin/whatsfordinner/pkg/logger"
 	"github.com/sashabaranov/go-openai"
 )
@@ -18,6 +19,26 @@ type Client struct {
 	logger *logger.Logger
 }
 
+// Preferences are a family's settings that shape the suggestions
+type Preferences struct {
+	Cuisines     []string
+	DietaryRules []string // Rules every dish must follow, e.g. "vegetarian", "Anna: lactose-free"
+	Allergies    []string // Ingredients that must never be used, e.g. "peanut"
+	Language     string   // Language for dish names and descriptions, e.g. "Russian"
+}
+
+// recipeRules returns prompt lines asking for a recipe that follows the dietary rules and allergies, if there are any
+func (p Preferences) recipeRules() string {
+	var rules string
+	if len(p.DietaryRules) > 0 {
+		rules += fmt.Sprintf("Adapt the recipe to these dietary rules: %s.\n", strings.Join(p.DietaryRules, ", "))
+	}
+	if len(p.Allergies) > 0 {
+		rules += fmt.Sprintf("The family has allergies: never use %s, not even in small amounts, and replace them with safe alternatives.\n", strings.Join(p.Allergies, ", "))
+	}
+	return rules
+}
+
 // New creates a new OpenAI client
 func New(apiKey, apiBase, model string) *Client {
 	config := openai.DefaultConfig(apiKey)
@@ -34,7 +55,8 @@ func New(apiKey, apiBase, model string) *Client {
 }
 
 // GetDishInfo retrieves information about a dish from the LLM
-func (c *Client) GetDishInfo(dishName string, cuisine ...string) (map[string]interface{}, error) {
+// The recipe follows the dietary rules and allergies in prefs; pass empty preferences to get the dish as it is
+func (c *Client) GetDishInfo(dishName string, prefs Preferences, cuisine ...string) (map[string]interface{}, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
@@ -47,10 +69,13 @@ Return the information in the following JSON format:
 {
   "name": "Full dish name",
   "cuisine": "Cuisine type",
-  "ingredients_needed": ["ingredient1", "ingredient2", ...],
+  "ingredients_needed": ["200 g ingredient1", "2 ingredient2", ...],
   "instructions": ["step1", "step2", ...],
-  "description": "Brief description of the dish"
+  "description": "Brief description of the dish",
+  "servings": 4
 }
+Each ingredient should start with its amount and a metric unit (g, kg, ml, l, tsp, tbsp, pcs) when it can be measured.
+"servings" is how many people the amounts are for.
 Only return the JSON, no other text.
 `, dishName, cuisine[0])
 		c.logger.Info("Requesting dish info for %s (%s cuisine)", dishName, cuisine[0])
@@ -63,14 +88,18 @@ Return the information in the following JSON format:
 {
   "name": "Full dish name",
   "cuisine": "Cuisine type",
-  "ingredients_needed": ["ingredient1", "ingredient2", ...],
+  "ingredients_needed": ["200 g ingredient1", "2 ingredient2", ...],
   "instructions": ["step1", "step2", ...],
-  "description": "Brief description of the dish"
+  "description": "Brief description of the dish",
+  "servings": 4
 }
+Each ingredient should start with its amount and a metric unit (g, kg, ml, l, tsp, tbsp, pcs) when it can be measured.
+"servings" is how many people the amounts are for.
 Only return the JSON, no other text.
 `, dishName)
 		c.logger.Info("Requesting dish info for %s (cuisine not specified)", dishName)
 	}
+	prompt += prefs.recipeRules()
 
 	c.logger.Debug("OpenAI prompt (first 100 chars): %s", truncateString(prompt, 100))
 
@@ -116,6 +145,138 @@ Only return the JSON, no other text.
 	return result, nil
 }
 
+// ExtractRecipe extracts the recipe from the text of a web page that has no structured recipe data
+// The result has the shape of a schema.org Recipe, so it's read the same way as JSON-LD from the page
+func (c *Client) ExtractRecipe(pageText string) (map[string]interface{}, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	prompt := fmt.Sprintf(`
+Below is the text of a web page with a recipe. Extract the recipe exactly as the page gives it, without inventing anything.
+Return it as schema.org Recipe JSON:
+{
+  "@type": "Recipe",
+  "name": "Dish name",
+  "description": "Brief description, if the page has one",
+  "recipeCuisine": "Cuisine type, if the page mentions it",
+  "recipeYield": "Number of servings, e.g. 4",
+  "prepTime": "ISO 8601 duration, e.g. PT15M",
+  "cookTime": "ISO 8601 duration, e.g. PT1H",
+  "totalTime": "ISO 8601 duration",
+  "recipeIngredient": ["200 g ingredient1", "2 ingredient2", ...],
+  "recipeInstructions": ["step1", "step2", ...]
+}
+Leave out fields the page doesn't give. If the page has no recipe, return {}.
+Only return the JSON, no other text.
+
+Page:
+%s
+`, pageText)
+
+	c.logger.Info("Extracting a recipe from %d characters of page text", len(pageText))
+
+	resp, err := c.client.CreateChatCompletion(
+		ctx,
+		openai.ChatCompletionRequest{
+			Model: c.model,
+			Messages: []openai.ChatCompletionMessage{
+				{
+					Role:    openai.ChatMessageRoleSystem,
+					Content: "You are a cooking expert who extracts recipes from web pages accurately.",
+				},
+				{
+					Role:    openai.ChatMessageRoleUser,
+					Content: prompt,
+				},
+			},
+			Temperature: 0,
+		},
+	)
+
+	if err != nil {
+		return nil, fmt.Errorf("OpenAI API error: %w", err)
+	}
+
+	if len(resp.Choices) == 0 {
+		return nil, fmt.Errorf("no response from OpenAI API")
+	}
+
+	content := cleanJSONResponse(resp.Choices[0].Message.Content)
+
+	var result map[string]interface{}
+	if err := json.Unmarshal([]byte(content), &result); err != nil {
+		c.logger.Error("Failed to parse response: %v, Content: %s", err, content)
+		return nil, fmt.Errorf("failed to parse OpenAI response: %w", err)
+	}
+
+	return result, nil
+}
+
+// EstimateNutrition estimates the calories, protein, fat and carbs of one serving of a dish
+// It's used for recipes the nutrient table can't cover; ingredients may be empty, then the dish name is all there is
+func (c *Client) EstimateNutrition(dishName string, ingredients []string, servings int) (map[string]interface{}, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	ingredientsStr := "not known, assume a typical recipe"
+	if len(ingredients) > 0 {
+		ingredientsStr = strings.Join(ingredients, "; ")
+	}
+
+	prompt := fmt.Sprintf(`
+Estimate the nutrients of one serving of "%s".
+The recipe makes %d servings with these ingredients: %s
+
+Return the estimate in the following JSON format:
+{
+  "calories": 550,
+  "protein": 30,
+  "fat": 20,
+  "carbs": 60
+}
+Calories are in kcal, protein, fat and carbs in grams, all per serving.
+Only return the JSON, no other text.
+`, dishName, servings, ingredientsStr)
+
+	c.logger.Info("Requesting a nutrition estimate for %s", dishName)
+
+	resp, err := c.client.CreateChatCompletion(
+		ctx,
+		openai.ChatCompletionRequest{
+			Model: c.model,
+			Messages: []openai.ChatCompletionMessage{
+				{
+					Role:    openai.ChatMessageRoleSystem,
+					Content: "You are a nutritionist who estimates the nutrients of home-cooked dishes.",
+				},
+				{
+					Role:    openai.ChatMessageRoleUser,
+					Content: prompt,
+				},
+			},
+			Temperature: 0,
+		},
+	)
+
+	if err != nil {
+		return nil, fmt.Errorf("OpenAI API error: %w", err)
+	}
+
+	if len(resp.Choices) == 0 {
+		return nil, fmt.Errorf("no response from OpenAI API")
+	}
+
+	content := cleanJSONResponse(resp.Choices[0].Message.Content)
+
+	var result map[string]interface{}
+	if err := json.Unmarshal([]byte(content), &result); err != nil {
+		c.logger.Error("Failed to parse response: %v, Content: %s", err, content)
+		return nil, fmt.Errorf("failed to parse OpenAI response: %w", err)
+	}
+
+	return result, nil
+}
+
 // GenerateChatMessage generates a chat message for a specific intent
 func (c *Client) GenerateChatMessage(intent string, contextData map[string]interface{}) (string, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
@@ -172,8 +333,9 @@ func (c *Client) ExtractIngredientsFromPhoto(photoURL string) ([]string, error)
 
 	prompt := `You are a computer vision expert. Look at the image of a fridge or pantry and list all visible food ingredients.
 Be thorough and try to identify as many food items as possible.
+Use short, generic English names in the singular and list each ingredient only once.
 Return only a JSON array of ingredient names, no other text.
-For example: ["eggs", "milk", "tomatoes", "chicken breast"]
+For example: ["egg", "milk", "tomato", "chicken breast"]
 `
 
 	c.logger.Info("Extracting ingredients from photo")
@@ -230,7 +392,7 @@ For example: ["eggs", "milk", "tomatoes", "chicken breast"]
 		c.logger.Error("Failed to parse response: %v, Content: %s", err, content)
 
 		// Try to extract ingredients using a more lenient approach
-		extractedIngredients := extractIngredientsFromText(content)
+		extractedIngredients := dedupeIngredients(extractIngredientsFromText(content))
 		if len(extractedIngredients) > 0 {
 			c.logger.Info("Extracted %d ingredients using fallback method", len(extractedIngredients))
 			return extractedIngredients, nil
@@ -239,6 +401,7 @@ For example: ["eggs", "milk", "tomatoes", "chicken breast"]
 		return nil, fmt.Errorf("failed to parse OpenAI response: %w", err)
 	}
 
+	ingredients = dedupeIngredients(ingredients)
 	c.logger.Info("Successfully extracted %d ingredients from photo", len(ingredients))
 	return ingredients, nil
 }
@@ -250,8 +413,10 @@ func (c *Client) ParseIngredientsFromText(text string) ([]string, error) {
 
 	prompt := fmt.Sprintf(`
 You are a cooking assistant. Extract all food ingredients from the following text.
-Return only a JSON array of ingredient names, no other text.
-For example: ["eggs", "milk", "tomatoes", "chicken breast"]
+Use short, generic English names in the singular.
+If the text mentions a quantity, keep it in parentheses after the name using a metric unit (g, kg, ml, l, pcs).
+Return only a JSON array of ingredients, no other text.
+For example: ["eggs (6 pcs)", "milk (1 l)", "tomatoes", "chicken breast (500 g)"]
 
 Text: %s
 `, text)
@@ -293,25 +458,48 @@ Text: %s
 		return nil, fmt.Errorf("failed to parse OpenAI response: %w", err)
 	}
 
-	return ingredients, nil
+	return dedupeIngredients(ingredients), nil
 }
 
-// SuggestDinnerOptions suggests dinner options based on available ingredients and cuisines
-func (c *Client) SuggestDinnerOptions(ingredients []string, cuisines []string, count int) ([]map[string]interface{}, error) {
+// SuggestDinnerOptions suggests dinner options based on available ingredients and the family's preferences
+// Ingredients in useSoon are close to their expiry date and should be preferred
+func (c *Client) SuggestDinnerOptions(ingredients []string, useSoon []string, prefs Preferences, count int) ([]map[string]interface{}, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
 	// Convert ingredients and cuisines to strings for the prompt
 	ingredientsStr := strings.Join(ingredients, ", ")
-	cuisinesStr := strings.Join(cuisines, ", ")
+	cuisinesStr := strings.Join(prefs.Cuisines, ", ")
+	useSoonStr := "none"
+	if len(useSoon) > 0 {
+		useSoonStr = strings.Join(useSoon, ", ")
+	}
+	dietStr := "none"
+	if len(prefs.DietaryRules) > 0 {
+		dietStr = strings.Join(prefs.DietaryRules, ", ")
+	}
+	allergiesStr := "none"
+	if len(prefs.Allergies) > 0 {
+		allergiesStr = strings.Join(prefs.Allergies, ", ")
+	}
+	language := prefs.Language
+	if language == "" {
+		language = "English"
+	}
 
 	prompt := fmt.Sprintf(`
 You are a cooking expert. Based on the available ingredients and preferred cuisines, suggest %d dinner options.
 
 Available ingredients: %s
 
+Ingredients that expire soon and should be used first: %s
+
 Preferred cuisines: %s
 
+Dietary rules every dish must follow: %s
+
+Allergies: never suggest a dish with these ingredients, not even in small amounts: %s
+
 Return the suggestions in the following JSON format:
 [
   {
@@ -319,15 +507,19 @@ Return the suggestions in the following JSON format:
     "cuisine": "Cuisine type",
     "description": "Brief description of the dish",
     "ingredients_needed": ["ingredient1", "ingredient2", ...],
-    "ingredients_missing": ["ingredient1", "ingredient2", ...]
+    "ingredients_missing": ["ingredient1", "ingredient2", ...],
+    "nutrition": {"calories": 550, "protein": 30, "fat": 20, "carbs": 60}
   },
   ...
 ]
 
+Prefer dishes that use the ingredients that expire soon.
+"nutrition" is your estimate for one serving: calories in kcal, protein, fat and carbs in grams.
+Write the dish names and descriptions in %s, but keep the JSON keys in English.
 Only return the JSON array, no other text.
-`, count, ingredientsStr, cuisinesStr)
+`, count, ingredientsStr, useSoonStr, cuisinesStr, dietStr, allergiesStr, language)
 
-	c.logger.Info("Requesting dinner suggestions based on %d ingredients and %d cuisines", len(ingredients), len(cuisines))
+	c.logger.Info("Requesting dinner suggestions based on %d ingredients and %d cuisines", len(ingredients), len(prefs.Cuisines))
 	c.logger.Debug("OpenAI prompt (first 100 chars): %s", truncateString(prompt, 100))
 
 	resp, err := c.client.CreateChatCompletion(
@@ -439,3 +631,22 @@ func extractIngredientsFromText(s string) []string {
 
 	return ingredients
 }
+
+// dedupeIngredients drops entries that name the same canonical ingredient as an earlier one
+// Entries with a quantity are kept, so "tomatoes (2 pcs)" and "tomatoes (500 g)" can add up
+func dedupeIngredients(list []string) []string {
+	seen := make(map[string]bool)
+	result := make([]string, 0, len(list))
+	for _, item := range list {
+		canonical := ingredients.Canonicalize(item)
+		if canonical == "" {
+			continue
+		}
+		if seen[canonical] && !strings.Contains(item, "(") {
+			continue
+		}
+		seen[canonical] = true
+		result = append(result, item)
+	}
+	return result
+}
@@ -0,0 +1,126 @@
+package planner
+
+import (
+	"strings"
+
+	"github.com/korjavin/whatsfordinner/pkg/dinner"
+	"github.com/korjavin/whatsfordinner/pkg/ingredients"
+	"github.com/korjavin/whatsfordinner/pkg/models"
+)
+
+// Weights for balancing a week on top of each dish's own ranking score
+const (
+	reuseWeight          = 0.3  // Bonus for missing ingredients that other days need to buy anyway
+	cuisineRepeatPenalty = 0.15 // Penalty per other day with the same cuisine
+	backToBackPenalty    = 0.5  // Extra penalty for the same cuisine on consecutive days
+)
+
+// pickWeek picks a dish for each of the given number of days from ranked candidates
+// Each day takes the candidate that scores best next to the days already picked,
+// so the week mixes cuisines and shares shopping between days
+// The candidates that weren't picked are returned as alternatives, best first
+func pickWeek(candidates []dinner.RankedDish, days int) ([]models.Dish, []models.Dish) {
+	remaining := uniqueDishes(candidates)
+	picked := make([]dinner.RankedDish, 0, days)
+
+	for len(picked) < days && len(remaining) > 0 {
+		best := 0
+		bestScore := weekScore(remaining[0], picked)
+		for i := 1; i < len(remaining); i++ {
+			if score := weekScore(remaining[i], picked); score > bestScore {
+				best, bestScore = i, score
+			}
+		}
+
+		picked = append(picked, remaining[best])
+		remaining = append(remaining[:best], remaining[best+1:]...)
+	}
+
+	return dishesOf(picked), dishesOf(remaining)
+}
+
+// weekScore scores a candidate as the next day after the days already picked
+func weekScore(candidate dinner.RankedDish, picked []dinner.RankedDish) float64 {
+	score := candidate.Score
+
+	// Shopping the other days need anyway
+	if len(candidate.Missing) > 0 {
+		needed := make(map[string]bool)
+		for _, day := range picked {
+			for _, name := range day.Missing {
+				needed[ingredients.Canonicalize(name)] = true
+			}
+		}
+
+		shared := 0
+		for _, name := range candidate.Missing {
+			if needed[ingredients.Canonicalize(name)] {
+				shared++
+			}
+		}
+		score += reuseWeight * float64(shared) / float64(len(candidate.Missing))
+	}
+
+	// Variety of cuisines
+	for i, day := range picked {
+		if !sameCuisine(day.Dish, candidate.Dish) {
+			continue
+		}
+		score -= cuisineRepeatPenalty
+		if i == len(picked)-1 {
+			score -= backToBackPenalty
+		}
+	}
+
+	return score
+}
+
+// pickAlternative returns the index of the alternative that best replaces the dish on a day of the plan,
+// preferring a cuisine the neighbouring days don't have, or -1 if there are no alternatives
+func pickAlternative(plan *models.WeekPlan, dayIndex int) int {
+	if len(plan.Alternatives) == 0 {
+		return -1
+	}
+
+	for i, alternative := range plan.Alternatives {
+		if dayIndex > 0 && sameCuisine(plan.Days[dayIndex-1].Dish, alternative) {
+			continue
+		}
+		if dayIndex < len(plan.Days)-1 && sameCuisine(plan.Days[dayIndex+1].Dish, alternative) {
+			continue
+		}
+		return i
+	}
+
+	return 0
+}
+
+// sameCuisine reports whether two dishes have the same, known cuisine
+func sameCuisine(a, b models.Dish) bool {
+	cuisine := strings.TrimSpace(a.Cuisine)
+	return cuisine != "" && strings.EqualFold(cuisine, strings.TrimSpace(b.Cuisine))
+}
+
+// uniqueDishes drops candidates with a name already seen, keeping the order
+func uniqueDishes(candidates []dinner.RankedDish) []dinner.RankedDish {
+	seen := make(map[string]bool)
+	result := make([]dinner.RankedDish, 0, len(candidates))
+	for _, candidate := range candidates {
+		name := strings.ToLower(strings.TrimSpace(candidate.Dish.Name))
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		result = append(result, candidate)
+	}
+	return result
+}
+
+// dishesOf returns the dishes of ranked candidates
+func dishesOf(ranked []dinner.RankedDish) []models.Dish {
+	dishes := make([]models.Dish, len(ranked))
+	for i, rankedDish := range ranked {
+		dishes[i] = rankedDish.Dish
+	}
+	return dishes
+}
@@ -0,0 +1,5 @@
+// Package planner provides the weekly meal planner.
+// It proposes a dinner for each day of the coming week, balancing cuisines and reusing ingredients across days,
+// lets the family approve or swap each day, and saves the plan together with one combined shopping list.
+// The scheduler announces each day's planned dinner instead of starting a poll.
+package planner
@@ -0,0 +1,291 @@
+package planner
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/korjavin/whatsfordinner/pkg/dinner"
+	"github.com/korjavin/whatsfordinner/pkg/fridge"
+	"github.com/korjavin/whatsfordinner/pkg/logger"
+	"github.com/korjavin/whatsfordinner/pkg/models"
+	"github.com/korjavin/whatsfordinner/pkg/openai"
+	"github.com/korjavin/whatsfordinner/pkg/shopping"
+	"github.com/korjavin/whatsfordinner/pkg/storage"
+)
+
+// DateLayout is how the days of a plan are stored
+const DateLayout = "2006-01-02"
+
+// spareSuggestions is how many dishes beyond one per day are requested, to have alternatives for swaps
+const spareSuggestions = 5
+
+// planPollPrefix starts the IDs of the votes the scheduler creates for planned dinners
+const planPollPrefix = "plan-"
+
+var (
+	// ErrNoPlan is returned when a channel has no week plan
+	ErrNoPlan = errors.New("no week plan")
+	// ErrDayNotPlanned is returned when a day isn't part of the week plan
+	ErrDayNotPlanned = errors.New("day is not in the week plan")
+	// ErrNoAlternatives is returned when there's nothing left to swap a dish with
+	ErrNoAlternatives = errors.New("no alternatives left")
+	// ErrNoDishes is returned when no dishes could be found to plan with
+	ErrNoDishes = errors.New("no dishes to plan with")
+)
+
+// Service provides weekly meal planning functionality
+type Service struct {
+	store           *storage.Store
+	fridgeService   *fridge.Service
+	dinnerService   *dinner.Service
+	shoppingService *shopping.Service
+	openaiClient    *openai.Client
+	logger          *logger.Logger
+}
+
+// New creates a new planner service
+func New(
+	store *storage.Store,
+	fridgeService *fridge.Service,
+	dinnerService *dinner.Service,
+	shoppingService *shopping.Service,
+	openaiClient *openai.Client,
+) *Service {
+	return &Service{
+		store:           store,
+		fridgeService:   fridgeService,
+		dinnerService:   dinnerService,
+		shoppingService: shoppingService,
+		openaiClient:    openaiClient,
+		logger:          logger.New("planner"),
+	}
+}
+
+// Propose plans a dinner for each of the given days and saves it as a draft, replacing any previous plan
+// Suggestions come from the LLM, falling back to the known dishes if it fails
+func (s *Service) Propose(channelID int64, dates []time.Time, prefs openai.Preferences) (*models.WeekPlan, error) {
+	if len(dates) == 0 {
+		return nil, fmt.Errorf("no days to plan")
+	}
+
+	items, err := s.fridgeService.ListIngredients(channelID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list ingredients: %w", err)
+	}
+
+	names := make([]string, len(items))
+	var useSoon []string
+	now := time.Now()
+	for i, ingredient := range items {
+		names[i] = ingredient.Name
+		if fridge.IsExpiringSoon(ingredient, now, fridge.UseSoonWindow) {
+			useSoon = append(useSoon, ingredient.Name)
+		}
+	}
+
+	var dishes []models.Dish
+	suggestions, err := s.openaiClient.SuggestDinnerOptions(names, useSoon, prefs, len(dates)+spareSuggestions)
+	if err != nil {
+		s.logger.Error("Failed to get suggestions for the week plan of channel %d: %v", channelID, err)
+	}
+	for _, suggestion := range suggestions {
+		dishes = append(dishes, dinner.DishFromInfo(suggestion))
+	}
+
+	if len(dishes) < len(dates) {
+		known, err := s.dinnerService.GetDishes(channelID)
+		if err != nil {
+			s.logger.Error("Failed to get known dishes: %v", err)
+		}
+		dishes = append(dishes, known...)
+	}
+
+	if len(dishes) == 0 {
+		return nil, ErrNoDishes
+	}
+
+	ranked, err := s.dinnerService.RankDishes(channelID, dishes, prefs.Cuisines)
+	if err != nil {
+		return nil, fmt.Errorf("failed to rank dishes: %w", err)
+	}
+
+	picked, alternatives := pickWeek(ranked, len(dates))
+
+	plan := &models.WeekPlan{
+		ID:           fmt.Sprintf("week_plan:%d", channelID),
+		ChannelID:    channelID,
+		Days:         make([]models.PlannedDay, len(picked)),
+		Alternatives: alternatives,
+		CreatedAt:    time.Now(),
+		LastUpdated:  time.Now(),
+	}
+	for i, dish := range picked {
+		plan.Days[i] = models.PlannedDay{
+			Date: dates[i].Format(DateLayout),
+			Dish: dish,
+		}
+	}
+
+	s.logger.Info("Proposed a %d-day plan for channel %d with %d alternatives", len(plan.Days), channelID, len(alternatives))
+	if err := s.store.Set(plan.ID, plan); err != nil {
+		return nil, fmt.Errorf("failed to save week plan: %w", err)
+	}
+
+	return plan, nil
+}
+
+// GetPlan returns a channel's week plan
+func (s *Service) GetPlan(channelID int64) (*models.WeekPlan, error) {
+	var plan models.WeekPlan
+	err := s.store.Get(fmt.Sprintf("week_plan:%d", channelID), &plan)
+	if err != nil {
+		return nil, ErrNoPlan
+	}
+	return &plan, nil
+}
+
+// updateDay applies a change to one day of a channel's week plan and saves it
+func (s *Service) updateDay(channelID int64, date string, apply func(plan *models.WeekPlan, index int) error) (*models.WeekPlan, error) {
+	plan, err := s.GetPlan(channelID)
+	if err != nil {
+		return nil, err
+	}
+
+	index := findDay(plan, date)
+	if index < 0 {
+		return nil, ErrDayNotPlanned
+	}
+
+	if err := apply(plan, index); err != nil {
+		return nil, err
+	}
+
+	plan.LastUpdated = time.Now()
+	if err := s.store.Set(plan.ID, plan); err != nil {
+		return nil, fmt.Errorf("failed to save week plan: %w", err)
+	}
+
+	return plan, nil
+}
+
+// Approve records a family member's vote to keep a day's dish; voting again takes the vote back
+func (s *Service) Approve(channelID int64, date, userID string) (*models.WeekPlan, error) {
+	return s.updateDay(channelID, date, func(plan *models.WeekPlan, index int) error {
+		day := &plan.Days[index]
+		for i, approval := range day.Approvals {
+			if approval == userID {
+				day.Approvals = append(day.Approvals[:i], day.Approvals[i+1:]...)
+				return nil
+			}
+		}
+		day.Approvals = append(day.Approvals, userID)
+		return nil
+	})
+}
+
+// Swap replaces a day's dish with the best alternative; the old dish goes to the end of the alternatives
+func (s *Service) Swap(channelID int64, date string) (*models.WeekPlan, error) {
+	return s.updateDay(channelID, date, func(plan *models.WeekPlan, index int) error {
+		day := &plan.Days[index]
+		if day.Announced {
+			return fmt.Errorf("%s was already announced", day.Dish.Name)
+		}
+
+		alternative := pickAlternative(plan, index)
+		if alternative < 0 {
+			return ErrNoAlternatives
+		}
+
+		s.logger.Info("Swapping %s for %s on %s in channel %d", day.Dish.Name, plan.Alternatives[alternative].Name, date, channelID)
+		old := day.Dish
+		day.Dish = plan.Alternatives[alternative]
+		day.Approvals = nil
+		plan.Alternatives = append(plan.Alternatives[:alternative], plan.Alternatives[alternative+1:]...)
+		plan.Alternatives = append(plan.Alternatives, old)
+		return nil
+	})
+}
+
+// Confirm saves the plan and puts everything the fridge is missing for the coming days on the shopping list
+// Returns the number of ingredients added
+func (s *Service) Confirm(channelID int64) (*models.WeekPlan, int, error) {
+	plan, err := s.GetPlan(channelID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	missingCount := 0
+	for _, day := range plan.Days {
+		if day.Announced {
+			continue
+		}
+		// Shop for as many people as the family usually cooks for
+		missing, err := s.shoppingService.PlanForDish(channelID, s.dinnerService.ScaleForChannel(channelID, day.Dish))
+		if err != nil {
+			s.logger.Error("Failed to plan shopping for %s: %v", day.Dish.Name, err)
+			continue
+		}
+		missingCount += len(missing)
+	}
+
+	plan.Confirmed = true
+	plan.LastUpdated = time.Now()
+	s.logger.Info("Confirmed the week plan of channel %d", channelID)
+	if err := s.store.Set(plan.ID, plan); err != nil {
+		return nil, 0, fmt.Errorf("failed to save week plan: %w", err)
+	}
+
+	return plan, missingCount, nil
+}
+
+// Discard deletes a channel's week plan; the scheduler goes back to daily polls
+func (s *Service) Discard(channelID int64) error {
+	s.logger.Info("Discarding the week plan of channel %d", channelID)
+	return s.store.Delete(fmt.Sprintf("week_plan:%d", channelID))
+}
+
+// PlannedFor returns the confirmed dinner planned for a day that hasn't been announced yet, or nil
+func (s *Service) PlannedFor(channelID int64, date time.Time) *models.PlannedDay {
+	plan, err := s.GetPlan(channelID)
+	if err != nil || !plan.Confirmed {
+		return nil
+	}
+
+	index := findDay(plan, date.Format(DateLayout))
+	if index < 0 || plan.Days[index].Announced {
+		return nil
+	}
+
+	return &plan.Days[index]
+}
+
+// MarkAnnounced records that a day's planned dinner has been announced
+func (s *Service) MarkAnnounced(channelID int64, date string) error {
+	_, err := s.updateDay(channelID, date, func(plan *models.WeekPlan, index int) error {
+		plan.Days[index].Announced = true
+		return nil
+	})
+	return err
+}
+
+// PollID returns the ID of the vote that stands in for the poll on a planned day
+func PollID(channelID int64, date string) string {
+	return fmt.Sprintf("%s%d-%s", planPollPrefix, channelID, date)
+}
+
+// IsPlanPoll reports whether a vote stands in for the poll on a planned day
+func IsPlanPoll(pollID string) bool {
+	return strings.HasPrefix(pollID, planPollPrefix)
+}
+
+// findDay returns the index of a date in the plan, or -1
+func findDay(plan *models.WeekPlan, date string) int {
+	for i, day := range plan.Days {
+		if day.Date == date {
+			return i
+		}
+	}
+	return -1
+}
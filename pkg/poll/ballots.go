@@ -0,0 +1,194 @@
+package poll
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/korjavin/whatsfordinner/pkg/models"
+)
+
+var (
+	// ErrVoteEnded is returned for ballots cast after a vote has closed
+	ErrVoteEnded = errors.New("vote has already ended")
+	// ErrEmptyRanking is returned when a member finishes a ranking without any dish in it
+	ErrEmptyRanking = errors.New("ranking is empty")
+)
+
+// RecordApproval records the dishes a member approves of in an approval vote; none withdraws their ballot
+func (s *Service) RecordApproval(channelID int64, pollID, userID string, options []string) error {
+	vote, err := s.openVote(channelID, pollID)
+	if err != nil {
+		return err
+	}
+
+	for _, option := range options {
+		if !contains(vote.Options, option) {
+			return fmt.Errorf("invalid option: %s", option)
+		}
+	}
+
+	if vote.Approvals == nil {
+		vote.Approvals = make(map[string][]string)
+	}
+	if len(options) == 0 {
+		delete(vote.Approvals, userID)
+	} else {
+		vote.Approvals[userID] = options
+	}
+
+	return s.store.Set(fmt.Sprintf("vote:%d:%s", channelID, pollID), vote)
+}
+
+// RecordRanking puts a dish next in a member's ranking and returns the ranking so far
+// Ranking all dishes but one puts the last one at the end and finishes the ranking; done reports whether it's finished
+// Rankings can't be changed once finished, only started over
+func (s *Service) RecordRanking(channelID int64, pollID, userID, option string) (ranking []string, done bool, err error) {
+	vote, err := s.openVote(channelID, pollID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if !contains(vote.Options, option) {
+		return nil, false, fmt.Errorf("invalid option: %s", option)
+	}
+
+	if vote.Rankings == nil {
+		vote.Rankings = make(map[string][]string)
+	}
+	if vote.RankingsDone == nil {
+		vote.RankingsDone = make(map[string]bool)
+	}
+
+	ranking = vote.Rankings[userID]
+	if vote.RankingsDone[userID] || contains(ranking, option) {
+		return ranking, vote.RankingsDone[userID], nil
+	}
+
+	ranking = append(ranking, option)
+	if len(ranking) == len(vote.Options)-1 {
+		for _, last := range vote.Options {
+			if !contains(ranking, last) {
+				ranking = append(ranking, last)
+			}
+		}
+	}
+	if len(ranking) == len(vote.Options) {
+		vote.RankingsDone[userID] = true
+	}
+	vote.Rankings[userID] = ranking
+
+	if err := s.store.Set(fmt.Sprintf("vote:%d:%s", channelID, pollID), vote); err != nil {
+		return nil, false, err
+	}
+	return ranking, vote.RankingsDone[userID], nil
+}
+
+// FinishRanking finishes a member's ranking without ranking every dish
+func (s *Service) FinishRanking(channelID int64, pollID, userID string) ([]string, error) {
+	vote, err := s.openVote(channelID, pollID)
+	if err != nil {
+		return nil, err
+	}
+
+	ranking := vote.Rankings[userID]
+	if len(ranking) == 0 {
+		return nil, ErrEmptyRanking
+	}
+
+	if vote.RankingsDone == nil {
+		vote.RankingsDone = make(map[string]bool)
+	}
+	vote.RankingsDone[userID] = true
+
+	if err := s.store.Set(fmt.Sprintf("vote:%d:%s", channelID, pollID), vote); err != nil {
+		return nil, err
+	}
+	return ranking, nil
+}
+
+// ResetRanking throws away a member's ranking, so they can rank the dishes again
+func (s *Service) ResetRanking(channelID int64, pollID, userID string) error {
+	vote, err := s.openVote(channelID, pollID)
+	if err != nil {
+		return err
+	}
+
+	delete(vote.Rankings, userID)
+	delete(vote.RankingsDone, userID)
+
+	return s.store.Set(fmt.Sprintf("vote:%d:%s", channelID, pollID), vote)
+}
+
+// openVote returns a vote that still takes ballots
+func (s *Service) openVote(channelID int64, pollID string) (*models.VoteState, error) {
+	vote, err := s.GetVote(channelID, pollID)
+	if err != nil {
+		return nil, err
+	}
+	if !vote.EndedAt.IsZero() {
+		return nil, ErrVoteEnded
+	}
+	return vote, nil
+}
+
+// IsSupporter reports whether a member's ballot counted for the winning dish, so they may volunteer to cook it
+// When nobody voted, as for a dinner from the week plan, everyone may
+func IsSupporter(vote *models.VoteState, userID string) bool {
+	if len(vote.Supporters) > 0 {
+		return contains(vote.Supporters, userID)
+	}
+	if Voters(vote) == 0 {
+		return true
+	}
+
+	switch ModeOf(vote) {
+	case ModeApproval:
+		return contains(vote.Approvals[userID], vote.WinningDish)
+	case ModeRanked:
+		return contains(vote.Rankings[userID], vote.WinningDish)
+	default:
+		return vote.Votes[userID] == vote.WinningDish
+	}
+}
+
+// CarryOverBallots copies the ballots of a replaced vote to the vote replacing it, for the dishes still in it
+func (s *Service) CarryOverBallots(channelID int64, from, to *models.VoteState) error {
+	for userID, option := range from.Votes {
+		if contains(to.Options, option) {
+			to.Votes[userID] = option
+		}
+	}
+
+	for userID, approved := range from.Approvals {
+		if kept := keepOptions(approved, to.Options); len(kept) > 0 {
+			if to.Approvals == nil {
+				to.Approvals = make(map[string][]string)
+			}
+			to.Approvals[userID] = kept
+		}
+	}
+
+	for userID, ranking := range from.Rankings {
+		if kept := keepOptions(ranking, to.Options); len(kept) > 0 {
+			if to.Rankings == nil {
+				to.Rankings = make(map[string][]string)
+				to.RankingsDone = make(map[string]bool)
+			}
+			to.Rankings[userID] = kept
+			to.RankingsDone[userID] = from.RankingsDone[userID]
+		}
+	}
+
+	return s.store.Set(fmt.Sprintf("vote:%d:%s", channelID, to.PollID), to)
+}
+
+// keepOptions returns the dishes of a ballot that are among the options, in ballot order
+func keepOptions(ballot, options []string) []string {
+	var kept []string
+	for _, option := range ballot {
+		if contains(options, option) {
+			kept = append(kept, option)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,180 @@
+package poll
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/korjavin/whatsfordinner/pkg/models"
+)
+
+// Outcomes of asking for a cook
+const (
+	OutcomeCooking  = "cooking"   // A cook was picked and the dinner started
+	OutcomeNoDinner = "no_dinner" // Nobody cooked, or the family called dinner off
+)
+
+var (
+	// ErrCookDecided is returned for volunteer buttons once the cook is picked or dinner is called off
+	ErrCookDecided = errors.New("the cook has already been decided")
+	// ErrNotWinningVoter is returned when someone who didn't vote for the winning dish volunteers
+	ErrNotWinningVoter = errors.New("user did not vote for the winning dish")
+)
+
+// RequestCook records that the family was asked who cooks the winning dish, in the given message
+// The vote becomes the channel's current vote again, so the scheduler can follow up; asking again counts as a retry
+func (s *Service) RequestCook(channelID int64, pollID string, messageID int) (*models.VoteState, error) {
+	voteKey := fmt.Sprintf("vote:%d:%s", channelID, pollID)
+	var vote models.VoteState
+	if err := s.store.Get(voteKey, &vote); err != nil {
+		return nil, err
+	}
+
+	if vote.Outcome != "" {
+		return nil, ErrCookDecided
+	}
+
+	if !vote.CookRequestedAt.IsZero() {
+		vote.CookRetries++
+	}
+	vote.CookRequestedAt = time.Now()
+	vote.CookRequestMessageID = messageID
+
+	if err := s.store.Set(voteKey, vote); err != nil {
+		return nil, err
+	}
+
+	channelKey := fmt.Sprintf("channel:%d", channelID)
+	var channelState models.ChannelState
+	if err := s.store.Get(channelKey, &channelState); err != nil {
+		return nil, fmt.Errorf("failed to get channel state: %w", err)
+	}
+
+	channelState.CurrentVote = &vote
+	channelState.LastActivity = time.Now()
+	if err := s.store.Set(channelKey, channelState); err != nil {
+		return nil, err
+	}
+
+	return &vote, nil
+}
+
+// AssignCook makes a family member the cook without them volunteering, e.g. by rotation when nobody volunteers
+func (s *Service) AssignCook(channelID int64, pollID, userID, username string) (*models.VoteState, error) {
+	voteKey := fmt.Sprintf("vote:%d:%s", channelID, pollID)
+	var vote models.VoteState
+	if err := s.store.Get(voteKey, &vote); err != nil {
+		return nil, err
+	}
+
+	if vote.Outcome != "" {
+		return nil, ErrCookDecided
+	}
+
+	if vote.VolunteerNames == nil {
+		vote.VolunteerNames = make(map[string]string)
+	}
+	vote.VolunteerNames[userID] = username
+	vote.SelectedCook = userID
+	vote.Outcome = OutcomeCooking
+	vote.DecidedAt = time.Now()
+
+	if err := s.saveVote(channelID, &vote); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Assigned %s to cook vote %s in channel %d", username, pollID, channelID)
+	return &vote, nil
+}
+
+// CancelDinner records that there's no dinner from this vote today
+func (s *Service) CancelDinner(channelID int64, pollID string) (*models.VoteState, error) {
+	voteKey := fmt.Sprintf("vote:%d:%s", channelID, pollID)
+	var vote models.VoteState
+	if err := s.store.Get(voteKey, &vote); err != nil {
+		return nil, err
+	}
+
+	if vote.Outcome != "" {
+		return nil, ErrCookDecided
+	}
+
+	vote.Outcome = OutcomeNoDinner
+	vote.DecidedAt = time.Now()
+
+	if err := s.saveVote(channelID, &vote); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("No dinner from vote %s in channel %d today", pollID, channelID)
+	return &vote, nil
+}
+
+// saveVote saves a vote and keeps the channel's copy of it up to date
+// A decided vote stops being the current one; the dinner takes over from there
+func (s *Service) saveVote(channelID int64, vote *models.VoteState) error {
+	voteKey := fmt.Sprintf("vote:%d:%s", channelID, vote.PollID)
+	if err := s.store.Set(voteKey, vote); err != nil {
+		return err
+	}
+
+	channelKey := fmt.Sprintf("channel:%d", channelID)
+	var channelState models.ChannelState
+	if err := s.store.Get(channelKey, &channelState); err != nil {
+		return nil // Nothing to keep up to date
+	}
+
+	if channelState.CurrentVote == nil || channelState.CurrentVote.PollID != vote.PollID {
+		return nil
+	}
+
+	if vote.Outcome != "" {
+		channelState.CurrentVote = nil
+	} else {
+		channelState.CurrentVote = vote
+	}
+	channelState.LastActivity = time.Now()
+	return s.store.Set(channelKey, channelState)
+}
+
+// PickCook picks the cook among the volunteers
+// Picked at random, every volunteer has the same chance; picked fairly, a volunteer who cooked n dinners lately
+// is weighted 1/(n+1), so whoever cooked least is the most likely to cook tonight
+// roll is a random number in [0, 1), e.g. from rand.Float64
+func PickCook(volunteers []string, recentCooks map[string]int, fair bool, roll float64) string {
+	if len(volunteers) == 0 {
+		return ""
+	}
+
+	weights := make([]float64, len(volunteers))
+	var total float64
+	for i, volunteer := range volunteers {
+		weights[i] = 1
+		if fair {
+			weights[i] = 1 / float64(recentCooks[volunteer]+1)
+		}
+		total += weights[i]
+	}
+
+	target := roll * total
+	for i, weight := range weights {
+		if target < weight {
+			return volunteers[i]
+		}
+		target -= weight
+	}
+	return volunteers[len(volunteers)-1]
+}
+
+// VolunteerNames returns the usernames of a vote's volunteers in the order they volunteered
+func VolunteerNames(vote *models.VoteState) []string {
+	names := make([]string, 0, len(vote.CookVolunteers))
+	for _, volunteer := range vote.CookVolunteers {
+		if name := vote.VolunteerNames[volunteer]; name != "" {
+			names = append(names, name)
+		} else {
+			names = append(names, volunteer)
+		}
+	}
+	return names
+}
@@ -1,3 +1,7 @@
 // Package poll provides functionality for managing polls and votes.
 // It handles creating polls, collecting votes, and determining winners.
+// Votes are single choice, approval or ranked choice; ranked votes are decided by instant runoff,
+// and the result records the rounds and tie-breaks so the bot can explain how the winner was picked.
+// Once a dish wins, it tracks asking the family for a cook: the volunteers, picking one of them
+// at random or fairly by who cooked least lately, asking again, and calling dinner off.
 package poll
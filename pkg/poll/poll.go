@@ -24,12 +24,13 @@ func New(store *storage.Store) *Service {
 	}
 }
 
-// CreateVote creates a new vote
-func (s *Service) CreateVote(channelID int64, pollID string, messageID int, options []string) (*models.VoteState, error) {
+// CreateVote creates a new vote in one of the voting modes
+func (s *Service) CreateVote(channelID int64, pollID string, messageID int, options []string, mode string) (*models.VoteState, error) {
 	vote := &models.VoteState{
 		PollID:    pollID,
 		MessageID: messageID,
 		Options:   options,
+		Mode:      mode,
 		Votes:     make(map[string]string),
 		StartedAt: time.Now(),
 	}
@@ -102,37 +103,27 @@ func (s *Service) RecordVote(channelID int64, pollID, userID, option string) err
 
 // GetVoteResults returns the results of a vote
 func (s *Service) GetVoteResults(channelID int64, pollID string) (map[string]int, string, error) {
-	voteKey := fmt.Sprintf("vote:%d:%s", channelID, pollID)
-	var vote models.VoteState
-	err := s.store.Get(voteKey, &vote)
+	result, err := s.GetResult(channelID, pollID)
 	if err != nil {
 		return nil, "", err
 	}
 
-	// Count votes for each option
-	results := make(map[string]int)
-	for _, option := range vote.Options {
-		results[option] = 0
-	}
-
-	for _, option := range vote.Votes {
-		results[option]++
-	}
+	return result.Counts, result.Winner, nil
+}
 
-	// Find the winning option
-	var winningOption string
-	var maxVotes int
-	for option, count := range results {
-		if count > maxVotes {
-			maxVotes = count
-			winningOption = option
-		}
+// GetResult tallies a vote in its voting mode
+func (s *Service) GetResult(channelID int64, pollID string) (Result, error) {
+	voteKey := fmt.Sprintf("vote:%d:%s", channelID, pollID)
+	var vote models.VoteState
+	err := s.store.Get(voteKey, &vote)
+	if err != nil {
+		return Result{}, err
 	}
 
-	return results, winningOption, nil
+	return Tally(&vote), nil
 }
 
-// EndVote marks a vote as ended and records the winning dish
+// EndVote marks a vote as ended and records the winning dish with the voters who counted for it
 func (s *Service) EndVote(channelID int64, pollID, winningDish string) error {
 	voteKey := fmt.Sprintf("vote:%d:%s", channelID, pollID)
 	var vote models.VoteState
@@ -143,6 +134,9 @@ func (s *Service) EndVote(channelID int64, pollID, winningDish string) error {
 
 	vote.EndedAt = time.Now()
 	vote.WinningDish = winningDish
+	if result := Tally(&vote); result.Winner == winningDish {
+		vote.Supporters = result.Supporters
+	}
 
 	err = s.store.Set(voteKey, vote)
 	if err != nil {
@@ -171,32 +165,46 @@ func (s *Service) EndVote(channelID int64, pollID, winningDish string) error {
 }
 
 // AddCookVolunteer adds a cook volunteer to a vote
-func (s *Service) AddCookVolunteer(channelID int64, pollID, userID string) error {
+// Only those who voted for the winning dish may volunteer, and only until the cook is decided
+func (s *Service) AddCookVolunteer(channelID int64, pollID, userID, username string) (*models.VoteState, error) {
 	voteKey := fmt.Sprintf("vote:%d:%s", channelID, pollID)
 	var vote models.VoteState
 	err := s.store.Get(voteKey, &vote)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	// Check if the user voted for the winning dish
-	if vote.Votes[userID] != vote.WinningDish && len(vote.Votes) > 0 {
-		return fmt.Errorf("user did not vote for the winning dish")
+	if vote.Outcome != "" {
+		return nil, ErrCookDecided
+	}
+
+	if !IsSupporter(&vote, userID) {
+		return nil, ErrNotWinningVoter
 	}
 
 	// Add the volunteer if not already added
 	for _, volunteer := range vote.CookVolunteers {
 		if volunteer == userID {
-			return nil // Already volunteered
+			return &vote, nil // Already volunteered
 		}
 	}
 
 	vote.CookVolunteers = append(vote.CookVolunteers, userID)
+	if vote.VolunteerNames == nil {
+		vote.VolunteerNames = make(map[string]string)
+	}
+	vote.VolunteerNames[userID] = username
+	if vote.FirstVolunteerAt.IsZero() {
+		vote.FirstVolunteerAt = time.Now()
+	}
 
-	return s.store.Set(voteKey, vote)
+	if err := s.saveVote(channelID, &vote); err != nil {
+		return nil, err
+	}
+	return &vote, nil
 }
 
-// SelectCook selects a cook from the volunteers
+// SelectCook selects a cook from the volunteers, which decides who cooks the winning dish
 func (s *Service) SelectCook(channelID int64, pollID, userID string) error {
 	voteKey := fmt.Sprintf("vote:%d:%s", channelID, pollID)
 	var vote models.VoteState
@@ -205,6 +213,10 @@ func (s *Service) SelectCook(channelID int64, pollID, userID string) error {
 		return err
 	}
 
+	if vote.Outcome != "" {
+		return ErrCookDecided
+	}
+
 	// Check if the user is a volunteer
 	isVolunteer := false
 	for _, volunteer := range vote.CookVolunteers {
@@ -219,13 +231,16 @@ func (s *Service) SelectCook(channelID int64, pollID, userID string) error {
 	}
 
 	vote.SelectedCook = userID
+	vote.Outcome = OutcomeCooking
+	vote.DecidedAt = time.Now()
 
-	return s.store.Set(voteKey, vote)
+	return s.saveVote(channelID, &vote)
 }
 
-// CheckVoteThreshold checks if the vote has reached the threshold to be closed
-// Returns true if the threshold is reached, the winning option, and an error if any
-func (s *Service) CheckVoteThreshold(channelID int64, pollID string, channelMemberCount int, thresholdPercent float64) (bool, string, error) {
+// CheckVoteThreshold checks if the vote has reached the quorum to be closed
+// The quorum is taken of the members eligible to vote, those eating at home, with at least minVotes votes
+// Returns true if the quorum is reached, the winning option, and an error if any
+func (s *Service) CheckVoteThreshold(channelID int64, pollID string, eligible int, thresholdPercent float64, minVotes int) (bool, string, error) {
 	voteKey := fmt.Sprintf("vote:%d:%s", channelID, pollID)
 	var vote models.VoteState
 	err := s.store.Get(voteKey, &vote)
@@ -239,22 +254,16 @@ func (s *Service) CheckVoteThreshold(channelID int64, pollID string, channelMemb
 	}
 
 	// Calculate the threshold
-	threshold := int(math.Ceil(float64(channelMemberCount) * thresholdPercent))
-	s.logger.Debug("Threshold: %d (channel members: %d, threshold percent: %.2f)", threshold, channelMemberCount, thresholdPercent)
+	threshold := Quorum(eligible, thresholdPercent, minVotes)
+	s.logger.Debug("Threshold: %d (eligible members: %d, threshold percent: %.2f, minimum votes: %d)", threshold, eligible, thresholdPercent, minVotes)
 
 	// Count the total votes
-	totalVotes := len(vote.Votes)
+	totalVotes := Voters(&vote)
 	s.logger.Debug("Total votes: %d", totalVotes)
 
 	// Check if the threshold is reached
 	if totalVotes >= threshold {
-		// Get the results
-		_, winningOption, err := s.GetVoteResults(channelID, pollID)
-		if err != nil {
-			return false, "", err
-		}
-
-		return true, winningOption, nil
+		return true, Tally(&vote).Winner, nil
 	}
 
 	return false, "", nil
@@ -272,6 +281,27 @@ func (s *Service) GetVote(channelID int64, pollID string) (*models.VoteState, er
 	return &vote, nil
 }
 
+// MappedChannel returns the channel of a poll from the poll's mapping alone, without searching the channels
+// for polls that have none; cheap enough to look up for every poll answer
+func (s *Service) MappedChannel(pollID string) (int64, error) {
+	var channelID int64
+	if err := s.store.Get(fmt.Sprintf("poll_mapping:%s", pollID), &channelID); err != nil {
+		return 0, fmt.Errorf("no mapping for poll %s: %w", pollID, err)
+	}
+	return channelID, nil
+}
+
+// MapPoll maps a poll to its channel unless it already is, e.g. for a poll still running after a restart
+func (s *Service) MapPoll(channelID int64, pollID string) error {
+	if _, err := s.MappedChannel(pollID); err == nil {
+		return nil
+	}
+	if err := s.store.Set(fmt.Sprintf("poll_mapping:%s", pollID), channelID); err != nil {
+		return fmt.Errorf("failed to map poll %s: %w", pollID, err)
+	}
+	return nil
+}
+
 // FindChannelByPollID finds the channel ID that contains a poll with the given ID
 func (s *Service) FindChannelByPollID(pollID string) (int64, error) {
 	// Create a direct mapping for poll ID to channel ID
@@ -377,3 +407,19 @@ func (s *Service) AddOptionToVote(channelID int64, pollID string, newOption stri
 
 	return &vote, nil
 }
+
+// Quorum returns how many votes close a poll: the threshold share of the eligible members, but at least minVotes
+// The minimum is capped at the eligible members, so a family with fewer people at home can still close the poll
+func Quorum(eligible int, thresholdPercent float64, minVotes int) int {
+	quorum := int(math.Ceil(float64(eligible) * thresholdPercent))
+	if minVotes > eligible {
+		minVotes = eligible
+	}
+	if quorum < minVotes {
+		quorum = minVotes
+	}
+	if quorum < 1 {
+		quorum = 1
+	}
+	return quorum
+}
@@ -0,0 +1,335 @@
+package poll
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/korjavin/whatsfordinner/pkg/models"
+)
+
+// Voting modes
+const (
+	ModePlurality = "plurality" // One dish each, the most votes win
+	ModeApproval  = "approval"  // Any number of dishes each, the most approvals win
+	ModeRanked    = "ranked"    // Dishes in order of preference, decided by instant runoff
+)
+
+// Round is one round of an instant runoff
+type Round struct {
+	Counts     map[string]int // Ballots for each dish still in the running
+	Eliminated []string       // Dishes out after this round, empty in the last round
+	TieBreak   string         // How a tie for last place was broken, empty if there was none
+}
+
+// Result is the outcome of a vote with how the winner was decided
+type Result struct {
+	Mode       string
+	Options    []string
+	Winner     string         // Empty if nobody voted
+	Voters     int            // Ballots with at least one dish; for ranked votes, finished rankings
+	Counts     map[string]int // Votes or approvals of each dish; for ranked votes, the last round
+	Rounds     []Round        // Rounds of an instant runoff
+	TieBreak   string         // How a tie for first place was broken, empty if there was none
+	Supporters []string       // UserIDs whose ballots counted for the winner, sorted
+}
+
+// ModeOf returns the voting mode of a vote; votes from before modes existed are plurality votes
+func ModeOf(vote *models.VoteState) string {
+	if vote.Mode == ModeApproval || vote.Mode == ModeRanked {
+		return vote.Mode
+	}
+	return ModePlurality
+}
+
+// Voters returns how many members have cast their ballot; a ranking counts once it's finished
+func Voters(vote *models.VoteState) int {
+	switch ModeOf(vote) {
+	case ModeApproval:
+		return len(vote.Approvals)
+	case ModeRanked:
+		return len(finishedRankings(vote))
+	default:
+		return len(vote.Votes)
+	}
+}
+
+// Tally counts a vote and decides the winner
+// Ties go to the dish listed first in the poll, the better match among the suggestions;
+// in an instant runoff, a tie for last place knocks out the dish that did worse in the rounds before, then the one listed last
+func Tally(vote *models.VoteState) Result {
+	result := Result{
+		Mode:    ModeOf(vote),
+		Options: vote.Options,
+		Counts:  make(map[string]int),
+	}
+	for _, option := range vote.Options {
+		result.Counts[option] = 0
+	}
+
+	switch result.Mode {
+	case ModeApproval:
+		for _, approved := range vote.Approvals {
+			for _, option := range approved {
+				if _, ok := result.Counts[option]; ok {
+					result.Counts[option]++
+				}
+			}
+		}
+		result.Voters = len(vote.Approvals)
+		result.Winner, result.TieBreak = mostVotes(result.Counts, vote.Options)
+		for userID, approved := range vote.Approvals {
+			if contains(approved, result.Winner) {
+				result.Supporters = append(result.Supporters, userID)
+			}
+		}
+	case ModeRanked:
+		tallyRanked(vote, &result)
+	default:
+		for _, option := range vote.Votes {
+			if _, ok := result.Counts[option]; ok {
+				result.Counts[option]++
+			}
+		}
+		result.Voters = len(vote.Votes)
+		result.Winner, result.TieBreak = mostVotes(result.Counts, vote.Options)
+		for userID, option := range vote.Votes {
+			if option == result.Winner {
+				result.Supporters = append(result.Supporters, userID)
+			}
+		}
+	}
+
+	if result.Voters == 0 {
+		result.Winner = ""
+		result.TieBreak = ""
+		result.Supporters = nil
+	}
+	sort.Strings(result.Supporters)
+	return result
+}
+
+// tallyRanked runs an instant runoff: the dish with the fewest first choices is knocked out and its ballots
+// go to their next choice, until a dish has a majority of the ballots still counting
+// Only finished rankings count, the same ballots the quorum counts
+func tallyRanked(vote *models.VoteState, result *Result) {
+	remaining := make(map[string]bool)
+	for _, option := range vote.Options {
+		remaining[option] = true
+	}
+
+	rankings := finishedRankings(vote)
+	result.Voters = len(rankings)
+	if result.Voters == 0 {
+		return
+	}
+
+	for {
+		counts := make(map[string]int)
+		for option := range remaining {
+			counts[option] = 0
+		}
+		active := 0
+		for _, ranking := range rankings {
+			if choice := topChoice(ranking, remaining); choice != "" {
+				counts[choice]++
+				active++
+			}
+		}
+		round := Round{Counts: counts}
+		result.Counts = counts
+
+		leader, tieBreak := mostVotes(counts, vote.Options)
+		if len(remaining) == 1 || counts[leader]*2 > active || active == 0 {
+			result.Winner = leader
+			result.TieBreak = tieBreak
+			result.Rounds = append(result.Rounds, round)
+			break
+		}
+
+		// Knock out every dish nobody ranked first at once, unless that would knock out all of them
+		var zero []string
+		for _, option := range vote.Options {
+			if remaining[option] && counts[option] == 0 {
+				zero = append(zero, option)
+			}
+		}
+		if len(zero) > 0 && len(zero) < len(remaining) {
+			round.Eliminated = zero
+		} else {
+			loser, tieBreak := fewestVotes(counts, vote.Options, result.Rounds)
+			round.Eliminated = []string{loser}
+			round.TieBreak = tieBreak
+		}
+		for _, option := range round.Eliminated {
+			delete(remaining, option)
+		}
+		result.Rounds = append(result.Rounds, round)
+	}
+
+	for userID, ranking := range rankings {
+		if topChoice(ranking, remaining) == result.Winner {
+			result.Supporters = append(result.Supporters, userID)
+		}
+	}
+}
+
+// finishedRankings returns the rankings members finished, by UserID; rankings still being made aren't ballots yet
+func finishedRankings(vote *models.VoteState) map[string][]string {
+	rankings := make(map[string][]string)
+	for userID, ranking := range vote.Rankings {
+		if vote.RankingsDone[userID] && len(ranking) > 0 {
+			rankings[userID] = ranking
+		}
+	}
+	return rankings
+}
+
+// topChoice returns the most preferred dish of a ranking that's still in the running, or "" if none is
+func topChoice(ranking []string, remaining map[string]bool) string {
+	for _, option := range ranking {
+		if remaining[option] {
+			return option
+		}
+	}
+	return ""
+}
+
+// mostVotes returns the dish with the most votes; ties go to the dish listed first
+func mostVotes(counts map[string]int, options []string) (string, string) {
+	winner := ""
+	var tied []string
+	for _, option := range options {
+		count, ok := counts[option]
+		if !ok {
+			continue
+		}
+		switch {
+		case winner == "" || count > counts[winner]:
+			winner = option
+			tied = []string{option}
+		case count == counts[winner]:
+			tied = append(tied, option)
+		}
+	}
+
+	if len(tied) < 2 {
+		return winner, ""
+	}
+	return winner, fmt.Sprintf("%s tied with %s; %s was listed first in the poll.", strings.Join(tied, ", "), plural(counts[winner], "vote"), winner)
+}
+
+// fewestVotes returns the dish to knock out of an instant runoff: the one with the fewest votes
+// Ties go against the dish with fewer votes in the rounds before, most recent first, then the one listed last
+func fewestVotes(counts map[string]int, options []string, rounds []Round) (string, string) {
+	var tied []string
+	for _, option := range options {
+		count, ok := counts[option]
+		if !ok {
+			continue
+		}
+		switch {
+		case len(tied) == 0 || count < counts[tied[0]]:
+			tied = []string{option}
+		case count == counts[tied[0]]:
+			tied = append(tied, option)
+		}
+	}
+	if len(tied) == 1 {
+		return tied[0], ""
+	}
+
+	for i := len(rounds) - 1; i >= 0; i-- {
+		var fewest []string
+		for _, option := range tied {
+			switch {
+			case len(fewest) == 0 || rounds[i].Counts[option] < rounds[i].Counts[fewest[0]]:
+				fewest = []string{option}
+			case rounds[i].Counts[option] == rounds[i].Counts[fewest[0]]:
+				fewest = append(fewest, option)
+			}
+		}
+		if len(fewest) == 1 {
+			return fewest[0], fmt.Sprintf("%s tied for last; %s had fewer votes in round %d.", strings.Join(tied, ", "), fewest[0], i+1)
+		}
+		tied = fewest
+	}
+
+	loser := tied[len(tied)-1]
+	return loser, fmt.Sprintf("%s tied for last; %s was listed last in the poll.", strings.Join(tied, ", "), loser)
+}
+
+// Explain describes how the winner of a vote was decided, for the results message
+func (r Result) Explain() string {
+	if r.Winner == "" {
+		return "😢 Nobody voted."
+	}
+
+	var text string
+	switch r.Mode {
+	case ModeApproval:
+		text = fmt.Sprintf("🏆 *%s* won, approved by %d of %s.\n", r.Winner, r.Counts[r.Winner], plural(r.Voters, "voter"))
+		text += fmt.Sprintf("Approvals: %s\n", r.formatCounts(r.Counts))
+	case ModeRanked:
+		if len(r.Rounds) == 1 {
+			text = fmt.Sprintf("🏆 *%s* won with a majority of first choices.\n", r.Winner)
+		} else {
+			text = fmt.Sprintf("🏆 *%s* won by instant runoff after %d rounds.\n", r.Winner, len(r.Rounds))
+		}
+		for i, round := range r.Rounds {
+			text += fmt.Sprintf("Round %d: %s", i+1, r.formatCounts(round.Counts))
+			if len(round.Eliminated) > 0 {
+				text += fmt.Sprintf(" → %s out", strings.Join(round.Eliminated, ", "))
+			}
+			text += "\n"
+			if round.TieBreak != "" {
+				text += fmt.Sprintf("⚖️ %s\n", round.TieBreak)
+			}
+		}
+	default:
+		text = fmt.Sprintf("🏆 *%s* won with %s of %d.\n", r.Winner, plural(r.Counts[r.Winner], "vote"), r.Voters)
+		text += fmt.Sprintf("Votes: %s\n", r.formatCounts(r.Counts))
+	}
+
+	if r.TieBreak != "" {
+		text += fmt.Sprintf("⚖️ %s\n", r.TieBreak)
+	}
+	return text
+}
+
+// formatCounts formats the counts of a result, most votes first, e.g. "Borscht 3 · Pasta 2"
+func (r Result) formatCounts(counts map[string]int) string {
+	options := make([]string, 0, len(counts))
+	for _, option := range r.Options {
+		if _, ok := counts[option]; ok {
+			options = append(options, option)
+		}
+	}
+	sort.SliceStable(options, func(i, j int) bool {
+		return counts[options[i]] > counts[options[j]]
+	})
+
+	parts := make([]string, len(options))
+	for i, option := range options {
+		parts[i] = fmt.Sprintf("%s %d", option, counts[option])
+	}
+	return strings.Join(parts, " · ")
+}
+
+// plural formats a count with a noun, e.g. "1 vote" or "3 votes"
+func plural(count int, noun string) string {
+	if count == 1 {
+		return fmt.Sprintf("1 %s", noun)
+	}
+	return fmt.Sprintf("%d %ss", count, noun)
+}
+
+// contains reports whether a list holds a string
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
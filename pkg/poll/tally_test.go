@@ -0,0 +1,189 @@
+package poll
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/korjavin/whatsfordinner/pkg/models"
+)
+
+// ranked makes a ranked vote from finished rankings
+func ranked(options []string, rankings map[string][]string) *models.VoteState {
+	done := make(map[string]bool)
+	for userID := range rankings {
+		done[userID] = true
+	}
+	return &models.VoteState{
+		Options:      options,
+		Mode:         ModeRanked,
+		Rankings:     rankings,
+		RankingsDone: done,
+	}
+}
+
+func TestTallyRanked(t *testing.T) {
+	tests := []struct {
+		name       string
+		vote       *models.VoteState
+		winner     string
+		voters     int
+		eliminated [][]string // Eliminated dishes of each round
+		tieBreaks  []string   // Tie-breaks of each round
+		supporters []string
+	}{
+		{
+			name: "majority of first choices",
+			vote: ranked([]string{"A", "B", "C"}, map[string][]string{
+				"u1": {"A", "B", "C"},
+				"u2": {"A", "C", "B"},
+				"u3": {"B", "A", "C"},
+			}),
+			winner:     "A",
+			voters:     3,
+			eliminated: [][]string{nil},
+			tieBreaks:  []string{""},
+			supporters: []string{"u1", "u2"},
+		},
+		{
+			name: "dishes nobody ranked first go at once, then a tie for last goes to the one listed last",
+			vote: ranked([]string{"A", "B", "C", "D"}, map[string][]string{
+				"u1": {"A", "B"},
+				"u2": {"B", "A"},
+				"u3": {"A"},
+				"u4": {"B"},
+			}),
+			winner:     "A",
+			voters:     4,
+			eliminated: [][]string{{"C", "D"}, {"B"}, nil},
+			tieBreaks:  []string{"", "A, B tied for last; B was listed last in the poll.", ""},
+			supporters: []string{"u1", "u2", "u3"},
+		},
+		{
+			name: "a tie for last goes to the dish with fewer votes in the round before, and exhausted ballots stop counting",
+			vote: ranked([]string{"A", "B", "C", "D"}, map[string][]string{
+				"a1": {"A"}, "a2": {"A"}, "a3": {"A"}, "a4": {"A"},
+				"b1": {"B"}, "b2": {"B"}, "b3": {"B"},
+				"c1": {"C", "B"}, "c2": {"C", "B"},
+				"d1": {"D", "C"},
+			}),
+			winner:     "B",
+			voters:     10,
+			eliminated: [][]string{{"D"}, {"C"}, nil},
+			tieBreaks:  []string{"", "B, C tied for last; C had fewer votes in round 1.", ""},
+			supporters: []string{"b1", "b2", "b3", "c1", "c2"},
+		},
+		{
+			name: "a tie for first is broken by knocking out the dish listed last",
+			vote: ranked([]string{"A", "B"}, map[string][]string{
+				"u1": {"A", "B"},
+				"u2": {"B", "A"},
+			}),
+			winner:     "A",
+			voters:     2,
+			eliminated: [][]string{{"B"}, nil},
+			tieBreaks:  []string{"A, B tied for last; B was listed last in the poll.", ""},
+			supporters: []string{"u1", "u2"},
+		},
+		{
+			name: "unfinished rankings aren't ballots",
+			vote: &models.VoteState{
+				Options:      []string{"A", "B"},
+				Mode:         ModeRanked,
+				Rankings:     map[string][]string{"u1": {"A"}, "u2": {"B"}, "u3": {"B"}},
+				RankingsDone: map[string]bool{"u1": true},
+			},
+			winner:     "A",
+			voters:     1,
+			eliminated: [][]string{nil},
+			tieBreaks:  []string{""},
+			supporters: []string{"u1"},
+		},
+		{
+			name: "nobody finished a ranking",
+			vote: &models.VoteState{
+				Options:  []string{"A", "B"},
+				Mode:     ModeRanked,
+				Rankings: map[string][]string{"u1": {"A"}},
+			},
+			winner: "",
+			voters: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := Tally(tt.vote)
+			if result.Winner != tt.winner {
+				t.Errorf("winner = %q, want %q", result.Winner, tt.winner)
+			}
+			if result.Voters != tt.voters {
+				t.Errorf("voters = %d, want %d", result.Voters, tt.voters)
+			}
+			if voters := Voters(tt.vote); voters != result.Voters {
+				t.Errorf("Voters() = %d, but the tally counted %d", voters, result.Voters)
+			}
+			if !reflect.DeepEqual(result.Supporters, tt.supporters) {
+				t.Errorf("supporters = %v, want %v", result.Supporters, tt.supporters)
+			}
+
+			if len(result.Rounds) != len(tt.eliminated) {
+				t.Fatalf("got %d rounds, want %d: %+v", len(result.Rounds), len(tt.eliminated), result.Rounds)
+			}
+			for i, round := range result.Rounds {
+				if !reflect.DeepEqual(round.Eliminated, tt.eliminated[i]) {
+					t.Errorf("round %d eliminated %v, want %v", i+1, round.Eliminated, tt.eliminated[i])
+				}
+				if round.TieBreak != tt.tieBreaks[i] {
+					t.Errorf("round %d tie-break = %q, want %q", i+1, round.TieBreak, tt.tieBreaks[i])
+				}
+			}
+		})
+	}
+}
+
+func TestTallyTieForFirst(t *testing.T) {
+	tests := []struct {
+		name       string
+		vote       *models.VoteState
+		winner     string
+		tieBreak   string
+		supporters []string
+	}{
+		{
+			name: "plurality",
+			vote: &models.VoteState{
+				Options: []string{"A", "B", "C"},
+				Votes:   map[string]string{"u1": "B", "u2": "A", "u3": "C", "u4": "B", "u5": "A"},
+			},
+			winner:     "A",
+			tieBreak:   "A, B tied with 2 votes; A was listed first in the poll.",
+			supporters: []string{"u2", "u5"},
+		},
+		{
+			name: "approval",
+			vote: &models.VoteState{
+				Options:   []string{"A", "B", "C"},
+				Mode:      ModeApproval,
+				Approvals: map[string][]string{"u1": {"C", "B"}, "u2": {"B", "C"}, "u3": {"A"}},
+			},
+			winner:     "B",
+			tieBreak:   "B, C tied with 2 votes; B was listed first in the poll.",
+			supporters: []string{"u1", "u2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := Tally(tt.vote)
+			if result.Winner != tt.winner {
+				t.Errorf("winner = %q, want %q", result.Winner, tt.winner)
+			}
+			if result.TieBreak != tt.tieBreak {
+				t.Errorf("tie-break = %q, want %q", result.TieBreak, tt.tieBreak)
+			}
+			if !reflect.DeepEqual(result.Supporters, tt.supporters) {
+				t.Errorf("supporters = %v, want %v", result.Supporters, tt.supporters)
+			}
+		})
+	}
+}
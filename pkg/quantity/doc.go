@@ -0,0 +1,5 @@
+// Package quantity provides structured ingredient amounts with units.
+// It parses free-form strings from users and the LLM, converts between compatible units,
+// and supports the arithmetic needed to decrement fridge inventory and to scale recipes,
+// rounding scaled amounts to what's practical to measure.
+package quantity
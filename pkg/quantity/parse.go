@@ -0,0 +1,173 @@
+package quantity
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// unitAliases maps the spellings users and the LLM use to canonical units
+// Keys are lowercase with dots removed (see unitKey)
+var unitAliases = map[string]Unit{
+	// Count
+	"pcs": Piece, "pc": Piece, "piece": Piece, "pieces": Piece,
+	"шт": Piece, "штук": Piece, "штуки": Piece, "штука": Piece,
+	// Mass
+	"mg": Milligram, "milligram": Milligram, "milligrams": Milligram, "мг": Milligram,
+	"g": Gram, "gr": Gram, "gram": Gram, "grams": Gram, "gramme": Gram, "grammes": Gram,
+	"г": Gram, "гр": Gram, "грамм": Gram, "грамма": Gram, "граммов": Gram,
+	"kg": Kilogram, "kgs": Kilogram, "kilo": Kilogram, "kilos": Kilogram, "kilogram": Kilogram, "kilograms": Kilogram,
+	"кг": Kilogram, "килограмм": Kilogram, "килограмма": Kilogram,
+	"oz": Ounce, "ounce": Ounce, "ounces": Ounce,
+	"lb": Pound, "lbs": Pound, "pound": Pound, "pounds": Pound,
+	// Volume
+	"ml": Milliliter, "milliliter": Milliliter, "milliliters": Milliliter, "millilitre": Milliliter, "millilitres": Milliliter,
+	"мл": Milliliter,
+	"l":  Liter, "liter": Liter, "liters": Liter, "litre": Liter, "litres": Liter, "ltr": Liter,
+	"л": Liter, "литр": Liter, "литра": Liter, "литров": Liter,
+	"tsp": Teaspoon, "teaspoon": Teaspoon, "teaspoons": Teaspoon, "чл": Teaspoon,
+	"tbsp": Tablespoon, "tbs": Tablespoon, "tablespoon": Tablespoon, "tablespoons": Tablespoon, "стл": Tablespoon,
+	"cup": Cup, "cups": Cup, "стакан": Cup, "стакана": Cup, "стаканов": Cup,
+}
+
+// unicodeFractions maps vulgar fraction characters to their values
+var unicodeFractions = map[string]float64{
+	"½": 0.5, "⅓": 1.0 / 3, "⅔": 2.0 / 3, "¼": 0.25, "¾": 0.75, "⅛": 0.125,
+}
+
+const numberPattern = `(\d+\s+\d+/\d+|\d+/\d+|\d+(?:[.,]\d+)?|[½⅓⅔¼¾⅛])`
+
+var (
+	// "200 g", "1,5kg", "1 1/2 cups", "3"
+	quantityRe = regexp.MustCompile(`^\s*` + numberPattern + `\s*([\p{L}.]*)\s*$`)
+	// "200 g flour", "2 eggs", "½ cup milk"
+	leadingRe = regexp.MustCompile(`^\s*` + numberPattern + `\s*([\p{L}.]+)?\s*(.*)$`)
+	// "milk 1 l", "flour: 200g", "eggs - 6"
+	trailingRe = regexp.MustCompile(`^(.+?)[\s:,\-–]+` + numberPattern + `\s*([\p{L}.]*)\s*$`)
+	// "cherry tomatoes (250g)"
+	parenthesesRe = regexp.MustCompile(`^(.+?)\s*\((.+)\)\s*$`)
+)
+
+// ParseUnit resolves a unit spelling such as "grams" or "ст.л." to a canonical unit
+func ParseUnit(s string) (Unit, bool) {
+	unit, ok := unitAliases[unitKey(s)]
+	return unit, ok
+}
+
+// Parse parses a quantity string such as "200 g", "1.5 kg" or "3"
+// A bare number is interpreted as a count of pieces
+func Parse(s string) (Quantity, error) {
+	matches := quantityRe.FindStringSubmatch(s)
+	if matches == nil {
+		return Quantity{}, fmt.Errorf("invalid quantity: %q", s)
+	}
+
+	amount, err := parseNumber(matches[1])
+	if err != nil {
+		return Quantity{}, err
+	}
+
+	if matches[2] == "" {
+		return Quantity{Amount: amount, Unit: Piece}, nil
+	}
+
+	unit, ok := ParseUnit(matches[2])
+	if !ok {
+		return Quantity{}, fmt.Errorf("unknown unit: %q", matches[2])
+	}
+
+	return Quantity{Amount: amount, Unit: unit}, nil
+}
+
+// ParseIngredient splits an ingredient line into a name and a quantity
+// It understands "200 g flour", "2 eggs", "milk 1 l" and "cherry tomatoes (250g)"
+// If no quantity is found, the trimmed line is returned as the name and ok is false
+func ParseIngredient(s string) (name string, q Quantity, ok bool) {
+	s = strings.TrimSpace(s)
+
+	// "name (quantity)"
+	if matches := parenthesesRe.FindStringSubmatch(s); matches != nil {
+		if parsed, err := Parse(matches[2]); err == nil {
+			return strings.TrimSpace(matches[1]), parsed, true
+		}
+	}
+
+	// "quantity [unit] name"
+	if matches := leadingRe.FindStringSubmatch(s); matches != nil {
+		amount, err := parseNumber(matches[1])
+		if err == nil {
+			unitToken, rest := matches[2], strings.TrimSpace(matches[3])
+			unit, isUnit := ParseUnit(unitToken)
+			switch {
+			case isUnit && rest != "":
+				rest = strings.TrimPrefix(rest, "of ")
+				return strings.TrimSpace(rest), Quantity{Amount: amount, Unit: unit}, true
+			case !isUnit && unitToken != "":
+				// The token after the number is part of the name, e.g. "2 eggs"
+				return strings.TrimSpace(unitToken + " " + rest), Quantity{Amount: amount, Unit: Piece}, true
+			}
+		}
+	}
+
+	// "name quantity [unit]"
+	if matches := trailingRe.FindStringSubmatch(s); matches != nil {
+		amount, err := parseNumber(matches[2])
+		if err == nil {
+			if matches[3] == "" {
+				return strings.TrimSpace(matches[1]), Quantity{Amount: amount, Unit: Piece}, true
+			}
+			if unit, isUnit := ParseUnit(matches[3]); isUnit {
+				return strings.TrimSpace(matches[1]), Quantity{Amount: amount, Unit: unit}, true
+			}
+		}
+	}
+
+	return s, Quantity{}, false
+}
+
+// parseNumber parses integers, decimals with either separator, fractions and mixed numbers
+func parseNumber(s string) (float64, error) {
+	s = strings.TrimSpace(s)
+
+	if value, ok := unicodeFractions[s]; ok {
+		return value, nil
+	}
+
+	// Mixed number, e.g. "1 1/2"
+	if fields := strings.Fields(s); len(fields) == 2 {
+		whole, err := parseNumber(fields[0])
+		if err != nil {
+			return 0, err
+		}
+		fraction, err := parseNumber(fields[1])
+		if err != nil {
+			return 0, err
+		}
+		return whole + fraction, nil
+	}
+
+	// Simple fraction, e.g. "1/2"
+	if numerator, denominator, found := strings.Cut(s, "/"); found {
+		n, err := strconv.ParseFloat(numerator, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid number: %q", s)
+		}
+		d, err := strconv.ParseFloat(denominator, 64)
+		if err != nil || d == 0 {
+			return 0, fmt.Errorf("invalid number: %q", s)
+		}
+		return n / d, nil
+	}
+
+	value, err := strconv.ParseFloat(strings.Replace(s, ",", ".", 1), 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid number: %q", s)
+	}
+	return value, nil
+}
+
+// unitKey normalizes a unit spelling for alias lookup
+func unitKey(s string) string {
+	return strings.ReplaceAll(strings.ToLower(strings.TrimSpace(s)), ".", "")
+}
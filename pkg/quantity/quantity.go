@@ -0,0 +1,197 @@
+package quantity
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// Dimension represents the physical dimension of a unit
+type Dimension int
+
+const (
+	// DimensionCount is used for countable items (eggs, onions)
+	DimensionCount Dimension = iota
+	// DimensionMass is used for weighed items (flour, meat)
+	DimensionMass
+	// DimensionVolume is used for measured liquids and spoonfuls
+	DimensionVolume
+)
+
+// Unit represents a measurement unit
+type Unit string
+
+const (
+	// Piece is a single countable item
+	Piece Unit = "pcs"
+	// Milligram is a thousandth of a gram
+	Milligram Unit = "mg"
+	// Gram is the base unit for mass
+	Gram Unit = "g"
+	// Kilogram is a thousand grams
+	Kilogram Unit = "kg"
+	// Ounce is an avoirdupois ounce
+	Ounce Unit = "oz"
+	// Pound is an avoirdupois pound
+	Pound Unit = "lb"
+	// Milliliter is the base unit for volume
+	Milliliter Unit = "ml"
+	// Liter is a thousand milliliters
+	Liter Unit = "l"
+	// Teaspoon is a metric teaspoon (5 ml)
+	Teaspoon Unit = "tsp"
+	// Tablespoon is a metric tablespoon (15 ml)
+	Tablespoon Unit = "tbsp"
+	// Cup is a metric cup (250 ml)
+	Cup Unit = "cup"
+)
+
+// ErrIncompatibleUnits is returned when two quantities can't be converted into each other
+var ErrIncompatibleUnits = errors.New("incompatible units")
+
+// unitInfo describes how a unit relates to the base unit of its dimension
+type unitInfo struct {
+	dimension Dimension
+	factor    float64 // how many base units (pcs, g, ml) one unit is worth
+}
+
+var units = map[Unit]unitInfo{
+	Piece:      {DimensionCount, 1},
+	Milligram:  {DimensionMass, 0.001},
+	Gram:       {DimensionMass, 1},
+	Kilogram:   {DimensionMass, 1000},
+	Ounce:      {DimensionMass, 28.3495},
+	Pound:      {DimensionMass, 453.592},
+	Milliliter: {DimensionVolume, 1},
+	Liter:      {DimensionVolume, 1000},
+	Teaspoon:   {DimensionVolume, 5},
+	Tablespoon: {DimensionVolume, 15},
+	Cup:        {DimensionVolume, 250},
+}
+
+// Quantity is an amount expressed in a unit
+type Quantity struct {
+	Amount float64 `json:"amount"`
+	Unit   Unit    `json:"unit"`
+}
+
+// New creates a new quantity
+func New(amount float64, unit Unit) Quantity {
+	return Quantity{Amount: amount, Unit: unit}
+}
+
+// Dimension returns the dimension of the unit
+func (u Unit) Dimension() Dimension {
+	return units[u].dimension
+}
+
+// Valid reports whether the unit is known
+func (u Unit) Valid() bool {
+	_, ok := units[u]
+	return ok
+}
+
+// IsZero reports whether the quantity has no unit (i.e. it was never set)
+func (q Quantity) IsZero() bool {
+	return q.Unit == ""
+}
+
+// IsEmpty reports whether nothing is left of the quantity
+func (q Quantity) IsEmpty() bool {
+	// Treat tiny leftovers from float arithmetic as nothing
+	return q.Amount <= 1e-9
+}
+
+// Compatible reports whether two quantities can be converted into each other
+func (q Quantity) Compatible(other Quantity) bool {
+	if !q.Unit.Valid() || !other.Unit.Valid() {
+		return false
+	}
+	return q.Unit.Dimension() == other.Unit.Dimension()
+}
+
+// Convert converts the quantity to another unit of the same dimension
+func (q Quantity) Convert(to Unit) (Quantity, error) {
+	from, ok := units[q.Unit]
+	if !ok {
+		return Quantity{}, fmt.Errorf("unknown unit: %s", q.Unit)
+	}
+	target, ok := units[to]
+	if !ok {
+		return Quantity{}, fmt.Errorf("unknown unit: %s", to)
+	}
+	if from.dimension != target.dimension {
+		return Quantity{}, fmt.Errorf("%w: %s and %s", ErrIncompatibleUnits, q.Unit, to)
+	}
+
+	return Quantity{
+		Amount: q.Amount * from.factor / target.factor,
+		Unit:   to,
+	}, nil
+}
+
+// Add returns the sum of two quantities expressed in the receiver's unit
+func (q Quantity) Add(other Quantity) (Quantity, error) {
+	converted, err := other.Convert(q.Unit)
+	if err != nil {
+		return Quantity{}, err
+	}
+	return Quantity{Amount: q.Amount + converted.Amount, Unit: q.Unit}, nil
+}
+
+// Sub returns the difference of two quantities expressed in the receiver's unit
+// The result never goes below zero
+func (q Quantity) Sub(other Quantity) (Quantity, error) {
+	converted, err := other.Convert(q.Unit)
+	if err != nil {
+		return Quantity{}, err
+	}
+	return Quantity{Amount: math.Max(0, q.Amount-converted.Amount), Unit: q.Unit}, nil
+}
+
+// Scale multiplies the quantity by a factor
+func (q Quantity) Scale(factor float64) Quantity {
+	return Quantity{Amount: q.Amount * factor, Unit: q.Unit}
+}
+
+// Normalize expresses metric quantities in the most readable unit (e.g. 1500 g -> 1.5 kg)
+// Kitchen units like spoons and cups are kept as they are
+func (q Quantity) Normalize() Quantity {
+	switch q.Unit {
+	case Milligram, Gram, Kilogram:
+		grams, _ := q.Convert(Gram)
+		switch {
+		case grams.Amount >= 1000:
+			normalized, _ := grams.Convert(Kilogram)
+			return normalized
+		case grams.Amount > 0 && grams.Amount < 1:
+			normalized, _ := grams.Convert(Milligram)
+			return normalized
+		default:
+			return grams
+		}
+	case Milliliter, Liter:
+		milliliters, _ := q.Convert(Milliliter)
+		if milliliters.Amount >= 1000 {
+			normalized, _ := milliliters.Convert(Liter)
+			return normalized
+		}
+		return milliliters
+	}
+	return q
+}
+
+// String formats the quantity for display, e.g. "1.5 kg" or "3 pcs"
+func (q Quantity) String() string {
+	if q.IsZero() {
+		return ""
+	}
+	return formatAmount(q.Amount) + " " + string(q.Unit)
+}
+
+// formatAmount formats an amount with at most two decimals and no trailing zeros
+func formatAmount(amount float64) string {
+	rounded := math.Round(amount*100) / 100
+	return strconv.FormatFloat(rounded, 'f', -1, 64)
+}
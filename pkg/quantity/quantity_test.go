@@ -0,0 +1,158 @@
+package quantity
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+// near reports whether two amounts are equal up to float rounding
+func near(a, b float64) bool {
+	return math.Abs(a-b) < 1e-6
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Quantity
+		wantErr bool
+	}{
+		{"200 g", New(200, Gram), false},
+		{"200g", New(200, Gram), false},
+		{"1,5 kg", New(1.5, Kilogram), false},
+		{"1.5 kg", New(1.5, Kilogram), false},
+		{"3", New(3, Piece), false},
+		{"1/2 cup", New(0.5, Cup), false},
+		{"1 1/2 cups", New(1.5, Cup), false},
+		{"½ l", New(0.5, Liter), false},
+		{"2 ст.л.", New(2, Tablespoon), false},
+		{"500 грамм", New(500, Gram), false},
+		{"2 Tbsp", New(2, Tablespoon), false},
+		{"3 шт", New(3, Piece), false},
+		{"2 handfuls", Quantity{}, true},
+		{"some", Quantity{}, true},
+		{"1/0 g", Quantity{}, true},
+		{"", Quantity{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := Parse(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse(%q) error = %v, want error %v", tt.in, err, tt.wantErr)
+			}
+			if !tt.wantErr && (got.Unit != tt.want.Unit || !near(got.Amount, tt.want.Amount)) {
+				t.Fatalf("Parse(%q) = %v, want %v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseIngredient(t *testing.T) {
+	tests := []struct {
+		in       string
+		wantName string
+		want     Quantity
+		wantOK   bool
+	}{
+		{"200 g flour", "flour", New(200, Gram), true},
+		{"200g of flour", "flour", New(200, Gram), true},
+		{"2 eggs", "eggs", New(2, Piece), true},
+		{"½ cup milk", "milk", New(0.5, Cup), true},
+		{"milk 1 l", "milk", New(1, Liter), true},
+		{"flour: 200g", "flour", New(200, Gram), true},
+		{"eggs - 6", "eggs", New(6, Piece), true},
+		{"cherry tomatoes (250g)", "cherry tomatoes", New(250, Gram), true},
+		{"eggs (2)", "eggs", New(2, Piece), true},
+		{"salt to taste", "salt to taste", Quantity{}, false},
+		{"  basil  ", "basil", Quantity{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			name, got, ok := ParseIngredient(tt.in)
+			if ok != tt.wantOK || name != tt.wantName {
+				t.Fatalf("ParseIngredient(%q) = %q, %v, %v; want %q, %v, %v", tt.in, name, got, ok, tt.wantName, tt.want, tt.wantOK)
+			}
+			if ok && (got.Unit != tt.want.Unit || !near(got.Amount, tt.want.Amount)) {
+				t.Fatalf("ParseIngredient(%q) quantity = %v, want %v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestArithmetic(t *testing.T) {
+	tests := []struct {
+		name    string
+		do      func() (Quantity, error)
+		want    Quantity
+		wantErr error
+	}{
+		{"convert kg to g", func() (Quantity, error) { return New(1.5, Kilogram).Convert(Gram) }, New(1500, Gram), nil},
+		{"convert tbsp to ml", func() (Quantity, error) { return New(2, Tablespoon).Convert(Milliliter) }, New(30, Milliliter), nil},
+		{"convert across dimensions", func() (Quantity, error) { return New(1, Kilogram).Convert(Liter) }, Quantity{}, ErrIncompatibleUnits},
+		{"add in the receiver's unit", func() (Quantity, error) { return New(1, Kilogram).Add(New(250, Gram)) }, New(1.25, Kilogram), nil},
+		{"add pieces to mass", func() (Quantity, error) { return New(1, Kilogram).Add(New(3, Piece)) }, Quantity{}, ErrIncompatibleUnits},
+		{"subtract", func() (Quantity, error) { return New(1, Liter).Sub(New(200, Milliliter)) }, New(0.8, Liter), nil},
+		{"subtract more than there is", func() (Quantity, error) { return New(100, Gram).Sub(New(1, Kilogram)) }, New(0, Gram), nil},
+		{"subtract across dimensions", func() (Quantity, error) { return New(500, Gram).Sub(New(2, Piece)) }, Quantity{}, ErrIncompatibleUnits},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.do()
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && (got.Unit != tt.want.Unit || !near(got.Amount, tt.want.Amount)) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		in   Quantity
+		want string
+	}{
+		{New(1500, Gram), "1.5 kg"},
+		{New(0.5, Kilogram), "500 g"},
+		{New(0.5, Gram), "500 mg"},
+		{New(1200, Milliliter), "1.2 l"},
+		{New(0.25, Liter), "250 ml"},
+		{New(3, Tablespoon), "3 tbsp"},
+		{New(2, Piece), "2 pcs"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.in.Normalize().String(); got != tt.want {
+			t.Errorf("%v normalized = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestScaleIngredient(t *testing.T) {
+	tests := []struct {
+		line   string
+		factor float64
+		want   string
+	}{
+		{"200 g flour", 1.5, "300 g flour"},
+		{"225 g butter", 1.5, "340 g butter"},
+		{"800 g potatoes", 2, "1.6 kg potatoes"},
+		{"2 eggs", 1.5, "3 eggs"},
+		{"1 onion", 0.5, "0.5 onion"},
+		{"1 tbsp oil", 1.5, "1.5 tbsp oil"},
+		{"salt to taste", 2, "salt to taste"},
+		{"200 g flour", 1, "200 g flour"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			if got := ScaleIngredient(tt.line, tt.factor); got != tt.want {
+				t.Fatalf("ScaleIngredient(%q, %v) = %q, want %q", tt.line, tt.factor, got, tt.want)
+			}
+		})
+	}
+}
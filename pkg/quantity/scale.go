@@ -0,0 +1,63 @@
+package quantity
+
+import (
+	"math"
+)
+
+// Round rounds a quantity to an amount that's practical to measure in a kitchen
+// Grams and milliliters get coarser steps as they grow (e.g. 337 g -> 340 g), spoons and cups
+// go to quarters, and pieces to halves for small counts and whole ones above that
+func (q Quantity) Round() Quantity {
+	switch q.Unit {
+	case Milligram, Gram, Kilogram:
+		grams, _ := q.Convert(Gram)
+		grams.Amount = roundMetric(grams.Amount)
+		return grams.Normalize()
+	case Milliliter, Liter:
+		milliliters, _ := q.Convert(Milliliter)
+		milliliters.Amount = roundMetric(milliliters.Amount)
+		return milliliters.Normalize()
+	case Piece:
+		if q.Amount < 2 {
+			return Quantity{Amount: math.Max(0.5, roundTo(q.Amount, 0.5)), Unit: q.Unit}
+		}
+		return Quantity{Amount: math.Round(q.Amount), Unit: q.Unit}
+	default:
+		return Quantity{Amount: math.Max(0.25, roundTo(q.Amount, 0.25)), Unit: q.Unit}
+	}
+}
+
+// roundMetric rounds grams or milliliters to a step that suits their size
+func roundMetric(amount float64) float64 {
+	switch {
+	case amount < 10:
+		return math.Max(1, math.Round(amount))
+	case amount < 100:
+		return roundTo(amount, 5)
+	case amount < 1000:
+		return roundTo(amount, 10)
+	default:
+		return roundTo(amount, 50)
+	}
+}
+
+// roundTo rounds an amount to the nearest multiple of step
+func roundTo(amount, step float64) float64 {
+	return math.Round(amount/step) * step
+}
+
+// ScaleIngredient scales the amount in an ingredient line, e.g. "200 g flour" by 1.5 -> "300 g flour"
+// Lines without an amount, like "salt to taste", are returned unchanged
+func ScaleIngredient(line string, factor float64) string {
+	name, q, ok := ParseIngredient(line)
+	if !ok || factor == 1 {
+		return line
+	}
+
+	scaled := q.Scale(factor).Round()
+	if scaled.Unit == Piece {
+		// "3 eggs" reads better than "3 pcs eggs"
+		return formatAmount(scaled.Amount) + " " + name
+	}
+	return scaled.String() + " " + name
+}
@@ -0,0 +1,8 @@
+// Package recipes provides the family recipe book.
+// Each channel keeps its own recipes, added and edited from chat, and every recipe remembers
+// the family member who added it, who is the only one allowed to change or delete it.
+// Recipes can also be imported from web pages, reading their schema.org Recipe JSON-LD or microdata,
+// with the LLM as a fallback for pages without structured data.
+// Recipe collections can be exported and imported as JSON, Markdown cards, schema.org JSON-LD or a zip of those.
+// The dinner service suggests dishes from the family's book together with the shared base catalog.
+package recipes
@@ -0,0 +1,413 @@
+package recipes
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/korjavin/whatsfordinner/pkg/models"
+)
+
+// Formats recipes can be exported in
+const (
+	FormatJSON     = "json"   // The dishes as the bot stores them
+	FormatMarkdown = "md"     // Human-readable cards, one after another
+	FormatJSONLD   = "jsonld" // schema.org Recipe objects other recipe apps understand
+	FormatZip      = "zip"    // All of the above, plus one Markdown card per dish
+)
+
+// Formats lists the export formats, for usage messages
+var Formats = []string{FormatZip, FormatJSON, FormatMarkdown, FormatJSONLD}
+
+// cardSeparator separates the cards in a Markdown export
+const cardSeparator = "\n---\n\n"
+
+// maxArchiveFileSize limits how much of a single file in an imported zip is read
+const maxArchiveFileSize = 10 << 20
+
+// Export encodes dishes in one of the formats and returns the file name to save them under
+func Export(dishes []models.Dish, format string) ([]byte, string, error) {
+	switch strings.ToLower(strings.TrimPrefix(format, ".")) {
+	case FormatJSON:
+		data, err := json.MarshalIndent(dishes, "", "  ")
+		return data, "recipes.json", err
+	case FormatMarkdown, "markdown":
+		return []byte(markdownCards(dishes)), "recipes.md", nil
+	case FormatJSONLD, "json-ld":
+		data, err := exportJSONLD(dishes)
+		return data, "recipes.jsonld", err
+	case FormatZip, "":
+		data, err := exportZip(dishes)
+		return data, "recipes.zip", err
+	default:
+		return nil, "", fmt.Errorf("unknown format %q, use one of %s", format, strings.Join(Formats, ", "))
+	}
+}
+
+// exportJSONLD encodes dishes as a list of schema.org Recipe objects
+func exportJSONLD(dishes []models.Dish) ([]byte, error) {
+	recipes := make([]map[string]interface{}, len(dishes))
+	for i, dish := range dishes {
+		recipes[i] = schemaFromDish(dish)
+	}
+	return json.MarshalIndent(recipes, "", "  ")
+}
+
+// exportZip packs the dishes in every format, with a Markdown card per dish under cards/
+func exportZip(dishes []models.Dish) ([]byte, error) {
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+
+	files := make(map[string][]byte)
+	for _, format := range []string{FormatJSON, FormatMarkdown, FormatJSONLD} {
+		data, name, err := Export(dishes, format)
+		if err != nil {
+			return nil, err
+		}
+		files[name] = data
+	}
+	for _, dish := range dishes {
+		name := cardFileName(dish.Name)
+		// Dishes whose names only differ in punctuation would overwrite each other
+		for i := 2; files[name] != nil; i++ {
+			name = cardFileName(fmt.Sprintf("%s %d", dish.Name, i))
+		}
+		files[name] = []byte(markdownCard(dish))
+	}
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		w, err := archive.Create(name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to add %s to the archive: %w", name, err)
+		}
+		if _, err := w.Write(files[name]); err != nil {
+			return nil, fmt.Errorf("failed to add %s to the archive: %w", name, err)
+		}
+	}
+
+	if err := archive.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write the archive: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// cardFileName returns the file name of a dish's card in a zip export, e.g. "cards/grandmas-borscht.md"
+func cardFileName(name string) string {
+	var slug strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			slug.WriteRune(r)
+			dash = false
+		} else if !dash && slug.Len() > 0 {
+			slug.WriteRune('-')
+			dash = true
+		}
+	}
+	return "cards/" + strings.TrimSuffix(slug.String(), "-") + ".md"
+}
+
+// schemaFromDish maps a dish onto a schema.org Recipe
+func schemaFromDish(dish models.Dish) map[string]interface{} {
+	recipe := map[string]interface{}{
+		"@context":           "https://schema.org",
+		"@type":              "Recipe",
+		"name":               dish.Name,
+		"recipeIngredient":   dish.Ingredients,
+		"recipeInstructions": howToSteps(dish.Instructions),
+	}
+	if dish.Ingredients == nil {
+		recipe["recipeIngredient"] = []string{}
+	}
+	if dish.Cuisine != "" {
+		recipe["recipeCuisine"] = dish.Cuisine
+	}
+	if dish.Description != "" {
+		recipe["description"] = dish.Description
+	}
+	if dish.Servings > 0 {
+		recipe["recipeYield"] = strconv.Itoa(dish.Servings)
+	}
+	for key, minutes := range map[string]int{"prepTime": dish.PrepMinutes, "cookTime": dish.CookMinutes, "totalTime": dish.TotalMinutes} {
+		if minutes > 0 {
+			recipe[key] = isoDuration(minutes)
+		}
+	}
+	if dish.SourceURL != "" {
+		recipe["url"] = dish.SourceURL
+	}
+	return recipe
+}
+
+// howToSteps wraps instructions in schema.org HowToStep objects
+func howToSteps(instructions []string) []map[string]string {
+	steps := make([]map[string]string, len(instructions))
+	for i, instruction := range instructions {
+		steps[i] = map[string]string{"@type": "HowToStep", "text": instruction}
+	}
+	return steps
+}
+
+// isoDuration formats minutes as an ISO 8601 duration, e.g. "PT1H30M"
+func isoDuration(minutes int) string {
+	duration := "PT"
+	if minutes >= 60 {
+		duration += fmt.Sprintf("%dH", minutes/60)
+	}
+	if minutes%60 > 0 || minutes < 60 {
+		duration += fmt.Sprintf("%dM", minutes%60)
+	}
+	return duration
+}
+
+// markdownCards formats dishes as Markdown cards separated by rules
+func markdownCards(dishes []models.Dish) string {
+	cards := make([]string, len(dishes))
+	for i, dish := range dishes {
+		cards[i] = markdownCard(dish)
+	}
+	return strings.Join(cards, cardSeparator)
+}
+
+// markdownCard formats a dish as a Markdown card that ParseCollection can read back
+func markdownCard(dish models.Dish) string {
+	var card strings.Builder
+	fmt.Fprintf(&card, "# %s\n\n", dish.Name)
+
+	var fields []string
+	if dish.Cuisine != "" {
+		fields = append(fields, "Cuisine: "+dish.Cuisine)
+	}
+	if dish.Servings > 0 {
+		fields = append(fields, fmt.Sprintf("Servings: %d", dish.Servings))
+	}
+	for _, field := range []struct {
+		key     string
+		minutes int
+	}{{"Prep", dish.PrepMinutes}, {"Cook", dish.CookMinutes}, {"Total", dish.TotalMinutes}} {
+		if field.minutes > 0 {
+			fields = append(fields, fmt.Sprintf("%s: %d min", field.key, field.minutes))
+		}
+	}
+	if dish.SourceURL != "" {
+		fields = append(fields, "Source: "+dish.SourceURL)
+	}
+	if len(fields) > 0 {
+		card.WriteString(strings.Join(fields, "\n") + "\n\n")
+	}
+
+	if dish.Description != "" {
+		fmt.Fprintf(&card, "%s\n\n", dish.Description)
+	}
+
+	card.WriteString("## Ingredients\n\n")
+	for _, ingredient := range dish.Ingredients {
+		fmt.Fprintf(&card, "- %s\n", ingredient)
+	}
+
+	card.WriteString("\n## Instructions\n\n")
+	for i, instruction := range dish.Instructions {
+		fmt.Fprintf(&card, "%d. %s\n", i+1, instruction)
+	}
+
+	return card.String()
+}
+
+// ParseCollection reads dishes exported by Export, or by other apps as JSON-LD
+// The format is told by the file name's extension and, failing that, by the content
+func ParseCollection(data []byte, name string) ([]models.Dish, error) {
+	ext := strings.ToLower(path.Ext(name))
+	trimmed := bytes.TrimSpace(data)
+
+	switch {
+	case ext == ".zip" || bytes.HasPrefix(data, []byte("PK\x03\x04")):
+		return parseZip(data)
+	case ext == ".json" || ext == ".jsonld" || bytes.HasPrefix(trimmed, []byte("[")) || bytes.HasPrefix(trimmed, []byte("{")):
+		return parseJSON(trimmed)
+	default:
+		return parseMarkdownCards(string(data)), nil
+	}
+}
+
+// parseZip reads a zip export, preferring the JSON file, which keeps everything, over JSON-LD and the cards
+func parseZip(data []byte) ([]models.Dish, error) {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open the archive: %w", err)
+	}
+
+	files := make(map[string][]byte)
+	var cards []string
+	for _, file := range archive.File {
+		if file.FileInfo().IsDir() {
+			continue
+		}
+		r, err := file.Open()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", file.Name, err)
+		}
+		content, err := io.ReadAll(io.LimitReader(r, maxArchiveFileSize))
+		r.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", file.Name, err)
+		}
+		files[path.Base(file.Name)] = content
+		if strings.HasSuffix(file.Name, ".md") && path.Base(file.Name) != "recipes.md" {
+			cards = append(cards, string(content))
+		}
+	}
+
+	for _, name := range []string{"recipes.json", "recipes.jsonld"} {
+		if content, ok := files[name]; ok {
+			return parseJSON(bytes.TrimSpace(content))
+		}
+	}
+	if content, ok := files["recipes.md"]; ok {
+		return parseMarkdownCards(string(content)), nil
+	}
+	return parseMarkdownCards(strings.Join(cards, cardSeparator)), nil
+}
+
+// parseJSON reads a JSON export, either dishes as the bot stores them or schema.org Recipes
+func parseJSON(data []byte) ([]models.Dish, error) {
+	var decoded interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %w", err)
+	}
+
+	var schemaRecipes []map[string]interface{}
+	collectRecipes(decoded, &schemaRecipes)
+	if len(schemaRecipes) > 0 {
+		dishes := make([]models.Dish, 0, len(schemaRecipes))
+		for _, recipe := range schemaRecipes {
+			if dish := dishFromSchema(recipe); dish.Name != "" {
+				dishes = append(dishes, dish)
+			}
+		}
+		return dishes, nil
+	}
+
+	var dishes []models.Dish
+	if bytes.HasPrefix(data, []byte("{")) {
+		var dish models.Dish
+		if err := json.Unmarshal(data, &dish); err != nil {
+			return nil, fmt.Errorf("invalid dish: %w", err)
+		}
+		dishes = []models.Dish{dish}
+	} else if err := json.Unmarshal(data, &dishes); err != nil {
+		return nil, fmt.Errorf("invalid dishes: %w", err)
+	}
+
+	named := dishes[:0]
+	for _, dish := range dishes {
+		if strings.TrimSpace(dish.Name) != "" {
+			named = append(named, dish)
+		}
+	}
+	return named, nil
+}
+
+// collectRecipes gathers all schema.org Recipe nodes in decoded JSON-LD
+func collectRecipes(data interface{}, recipes *[]map[string]interface{}) {
+	switch value := data.(type) {
+	case map[string]interface{}:
+		if isType(value["@type"], "Recipe") {
+			*recipes = append(*recipes, value)
+			return
+		}
+		for _, child := range value {
+			collectRecipes(child, recipes)
+		}
+	case []interface{}:
+		for _, child := range value {
+			collectRecipes(child, recipes)
+		}
+	}
+}
+
+// parseMarkdownCards reads Markdown cards as written by markdownCard
+// Each "# " heading starts a dish; "Key: value" lines before the sections set its details,
+// other text is its description
+func parseMarkdownCards(text string) []models.Dish {
+	var dishes []models.Dish
+	var dish *models.Dish
+	section := ""
+	var description []string
+
+	finish := func() {
+		if dish != nil && dish.Name != "" {
+			dish.Description = strings.Join(description, " ")
+			dishes = append(dishes, *dish)
+		}
+	}
+
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "# "):
+			finish()
+			dish = &models.Dish{Name: strings.TrimSpace(line[2:])}
+			section = ""
+			description = nil
+		case dish == nil || line == "" || line == "---":
+			continue
+		case strings.HasPrefix(line, "## "):
+			section = strings.ToLower(strings.TrimSpace(line[3:]))
+		case strings.HasPrefix(section, "ingredient"):
+			// One ingredient per line, which may well have a comma in it
+			if ingredient := strings.TrimSpace(strings.TrimLeft(line, "-•*")); ingredient != "" {
+				dish.Ingredients = append(dish.Ingredients, ingredient)
+			}
+		case strings.HasPrefix(section, "instruction") || strings.HasPrefix(section, "step"):
+			dish.Instructions = append(dish.Instructions, ParseSteps(line)...)
+		case section == "":
+			if !setCardField(dish, line) {
+				description = append(description, line)
+			}
+		}
+	}
+	finish()
+
+	return dishes
+}
+
+// setCardField sets a detail of a dish from a "Key: value" line of a card, reporting whether it was one
+func setCardField(dish *models.Dish, line string) bool {
+	key, value, found := strings.Cut(line, ":")
+	if !found {
+		return false
+	}
+	value = strings.TrimSpace(value)
+	number, _ := strconv.Atoi(strings.TrimSuffix(value, " min"))
+
+	switch strings.ToLower(strings.TrimSpace(key)) {
+	case "cuisine":
+		dish.Cuisine = value
+	case "source":
+		dish.SourceURL = value
+	case "servings":
+		dish.Servings = number
+	case "prep":
+		dish.PrepMinutes = number
+	case "cook":
+		dish.CookMinutes = number
+	case "total":
+		dish.TotalMinutes = number
+	default:
+		return false
+	}
+	return true
+}
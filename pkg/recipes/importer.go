@@ -0,0 +1,459 @@
+package recipes
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/korjavin/whatsfordinner/pkg/logger"
+	"github.com/korjavin/whatsfordinner/pkg/models"
+	"github.com/korjavin/whatsfordinner/pkg/openai"
+	"golang.org/x/net/html"
+)
+
+// maxPageSize limits how much of a page is downloaded
+const maxPageSize = 2 << 20
+
+// maxFallbackText limits how much page text is sent to the LLM for pages without structured data
+const maxFallbackText = 12000
+
+// ErrNoRecipe is returned when a page has no recipe the importer can read
+var ErrNoRecipe = errors.New("no recipe found on the page")
+
+var (
+	tagPattern      = regexp.MustCompile(`<[^>]*>`)
+	numberPattern   = regexp.MustCompile(`\d+`)
+	lineBreaks      = strings.NewReplacer("<br>", "\n", "<br/>", "\n", "<br />", "\n", "</p>", "\n", "</li>", "\n")
+	durationPattern = regexp.MustCompile(`(?i)^P(?:\d+Y)?(?:\d+M)?(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:[\d.]+S)?)?$`)
+)
+
+// blockTags are elements that start a new line in a page's text
+var blockTags = map[string]bool{
+	"p": true, "div": true, "li": true, "br": true, "tr": true, "section": true, "article": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true, "ol": true, "ul": true,
+}
+
+// Importer reads recipes from web pages
+type Importer struct {
+	httpClient   *http.Client
+	openaiClient *openai.Client
+	logger       *logger.Logger
+}
+
+// NewImporter creates a new recipe importer; without an OpenAI client only structured data is read
+func NewImporter(openaiClient *openai.Client) *Importer {
+	return &Importer{
+		httpClient:   &http.Client{Timeout: 20 * time.Second},
+		openaiClient: openaiClient,
+		logger:       logger.New("importer"),
+	}
+}
+
+// FromURL downloads a page and reads the recipe on it
+func (i *Importer) FromURL(pageURL string) (models.Dish, error) {
+	parsed, err := url.Parse(strings.TrimSpace(pageURL))
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return models.Dish{}, fmt.Errorf("invalid link: %q", pageURL)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, parsed.String(), nil)
+	if err != nil {
+		return models.Dish{}, fmt.Errorf("invalid link: %w", err)
+	}
+	req.Header.Set("User-Agent", "Mozilla/5.0 (compatible; whatsfordinner)")
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+
+	i.logger.Info("Importing a recipe from %s", parsed.String())
+	resp, err := i.httpClient.Do(req)
+	if err != nil {
+		return models.Dish{}, fmt.Errorf("failed to download the page: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return models.Dish{}, fmt.Errorf("failed to download the page: %s", resp.Status)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxPageSize))
+	if err != nil {
+		return models.Dish{}, fmt.Errorf("failed to download the page: %w", err)
+	}
+
+	dish, err := i.FromHTML(string(body))
+	if err != nil {
+		return models.Dish{}, err
+	}
+	dish.SourceURL = parsed.String()
+	return dish, nil
+}
+
+// FromHTML reads the recipe in a page's HTML
+// schema.org Recipe JSON-LD is tried first, then microdata, and only then the LLM on the page's text
+func (i *Importer) FromHTML(page string) (models.Dish, error) {
+	doc, err := html.Parse(strings.NewReader(page))
+	if err != nil {
+		return models.Dish{}, fmt.Errorf("failed to parse the page: %w", err)
+	}
+
+	if recipe := findJSONLDRecipe(doc); recipe != nil {
+		if dish := dishFromSchema(recipe); isComplete(dish) {
+			i.logger.Info("Read %s from JSON-LD", dish.Name)
+			return dish, nil
+		}
+	}
+
+	if recipe := findMicrodataRecipe(doc); recipe != nil {
+		if dish := dishFromSchema(recipe); isComplete(dish) {
+			i.logger.Info("Read %s from microdata", dish.Name)
+			return dish, nil
+		}
+	}
+
+	if i.openaiClient == nil {
+		return models.Dish{}, ErrNoRecipe
+	}
+
+	text := []rune(textContent(doc))
+	if len(text) == 0 {
+		return models.Dish{}, ErrNoRecipe
+	}
+	if len(text) > maxFallbackText {
+		text = text[:maxFallbackText]
+	}
+
+	i.logger.Info("No structured recipe data on the page, asking the LLM")
+	recipe, err := i.openaiClient.ExtractRecipe(string(text))
+	if err != nil {
+		return models.Dish{}, fmt.Errorf("failed to read the recipe: %w", err)
+	}
+
+	dish := dishFromSchema(recipe)
+	if !isComplete(dish) {
+		return models.Dish{}, ErrNoRecipe
+	}
+	return dish, nil
+}
+
+// isComplete reports whether a dish has enough to be worth saving
+func isComplete(dish models.Dish) bool {
+	return dish.Name != "" && len(dish.Ingredients) > 0
+}
+
+// findJSONLDRecipe returns the first schema.org Recipe in a page's JSON-LD scripts
+func findJSONLDRecipe(doc *html.Node) map[string]interface{} {
+	var recipe map[string]interface{}
+	walk(doc, func(n *html.Node) bool {
+		if recipe != nil {
+			return false
+		}
+		if n.Type != html.ElementNode || n.Data != "script" || !strings.Contains(attr(n, "type"), "ld+json") {
+			return true
+		}
+
+		var script strings.Builder
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			script.WriteString(c.Data)
+		}
+
+		var data interface{}
+		if err := json.Unmarshal([]byte(script.String()), &data); err == nil {
+			recipe = searchRecipe(data)
+		}
+		return false
+	})
+	return recipe
+}
+
+// searchRecipe looks for a Recipe node in decoded JSON-LD, which may be nested in a @graph or another node
+func searchRecipe(data interface{}) map[string]interface{} {
+	switch value := data.(type) {
+	case map[string]interface{}:
+		if isType(value["@type"], "Recipe") {
+			return value
+		}
+		for _, child := range value {
+			if recipe := searchRecipe(child); recipe != nil {
+				return recipe
+			}
+		}
+	case []interface{}:
+		for _, child := range value {
+			if recipe := searchRecipe(child); recipe != nil {
+				return recipe
+			}
+		}
+	}
+	return nil
+}
+
+// isType reports whether a JSON-LD @type, a string or a list, names the given schema.org type
+func isType(value interface{}, want string) bool {
+	switch typ := value.(type) {
+	case string:
+		return path.Base(strings.TrimPrefix(typ, "schema:")) == want
+	case []interface{}:
+		for _, t := range typ {
+			if isType(t, want) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// findMicrodataRecipe returns the first schema.org Recipe item in a page's microdata, shaped like JSON-LD
+func findMicrodataRecipe(doc *html.Node) map[string]interface{} {
+	var recipe map[string]interface{}
+	walk(doc, func(n *html.Node) bool {
+		if recipe != nil {
+			return false
+		}
+		if n.Type == html.ElementNode && hasAttr(n, "itemscope") && microdataType(n) == "Recipe" {
+			recipe = microdataItem(n)
+			return false
+		}
+		return true
+	})
+	return recipe
+}
+
+// microdataType returns the schema.org type of an item, e.g. "Recipe" for itemtype="https://schema.org/Recipe"
+func microdataType(n *html.Node) string {
+	for _, itemType := range strings.Fields(attr(n, "itemtype")) {
+		if strings.Contains(itemType, "schema.org") {
+			return path.Base(itemType)
+		}
+	}
+	return ""
+}
+
+// microdataItem collects the properties of an item; every property is a list, as it may repeat
+// Nested items become maps, so a HowToStep's text is read like in JSON-LD
+func microdataItem(item *html.Node) map[string]interface{} {
+	props := map[string]interface{}{"@type": microdataType(item)}
+	for c := item.FirstChild; c != nil; c = c.NextSibling {
+		walk(c, func(n *html.Node) bool {
+			if n.Type != html.ElementNode {
+				return true
+			}
+
+			nested := hasAttr(n, "itemscope")
+			if names := strings.Fields(attr(n, "itemprop")); len(names) > 0 {
+				var value interface{}
+				if nested {
+					value = microdataItem(n)
+				} else {
+					value = microdataValue(n)
+				}
+				for _, name := range names {
+					list, _ := props[name].([]interface{})
+					props[name] = append(list, value)
+				}
+			}
+
+			// Properties inside a nested item belong to that item
+			return !nested
+		})
+	}
+	return props
+}
+
+// microdataValue returns the value of a property element
+func microdataValue(n *html.Node) string {
+	if content := attr(n, "content"); content != "" {
+		return content
+	}
+	switch n.Data {
+	case "a", "link":
+		return attr(n, "href")
+	case "img":
+		return attr(n, "src")
+	case "time":
+		if datetime := attr(n, "datetime"); datetime != "" {
+			return datetime
+		}
+	case "data", "meter":
+		return attr(n, "value")
+	}
+	return textContent(n)
+}
+
+// dishFromSchema maps a schema.org Recipe, from JSON-LD, microdata or the LLM, onto a dish
+func dishFromSchema(recipe map[string]interface{}) models.Dish {
+	ingredients := schemaTexts(recipe["recipeIngredient"])
+	if len(ingredients) == 0 {
+		// The older name of the property
+		ingredients = schemaTexts(recipe["ingredients"])
+	}
+
+	return models.Dish{
+		Name:         schemaText(recipe["name"]),
+		Cuisine:      schemaText(recipe["recipeCuisine"]),
+		Description:  schemaText(recipe["description"]),
+		Ingredients:  ingredients,
+		Instructions: schemaSteps(recipe["recipeInstructions"]),
+		Servings:     schemaServings(recipe["recipeYield"]),
+		PrepMinutes:  durationMinutes(schemaText(recipe["prepTime"])),
+		CookMinutes:  durationMinutes(schemaText(recipe["cookTime"])),
+		TotalMinutes: durationMinutes(schemaText(recipe["totalTime"])),
+		SourceURL:    schemaText(recipe["url"]),
+	}
+}
+
+// schemaText returns the first non-empty text of a property value
+func schemaText(value interface{}) string {
+	texts := schemaTexts(value)
+	if len(texts) == 0 {
+		return ""
+	}
+	return texts[0]
+}
+
+// schemaTexts returns the texts of a property value, which may be a string, a number, a list or a node
+func schemaTexts(value interface{}) []string {
+	var texts []string
+	switch v := value.(type) {
+	case string:
+		if text := cleanText(v); text != "" {
+			texts = append(texts, text)
+		}
+	case float64:
+		texts = append(texts, strconv.FormatFloat(v, 'f', -1, 64))
+	case []interface{}:
+		for _, item := range v {
+			texts = append(texts, schemaTexts(item)...)
+		}
+	case map[string]interface{}:
+		for _, key := range []string{"text", "name", "@value"} {
+			if text := schemaText(v[key]); text != "" {
+				texts = append(texts, text)
+				break
+			}
+		}
+	}
+	return texts
+}
+
+// schemaSteps returns the steps of recipeInstructions: text, a list of texts or HowToSteps,
+// or HowToSections that group steps
+func schemaSteps(value interface{}) []string {
+	var steps []string
+	switch v := value.(type) {
+	case string:
+		for _, step := range ParseSteps(tagPattern.ReplaceAllString(lineBreaks.Replace(v), "")) {
+			if step = cleanText(step); step != "" {
+				steps = append(steps, step)
+			}
+		}
+	case []interface{}:
+		for _, item := range v {
+			steps = append(steps, schemaSteps(item)...)
+		}
+	case map[string]interface{}:
+		if elements, ok := v["itemListElement"]; ok {
+			return schemaSteps(elements)
+		}
+		steps = append(steps, schemaSteps(schemaText(v))...)
+	}
+	return steps
+}
+
+// schemaServings returns the number of servings in recipeYield, e.g. 4 for "4 servings", or zero
+func schemaServings(value interface{}) int {
+	for _, text := range schemaTexts(value) {
+		if number := numberPattern.FindString(text); number != "" {
+			servings, _ := strconv.Atoi(number)
+			return servings
+		}
+	}
+	return 0
+}
+
+// durationMinutes converts an ISO 8601 duration, e.g. "PT1H30M", to minutes, or zero if it isn't one
+func durationMinutes(duration string) int {
+	match := durationPattern.FindStringSubmatch(strings.TrimSpace(duration))
+	if match == nil {
+		return 0
+	}
+
+	minutes := 0
+	for i, unit := range []int{24 * 60, 60, 1} {
+		if value, err := strconv.Atoi(match[i+1]); err == nil {
+			minutes += value * unit
+		}
+	}
+	return minutes
+}
+
+// cleanText unescapes HTML entities, strips tags and collapses whitespace
+func cleanText(s string) string {
+	s = tagPattern.ReplaceAllString(lineBreaks.Replace(html.UnescapeString(s)), "")
+	// Entities may have been escaped twice, e.g. "&amp;amp;"
+	s = html.UnescapeString(s)
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// textContent returns the visible text of a node, with block elements on their own lines
+func textContent(n *html.Node) string {
+	var text strings.Builder
+	walk(n, func(n *html.Node) bool {
+		switch n.Type {
+		case html.TextNode:
+			text.WriteString(n.Data)
+		case html.ElementNode:
+			if n.Data == "script" || n.Data == "style" || n.Data == "noscript" {
+				return false
+			}
+			if blockTags[n.Data] {
+				text.WriteString("\n")
+			}
+		}
+		return true
+	})
+
+	var lines []string
+	for _, line := range strings.Split(text.String(), "\n") {
+		if line = strings.Join(strings.Fields(line), " "); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// walk calls visit for a node and its descendants, depth first; visit returns false to skip a node's children
+func walk(n *html.Node, visit func(n *html.Node) bool) {
+	if !visit(n) {
+		return
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		walk(c, visit)
+	}
+}
+
+// attr returns the value of an attribute, or an empty string
+func attr(n *html.Node, name string) string {
+	for _, a := range n.Attr {
+		if a.Key == name {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// hasAttr reports whether an element has an attribute, even an empty one like itemscope
+func hasAttr(n *html.Node, name string) bool {
+	for _, a := range n.Attr {
+		if a.Key == name {
+			return true
+		}
+	}
+	return false
+}
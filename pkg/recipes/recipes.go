@@ -0,0 +1,306 @@
+package recipes
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/korjavin/whatsfordinner/pkg/logger"
+	"github.com/korjavin/whatsfordinner/pkg/models"
+	"github.com/korjavin/whatsfordinner/pkg/storage"
+)
+
+var (
+	// ErrNotFound is returned when a recipe isn't in the family's book
+	ErrNotFound = errors.New("recipe not found")
+	// ErrExists is returned when adding a recipe whose name is already taken
+	ErrExists = errors.New("recipe already exists")
+	// ErrNotOwner is returned when someone other than the owner changes a recipe
+	ErrNotOwner = errors.New("only the owner can change this recipe")
+)
+
+// Service provides recipe book functionality
+type Service struct {
+	store  *storage.Store
+	logger *logger.Logger
+}
+
+// New creates a new recipe service
+func New(store *storage.Store) *Service {
+	return &Service{
+		store:  store,
+		logger: logger.New("recipes"),
+	}
+}
+
+// recipeKey returns the store key of a recipe; names are matched case-insensitively
+func recipeKey(channelID int64, name string) string {
+	return fmt.Sprintf("recipe:%d:%s", channelID, normalizeName(name))
+}
+
+// Get returns a recipe from a channel's book by name
+func (s *Service) Get(channelID int64, name string) (*models.Recipe, error) {
+	var recipe models.Recipe
+	err := s.store.Get(recipeKey(channelID, name), &recipe)
+	if err != nil {
+		return nil, ErrNotFound
+	}
+	return &recipe, nil
+}
+
+// List returns the recipes in a channel's book, sorted by name
+func (s *Service) List(channelID int64) ([]models.Recipe, error) {
+	keys, err := s.store.List(fmt.Sprintf("recipe:%d:", channelID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list recipes: %w", err)
+	}
+
+	recipes := make([]models.Recipe, 0, len(keys))
+	for _, key := range keys {
+		var recipe models.Recipe
+		if err := s.store.Get(key, &recipe); err != nil {
+			s.logger.Error("Failed to get recipe %s: %v", key, err)
+			continue
+		}
+		recipes = append(recipes, recipe)
+	}
+
+	sort.Slice(recipes, func(i, j int) bool {
+		return strings.ToLower(recipes[i].Dish.Name) < strings.ToLower(recipes[j].Dish.Name)
+	})
+
+	return recipes, nil
+}
+
+// Dishes returns the dishes in a channel's book
+func (s *Service) Dishes(channelID int64) ([]models.Dish, error) {
+	recipes, err := s.List(channelID)
+	if err != nil {
+		return nil, err
+	}
+
+	dishes := make([]models.Dish, len(recipes))
+	for i, recipe := range recipes {
+		dishes[i] = recipe.Dish
+	}
+	return dishes, nil
+}
+
+// Add adds a recipe to a channel's book, owned by the family member who adds it
+func (s *Service) Add(channelID int64, userID, username string, dish models.Dish) (*models.Recipe, error) {
+	dish.Name = strings.TrimSpace(dish.Name)
+	if dish.Name == "" {
+		return nil, fmt.Errorf("a recipe needs a name")
+	}
+	if _, err := s.Get(channelID, dish.Name); err == nil {
+		return nil, ErrExists
+	}
+
+	recipe := &models.Recipe{
+		ID:            recipeKey(channelID, dish.Name),
+		ChannelID:     channelID,
+		Dish:          dish,
+		OwnerID:       userID,
+		OwnerUsername: username,
+		CreatedAt:     time.Now(),
+		LastUpdated:   time.Now(),
+	}
+
+	s.logger.Info("Adding recipe %s by %s to channel %d", dish.Name, username, channelID)
+	if err := s.store.Set(recipe.ID, recipe); err != nil {
+		return nil, fmt.Errorf("failed to save recipe: %w", err)
+	}
+
+	return recipe, nil
+}
+
+// Update replaces the dish of a recipe in a channel's book; only the owner may do that
+// The recipe is found by the dish's name, so a recipe can't be renamed this way
+func (s *Service) Update(channelID int64, userID, username string, dish models.Dish) (*models.Recipe, error) {
+	recipe, err := s.Get(channelID, dish.Name)
+	if err != nil {
+		return nil, err
+	}
+	if recipe.OwnerID != userID {
+		return nil, ErrNotOwner
+	}
+
+	// Keep the spelling the recipe was added with
+	dish.Name = recipe.Dish.Name
+	recipe.Dish = dish
+	recipe.UpdatedBy = username
+	recipe.LastUpdated = time.Now()
+
+	s.logger.Info("Updating recipe %s by %s in channel %d", dish.Name, username, channelID)
+	if err := s.store.Set(recipe.ID, recipe); err != nil {
+		return nil, fmt.Errorf("failed to save recipe: %w", err)
+	}
+
+	return recipe, nil
+}
+
+// Delete removes a recipe from a channel's book and returns it; only the owner may do that
+func (s *Service) Delete(channelID int64, userID, name string) (*models.Recipe, error) {
+	recipe, err := s.Get(channelID, name)
+	if err != nil {
+		return nil, err
+	}
+	if recipe.OwnerID != userID {
+		return nil, ErrNotOwner
+	}
+
+	s.logger.Info("Deleting recipe %s from channel %d", recipe.Dish.Name, channelID)
+	if err := s.store.Delete(recipe.ID); err != nil {
+		return nil, fmt.Errorf("failed to delete recipe: %w", err)
+	}
+
+	return recipe, nil
+}
+
+// catalogKey returns the store key of a dish in the base catalog shared by all channels
+func catalogKey(dish models.Dish) string {
+	return fmt.Sprintf("dish:%s:%s", dish.Cuisine, dish.Name)
+}
+
+// Catalog returns the dishes in the base catalog shared by all channels
+func (s *Service) Catalog() ([]models.Dish, error) {
+	keys, err := s.store.List("dish:")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list dishes: %w", err)
+	}
+
+	dishes := make([]models.Dish, 0, len(keys))
+	for _, key := range keys {
+		var dish models.Dish
+		if err := s.store.Get(key, &dish); err != nil {
+			s.logger.Error("Failed to get dish %s: %v", key, err)
+			continue
+		}
+		dishes = append(dishes, dish)
+	}
+	return dishes, nil
+}
+
+// AddToCatalog saves a dish to the base catalog shared by all channels
+func (s *Service) AddToCatalog(dish models.Dish) error {
+	if err := s.store.Set(catalogKey(dish), dish); err != nil {
+		return fmt.Errorf("failed to save dish %s: %w", dish.Name, err)
+	}
+	return nil
+}
+
+// ImportResult lists the names of the dishes an import added and the ones it skipped as duplicates
+type ImportResult struct {
+	Added      []string
+	Duplicates []string
+}
+
+// Import adds dishes to a channel's book, owned by the family member who imports them
+// Dishes already in the book or in the base catalog are skipped as duplicates
+func (s *Service) Import(channelID int64, userID, username string, dishes []models.Dish) (ImportResult, error) {
+	known, err := s.knownNames(channelID)
+	if err != nil {
+		return ImportResult{}, err
+	}
+
+	return s.importDishes(dishes, known, func(dish models.Dish) error {
+		_, err := s.Add(channelID, userID, username, dish)
+		return err
+	})
+}
+
+// ImportToCatalog adds dishes to the base catalog, skipping dishes that are already in it
+func (s *Service) ImportToCatalog(dishes []models.Dish) (ImportResult, error) {
+	known, err := s.knownNames(0)
+	if err != nil {
+		return ImportResult{}, err
+	}
+
+	return s.importDishes(dishes, known, s.AddToCatalog)
+}
+
+// importDishes saves the dishes whose names aren't known yet, including names earlier in the same import
+func (s *Service) importDishes(dishes []models.Dish, known map[string]bool, save func(dish models.Dish) error) (ImportResult, error) {
+	var result ImportResult
+	for _, dish := range dishes {
+		dish.Name = strings.TrimSpace(dish.Name)
+		name := normalizeName(dish.Name)
+		if name == "" {
+			continue
+		}
+		if known[name] {
+			result.Duplicates = append(result.Duplicates, dish.Name)
+			continue
+		}
+
+		if err := save(dish); err != nil {
+			return result, err
+		}
+		known[name] = true
+		result.Added = append(result.Added, dish.Name)
+	}
+
+	s.logger.Info("Imported %d dishes, skipped %d duplicates", len(result.Added), len(result.Duplicates))
+	return result, nil
+}
+
+// knownNames returns the normalized names of the base catalog's dishes and, for a channel, its recipe book's
+func (s *Service) knownNames(channelID int64) (map[string]bool, error) {
+	dishes, err := s.Catalog()
+	if err != nil {
+		return nil, err
+	}
+	if channelID != 0 {
+		book, err := s.Dishes(channelID)
+		if err != nil {
+			return nil, err
+		}
+		dishes = append(dishes, book...)
+	}
+
+	known := make(map[string]bool, len(dishes))
+	for _, dish := range dishes {
+		known[normalizeName(dish.Name)] = true
+	}
+	return known, nil
+}
+
+// normalizeName returns how dish names are compared: lowercased, with single spaces
+func normalizeName(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
+
+// ParseIngredients splits typed ingredients into a list, one per line or separated by commas or semicolons
+// List bullets like "-" or "•" are dropped
+func ParseIngredients(text string) []string {
+	var ingredients []string
+	for _, item := range strings.FieldsFunc(text, func(r rune) bool {
+		return r == '\n' || r == ',' || r == ';'
+	}) {
+		item = strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(item), "-•*–"))
+		if item != "" {
+			ingredients = append(ingredients, item)
+		}
+	}
+	return ingredients
+}
+
+// ParseSteps splits typed instructions into steps, one per line
+// Numbering like "1." or "2)" is dropped, since steps are numbered when shown
+func ParseSteps(text string) []string {
+	var steps []string
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(line), "-•*–"))
+		if number := strings.IndexFunc(line, func(r rune) bool { return !unicode.IsDigit(r) }); number > 0 &&
+			(line[number] == '.' || line[number] == ')') {
+			line = strings.TrimSpace(line[number+1:])
+		}
+		if line != "" {
+			steps = append(steps, line)
+		}
+	}
+	return steps
+}
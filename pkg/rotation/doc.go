@@ -0,0 +1,6 @@
+// Package rotation provides a fair cook rotation for each family.
+// Members join the rotation when they vote or volunteer, and can leave and rejoin it.
+// Their cooking load is the number of dinners they cooked over a rolling window; whoever cooked least
+// is next in turn. The scheduler uses the order to nudge members who haven't cooked in a while,
+// to weigh fair picks among volunteers, and to assign the cook when nobody volunteers.
+package rotation
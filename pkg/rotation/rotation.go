@@ -0,0 +1,217 @@
+package rotation
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/korjavin/whatsfordinner/pkg/logger"
+	"github.com/korjavin/whatsfordinner/pkg/models"
+	"github.com/korjavin/whatsfordinner/pkg/poll"
+	"github.com/korjavin/whatsfordinner/pkg/storage"
+)
+
+// Window is how far back the cooking load is counted
+const Window = 28 * 24 * time.Hour
+
+// NudgeAfter is how long a member goes without cooking before they're reminded it's their turn
+const NudgeAfter = 7 * 24 * time.Hour
+
+// maxNudges is how many members are mentioned in one request for a cook
+const maxNudges = 2
+
+// Service keeps track of who takes turns cooking and how much each of them cooked lately
+type Service struct {
+	store  *storage.Store
+	logger *logger.Logger
+}
+
+// New creates a new rotation service
+func New(store *storage.Store) *Service {
+	return &Service{
+		store:  store,
+		logger: logger.New("rotation"),
+	}
+}
+
+// memberKey returns the store key of a member of a channel's rotation
+func memberKey(channelID int64, userID string) string {
+	return fmt.Sprintf("rotation:%d:%s", channelID, userID)
+}
+
+// Seen records that a family member voted or volunteered, adding them to the rotation the first time
+func (s *Service) Seen(channelID int64, userID, username string) error {
+	member := s.getOrNew(channelID, userID)
+	if username != "" {
+		member.Username = username
+	}
+	member.LastSeen = time.Now()
+
+	if err := s.store.Set(memberKey(channelID, userID), member); err != nil {
+		return fmt.Errorf("failed to save rotation member: %w", err)
+	}
+	return nil
+}
+
+// SetPaused takes a member out of the rotation, or puts them back in
+// Members who aren't in the rotation yet are added
+func (s *Service) SetPaused(channelID int64, userID, username string, paused bool) error {
+	member := s.getOrNew(channelID, userID)
+	if username != "" {
+		member.Username = username
+	}
+	member.Paused = paused
+
+	if err := s.store.Set(memberKey(channelID, userID), member); err != nil {
+		return fmt.Errorf("failed to save rotation member: %w", err)
+	}
+
+	s.logger.Info("Set paused of %s in the rotation of channel %d to %v", userID, channelID, paused)
+	return nil
+}
+
+// getOrNew returns a member of a channel's rotation, or a new member who joins now
+func (s *Service) getOrNew(channelID int64, userID string) models.RotationMember {
+	var member models.RotationMember
+	if err := s.store.Get(memberKey(channelID, userID), &member); err != nil {
+		member = models.RotationMember{
+			ChannelID: channelID,
+			UserID:    userID,
+			JoinedAt:  time.Now(),
+		}
+	}
+	return member
+}
+
+// Members returns everyone in a channel's rotation, paused members too
+func (s *Service) Members(channelID int64) ([]models.RotationMember, error) {
+	keys, err := s.store.List(fmt.Sprintf("rotation:%d:", channelID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list rotation members: %w", err)
+	}
+
+	members := make([]models.RotationMember, 0, len(keys))
+	for _, key := range keys {
+		var member models.RotationMember
+		if err := s.store.Get(key, &member); err != nil {
+			s.logger.Error("Failed to get rotation member %s: %v", key, err)
+			continue
+		}
+		members = append(members, member)
+	}
+	return members, nil
+}
+
+// Turn is a member's place in the rotation
+type Turn struct {
+	Member     models.RotationMember
+	Cooked     int       // Dinners cooked within the window
+	LastCooked time.Time // Zero if they never cooked
+}
+
+// Order returns the members taking turns, whose turn it is first
+// Whoever cooked the fewest dinners within the window goes first; ties go to whoever cooked longest ago,
+// then to whoever joined first. Paused members are left out
+func (s *Service) Order(channelID int64, now time.Time) ([]Turn, error) {
+	members, err := s.Members(channelID)
+	if err != nil {
+		return nil, err
+	}
+
+	loads, lastCooked, err := s.history(channelID, now)
+	if err != nil {
+		return nil, err
+	}
+
+	turns := make([]Turn, 0, len(members))
+	for _, member := range members {
+		if member.Paused {
+			continue
+		}
+		turns = append(turns, Turn{
+			Member:     member,
+			Cooked:     loads[member.UserID],
+			LastCooked: lastCooked[member.UserID],
+		})
+	}
+
+	sort.Slice(turns, func(i, j int) bool {
+		a, b := turns[i], turns[j]
+		if a.Cooked != b.Cooked {
+			return a.Cooked < b.Cooked
+		}
+		if !a.LastCooked.Equal(b.LastCooked) {
+			return a.LastCooked.Before(b.LastCooked)
+		}
+		if !a.Member.JoinedAt.Equal(b.Member.JoinedAt) {
+			return a.Member.JoinedAt.Before(b.Member.JoinedAt)
+		}
+		return a.Member.UserID < b.Member.UserID
+	})
+
+	return turns, nil
+}
+
+// Loads returns how many dinners each cook made within the window, by user ID
+func (s *Service) Loads(channelID int64, now time.Time) (map[string]int, error) {
+	loads, _, err := s.history(channelID, now)
+	return loads, err
+}
+
+// history counts the dinners each cook made within the window, and when each of them last cooked at all
+func (s *Service) history(channelID int64, now time.Time) (map[string]int, map[string]time.Time, error) {
+	dinnerKeys, err := s.store.List(fmt.Sprintf("dinner:%d:", channelID))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list dinners: %w", err)
+	}
+
+	loads := make(map[string]int)
+	lastCooked := make(map[string]time.Time)
+	since := now.Add(-Window)
+	for _, key := range dinnerKeys {
+		var dinner models.Dinner
+		if err := s.store.Get(key, &dinner); err != nil {
+			s.logger.Error("Failed to get dinner %s: %v", key, err)
+			continue
+		}
+		if dinner.Cook == "" {
+			continue
+		}
+
+		if dinner.StartedAt.After(since) {
+			loads[dinner.Cook]++
+		}
+		if dinner.StartedAt.After(lastCooked[dinner.Cook]) {
+			lastCooked[dinner.Cook] = dinner.StartedAt
+		}
+	}
+
+	return loads, lastCooked, nil
+}
+
+// Eligible returns the turns of the members who may cook a vote's winning dish, in turn order:
+// those who voted for it, or everyone when nobody voted, as for a dinner from the week plan
+func Eligible(turns []Turn, vote *models.VoteState) []Turn {
+	var eligible []Turn
+	for _, turn := range turns {
+		if poll.IsSupporter(vote, turn.Member.UserID) {
+			eligible = append(eligible, turn)
+		}
+	}
+	return eligible
+}
+
+// Nudges returns who to remind that it's their turn: the first members in turn order who haven't cooked for NudgeAfter
+func Nudges(turns []Turn, now time.Time) []Turn {
+	var nudges []Turn
+	for _, turn := range turns {
+		if !turn.LastCooked.IsZero() && now.Sub(turn.LastCooked) < NudgeAfter {
+			continue
+		}
+		nudges = append(nudges, turn)
+		if len(nudges) == maxNudges {
+			break
+		}
+	}
+	return nudges
+}
@@ -0,0 +1,210 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"github.com/korjavin/whatsfordinner/pkg/attendance"
+	"github.com/korjavin/whatsfordinner/pkg/models"
+	"github.com/korjavin/whatsfordinner/pkg/rotation"
+	"github.com/korjavin/whatsfordinner/pkg/settings"
+	"github.com/korjavin/whatsfordinner/pkg/workflow"
+)
+
+// askAttendance asks the family who's eating at home before a meal poll, and reports whether the poll waits for the answers
+// It doesn't ask when the channel skips the step or the family already answered for this meal today
+func (s *Service) askAttendance(channelID int64, meal string, channelSettings settings.Settings) bool {
+	if channelSettings.RSVPDeadline == 0 {
+		return false
+	}
+	if current := s.attendanceService.Get(channelID); current != nil && current.Meal == meal {
+		return false
+	}
+
+	now := channelSettings.Now()
+	deadline := now.Add(channelSettings.RSVPDeadline)
+	expiresAt := channelSettings.CutoffTime.On(now)
+	if !expiresAt.After(deadline) {
+		expiresAt = settings.StartOfDay(now).AddDate(0, 0, 1)
+	}
+
+	asked := &models.Attendance{Meal: meal, Deadline: deadline}
+	msg, err := s.bot.SendMessageWithKeyboard(channelID, AttendanceMessage(asked, channelSettings.Location), AttendanceKeyboard())
+	if err != nil {
+		s.logger.Error("Failed to ask who's eating at home: %v", err)
+		return false
+	}
+
+	if _, err := s.attendanceService.Start(channelID, meal, msg.MessageID, deadline, expiresAt); err != nil {
+		s.logger.Error("Failed to record who's asked to eat at home: %v", err)
+		return false
+	}
+	return true
+}
+
+// runAttendanceChecker starts the polls whose RSVP deadline has passed
+func (s *Service) runAttendanceChecker() {
+	s.logger.Info("Starting attendance checker")
+
+	ticker := time.NewTicker(cookCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.finishDueAttendance(time.Now())
+		case <-s.stopChan:
+			return
+		}
+	}
+}
+
+// finishDueAttendance counts the answers in every channel whose RSVP deadline has passed
+// The answers are kept in storage, so the poll still starts after a restart
+func (s *Service) finishDueAttendance(now time.Time) {
+	channelKeys, err := s.store.List("channel:")
+	if err != nil {
+		s.logger.Error("Failed to list channels: %v", err)
+		return
+	}
+
+	for _, channelKey := range channelKeys {
+		var channelState models.ChannelState
+		if err := s.store.Get(channelKey, &channelState); err != nil {
+			s.logger.Error("Failed to get channel state: %v", err)
+			continue
+		}
+
+		current := attendance.Current(channelState, now)
+		if attendance.IsOpen(current) && !now.Before(current.Deadline) {
+			s.FinishAttendance(channelState.ChannelID)
+		}
+	}
+}
+
+// FinishAttendance counts who's eating at home and starts the meal poll, or skips it when nobody is
+// It runs at the RSVP deadline, or as soon as every family member answered
+func (s *Service) FinishAttendance(channelID int64) {
+	current, err := s.attendanceService.Close(channelID)
+	if err != nil {
+		if !errors.Is(err, attendance.ErrClosed) && !errors.Is(err, attendance.ErrNotAsked) {
+			s.logger.Error("Failed to close attendance of channel %d: %v", channelID, err)
+		}
+		return
+	}
+
+	channelSettings, err := s.settingsService.Get(channelID)
+	if err != nil {
+		s.logger.Error("Failed to get settings for channel %d: %v", channelID, err)
+	}
+
+	// Members can still say they're away, so the buttons stay
+	if _, err := s.bot.EditMessage(channelID, current.MessageID, AttendanceMessage(current, channelSettings.Location), AttendanceKeyboard()); err != nil {
+		s.logger.Error("Failed to update the attendance message: %v", err)
+	}
+
+	if attendance.NobodyHome(current, s.memberCount(channelID)) {
+		s.bot.SendMessage(channelID, fmt.Sprintf("🏠 Nobody's eating at home, so there's no %s poll today.", current.Meal))
+		s.advanceWorkflow(channelID, workflow.Cancelled, "nobody's eating at home")
+		return
+	}
+
+	// The family may have started a poll with /dinner meanwhile
+	if vote, err := s.pollService.GetCurrentVote(channelID); err == nil && vote != nil && vote.EndedAt.IsZero() {
+		s.logger.Info("Not starting a %s poll for channel %d, one is already running", current.Meal, channelID)
+		return
+	}
+
+	s.startDinnerWorkflow(channelID, current.Meal)
+}
+
+// closeAttendance stops asking who's eating at home when the poll never started, e.g. at the cutoff
+func (s *Service) closeAttendance(channelID int64, text string) {
+	current, err := s.attendanceService.Close(channelID)
+	if err != nil {
+		if !errors.Is(err, attendance.ErrClosed) && !errors.Is(err, attendance.ErrNotAsked) {
+			s.logger.Error("Failed to close attendance of channel %d: %v", channelID, err)
+		}
+		return
+	}
+
+	if _, err := s.bot.EditMessage(channelID, current.MessageID, text); err != nil {
+		s.logger.Error("Failed to close the attendance message: %v", err)
+	}
+}
+
+// memberCount returns how many family members a channel has, not counting the bot
+func (s *Service) memberCount(channelID int64) int {
+	count, err := s.bot.GetChatMemberCount(channelID)
+	if err == nil {
+		return count - 1
+	}
+	s.logger.Error("Failed to get chat member count: %v", err)
+
+	var channelState models.ChannelState
+	if err := s.store.Get(fmt.Sprintf("channel:%d", channelID), &channelState); err != nil {
+		return 0
+	}
+	return channelState.MemberCount
+}
+
+// AttendanceMessage returns the "Eating at home tonight?" message with the answers so far
+func AttendanceMessage(current *models.Attendance, location *time.Location) string {
+	question := "🏠 Eating at home tonight?"
+	if current.Meal != DefaultMeal {
+		question = fmt.Sprintf("🏠 Eating at home for %s?", current.Meal)
+	}
+
+	var text string
+	if current.ClosedAt.IsZero() {
+		text = fmt.Sprintf("%s I'll start the %s poll at %s with whoever's in.\n", question, current.Meal, current.Deadline.In(location).Format("15:04"))
+	} else {
+		text = fmt.Sprintf("%s The %s poll has started; you can still say if you're away.\n", question, current.Meal)
+	}
+
+	if len(current.Attending) > 0 {
+		text += fmt.Sprintf("\n🏠 At home (%d): %s", len(current.Attending), formatUsernames(current.Attending))
+	}
+	if len(current.Away) > 0 {
+		text += fmt.Sprintf("\n🚶 Away (%d): %s", len(current.Away), formatUsernames(current.Away))
+	}
+	return text
+}
+
+// AttendanceKeyboard returns the buttons to say whether you're eating at home
+func AttendanceKeyboard() tgbotapi.InlineKeyboardMarkup {
+	return tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("🏠 I'm in", "rsvp:in"),
+			tgbotapi.NewInlineKeyboardButtonData("🚶 Not tonight", "rsvp:out"),
+		),
+	)
+}
+
+// formatUsernames formats the usernames of members, sorted, e.g. "@anna, @boris"
+func formatUsernames(members map[string]string) string {
+	usernames := make([]string, 0, len(members))
+	for _, username := range members {
+		usernames = append(usernames, "@"+username)
+	}
+	sort.Strings(usernames)
+	return strings.Join(usernames, ", ")
+}
+
+// atHome leaves out the turns of members who said they aren't eating at home
+func (s *Service) atHome(channelID int64, turns []rotation.Turn) []rotation.Turn {
+	current := s.attendanceService.Get(channelID)
+
+	var present []rotation.Turn
+	for _, turn := range turns {
+		if !attendance.IsAway(current, turn.Member.UserID) {
+			present = append(present, turn)
+		}
+	}
+	return present
+}
@@ -0,0 +1,275 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/korjavin/whatsfordinner/pkg/models"
+	"github.com/korjavin/whatsfordinner/pkg/settings"
+)
+
+// idleSleep is how long the scheduler sleeps when no channel has a job coming up;
+// it's woken earlier as soon as a channel appears or changes its schedule
+const idleSleep = 24 * time.Hour
+
+// expiryAlertTime is when the daily "use soon" list is posted, in each channel's timezone
+var expiryAlertTime = settings.Clock{Hour: 10}
+
+// dailyJob is something the scheduler runs at a time of day in a channel's own timezone
+type dailyJob struct {
+	name string
+	at   settings.Clock
+	// deadline returns the latest moment a missed run may still be caught up, e.g. after a restart
+	deadline func(fireAt time.Time) time.Time
+	run      func(channelState models.ChannelState, now time.Time)
+}
+
+// dailyJobs returns the jobs the scheduler runs in a channel on a day with the given meal polls
+func (s *Service) dailyJobs(channelSettings settings.Settings, meals []Meal) []dailyJob {
+	jobs := []dailyJob{
+		{
+			// Post the "use soon" list in the morning, before the family starts thinking about food
+			name: "expiry_alert",
+			at:   expiryAlertTime,
+			deadline: func(fireAt time.Time) time.Time {
+				return channelSettings.DinnerTime.On(fireAt)
+			},
+			run: func(channelState models.ChannelState, now time.Time) {
+				s.sendExpiryAlert(channelState.ChannelID, now)
+			},
+		},
+		{
+			// Close whatever is still open in the evening
+			name: "cutoff",
+			at:   channelSettings.CutoffTime,
+			deadline: func(fireAt time.Time) time.Time {
+				return settings.StartOfDay(fireAt).AddDate(0, 0, 1)
+			},
+			run: func(channelState models.ChannelState, now time.Time) {
+				if s.hasUnfinishedDinnerWorkflow(channelState) {
+					s.logger.Info("Stopping unfinished dinner workflow for channel %d", channelState.ChannelID)
+					s.endDinner(channelState.ChannelID, "It's getting late!", "cutoff")
+				}
+			},
+		},
+	}
+
+	for i, meal := range meals {
+		var previous *Meal
+		if i > 0 {
+			previous = &meals[i-1]
+		}
+		var next *Meal
+		if i < len(meals)-1 {
+			next = &meals[i+1]
+		}
+
+		jobs = append(jobs, dailyJob{
+			// Start the meal poll, unless the family already started one for this meal
+			name: meal.Name + "_poll",
+			at:   meal.At,
+			deadline: func(fireAt time.Time) time.Time {
+				// A missed poll isn't worth starting once the next meal is due
+				deadline := channelSettings.CutoffTime.On(fireAt)
+				if next != nil && next.At.On(fireAt).Before(deadline) {
+					deadline = next.At.On(fireAt)
+				}
+				return deadline
+			},
+			run: func(channelState models.ChannelState, now time.Time) {
+				// A poll started closer to this meal than to the previous one counts as this meal's poll
+				since := settings.StartOfDay(now)
+				if previous != nil {
+					previousAt, at := previous.At.On(now), meal.At.On(now)
+					since = previousAt.Add(at.Sub(previousAt) / 2)
+				}
+
+				if s.hasMealStartedSince(channelState, since) {
+					return
+				}
+
+				// Dinners planned with /week are announced rather than polled
+				if meal.Name == DefaultMeal {
+					if day := s.plannerService.PlannedFor(channelState.ChannelID, now); day != nil {
+						s.announcePlannedDinner(channelState.ChannelID, *day)
+						return
+					}
+				}
+
+				s.logger.Info("Starting %s workflow for channel %d", meal.Name, channelState.ChannelID)
+				s.startDinnerWorkflow(channelState.ChannelID, meal.Name)
+			},
+		})
+	}
+
+	return jobs
+}
+
+// runDailyJobs sleeps until the next daily job is due in any channel and runs it
+// A channel that's new or changed its settings or schedule wakes it early, so it sleeps until its new next job
+// Each run is recorded, so a job is neither run twice a day nor lost when the bot was down at fire time
+func (s *Service) runDailyJobs() {
+	s.logger.Info("Starting daily jobs")
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-timer.C:
+		case <-s.wake:
+		case <-s.stopChan:
+			return
+		}
+
+		next := s.runDueJobs(time.Now())
+		wait := idleSleep
+		if !next.IsZero() {
+			wait = max(time.Until(next), time.Second)
+		}
+		timer.Reset(wait)
+	}
+}
+
+// Reschedule wakes the daily jobs to work out when the next one is due, e.g. after a channel's settings changed
+func (s *Service) Reschedule() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// watchSchedules reschedules the daily jobs whenever a channel appears or changes a setting the jobs depend on,
+// or its schedule changes
+func (s *Service) watchSchedules() {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-s.stopChan
+		cancel()
+	}()
+
+	// Most changes to a channel's state are votes and dinners, which don't move its jobs
+	timings := make(map[string]string)
+	err := s.store.Watch(ctx, func(key string, value []byte) {
+		if strings.HasPrefix(key, "schedule:") {
+			s.Reschedule()
+			return
+		}
+
+		var channelState models.ChannelState
+		if len(value) > 0 {
+			if err := json.Unmarshal(value, &channelState); err != nil {
+				s.logger.Error("Failed to decode changed %s: %v", key, err)
+				return
+			}
+		}
+		timing := strings.Join([]string{channelState.DinnerTime, channelState.CutoffTime, channelState.Timezone}, "|")
+		if known, ok := timings[key]; !ok || known != timing {
+			timings[key] = timing
+			s.Reschedule()
+		}
+	}, "channel:", "schedule:")
+	if err != nil {
+		s.logger.Error("Stopped watching channels for schedule changes: %v", err)
+	}
+}
+
+// runDueJobs runs every daily job whose fire time has passed and returns when the next one is due,
+// or the zero time if no channel has one
+func (s *Service) runDueJobs(now time.Time) time.Time {
+	var next time.Time
+
+	// Get all channels
+	channelKeys, err := s.store.List("channel:")
+	if err != nil {
+		s.logger.Error("Failed to list channels: %v", err)
+		return next
+	}
+
+	for _, channelKey := range channelKeys {
+		var channelState models.ChannelState
+		err := s.store.Get(channelKey, &channelState)
+		if err != nil {
+			s.logger.Error("Failed to get channel state: %v", err)
+			continue
+		}
+
+		schedule, err := s.GetSchedule(channelState.ChannelID)
+		if err != nil {
+			s.logger.Error("Failed to get schedule for channel %d: %v", channelState.ChannelID, err)
+			continue
+		}
+
+		channelSettings := s.settingsService.Resolve(channelState)
+		local := now.In(channelSettings.Location)
+		meals := MealsOn(schedule, local, channelSettings.DinnerTime)
+
+		// Jobs already past today are due again tomorrow at the earliest, on tomorrow's schedule
+		tomorrow := local.AddDate(0, 0, 1)
+		for _, job := range s.dailyJobs(channelSettings, MealsOn(schedule, tomorrow, channelSettings.DinnerTime)) {
+			if fireAt := job.at.On(tomorrow); next.IsZero() || fireAt.Before(next) {
+				next = fireAt
+			}
+		}
+
+		for _, job := range s.dailyJobs(channelSettings, meals) {
+			fireAt := job.at.On(local)
+
+			// Jobs later today wake the scheduler early
+			if fireAt.After(local) {
+				if next.IsZero() || fireAt.Before(next) {
+					next = fireAt
+				}
+				continue
+			}
+
+			if s.isDue(channelState.ChannelID, job, fireAt, local) {
+				s.logger.Info("Running %s for channel %d (due at %s)", job.name, channelState.ChannelID, fireAt.Format("2006-01-02 15:04 MST"))
+				s.recordRun(channelState.ChannelID, job.name, now)
+				job.run(channelState, local)
+			}
+		}
+	}
+
+	return next
+}
+
+// isDue reports whether a job that should have fired at fireAt still has to run
+func (s *Service) isDue(channelID int64, job dailyJob, fireAt, now time.Time) bool {
+	if !s.lastRun(channelID, job.name).Before(fireAt) {
+		return false
+	}
+
+	// A deadline before the fire time (e.g. a cutoff earlier than dinner) means "until the end of the day"
+	deadline := job.deadline(fireAt)
+	if !deadline.After(fireAt) {
+		deadline = settings.StartOfDay(fireAt).AddDate(0, 0, 1)
+	}
+
+	if !now.Before(deadline) {
+		s.logger.Info("Skipping %s for channel %d, it's past %s", job.name, channelID, deadline.Format("15:04"))
+		s.recordRun(channelID, job.name, now)
+		return false
+	}
+
+	return true
+}
+
+// lastRun returns when a daily job last ran in a channel, or the zero time if never
+func (s *Service) lastRun(channelID int64, jobName string) time.Time {
+	var lastRun time.Time
+	if err := s.store.Get(fmt.Sprintf("schedule_run:%d:%s", channelID, jobName), &lastRun); err != nil {
+		return time.Time{}
+	}
+	return lastRun
+}
+
+// recordRun remembers that a daily job ran in a channel
+func (s *Service) recordRun(channelID int64, jobName string, at time.Time) {
+	if err := s.store.Set(fmt.Sprintf("schedule_run:%d:%s", channelID, jobName), at); err != nil {
+		s.logger.Error("Failed to record run of %s for channel %d: %v", jobName, channelID, err)
+	}
+}
@@ -1,4 +1,17 @@
 // Package scheduler provides scheduling functionality for dinner workflows.
-// It handles starting dinner workflows at specific times, stopping unfinished workflows,
-// and managing timeouts for cook volunteers.
+// It handles starting meal polls according to each channel's schedule rules, in its voting mode (Telegram polls or ranked ballots), stopping unfinished workflows at its cutoff time,
+// asking who's eating at home before a poll and starting it at the RSVP deadline,
+// following up on requests for a cook (picking one of the volunteers, asking again, calling dinner off),
+// pinging cooks when their cooking timers fire,
+// posting daily "use soon" alerts for expiring fridge items,
+// and moving each dinner's workflow along, timing out workflows left behind in a state.
+// Schedule rules pick the weekdays, times and meals polls start at, and skip rules or one-off skip dates leave days out;
+// without rules there's a dinner poll every day at the channel's dinner time.
+// On days with a dinner from a saved week plan, the planned dish is announced instead of starting a poll.
+// Daily jobs fire at their next due time in each channel's own timezone, so DST changes are handled,
+// and a job missed while the bot was down is caught up as long as it's still relevant.
+// Everything in flight is kept in storage: at startup, before any update is handled, the running polls are
+// mapped to their channels again and the follow-ups that came due while the bot was down run at once.
+// Between jobs the scheduler sleeps until the next one is due in any channel, and is woken early when
+// a channel appears or changes its times, timezone or schedule.
 package scheduler
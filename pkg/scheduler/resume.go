@@ -0,0 +1,61 @@
+package scheduler
+
+import (
+	"errors"
+	"time"
+
+	"github.com/korjavin/whatsfordinner/pkg/models"
+	"github.com/korjavin/whatsfordinner/pkg/workflow"
+)
+
+// Resume picks up the dinners that were in flight when the bot stopped; it runs before Start, so before any update
+// Running polls are mapped to their channels again, so their answers still reach the family's vote, and the
+// workflow timeouts, RSVP deadlines, cook requests and cooking timers that came due while the bot was down
+// are followed up at once rather than at their checkers' next tick
+// It returns the workflows of the dinners still in flight after that, one per channel
+func (s *Service) Resume(now time.Time) []models.Workflow {
+	s.logger.Info("Resuming the dinners in flight")
+
+	channelKeys, err := s.store.List("channel:")
+	if err != nil {
+		s.logger.Error("Failed to list channels: %v", err)
+		return nil
+	}
+
+	var channelIDs []int64
+	for _, channelKey := range channelKeys {
+		var channelState models.ChannelState
+		if err := s.store.Get(channelKey, &channelState); err != nil {
+			s.logger.Error("Failed to get channel state: %v", err)
+			continue
+		}
+		channelIDs = append(channelIDs, channelState.ChannelID)
+
+		if current := channelState.CurrentVote; current != nil && current.EndedAt.IsZero() {
+			if err := s.pollService.MapPoll(channelState.ChannelID, current.PollID); err != nil {
+				s.logger.Error("Failed to resume vote %s of channel %d: %v", current.PollID, channelState.ChannelID, err)
+			}
+		}
+	}
+
+	// Dinners left behind too long end first, so they aren't followed up
+	s.expireWorkflows(now)
+	s.finishDueAttendance(now)
+	s.followUpCookRequests(now)
+	s.ringDueTimers(now)
+
+	var inFlight []models.Workflow
+	for _, channelID := range channelIDs {
+		current, err := s.workflowService.Current(channelID)
+		if err != nil {
+			if !errors.Is(err, workflow.ErrNoWorkflow) {
+				s.logger.Error("Failed to get the workflow of channel %d: %v", channelID, err)
+			}
+			continue
+		}
+		if !workflow.IsEnded(current) {
+			inFlight = append(inFlight, *current)
+		}
+	}
+	return inFlight
+}
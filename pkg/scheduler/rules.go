@@ -0,0 +1,338 @@
+package scheduler
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/korjavin/whatsfordinner/pkg/models"
+	"github.com/korjavin/whatsfordinner/pkg/settings"
+)
+
+// DefaultMeal is the meal a rule polls for when it doesn't name one
+const DefaultMeal = "dinner"
+
+// skipDateLayout is how one-off skip dates are stored
+const skipDateLayout = "2006-01-02"
+
+// Meal is a poll the scheduler starts on a given day
+type Meal struct {
+	Name string
+	At   settings.Clock
+}
+
+// DayPlan is what the schedule says about one day
+type DayPlan struct {
+	Date    time.Time
+	Meals   []Meal
+	Skipped bool
+}
+
+// weekdayNames maps the day names users type to weekdays
+var weekdayNames = map[string]time.Weekday{
+	"mon": time.Monday, "monday": time.Monday, "пн": time.Monday, "понедельник": time.Monday,
+	"tue": time.Tuesday, "tues": time.Tuesday, "tuesday": time.Tuesday, "вт": time.Tuesday, "вторник": time.Tuesday,
+	"wed": time.Wednesday, "wednesday": time.Wednesday, "ср": time.Wednesday, "среда": time.Wednesday,
+	"thu": time.Thursday, "thur": time.Thursday, "thurs": time.Thursday, "thursday": time.Thursday, "чт": time.Thursday, "четверг": time.Thursday,
+	"fri": time.Friday, "friday": time.Friday, "пт": time.Friday, "пятница": time.Friday,
+	"sat": time.Saturday, "saturday": time.Saturday, "сб": time.Saturday, "суббота": time.Saturday,
+	"sun": time.Sunday, "sunday": time.Sunday, "вс": time.Sunday, "воскресенье": time.Sunday,
+}
+
+// weekdayGroups maps words for several days at once to weekdays, nil meaning every day
+var weekdayGroups = map[string][]time.Weekday{
+	"daily": nil, "everyday": nil, "ежедневно": nil,
+	"weekdays": {time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday},
+	"workdays": {time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday},
+	"будни":    {time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday},
+	"weekends": {time.Saturday, time.Sunday},
+	"weekend":  {time.Saturday, time.Sunday},
+	"выходные": {time.Saturday, time.Sunday},
+}
+
+// skipWords mark a rule as a day without polls
+var skipWords = map[string]bool{"skip": true, "off": true, "none": true, "пропуск": true}
+
+// ParseRule parses a schedule rule as typed by a user
+// Examples: "Mon-Fri 16:30", "weekends 12:00 brunch", "Fri skip", "18:00" (every day)
+func ParseRule(text string) (models.ScheduleRule, error) {
+	text = strings.ToLower(strings.TrimSpace(text))
+	text = strings.NewReplacer("–", "-", "—", "-", ",", " ", " - ", "-", "every day", "daily").Replace(text)
+	words := strings.Fields(text)
+	if len(words) == 0 {
+		return models.ScheduleRule{}, fmt.Errorf("empty rule")
+	}
+
+	// Leading words are days and an optional "skip"
+	var rule models.ScheduleRule
+	daysGiven, everyDay := false, false
+	i := 0
+	for ; i < len(words); i++ {
+		if skipWords[words[i]] {
+			rule.Skip = true
+			continue
+		}
+		days, ok := parseDays(words[i])
+		if !ok {
+			break
+		}
+		daysGiven = true
+		everyDay = everyDay || days == nil
+		rule.Days = append(rule.Days, days...)
+	}
+	if everyDay {
+		rule.Days = nil
+	}
+	rule.Days = normalizeDays(rule.Days)
+
+	rest := words[i:]
+	if len(rest) > 0 && skipWords[rest[len(rest)-1]] {
+		rule.Skip = true
+		rest = rest[:len(rest)-1]
+	}
+
+	if rule.Skip {
+		if len(rest) > 0 {
+			return models.ScheduleRule{}, fmt.Errorf("a skip rule only takes days, got %q", strings.Join(rest, " "))
+		}
+		if !daysGiven {
+			return models.ScheduleRule{}, fmt.Errorf("which days should be skipped?")
+		}
+		return rule, nil
+	}
+
+	if len(rest) == 0 {
+		return models.ScheduleRule{}, fmt.Errorf("missing time, e.g. 16:30")
+	}
+	clock, err := settings.ParseClock(rest[0])
+	if err != nil {
+		return models.ScheduleRule{}, err
+	}
+	rule.Time = clock.String()
+
+	rule.Meal = strings.Join(rest[1:], " ")
+	if rule.Meal == "" {
+		rule.Meal = DefaultMeal
+	}
+
+	return rule, nil
+}
+
+// parseDays parses a single day token: a day name, a group like "weekends" or a range like "mon-fri"
+// A nil slice with ok set means every day
+func parseDays(token string) ([]time.Weekday, bool) {
+	if days, ok := weekdayGroups[token]; ok {
+		return days, true
+	}
+	if day, ok := parseWeekday(token); ok {
+		return []time.Weekday{day}, true
+	}
+
+	from, to, found := strings.Cut(token, "-")
+	if !found {
+		return nil, false
+	}
+	first, ok := parseWeekday(from)
+	if !ok {
+		return nil, false
+	}
+	last, ok := parseWeekday(to)
+	if !ok {
+		return nil, false
+	}
+
+	// Ranges may wrap around the week, e.g. "fri-mon"
+	var days []time.Weekday
+	for day := first; ; day = (day + 1) % 7 {
+		days = append(days, day)
+		if day == last {
+			break
+		}
+	}
+	return days, true
+}
+
+// parseWeekday parses a day name, also in the plural, e.g. "skip fridays"
+func parseWeekday(name string) (time.Weekday, bool) {
+	if day, ok := weekdayNames[name]; ok {
+		return day, true
+	}
+	day, ok := weekdayNames[strings.TrimSuffix(name, "s")]
+	return day, ok
+}
+
+// normalizeDays sorts days Monday first and removes duplicates
+func normalizeDays(days []time.Weekday) []time.Weekday {
+	if len(days) == 0 {
+		return nil
+	}
+
+	seen := make(map[time.Weekday]bool)
+	result := make([]time.Weekday, 0, len(days))
+	for _, day := range days {
+		if !seen[day] {
+			seen[day] = true
+			result = append(result, day)
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return mondayFirst(result[i]) < mondayFirst(result[j])
+	})
+
+	if len(result) == 7 {
+		return nil
+	}
+	return result
+}
+
+// mondayFirst returns the position of a day in a week starting on Monday
+func mondayFirst(day time.Weekday) int {
+	return (int(day) + 6) % 7
+}
+
+// appliesTo reports whether a rule applies to a weekday
+func appliesTo(rule models.ScheduleRule, day time.Weekday) bool {
+	if len(rule.Days) == 0 {
+		return true
+	}
+	for _, ruleDay := range rule.Days {
+		if ruleDay == day {
+			return true
+		}
+	}
+	return false
+}
+
+// IsSkipped reports whether the schedule has no polls on a day, because of a skip rule or a one-off skip date
+func IsSkipped(schedule models.Schedule, day time.Time) bool {
+	date := day.Format(skipDateLayout)
+	for _, skipDate := range schedule.SkipDates {
+		if skipDate == date {
+			return true
+		}
+	}
+
+	for _, rule := range schedule.Rules {
+		if rule.Skip && appliesTo(rule, day.Weekday()) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// MealsOn returns the meal polls the schedule has on a day, earliest first
+// Without any meal rules there's a single dinner poll at the fallback time
+func MealsOn(schedule models.Schedule, day time.Time, fallback settings.Clock) []Meal {
+	if IsSkipped(schedule, day) {
+		return nil
+	}
+
+	var meals []Meal
+	hasMealRules := false
+	for _, rule := range schedule.Rules {
+		if rule.Skip {
+			continue
+		}
+		hasMealRules = true
+		if !appliesTo(rule, day.Weekday()) {
+			continue
+		}
+		clock, err := settings.ParseClock(rule.Time)
+		if err != nil {
+			continue
+		}
+		meals = append(meals, Meal{Name: rule.Meal, At: clock})
+	}
+
+	if !hasMealRules {
+		return []Meal{{Name: DefaultMeal, At: fallback}}
+	}
+
+	sort.SliceStable(meals, func(i, j int) bool {
+		return meals[i].At.Hour*60+meals[i].At.Minute < meals[j].At.Hour*60+meals[j].At.Minute
+	})
+
+	return meals
+}
+
+// FormatRule formats a rule for display, e.g. "Mon–Fri 16:30 dinner"
+func FormatRule(rule models.ScheduleRule) string {
+	days := FormatDays(rule.Days)
+	if rule.Skip {
+		return days + ": no polls"
+	}
+	return fmt.Sprintf("%s %s %s", days, rule.Time, rule.Meal)
+}
+
+// FormatDays formats weekdays compactly, e.g. "Every day", "Weekends", "Mon–Fri" or "Mon, Wed"
+func FormatDays(days []time.Weekday) string {
+	days = normalizeDays(days)
+	if len(days) == 0 {
+		return "Every day"
+	}
+	if len(days) == 2 && days[0] == time.Saturday && days[1] == time.Sunday {
+		return "Weekends"
+	}
+
+	// A run of consecutive days reads better as a range
+	consecutive := len(days) >= 3
+	for i := 1; i < len(days) && consecutive; i++ {
+		consecutive = mondayFirst(days[i]) == mondayFirst(days[i-1])+1
+	}
+	if consecutive {
+		return shortDay(days[0]) + "–" + shortDay(days[len(days)-1])
+	}
+
+	names := make([]string, len(days))
+	for i, day := range days {
+		names[i] = shortDay(day)
+	}
+	return strings.Join(names, ", ")
+}
+
+// shortDay returns the three-letter name of a weekday
+func shortDay(day time.Weekday) string {
+	return day.String()[:3]
+}
+
+// ParseSkipDate parses the day a user wants to skip, relative to now
+// Supported forms: "today", "tomorrow", a weekday name (its next occurrence, today included),
+// "2025-10-20", "20.10" and "20.10.2025"
+// The result is the start of that day in now's location
+func ParseSkipDate(s string, now time.Time) (time.Time, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	today := settings.StartOfDay(now)
+
+	switch s {
+	case "today", "сегодня":
+		return today, nil
+	case "tomorrow", "завтра":
+		return today.AddDate(0, 0, 1), nil
+	}
+
+	if day, ok := weekdayNames[s]; ok {
+		return today.AddDate(0, 0, (int(day)-int(today.Weekday())+7)%7), nil
+	}
+
+	for _, layout := range []string{"2006-01-02", "02.01.2006", "2.1.2006"} {
+		if t, err := time.ParseInLocation(layout, s, now.Location()); err == nil {
+			return t, nil
+		}
+	}
+
+	// Day and month only: assume the next occurrence of that date
+	for _, layout := range []string{"02.01", "2.1"} {
+		if t, err := time.ParseInLocation(layout, s, now.Location()); err == nil {
+			date := time.Date(now.Year(), t.Month(), t.Day(), 0, 0, 0, 0, now.Location())
+			if date.Before(today) {
+				date = date.AddDate(1, 0, 0)
+			}
+			return date, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("invalid date: %q", s)
+}
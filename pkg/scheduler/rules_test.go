@@ -0,0 +1,193 @@
+package scheduler
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/korjavin/whatsfordinner/pkg/models"
+	"github.com/korjavin/whatsfordinner/pkg/settings"
+)
+
+var (
+	mon, tue, wed, thu, fri, sat, sun = time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday, time.Saturday, time.Sunday
+)
+
+func TestParseRule(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    models.ScheduleRule
+		wantErr bool
+	}{
+		{"Mon-Fri 16:30", models.ScheduleRule{Days: []time.Weekday{mon, tue, wed, thu, fri}, Time: "16:30", Meal: "dinner"}, false},
+		{"weekends 12:00 brunch", models.ScheduleRule{Days: []time.Weekday{sat, sun}, Time: "12:00", Meal: "brunch"}, false},
+		{"18:00", models.ScheduleRule{Time: "18:00", Meal: "dinner"}, false},
+		{"every day 19:00", models.ScheduleRule{Time: "19:00", Meal: "dinner"}, false},
+		{"Fri skip", models.ScheduleRule{Days: []time.Weekday{fri}, Skip: true}, false},
+		{"skip Fridays", models.ScheduleRule{Days: []time.Weekday{fri}, Skip: true}, false},
+		{"skip Mondays, Wednesdays", models.ScheduleRule{Days: []time.Weekday{mon, wed}, Skip: true}, false},
+		{"Tuesdays 18:00 tacos", models.ScheduleRule{Days: []time.Weekday{tue}, Time: "18:00", Meal: "tacos"}, false},
+		{"fri-mon 20:00", models.ScheduleRule{Days: []time.Weekday{mon, fri, sat, sun}, Time: "20:00", Meal: "dinner"}, false},
+		{"sat sun mon-sun 18:00", models.ScheduleRule{Time: "18:00", Meal: "dinner"}, false},
+		{"пт пропуск", models.ScheduleRule{Days: []time.Weekday{fri}, Skip: true}, false},
+		{"", models.ScheduleRule{}, true},
+		{"skip", models.ScheduleRule{}, true},
+		{"fri skip 18:00", models.ScheduleRule{}, true},
+		{"mon-fri", models.ScheduleRule{}, true},
+		{"mon 25:00", models.ScheduleRule{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseRule(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseRule(%q) error = %v, want error %v", tt.in, err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("ParseRule(%q) = %+v, want %+v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseDays(t *testing.T) {
+	tests := []struct {
+		token  string
+		want   []time.Weekday
+		wantOK bool
+	}{
+		{"fri", []time.Weekday{fri}, true},
+		{"fridays", []time.Weekday{fri}, true},
+		{"weekdays", []time.Weekday{mon, tue, wed, thu, fri}, true},
+		{"daily", nil, true},
+		{"mon-wed", []time.Weekday{mon, tue, wed}, true},
+		{"fri-mon", []time.Weekday{fri, sat, sun, mon}, true},
+		{"sun-sun", []time.Weekday{sun}, true},
+		{"mon-funday", nil, false},
+		{"18:00", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.token, func(t *testing.T) {
+			got, ok := parseDays(tt.token)
+			if ok != tt.wantOK || !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("parseDays(%q) = %v, %v; want %v, %v", tt.token, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestMealsOn(t *testing.T) {
+	fallback := settings.Clock{Hour: 18}
+	friday := time.Date(2025, 10, 24, 9, 0, 0, 0, time.UTC)
+	saturday := friday.AddDate(0, 0, 1)
+	sunday := friday.AddDate(0, 0, 2)
+
+	rules := models.Schedule{Rules: []models.ScheduleRule{
+		{Days: []time.Weekday{sat, sun}, Time: "19:00", Meal: "dinner"},
+		{Days: []time.Weekday{sat, sun}, Time: "11:30", Meal: "brunch"},
+		{Days: []time.Weekday{mon, tue, wed, thu, fri}, Time: "17:00", Meal: "dinner"},
+		{Days: []time.Weekday{sun}, Skip: true},
+	}}
+
+	tests := []struct {
+		name     string
+		schedule models.Schedule
+		day      time.Time
+		want     []Meal
+	}{
+		{"no rules", models.Schedule{}, friday, []Meal{{Name: "dinner", At: fallback}}},
+		{"weekday rule", rules, friday, []Meal{{Name: "dinner", At: settings.Clock{Hour: 17}}}},
+		{"earliest first", rules, saturday, []Meal{{Name: "brunch", At: settings.Clock{Hour: 11, Minute: 30}}, {Name: "dinner", At: settings.Clock{Hour: 19}}}},
+		{"skip rule", rules, sunday, nil},
+		{"only skip rules", models.Schedule{Rules: []models.ScheduleRule{{Days: []time.Weekday{sun}, Skip: true}}}, friday, []Meal{{Name: "dinner", At: fallback}}},
+		{"skip date", models.Schedule{SkipDates: []string{"2025-10-24"}}, friday, nil},
+		{"no rule for the day", models.Schedule{Rules: []models.ScheduleRule{{Days: []time.Weekday{mon}, Time: "18:00", Meal: "dinner"}}}, friday, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MealsOn(tt.schedule, tt.day, fallback); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("MealsOn = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsSkipped(t *testing.T) {
+	friday := time.Date(2025, 10, 24, 20, 0, 0, 0, time.UTC)
+	schedule := models.Schedule{
+		Rules:     []models.ScheduleRule{{Days: []time.Weekday{fri}, Skip: true}, {Time: "18:00", Meal: "dinner"}},
+		SkipDates: []string{"2025-10-28"},
+	}
+
+	tests := []struct {
+		day  time.Time
+		want bool
+	}{
+		{friday, true},
+		{friday.AddDate(0, 0, 1), false},
+		{friday.AddDate(0, 0, 4), true},
+		{friday.AddDate(0, 0, 7), true},
+	}
+	for _, tt := range tests {
+		if got := IsSkipped(schedule, tt.day); got != tt.want {
+			t.Errorf("IsSkipped(%s) = %v, want %v", tt.day.Format("Mon 2006-01-02"), got, tt.want)
+		}
+	}
+}
+
+func TestParseSkipDate(t *testing.T) {
+	// A Wednesday afternoon
+	now := time.Date(2025, 10, 22, 15, 0, 0, 0, time.UTC)
+	day := func(year int, month time.Month, d int) time.Time {
+		return time.Date(year, month, d, 0, 0, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		in      string
+		want    time.Time
+		wantErr bool
+	}{
+		{"today", day(2025, 10, 22), false},
+		{"Tomorrow", day(2025, 10, 23), false},
+		{"wed", day(2025, 10, 22), false},
+		{"friday", day(2025, 10, 24), false},
+		{"mon", day(2025, 10, 27), false},
+		{"2025-11-03", day(2025, 11, 3), false},
+		{"03.11.2025", day(2025, 11, 3), false},
+		{"3.11", day(2025, 11, 3), false},
+		{"22.10", day(2025, 10, 22), false},
+		{"21.10", day(2026, 10, 21), false},
+		{"someday", time.Time{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseSkipDate(tt.in, now)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseSkipDate(%q) error = %v, want error %v", tt.in, err, tt.wantErr)
+			}
+			if !got.Equal(tt.want) {
+				t.Fatalf("ParseSkipDate(%q) = %v, want %v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatRule(t *testing.T) {
+	tests := []struct {
+		rule models.ScheduleRule
+		want string
+	}{
+		{models.ScheduleRule{Days: []time.Weekday{mon, tue, wed, thu, fri}, Time: "16:30", Meal: "dinner"}, "Mon–Fri 16:30 dinner"},
+		{models.ScheduleRule{Days: []time.Weekday{sat, sun}, Time: "12:00", Meal: "brunch"}, "Weekends 12:00 brunch"},
+		{models.ScheduleRule{Days: []time.Weekday{mon, wed}, Skip: true}, "Mon, Wed: no polls"},
+		{models.ScheduleRule{Time: "18:00", Meal: "dinner"}, "Every day 18:00 dinner"},
+	}
+	for _, tt := range tests {
+		if got := FormatRule(tt.rule); got != tt.want {
+			t.Errorf("FormatRule(%+v) = %q, want %q", tt.rule, got, tt.want)
+		}
+	}
+}
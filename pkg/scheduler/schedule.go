@@ -0,0 +1,139 @@
+package scheduler
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/korjavin/whatsfordinner/pkg/models"
+)
+
+// GetSchedule returns a channel's schedule, or an empty one if the channel never set it up
+func (s *Service) GetSchedule(channelID int64) (models.Schedule, error) {
+	scheduleKey := fmt.Sprintf("schedule:%d", channelID)
+	var schedule models.Schedule
+	err := s.store.Get(scheduleKey, &schedule)
+	if err != nil {
+		// Nothing stored yet: daily dinner at the channel's dinner time
+		return models.Schedule{ID: scheduleKey, ChannelID: channelID}, nil
+	}
+	return schedule, nil
+}
+
+// updateSchedule applies a change to a channel's schedule and saves it
+func (s *Service) updateSchedule(channelID int64, apply func(schedule *models.Schedule) error) error {
+	schedule, err := s.GetSchedule(channelID)
+	if err != nil {
+		return err
+	}
+
+	if err := apply(&schedule); err != nil {
+		return err
+	}
+
+	schedule.LastUpdated = time.Now()
+	return s.store.Set(schedule.ID, schedule)
+}
+
+// AddRule adds a rule to a channel's schedule
+func (s *Service) AddRule(channelID int64, rule models.ScheduleRule) error {
+	s.logger.Info("Adding schedule rule %q for channel %d", FormatRule(rule), channelID)
+	return s.updateSchedule(channelID, func(schedule *models.Schedule) error {
+		schedule.Rules = append(schedule.Rules, rule)
+		return nil
+	})
+}
+
+// RemoveRule removes the rule at the given index from a channel's schedule and returns it
+func (s *Service) RemoveRule(channelID int64, index int) (models.ScheduleRule, error) {
+	var removed models.ScheduleRule
+	err := s.updateSchedule(channelID, func(schedule *models.Schedule) error {
+		if index < 0 || index >= len(schedule.Rules) {
+			return fmt.Errorf("no rule number %d", index+1)
+		}
+		removed = schedule.Rules[index]
+		schedule.Rules = append(schedule.Rules[:index], schedule.Rules[index+1:]...)
+		return nil
+	})
+	if err != nil {
+		return models.ScheduleRule{}, err
+	}
+
+	s.logger.Info("Removed schedule rule %q for channel %d", FormatRule(removed), channelID)
+	return removed, nil
+}
+
+// ClearRules removes all rules from a channel's schedule, going back to a daily dinner poll
+func (s *Service) ClearRules(channelID int64) error {
+	s.logger.Info("Clearing schedule rules for channel %d", channelID)
+	return s.updateSchedule(channelID, func(schedule *models.Schedule) error {
+		schedule.Rules = nil
+		return nil
+	})
+}
+
+// SkipDay adds a one-off day without polls to a channel's schedule
+// Skip dates that have already passed are dropped at the same time
+func (s *Service) SkipDay(channelID int64, day time.Time) error {
+	channelSettings, err := s.settingsService.Get(channelID)
+	if err != nil {
+		return err
+	}
+	today := channelSettings.Today().Format(skipDateLayout)
+	date := day.Format(skipDateLayout)
+
+	s.logger.Info("Skipping %s for channel %d", date, channelID)
+	return s.updateSchedule(channelID, func(schedule *models.Schedule) error {
+		skipDates := []string{date}
+		for _, skipDate := range schedule.SkipDates {
+			// The layout sorts like the dates themselves
+			if skipDate != date && skipDate >= today {
+				skipDates = append(skipDates, skipDate)
+			}
+		}
+		schedule.SkipDates = skipDates
+		return nil
+	})
+}
+
+// UnskipDay removes a one-off skip date from a channel's schedule
+func (s *Service) UnskipDay(channelID int64, day time.Time) error {
+	date := day.Format(skipDateLayout)
+
+	s.logger.Info("No longer skipping %s for channel %d", date, channelID)
+	return s.updateSchedule(channelID, func(schedule *models.Schedule) error {
+		for i, skipDate := range schedule.SkipDates {
+			if skipDate == date {
+				schedule.SkipDates = append(schedule.SkipDates[:i], schedule.SkipDates[i+1:]...)
+				return nil
+			}
+		}
+		return fmt.Errorf("%s is not skipped", day.Format("Mon, Jan 2"))
+	})
+}
+
+// Plan returns what a channel's schedule looks like for the next days, starting today
+func (s *Service) Plan(channelID int64, days int) ([]DayPlan, error) {
+	channelSettings, err := s.settingsService.Get(channelID)
+	if err != nil {
+		return nil, err
+	}
+
+	schedule, err := s.GetSchedule(channelID)
+	if err != nil {
+		return nil, err
+	}
+
+	today := channelSettings.Today()
+	plan := make([]DayPlan, 0, days)
+	for i := 0; i < days; i++ {
+		// Calendar days, not 24-hour steps, so DST changes don't shift the dates
+		date := today.AddDate(0, 0, i)
+		plan = append(plan, DayPlan{
+			Date:    date,
+			Meals:   MealsOn(schedule, date, channelSettings.DinnerTime),
+			Skipped: IsSkipped(schedule, date),
+		})
+	}
+
+	return plan, nil
+}
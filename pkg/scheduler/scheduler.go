@@ -1,30 +1,46 @@
 package scheduler
 
 import (
+	"errors"
 	"fmt"
 	"time"
 
+	"github.com/korjavin/whatsfordinner/pkg/attendance"
+	"github.com/korjavin/whatsfordinner/pkg/cooking"
+	"github.com/korjavin/whatsfordinner/pkg/diet"
 	"github.com/korjavin/whatsfordinner/pkg/dinner"
 	"github.com/korjavin/whatsfordinner/pkg/fridge"
 	"github.com/korjavin/whatsfordinner/pkg/logger"
 	"github.com/korjavin/whatsfordinner/pkg/models"
+	"github.com/korjavin/whatsfordinner/pkg/nutrition"
 	"github.com/korjavin/whatsfordinner/pkg/openai"
+	"github.com/korjavin/whatsfordinner/pkg/planner"
 	"github.com/korjavin/whatsfordinner/pkg/poll"
+	"github.com/korjavin/whatsfordinner/pkg/rotation"
+	"github.com/korjavin/whatsfordinner/pkg/settings"
 	"github.com/korjavin/whatsfordinner/pkg/storage"
 	"github.com/korjavin/whatsfordinner/pkg/telegram"
+	"github.com/korjavin/whatsfordinner/pkg/workflow"
 )
 
 // Service provides scheduling functionality for dinner workflows
 type Service struct {
-	store         *storage.Store
-	bot           *telegram.Bot
-	fridgeService *fridge.Service
-	pollService   *poll.Service
-	dinnerService *dinner.Service
-	openaiClient  *openai.Client
-	logger        *logger.Logger
-	cuisines      []string
-	stopChan      chan struct{}
+	store             *storage.Store
+	bot               *telegram.Bot
+	fridgeService     *fridge.Service
+	pollService       *poll.Service
+	dinnerService     *dinner.Service
+	openaiClient      *openai.Client
+	settingsService   *settings.Service
+	plannerService    *planner.Service
+	dietService       *diet.Service
+	cookingService    *cooking.Service
+	rotationService   *rotation.Service
+	attendanceService *attendance.Service
+	workflowService   *workflow.Service
+	logger            *logger.Logger
+	stopChan          chan struct{}
+	wake              chan struct{} // Wakes the daily jobs when a channel's settings or schedule change
 }
 
 // New creates a new scheduler service
@@ -35,33 +51,53 @@ func New(
 	pollService *poll.Service,
 	dinnerService *dinner.Service,
 	openaiClient *openai.Client,
-	cuisines []string,
+	settingsService *settings.Service,
+	plannerService *planner.Service,
+	dietService *diet.Service,
+	cookingService *cooking.Service,
+	rotationService *rotation.Service,
+	attendanceService *attendance.Service,
+	workflowService *workflow.Service,
 ) *Service {
 	return &Service{
-		store:         store,
-		bot:           bot,
-		fridgeService: fridgeService,
-		pollService:   pollService,
-		dinnerService: dinnerService,
-		openaiClient:  openaiClient,
-		logger:        logger.New("scheduler"),
-		cuisines:      cuisines,
-		stopChan:      make(chan struct{}),
+		store:             store,
+		bot:               bot,
+		fridgeService:     fridgeService,
+		pollService:       pollService,
+		dinnerService:     dinnerService,
+		openaiClient:      openaiClient,
+		settingsService:   settingsService,
+		plannerService:    plannerService,
+		dietService:       dietService,
+		cookingService:    cookingService,
+		rotationService:   rotationService,
+		attendanceService: attendanceService,
+		workflowService:   workflowService,
+		logger:            logger.New("scheduler"),
+		stopChan:          make(chan struct{}),
+		wake:              make(chan struct{}, 1),
 	}
 }
 
 // Start starts the scheduler
 func (s *Service) Start() {
 	s.logger.Info("Starting dinner scheduler")
-	
-	// Start the daily dinner scheduler
-	go s.runDailyDinnerScheduler()
-	
-	// Start the dinner timeout checker
-	go s.runDinnerTimeoutChecker()
-	
+
+	// Start the daily jobs: "use soon" alerts, dinner polls and the evening cutoff
+	go s.runDailyJobs()
+	go s.watchSchedules()
+
 	// Start the cook volunteer timeout checker
 	go s.runCookVolunteerTimeoutChecker()
+
+	// Start pinging cooks when their cooking timers fire
+	go s.runCookingTimers()
+
+	// Start the polls whose RSVP deadline has passed
+	go s.runAttendanceChecker()
+
+	// Move on the dinner workflows left behind in a state
+	go s.runWorkflowTimeouts()
 }
 
 // Stop stops the scheduler
@@ -70,178 +106,52 @@ func (s *Service) Stop() {
 	close(s.stopChan)
 }
 
-// runDailyDinnerScheduler runs the daily dinner scheduler
-// It starts the dinner workflow at 3pm if it hasn't been started today
-func (s *Service) runDailyDinnerScheduler() {
-	s.logger.Info("Starting daily dinner scheduler")
-	
-	ticker := time.NewTicker(1 * time.Minute)
-	defer ticker.Stop()
-	
-	for {
-		select {
-		case <-ticker.C:
-			now := time.Now()
-			
-			// Check if it's around 3pm (15:00)
-			if now.Hour() == 15 && now.Minute() < 5 {
-				s.logger.Info("It's 3pm, checking if dinner workflow needs to be started")
-				
-				// Get all channels
-				channelKeys, err := s.store.List("channel:")
-				if err != nil {
-					s.logger.Error("Failed to list channels: %v", err)
-					continue
-				}
-				
-				for _, channelKey := range channelKeys {
-					var channelState models.ChannelState
-					err := s.store.Get(channelKey, &channelState)
-					if err != nil {
-						s.logger.Error("Failed to get channel state: %v", err)
-						continue
-					}
-					
-					// Check if dinner workflow has been started today
-					if !s.hasDinnerStartedToday(channelState) {
-						s.logger.Info("Starting dinner workflow for channel %d", channelState.ChannelID)
-						s.startDinnerWorkflow(channelState.ChannelID)
-					}
-				}
-			}
-		case <-s.stopChan:
-			return
-		}
+// sendExpiryAlert sends the "use soon" list to a channel
+func (s *Service) sendExpiryAlert(channelID int64, now time.Time) {
+	expiring, err := s.fridgeService.ExpiringSoon(channelID, fridge.UseSoonWindow)
+	if err != nil {
+		s.logger.Error("Failed to get expiring ingredients for channel %d: %v", channelID, err)
+		return
 	}
-}
 
-// runDinnerTimeoutChecker checks for dinner workflows that need to be stopped at 9pm
-func (s *Service) runDinnerTimeoutChecker() {
-	s.logger.Info("Starting dinner timeout checker")
-	
-	ticker := time.NewTicker(1 * time.Minute)
-	defer ticker.Stop()
-	
-	for {
-		select {
-		case <-ticker.C:
-			now := time.Now()
-			
-			// Check if it's around 9pm (21:00)
-			if now.Hour() == 21 && now.Minute() < 5 {
-				s.logger.Info("It's 9pm, checking for unfinished dinner workflows")
-				
-				// Get all channels
-				channelKeys, err := s.store.List("channel:")
-				if err != nil {
-					s.logger.Error("Failed to list channels: %v", err)
-					continue
-				}
-				
-				for _, channelKey := range channelKeys {
-					var channelState models.ChannelState
-					err := s.store.Get(channelKey, &channelState)
-					if err != nil {
-						s.logger.Error("Failed to get channel state: %v", err)
-						continue
-					}
-					
-					// Check if there's an active dinner or vote
-					if s.hasUnfinishedDinnerWorkflow(channelState) {
-						s.logger.Info("Stopping unfinished dinner workflow for channel %d", channelState.ChannelID)
-						s.stopDinnerWorkflow(channelState.ChannelID)
-					}
-				}
-			}
-		case <-s.stopChan:
-			return
-		}
+	if len(expiring) == 0 {
+		return
 	}
-}
 
-// runCookVolunteerTimeoutChecker checks for votes that need a cook volunteer
-func (s *Service) runCookVolunteerTimeoutChecker() {
-	s.logger.Info("Starting cook volunteer timeout checker")
-	
-	ticker := time.NewTicker(1 * time.Minute)
-	defer ticker.Stop()
-	
-	// Map to track when we started waiting for volunteers
-	volunteerWaitStart := make(map[string]time.Time)
-	
-	for {
-		select {
-		case <-ticker.C:
-			// Get all channels
-			channelKeys, err := s.store.List("channel:")
-			if err != nil {
-				s.logger.Error("Failed to list channels: %v", err)
-				continue
-			}
-			
-			for _, channelKey := range channelKeys {
-				var channelState models.ChannelState
-				err := s.store.Get(channelKey, &channelState)
-				if err != nil {
-					s.logger.Error("Failed to get channel state: %v", err)
-					continue
-				}
-				
-				// Check if there's a vote that has ended but no cook has been selected
-				if channelState.CurrentVote != nil && 
-				   !channelState.CurrentVote.EndedAt.IsZero() && 
-				   channelState.CurrentVote.WinningDish != "" && 
-				   len(channelState.CurrentVote.CookVolunteers) == 0 {
-					
-					voteID := channelState.CurrentVote.PollID
-					
-					// Check if we're already tracking this vote
-					startTime, exists := volunteerWaitStart[voteID]
-					if !exists {
-						// Start tracking this vote
-						volunteerWaitStart[voteID] = time.Now()
-						s.logger.Info("Started waiting for cook volunteers for vote %s in channel %d", voteID, channelState.ChannelID)
-					} else {
-						// Check if 15 minutes have passed
-						if time.Since(startTime) > 15*time.Minute {
-							s.logger.Info("No cook volunteers after 15 minutes for vote %s in channel %d", voteID, channelState.ChannelID)
-							
-							// Remove from tracking
-							delete(volunteerWaitStart, voteID)
-							
-							// Restart the dinner workflow
-							s.restartDinnerWorkflow(channelState.ChannelID)
-						}
-					}
-				} else if channelState.CurrentVote != nil && len(channelState.CurrentVote.CookVolunteers) > 0 {
-					// If there are volunteers, remove from tracking
-					voteID := channelState.CurrentVote.PollID
-					if _, exists := volunteerWaitStart[voteID]; exists {
-						delete(volunteerWaitStart, voteID)
-					}
-				}
-			}
-		case <-s.stopChan:
-			return
+	s.logger.Info("Sending expiry alert with %d ingredients to channel %d", len(expiring), channelID)
+
+	msgText := "⏳ *Use soon* – these are about to go off:\n\n"
+	for _, ingredient := range expiring {
+		expiresAt, _ := fridge.ExpiryDate(ingredient)
+		if expiresAt.Before(now) {
+			msgText += fmt.Sprintf("• %s – expired %s\n", ingredient.Name, expiresAt.Format("Jan 2"))
+		} else {
+			msgText += fmt.Sprintf("• %s – best before %s\n", ingredient.Name, expiresAt.Format("Jan 2"))
 		}
 	}
+	msgText += "\nI'll prefer dishes with these ingredients in tonight's suggestions."
+
+	s.bot.SendMessage(channelID, msgText)
 }
 
-// hasDinnerStartedToday checks if a dinner workflow has been started today for a channel
-func (s *Service) hasDinnerStartedToday(channelState models.ChannelState) bool {
+// hasMealStartedSince checks if a dinner workflow is running or has been started since the given time
+func (s *Service) hasMealStartedSince(channelState models.ChannelState, since time.Time) bool {
 	// Check if there's a current dinner or vote
 	if channelState.CurrentDinner != nil || channelState.CurrentVote != nil {
 		return true
 	}
-	
-	// Check for any dinner that started today
-	today := time.Now().Truncate(24 * time.Hour)
+
+	// Check if the family was asked who's eating at home since then
+	if channelState.Attendance != nil && channelState.Attendance.AskedAt.After(since) {
+		return true
+	}
+
 	dinnerKeys, err := s.store.List(fmt.Sprintf("dinner:%d:", channelState.ChannelID))
 	if err != nil {
 		s.logger.Error("Failed to list dinners: %v", err)
 		return false
 	}
-	
+
 	for _, dinnerKey := range dinnerKeys {
 		var dinner models.Dinner
 		err := s.store.Get(dinnerKey, &dinner)
@@ -249,20 +159,20 @@ func (s *Service) hasDinnerStartedToday(channelState models.ChannelState) bool {
 			s.logger.Error("Failed to get dinner %s: %v", dinnerKey, err)
 			continue
 		}
-		
-		// Check if the dinner started today
-		if dinner.StartedAt.After(today) {
+
+		// Check if the dinner started since then
+		if dinner.StartedAt.After(since) {
 			return true
 		}
 	}
-	
-	// Check for any vote that started today
+
+	// Check for any vote that started since then
 	voteKeys, err := s.store.List(fmt.Sprintf("vote:%d:", channelState.ChannelID))
 	if err != nil {
 		s.logger.Error("Failed to list votes: %v", err)
 		return false
 	}
-	
+
 	for _, voteKey := range voteKeys {
 		var vote models.VoteState
 		err := s.store.Get(voteKey, &vote)
@@ -270,13 +180,13 @@ func (s *Service) hasDinnerStartedToday(channelState models.ChannelState) bool {
 			s.logger.Error("Failed to get vote %s: %v", voteKey, err)
 			continue
 		}
-		
-		// Check if the vote started today
-		if vote.StartedAt.After(today) {
+
+		// Check if the vote started since then
+		if vote.StartedAt.After(since) {
 			return true
 		}
 	}
-	
+
 	return false
 }
 
@@ -286,22 +196,45 @@ func (s *Service) hasUnfinishedDinnerWorkflow(channelState models.ChannelState)
 	if channelState.CurrentDinner != nil && channelState.CurrentDinner.FinishedAt.IsZero() {
 		return true
 	}
-	
+
 	// Check if there's a current vote that hasn't ended
 	if channelState.CurrentVote != nil && channelState.CurrentVote.EndedAt.IsZero() {
 		return true
 	}
-	
+
+	// Check if the family is still waiting for a cook
+	if channelState.CurrentVote != nil && !channelState.CurrentVote.CookRequestedAt.IsZero() && channelState.CurrentVote.Outcome == "" {
+		return true
+	}
+
+	// Check if the family is still answering who's eating at home
+	if attendance.IsOpen(attendance.Current(channelState, time.Now())) {
+		return true
+	}
+
+	// Check if the dinner's workflow hasn't got to serving dinner, e.g. a cook was picked but never started
+	if current, err := s.workflowService.Current(channelState.ChannelID); err == nil && workflow.IsUnfinished(current) {
+		return true
+	}
+
 	return false
 }
 
-// startDinnerWorkflow starts the dinner workflow for a channel
-func (s *Service) startDinnerWorkflow(channelID int64) {
-	s.logger.Info("Starting dinner workflow for channel %d", channelID)
-	
+// startDinnerWorkflow starts the dinner workflow for a channel, polling for the given meal
+func (s *Service) startDinnerWorkflow(channelID int64, meal string) {
+	s.logger.Info("Starting %s workflow for channel %d", meal, channelID)
+	if !s.beginWorkflow(channelID, fmt.Sprintf("%s time", meal)) {
+		return
+	}
+
+	// Ask who's eating at home first; the poll starts once they answered
+	if channelSettings, _ := s.settingsService.Get(channelID); s.askAttendance(channelID, meal, channelSettings) {
+		return
+	}
+
 	// Send a message to the channel
-	s.bot.SendMessage(channelID, "🕒 It's dinner time! Let me suggest some options based on your fridge...")
-	
+	s.bot.SendMessage(channelID, fmt.Sprintf("🕒 It's %s time! Let me suggest some options based on your fridge...", meal))
+
 	// Get ingredients from the fridge
 	ingredients, err := s.fridgeService.ListIngredients(channelID)
 	if err != nil {
@@ -310,79 +243,135 @@ func (s *Service) startDinnerWorkflow(channelID int64) {
 		s.bot.SendMessage(channelID, errorMsg)
 		return
 	}
-	
+
 	if len(ingredients) == 0 {
 		s.bot.SendMessage(channelID, "😢 Your fridge is empty! Please add some ingredients with /sync_fridge or /add_photo before I can suggest dinner options.")
 		return
 	}
-	
-	// Extract ingredient names
+
+	// Extract ingredient names, noting the ones that should be used up soon
 	ingredientNames := make([]string, len(ingredients))
+	var useSoon []string
+	now := time.Now()
 	for i, ingredient := range ingredients {
 		ingredientNames[i] = ingredient.Name
+		if fridge.IsExpiringSoon(ingredient, now, fridge.UseSoonWindow) {
+			useSoon = append(useSoon, ingredient.Name)
+		}
 	}
-	
+
 	// Send a processing message
 	processingMsg, _ := s.bot.SendMessage(channelID, "🧐 Thinking about dinner options based on your ingredients... This might take a moment.")
-	
-	// Get dinner suggestions from OpenAI
-	aiSuggestions, err := s.openaiClient.SuggestDinnerOptions(ingredientNames, s.cuisines, 4)
+
+	// Get dinner suggestions from OpenAI, following the channel's settings and the family's diets
+	channelSettings, err := s.settingsService.Get(channelID)
+	if err != nil {
+		s.logger.Error("Failed to get settings for channel %d: %v", channelID, err)
+	}
+	prefs := s.dietService.Apply(channelID, openai.Preferences{
+		Cuisines:     channelSettings.Cuisines,
+		DietaryRules: channelSettings.DietaryRules,
+		Language:     channelSettings.LanguageName(),
+	})
+	aiSuggestions, err := s.openaiClient.SuggestDinnerOptions(ingredientNames, useSoon, prefs, 4)
 	if err != nil {
 		s.logger.Error("Failed to get dinner suggestions: %v", err)
 		s.bot.EditMessage(channelID, processingMsg.MessageID, "😢 Sorry, I couldn't come up with dinner suggestions right now. Please try again later or use the /dinner command manually.")
 		return
 	}
-	
-	if len(aiSuggestions) == 0 {
+
+	// Rank the suggestions, leaving out dishes with allergens
+	rankedDishes := s.dinnerService.RankSuggestions(channelID, aiSuggestions, channelSettings.Cuisines)
+	if len(rankedDishes) == 0 {
 		s.bot.EditMessage(channelID, processingMsg.MessageID, "😢 I couldn't find any suitable dishes based on your fridge contents. Try adding more ingredients with /fridge or suggest your own dishes with /suggest.")
 		return
 	}
-	
+
 	// Create options for the poll
-	options := make([]string, len(aiSuggestions))
-	
+	options := make([]string, len(rankedDishes))
+
 	// Create a detailed message with suggestions
-	detailedMsg := "🍲 Here are some dinner suggestions based on your ingredients:\n\n"
-	
-	// Add AI suggestions
-	for i, suggestion := range aiSuggestions {
-		name, _ := suggestion["name"].(string)
-		cuisine, _ := suggestion["cuisine"].(string)
-		description, _ := suggestion["description"].(string)
-		
-		options[i] = name
-		
-		detailedMsg += fmt.Sprintf("🍴 *%s* (%s)\n%s\n\n", name, cuisine, description)
-	}
-	
+	detailedMsg := fmt.Sprintf("🍲 Here are some %s suggestions based on your ingredients:\n\n", meal)
+
+	// Add AI suggestions, best match first, with the reasons they were picked
+	for i, rankedDish := range rankedDishes {
+		options[i] = rankedDish.Dish.Name
+
+		detailedMsg += fmt.Sprintf("🍴 *%s* (%s)\n%s\n", rankedDish.Dish.Name, rankedDish.Dish.Cuisine, rankedDish.Dish.Description)
+		if rankedDish.Dish.Nutrition != nil {
+			detailedMsg += fmt.Sprintf("🔥 %s per serving\n", nutrition.Format(rankedDish.Dish.Nutrition))
+		}
+		if explanation := rankedDish.Explanation(time.Now()); explanation != "" {
+			detailedMsg += fmt.Sprintf("_%s_\n", explanation)
+		}
+		detailedMsg += "\n"
+	}
+
 	// Edit the processing message to show the detailed suggestions
 	s.bot.EditMessage(channelID, processingMsg.MessageID, detailedMsg)
-	
+
 	// Create poll
-	pollMsg, err := s.bot.CreatePoll(channelID, "What should we cook tonight?", options)
-	if err != nil {
-		s.logger.Error("Failed to create poll: %v", err)
+	question := "What should we cook tonight?"
+	if meal != DefaultMeal {
+		question = fmt.Sprintf("What should we cook for %s?", meal)
+	}
+	if _, err := s.StartVote(channelID, question, options); err != nil {
+		s.logger.Error("Failed to start vote: %v", err)
 		s.bot.SendMessage(channelID, "😢 Sorry, I couldn't create a poll for dinner options. Please try again later or use the /dinner command manually.")
 		return
 	}
-	
-	// Store vote state
-	pollID := pollMsg.Poll.ID
-	s.logger.Info("Created poll with ID %s for channel %d", pollID, channelID)
-	
-	_, err = s.pollService.CreateVote(channelID, pollID, pollMsg.MessageID, options)
+
+	// Send a message with voting instructions
+	s.bot.SendMessage(channelID, fmt.Sprintf("🗳 %s The poll will close automatically when %.0f%% of those eating at home have voted.", VotingInstructions(channelSettings.VotingMode), channelSettings.VoteThreshold*100))
+}
+
+// announcePlannedDinner announces the dinner from the channel's week plan instead of starting a poll
+// The dish is recorded as an already decided vote, so the usual cook volunteer flow takes over
+func (s *Service) announcePlannedDinner(channelID int64, day models.PlannedDay) {
+	s.logger.Info("Announcing planned dinner %s for channel %d", day.Dish.Name, channelID)
+	if !s.beginWorkflow(channelID, "planned dinner") {
+		return
+	}
+
+	pollID := planner.PollID(channelID, day.Date)
+	_, err := s.pollService.CreateVote(channelID, pollID, 0, []string{day.Dish.Name}, poll.ModePlurality)
 	if err != nil {
-		s.logger.Error("Failed to create vote state: %v", err)
+		s.logger.Error("Failed to create vote for planned dinner: %v", err)
+		return
+	}
+	if err := s.workflowService.Link(channelID, pollID, ""); err != nil {
+		s.logger.Error("Failed to link planned dinner to its workflow: %v", err)
+	}
+
+	err = s.pollService.EndVote(channelID, pollID, day.Dish.Name)
+	if err != nil {
+		s.logger.Error("Failed to end vote for planned dinner: %v", err)
+	}
+
+	err = s.plannerService.MarkAnnounced(channelID, day.Date)
+	if err != nil {
+		s.logger.Error("Failed to mark planned dinner as announced: %v", err)
+	}
+
+	msgText := fmt.Sprintf("📅 Tonight's dinner from your week plan: *%s*", day.Dish.Name)
+	if day.Dish.Cuisine != "" {
+		msgText += fmt.Sprintf(" (%s)", day.Dish.Cuisine)
+	}
+	msgText += "\n\nWho wants to cook it? Press the button below to volunteer!"
+
+	if err := s.AskForCook(channelID, pollID, msgText); err != nil {
+		s.logger.Error("Failed to ask for a cook for planned dinner: %v", err)
 	}
-	
-	// Send a message with voting instructions
-	s.bot.SendMessage(channelID, "🗳 Please vote for your preferred dinner option! The poll will close automatically when 2/3 of the channel members have voted.")
 }
 
-// stopDinnerWorkflow stops the dinner workflow for a channel
-func (s *Service) stopDinnerWorkflow(channelID int64) {
-	s.logger.Info("Stopping dinner workflow for channel %d", channelID)
-	
+// endDinner ends whatever of a channel's dinner is still open without waiting for the family, e.g. at the cutoff
+// or when its workflow's state timed out: the question who's eating at home and the ballot are closed,
+// dinner is called off if nobody cooks, and a dinner being cooked is served with the cooking session ended.
+// The workflow moves along with each step and then ends, so it can't drift from what the family sees.
+// why starts the messages telling the family, e.g. "It's getting late!"; reason goes into the workflow's history
+func (s *Service) endDinner(channelID int64, why, reason string) {
+	s.logger.Info("Ending dinner of channel %d: %s", channelID, reason)
+
 	// Get channel state
 	channelKey := fmt.Sprintf("channel:%d", channelID)
 	var channelState models.ChannelState
@@ -391,55 +380,75 @@ func (s *Service) stopDinnerWorkflow(channelID int64) {
 		s.logger.Error("Failed to get channel state: %v", err)
 		return
 	}
-	
+
 	// Check if there's an active vote
 	if channelState.CurrentVote != nil && channelState.CurrentVote.EndedAt.IsZero() {
 		// End the vote
 		s.logger.Info("Ending vote %s for channel %d", channelState.CurrentVote.PollID, channelID)
-		
+
 		// Get the current results
-		results, winningOption, err := s.pollService.GetVoteResults(channelID, channelState.CurrentVote.PollID)
+		result, err := s.pollService.GetResult(channelID, channelState.CurrentVote.PollID)
+		winningOption := result.Winner
 		if err != nil {
 			s.logger.Error("Failed to get vote results: %v", err)
+		}
+		if winningOption == "" {
 			winningOption = "No winner"
 		}
-		
+
 		// End the vote
 		err = s.pollService.EndVote(channelID, channelState.CurrentVote.PollID, winningOption)
 		if err != nil {
 			s.logger.Error("Failed to end vote: %v", err)
 		}
-		
-		// Send a message
-		s.bot.SendMessage(channelID, "⏰ It's getting late! The dinner poll has been closed automatically.")
-		
-		// If there are votes, announce the winner
-		if len(results) > 0 {
-			s.bot.SendMessage(channelID, fmt.Sprintf("🏆 The winning dish is *%s*.", winningOption))
-		} else {
-			s.bot.SendMessage(channelID, "😢 Nobody voted for dinner today.")
-		}
+		s.CloseBallot(channelID, channelState.CurrentVote)
+
+		// Send a message with how the winner was decided
+		s.bot.SendMessage(channelID, fmt.Sprintf("⏰ %s The dinner poll has been closed automatically.\n\n%s", why, result.Explain()))
+	}
+
+	// Check if the family is still answering who's eating at home
+	if attendance.IsOpen(attendance.Current(channelState, time.Now())) {
+		s.logger.Info("Closing attendance for channel %d before its poll started", channelID)
+		s.closeAttendance(channelID, fmt.Sprintf("🏠 %s There's no poll today.", why))
 	}
-	
+
+	// Check if the family is still waiting for a cook
+	if channelState.CurrentVote != nil && !channelState.CurrentVote.CookRequestedAt.IsZero() && channelState.CurrentVote.Outcome == "" {
+		s.logger.Info("Nobody cooks vote %s for channel %d: %s", channelState.CurrentVote.PollID, channelID, reason)
+
+		vote := channelState.CurrentVote
+		s.callDinnerOff(channelID, vote, fmt.Sprintf("⏰ %s Nobody is cooking *%s*, so there's no dinner today.", why, vote.WinningDish))
+	}
+
 	// Check if there's an active dinner
 	if channelState.CurrentDinner != nil && channelState.CurrentDinner.FinishedAt.IsZero() {
 		// Finish the dinner
 		s.logger.Info("Finishing dinner %s for channel %d", channelState.CurrentDinner.ID, channelID)
-		
+
 		err := s.dinnerService.FinishDinner(channelID)
 		if err != nil {
 			s.logger.Error("Failed to finish dinner: %v", err)
 		}
-		
+
+		// Stop cooking step by step, with its timers
+		if _, err := s.cookingService.End(channelID); err != nil && !errors.Is(err, cooking.ErrNoSession) {
+			s.logger.Error("Failed to end cooking session: %v", err)
+		}
+
 		// Send a message
-		s.bot.SendMessage(channelID, "⏰ It's getting late! The dinner has been marked as finished automatically.")
+		s.bot.SendMessage(channelID, fmt.Sprintf("⏰ %s The dinner has been marked as finished automatically.", why))
+		s.advanceWorkflow(channelID, workflow.Served, "finished: "+reason)
 	}
+
+	// Whatever didn't get to dinner is over for today
+	s.endWorkflow(channelID, reason)
 }
 
 // restartDinnerWorkflow restarts the dinner workflow for a channel
 func (s *Service) restartDinnerWorkflow(channelID int64) {
 	s.logger.Info("Restarting dinner workflow for channel %d", channelID)
-	
+
 	// Get channel state
 	channelKey := fmt.Sprintf("channel:%d", channelID)
 	var channelState models.ChannelState
@@ -448,20 +457,20 @@ func (s *Service) restartDinnerWorkflow(channelID int64) {
 		s.logger.Error("Failed to get channel state: %v", err)
 		return
 	}
-	
+
 	// Check if there's an active vote that has ended
 	if channelState.CurrentVote != nil && !channelState.CurrentVote.EndedAt.IsZero() {
 		// Send a message
-		s.bot.SendMessage(channelID, "⏰ 15 minutes have passed and nobody volunteered to cook. Let's try again with a new poll!")
-		
+		s.bot.SendMessage(channelID, "⏰ Nobody volunteered to cook. Let's try again with a new poll!")
+
 		// Clear the current vote
 		channelState.CurrentVote = nil
 		err = s.store.Set(channelKey, channelState)
 		if err != nil {
 			s.logger.Error("Failed to update channel state: %v", err)
 		}
-		
+
 		// Start a new dinner workflow
-		s.startDinnerWorkflow(channelID)
+		s.startDinnerWorkflow(channelID, DefaultMeal)
 	}
 }
@@ -0,0 +1,67 @@
+package scheduler
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/korjavin/whatsfordinner/pkg/models"
+)
+
+// timerCheckInterval is how often the scheduler looks for cooking timers that fired
+const timerCheckInterval = 10 * time.Second
+
+// lateTimer is how late a timer has to ring to mention it, e.g. because the bot was down when it fired
+const lateTimer = time.Minute
+
+// runCookingTimers pings cooks when their timers fire
+// Timers are kept in storage, so the ones that fired while the bot was down ring as soon as it's back
+func (s *Service) runCookingTimers() {
+	s.logger.Info("Starting cooking timers")
+
+	ticker := time.NewTicker(timerCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		s.ringDueTimers(time.Now())
+
+		select {
+		case <-ticker.C:
+		case <-s.stopChan:
+			return
+		}
+	}
+}
+
+// ringDueTimers pings the cook of every timer that has fired by now and removes the timer
+func (s *Service) ringDueTimers(now time.Time) {
+	timers, err := s.cookingService.DueTimers(now)
+	if err != nil {
+		s.logger.Error("Failed to get due timers: %v", err)
+		return
+	}
+
+	for _, timer := range timers {
+		s.bot.SendMessage(timer.ChannelID, s.timerMessage(timer, now))
+
+		if err := s.cookingService.RemoveTimer(timer.ID); err != nil {
+			s.logger.Error("Failed to remove timer %s: %v", timer.ID, err)
+		}
+	}
+}
+
+// timerMessage tells the cook a timer is up, with the step it was started for
+func (s *Service) timerMessage(timer models.CookingTimer, now time.Time) string {
+	text := fmt.Sprintf("⏰ @%s, time's up! %s for step %d are over.", timer.CookUsername, timer.Label, timer.Step+1)
+
+	var dinner models.Dinner
+	if err := s.store.Get(timer.DinnerID, &dinner); err == nil && timer.Step < len(dinner.Dish.Instructions) {
+		text += fmt.Sprintf("\n\n%d. %s", timer.Step+1, dinner.Dish.Instructions[timer.Step])
+	}
+
+	if now.Sub(timer.FiresAt) > lateTimer {
+		channelSettings, _ := s.settingsService.Get(timer.ChannelID)
+		text += fmt.Sprintf("\n\n(It went off at %s, while I was offline.)", timer.FiresAt.In(channelSettings.Location).Format("15:04"))
+	}
+
+	return text
+}
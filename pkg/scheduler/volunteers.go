@@ -0,0 +1,272 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"github.com/korjavin/whatsfordinner/pkg/attendance"
+	"github.com/korjavin/whatsfordinner/pkg/models"
+	"github.com/korjavin/whatsfordinner/pkg/poll"
+	"github.com/korjavin/whatsfordinner/pkg/rotation"
+	"github.com/korjavin/whatsfordinner/pkg/settings"
+	"github.com/korjavin/whatsfordinner/pkg/workflow"
+)
+
+// cookCheckInterval is how often the scheduler follows up on requests for a cook
+const cookCheckInterval = 30 * time.Second
+
+// AskForCook asks the family who cooks the winning dish of a vote, in a message with the volunteer buttons
+// Members of the rotation who haven't cooked in a while are mentioned. The scheduler follows the request up:
+// it picks the cook once the volunteer window closes, and asks again or escalates when nobody volunteers
+func (s *Service) AskForCook(channelID int64, pollID, text string) error {
+	if nudges := s.cookNudges(channelID, pollID); len(nudges) > 0 {
+		text += fmt.Sprintf("\n\n🔔 @%s, you haven't cooked in a while – how about tonight?", strings.Join(nudges, ", @"))
+	}
+
+	msg, err := s.bot.SendMessageWithKeyboard(channelID, text, volunteerKeyboard(pollID))
+	if err != nil {
+		return fmt.Errorf("failed to ask for a cook: %w", err)
+	}
+
+	if _, err := s.pollService.RequestCook(channelID, pollID, msg.MessageID); err != nil {
+		return fmt.Errorf("failed to record the request for a cook: %w", err)
+	}
+
+	s.advanceWorkflow(channelID, workflow.AwaitingCook, "asked for a cook")
+	return nil
+}
+
+// cookNudges returns the usernames of the members whose turn it is to cook a vote's winning dish
+func (s *Service) cookNudges(channelID int64, pollID string) []string {
+	vote, err := s.pollService.GetVote(channelID, pollID)
+	if err != nil {
+		s.logger.Error("Failed to get vote %s: %v", pollID, err)
+		return nil
+	}
+
+	now := time.Now()
+	turns, err := s.rotationService.Order(channelID, now)
+	if err != nil {
+		s.logger.Error("Failed to get the cook rotation of channel %d: %v", channelID, err)
+		return nil
+	}
+
+	var usernames []string
+	for _, turn := range rotation.Nudges(s.atHome(channelID, rotation.Eligible(turns, vote)), now) {
+		if turn.Member.Username != "" {
+			usernames = append(usernames, turn.Member.Username)
+		}
+	}
+	return usernames
+}
+
+// runCookVolunteerTimeoutChecker follows up on the channels waiting for a cook
+func (s *Service) runCookVolunteerTimeoutChecker() {
+	s.logger.Info("Starting cook volunteer timeout checker")
+
+	ticker := time.NewTicker(cookCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.followUpCookRequests(time.Now())
+		case <-s.stopChan:
+			return
+		}
+	}
+}
+
+// followUpCookRequests picks a cook, asks again or escalates in every channel waiting for a cook
+// Requests are kept in storage, so the follow-up carries on after a restart
+func (s *Service) followUpCookRequests(now time.Time) {
+	channelKeys, err := s.store.List("channel:")
+	if err != nil {
+		s.logger.Error("Failed to list channels: %v", err)
+		return
+	}
+
+	for _, channelKey := range channelKeys {
+		var channelState models.ChannelState
+		if err := s.store.Get(channelKey, &channelState); err != nil {
+			s.logger.Error("Failed to get channel state: %v", err)
+			continue
+		}
+
+		current := channelState.CurrentVote
+		if current == nil || current.EndedAt.IsZero() || current.CookRequestedAt.IsZero() || current.Outcome != "" {
+			continue
+		}
+
+		// Only the dinner whose workflow waits for a cook is followed up; the cutoff ends any other
+		if s.workflowService.RequireVote(channelState.ChannelID, current.PollID, workflow.AwaitingCook) != nil {
+			continue
+		}
+
+		vote, err := s.pollService.GetVote(channelState.ChannelID, current.PollID)
+		if err != nil {
+			s.logger.Error("Failed to get vote %s: %v", current.PollID, err)
+			continue
+		}
+
+		channelSettings := s.settingsService.Resolve(channelState)
+		switch {
+		case len(vote.CookVolunteers) > 0:
+			if now.Sub(vote.FirstVolunteerAt) >= channelSettings.VolunteerWindow {
+				s.pickCook(channelState.ChannelID, vote, channelSettings)
+			}
+		case now.Sub(vote.CookRequestedAt) < channelSettings.CookTimeout:
+			// Still waiting for volunteers
+		case vote.CookRetries < channelSettings.CookRetries:
+			s.askAgainForCook(channelState.ChannelID, vote, channelSettings)
+		case channelSettings.NoCookAction == settings.NoCookAssign:
+			s.assignCookByRotation(channelState.ChannelID, vote)
+		case channelSettings.NoCookAction == settings.NoCookNewPoll:
+			s.closeCookRequest(channelState.ChannelID, vote, fmt.Sprintf("⏰ Nobody volunteered to cook %s.", vote.WinningDish))
+			s.restartDinnerWorkflow(channelState.ChannelID)
+		default:
+			s.callDinnerOff(channelState.ChannelID, vote, fmt.Sprintf("🙅 Nobody volunteered to cook *%s*, so there's no dinner today.", vote.WinningDish))
+		}
+	}
+}
+
+// pickCook picks the cook among a vote's volunteers and asks them to start cooking
+// Volunteers who said since that they aren't eating at home are left out
+func (s *Service) pickCook(channelID int64, vote *models.VoteState, channelSettings settings.Settings) {
+	current := s.attendanceService.Get(channelID)
+	var volunteers []string
+	for _, volunteer := range vote.CookVolunteers {
+		if !attendance.IsAway(current, volunteer) {
+			volunteers = append(volunteers, volunteer)
+		}
+	}
+	if len(volunteers) == 0 {
+		s.callDinnerOff(channelID, vote, fmt.Sprintf("🙅 Everyone who volunteered to cook *%s* is away tonight, so there's no dinner today.", vote.WinningDish))
+		return
+	}
+
+	fair := channelSettings.CookPick == settings.CookPickFair
+	recentCooks := make(map[string]int)
+	if fair {
+		loads, err := s.rotationService.Loads(channelID, time.Now())
+		if err != nil {
+			s.logger.Error("Failed to get the cooking load of channel %d: %v", channelID, err)
+		} else {
+			recentCooks = loads
+		}
+	}
+
+	cook := poll.PickCook(volunteers, recentCooks, fair, rand.Float64())
+	if err := s.pollService.SelectCook(channelID, vote.PollID, cook); err != nil {
+		if !errors.Is(err, poll.ErrCookDecided) {
+			s.logger.Error("Failed to select cook for vote %s: %v", vote.PollID, err)
+		}
+		return
+	}
+
+	username := vote.VolunteerNames[cook]
+	s.logger.Info("Picked %s of %d volunteers to cook %s in channel %d", username, len(volunteers), vote.WinningDish, channelID)
+
+	text := fmt.Sprintf("@%s has volunteered to cook %s tonight!", username, vote.WinningDish)
+	if len(volunteers) > 1 {
+		how := "at random"
+		if fair {
+			how = "by who cooked least lately"
+		}
+		text = fmt.Sprintf("🙋 Volunteers: @%s\n🎲 Picked %s: @%s cooks %s tonight!", strings.Join(poll.VolunteerNames(vote), ", @"), how, username, vote.WinningDish)
+	}
+	s.closeCookRequest(channelID, vote, text)
+	s.askCookToStart(channelID, vote.PollID, username, vote.WinningDish)
+}
+
+// assignCookByRotation makes whoever's turn it is cook a vote's winning dish after nobody volunteered
+// Without anyone in the rotation who may cook it, dinner is called off
+func (s *Service) assignCookByRotation(channelID int64, vote *models.VoteState) {
+	turns, err := s.rotationService.Order(channelID, time.Now())
+	if err != nil {
+		s.logger.Error("Failed to get the cook rotation of channel %d: %v", channelID, err)
+	}
+
+	eligible := s.atHome(channelID, rotation.Eligible(turns, vote))
+	if len(eligible) == 0 {
+		s.callDinnerOff(channelID, vote, fmt.Sprintf("🙅 Nobody volunteered to cook *%s* and nobody in the /rotation can take a turn, so there's no dinner today.", vote.WinningDish))
+		return
+	}
+
+	member := eligible[0].Member
+	if _, err := s.pollService.AssignCook(channelID, vote.PollID, member.UserID, member.Username); err != nil {
+		if !errors.Is(err, poll.ErrCookDecided) {
+			s.logger.Error("Failed to assign cook for vote %s: %v", vote.PollID, err)
+		}
+		return
+	}
+
+	s.closeCookRequest(channelID, vote, fmt.Sprintf("🔄 Nobody volunteered, so by rotation it's @%s's turn to cook %s tonight.", member.Username, vote.WinningDish))
+	s.askCookToStart(channelID, vote.PollID, member.Username, vote.WinningDish)
+}
+
+// askCookToStart tells the picked cook they're cooking tonight, with a button to start
+func (s *Service) askCookToStart(channelID int64, pollID, username, dish string) {
+	keyboard := tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("👩‍🍳 Start cooking", fmt.Sprintf("cook_start:%s", pollID)),
+		),
+	)
+	s.bot.SendMessageWithKeyboard(channelID, fmt.Sprintf("👩‍🍳 @%s, you're cooking *%s* tonight! Press the button when you're ready for the recipe.", username, dish), keyboard)
+}
+
+// askAgainForCook asks for a cook once more after nobody volunteered in time
+func (s *Service) askAgainForCook(channelID int64, vote *models.VoteState, channelSettings settings.Settings) {
+	s.logger.Info("No cook volunteers after %s for vote %s in channel %d, asking again", channelSettings.CookTimeout, vote.PollID, channelID)
+
+	s.closeCookRequest(channelID, vote, fmt.Sprintf("⏰ Nobody volunteered to cook %s in %d minutes.", vote.WinningDish, int(channelSettings.CookTimeout.Minutes())))
+
+	text := fmt.Sprintf("🙋 Still looking for a cook for *%s* tonight! If you voted for it, press the button below to volunteer.", vote.WinningDish)
+	if vote.CookRetries+1 == channelSettings.CookRetries {
+		text += "\n\nThis is the last call."
+	}
+	if err := s.AskForCook(channelID, vote.PollID, text); err != nil {
+		s.logger.Error("Failed to ask again for a cook: %v", err)
+	}
+}
+
+// callDinnerOff records that there's no dinner from a vote today and tells the family why
+func (s *Service) callDinnerOff(channelID int64, vote *models.VoteState, text string) {
+	if _, err := s.pollService.CancelDinner(channelID, vote.PollID); err != nil {
+		if !errors.Is(err, poll.ErrCookDecided) {
+			s.logger.Error("Failed to call off dinner for vote %s: %v", vote.PollID, err)
+		}
+		return
+	}
+
+	s.advanceWorkflow(channelID, workflow.Cancelled, "dinner called off")
+	s.closeCookRequest(channelID, vote, fmt.Sprintf("🙅 No dinner from %s today.", vote.WinningDish))
+	s.bot.SendMessage(channelID, text)
+}
+
+// closeCookRequest replaces the text of a request for a cook and removes its volunteer buttons
+func (s *Service) closeCookRequest(channelID int64, vote *models.VoteState, text string) {
+	if vote.CookRequestMessageID == 0 {
+		return
+	}
+	if _, err := s.bot.EditMessage(channelID, vote.CookRequestMessageID, text); err != nil {
+		s.logger.Error("Failed to close the request for a cook: %v", err)
+	}
+}
+
+// volunteerKeyboard returns the buttons to volunteer to cook the winning dish of a vote, or call dinner off
+func volunteerKeyboard(pollID string) tgbotapi.InlineKeyboardMarkup {
+	return tgbotapi.NewInlineKeyboardMarkup(
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("I'll cook!", fmt.Sprintf("volunteer:%s", pollID)),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("🙅 No dinner today", fmt.Sprintf("no_dinner:%s", pollID)),
+		),
+	)
+}
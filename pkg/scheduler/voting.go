@@ -0,0 +1,119 @@
+package scheduler
+
+import (
+	"fmt"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"github.com/korjavin/whatsfordinner/pkg/models"
+	"github.com/korjavin/whatsfordinner/pkg/poll"
+	"github.com/korjavin/whatsfordinner/pkg/workflow"
+)
+
+// rankedPollPrefix starts the IDs of ranked votes, which aren't Telegram polls and so have no poll ID of their own
+const rankedPollPrefix = "ranked-"
+
+// StartVote starts a vote on the options in the channel's voting mode
+// Plurality and approval votes are Telegram polls with one or any number of answers; a ranked vote is
+// a message with a button per dish, which members press in order of preference
+func (s *Service) StartVote(channelID int64, question string, options []string) (*models.VoteState, error) {
+	channelSettings, err := s.settingsService.Get(channelID)
+	if err != nil {
+		s.logger.Error("Failed to get settings for channel %d: %v", channelID, err)
+	}
+	mode := channelSettings.VotingMode
+
+	var pollID string
+	var messageID int
+	if mode == poll.ModeRanked {
+		pollID = fmt.Sprintf("%s%d-%d", rankedPollPrefix, channelID, time.Now().UnixNano())
+		msg, err := s.bot.SendMessageWithKeyboard(channelID, rankedBallotText(question, options), rankedBallotKeyboard(pollID, options))
+		if err != nil {
+			return nil, fmt.Errorf("failed to send ranked ballot: %w", err)
+		}
+		messageID = msg.MessageID
+	} else {
+		pollMsg, err := s.bot.CreatePoll(channelID, question, options, mode == poll.ModeApproval)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create poll: %w", err)
+		}
+		pollID = pollMsg.Poll.ID
+		messageID = pollMsg.MessageID
+	}
+
+	vote, err := s.pollService.CreateVote(channelID, pollID, messageID, options, mode)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create vote state: %w", err)
+	}
+
+	// A vote replacing the running one, e.g. with a new suggestion, keeps the family in the same workflow
+	if err := s.workflowService.Link(channelID, pollID, ""); err != nil {
+		s.logger.Error("Failed to link vote %s to its workflow: %v", pollID, err)
+	}
+	s.advanceWorkflow(channelID, workflow.Voting, "poll started")
+
+	s.logger.Info("Created %s vote %s for channel %d", mode, pollID, channelID)
+	return vote, nil
+}
+
+// CloseBallot stops a vote's message from taking ballots: a Telegram poll is stopped, the ranking buttons are removed
+func (s *Service) CloseBallot(channelID int64, vote *models.VoteState) {
+	if vote.MessageID == 0 {
+		return
+	}
+
+	if poll.ModeOf(vote) == poll.ModeRanked {
+		text := "🗳 The ranking is closed.\n\n"
+		for i, option := range vote.Options {
+			text += fmt.Sprintf("%d. %s\n", i+1, option)
+		}
+		if _, err := s.bot.EditMessage(channelID, vote.MessageID, text); err != nil {
+			s.logger.Error("Failed to close ranked ballot %s: %v", vote.PollID, err)
+		}
+		return
+	}
+
+	if err := s.bot.StopPoll(channelID, vote.MessageID); err != nil {
+		s.logger.Error("Failed to stop poll %s: %v", vote.PollID, err)
+	}
+}
+
+// VotingInstructions tells the family how to vote in a voting mode
+func VotingInstructions(mode string) string {
+	switch mode {
+	case poll.ModeApproval:
+		return "Please vote for every dish you'd be happy to eat tonight!"
+	case poll.ModeRanked:
+		return "Please rank the dishes by tapping them in the message above, your favorite first!"
+	default:
+		return "Please vote for your preferred dinner option!"
+	}
+}
+
+// rankedBallotText returns the message of a ranked vote, explaining how to rank the dishes
+func rankedBallotText(question string, options []string) string {
+	text := fmt.Sprintf("🗳 %s\n\nTap the dishes in order of preference, your favorite first. ", question)
+	text += "Tap ✅ Done to stop early, or ↩️ Start over to change your ranking.\n\n"
+	for i, option := range options {
+		text += fmt.Sprintf("%d. %s\n", i+1, option)
+	}
+	return text
+}
+
+// rankedBallotKeyboard returns a button per dish of a ranked vote, and the done and start over buttons
+func rankedBallotKeyboard(pollID string, options []string) tgbotapi.InlineKeyboardMarkup {
+	var rows [][]tgbotapi.InlineKeyboardButton
+	for i, option := range options {
+		rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("🍴 "+option, fmt.Sprintf("rank:%s:%d", pollID, i)),
+		))
+	}
+
+	rows = append(rows, tgbotapi.NewInlineKeyboardRow(
+		tgbotapi.NewInlineKeyboardButtonData("↩️ Start over", "rank_reset:"+pollID),
+		tgbotapi.NewInlineKeyboardButtonData("✅ Done", "rank_done:"+pollID),
+	))
+
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
@@ -0,0 +1,100 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/korjavin/whatsfordinner/pkg/models"
+	"github.com/korjavin/whatsfordinner/pkg/workflow"
+)
+
+// workflowCheckInterval is how often the scheduler looks for dinner workflows past their state's timeout
+const workflowCheckInterval = time.Minute
+
+// runWorkflowTimeouts ends the dinner workflows that stayed in a state too long
+func (s *Service) runWorkflowTimeouts() {
+	s.logger.Info("Starting workflow timeout checker")
+
+	ticker := time.NewTicker(workflowCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.expireWorkflows(time.Now())
+		case <-s.stopChan:
+			return
+		}
+	}
+}
+
+// expireWorkflows ends every dinner workflow whose state timed out by now
+func (s *Service) expireWorkflows(now time.Time) {
+	due, err := s.workflowService.Due(now)
+	if err != nil {
+		s.logger.Error("Failed to get the workflows that timed out: %v", err)
+		return
+	}
+	for _, timedOut := range due {
+		s.timeOut(timedOut, now)
+	}
+}
+
+// timeOut ends a workflow whose state timed out: a dinner that wasn't served yet ends as it does at the cutoff,
+// so the family sees it end too; a served one just stops waiting for ratings
+func (s *Service) timeOut(timedOut models.Workflow, now time.Time) {
+	timeout := workflow.Timeouts[workflow.State(timedOut.State)]
+	s.logger.Info("Workflow %s of channel %d timed out after %s %s", timedOut.ID, timedOut.ChannelID, timeout.After, timedOut.State)
+
+	// The channel's dinner ends only if it's still where it timed out; it may have moved on meanwhile
+	current, err := s.workflowService.Current(timedOut.ChannelID)
+	if err == nil && current.ID == timedOut.ID && workflow.IsUnfinished(current) {
+		if current.State == timedOut.State && current.StateSince.Equal(timedOut.StateSince) {
+			s.endDinner(timedOut.ChannelID, fmt.Sprintf("Nothing has happened for %d hours!", int(timeout.After.Hours())), workflow.TimeoutReason(timeout))
+		}
+		return
+	}
+
+	if _, err := s.workflowService.Expire(timedOut.ID, now); err != nil && !errors.Is(err, workflow.ErrWrongState) {
+		s.logger.Error("Failed to time out workflow %s: %v", timedOut.ID, err)
+	}
+}
+
+// advanceWorkflow moves the channel's dinner workflow to a state and reports whether its guard allowed it
+func (s *Service) advanceWorkflow(channelID int64, to workflow.State, reason string) bool {
+	if _, err := s.workflowService.Advance(channelID, to, reason); err != nil {
+		s.logger.Error("Failed to move the workflow of channel %d to %s: %v", channelID, to, err)
+		return false
+	}
+	return true
+}
+
+// beginWorkflow starts a dinner workflow in the channel, or keeps the one that's still proposing dishes,
+// and reports whether there is one to go on with
+func (s *Service) beginWorkflow(channelID int64, reason string) bool {
+	if _, err := s.workflowService.Begin(channelID, reason); err != nil {
+		s.logger.Error("Failed to begin the workflow of channel %d: %v", channelID, err)
+		return false
+	}
+	return true
+}
+
+// endWorkflow ends the channel's dinner workflow at the cutoff: a dinner that wasn't served is cancelled,
+// a served one is closed unless the family is still rating it
+func (s *Service) endWorkflow(channelID int64, reason string) {
+	current, err := s.workflowService.Current(channelID)
+	if err != nil {
+		if !errors.Is(err, workflow.ErrNoWorkflow) {
+			s.logger.Error("Failed to get the workflow of channel %d: %v", channelID, err)
+		}
+		return
+	}
+
+	switch {
+	case workflow.IsUnfinished(current):
+		s.advanceWorkflow(channelID, workflow.Cancelled, reason)
+	case workflow.State(current.State) == workflow.Served:
+		s.advanceWorkflow(channelID, workflow.Closed, reason)
+	}
+}
@@ -0,0 +1,65 @@
+package settings
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Clock is a time of day, e.g. the dinner time
+type Clock struct {
+	Hour   int
+	Minute int
+}
+
+// ParseClock parses a time of day such as "18:30", "18.30" or "18"
+func ParseClock(s string) (Clock, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return Clock{}, fmt.Errorf("empty time")
+	}
+
+	hourStr, minuteStr, found := strings.Cut(strings.ReplaceAll(s, ".", ":"), ":")
+	if !found {
+		minuteStr = "0"
+	}
+
+	hour, err := strconv.Atoi(hourStr)
+	if err != nil || hour < 0 || hour > 23 {
+		return Clock{}, fmt.Errorf("invalid time: %q", s)
+	}
+
+	minute, err := strconv.Atoi(minuteStr)
+	if err != nil || minute < 0 || minute > 59 {
+		return Clock{}, fmt.Errorf("invalid time: %q", s)
+	}
+
+	return Clock{Hour: hour, Minute: minute}, nil
+}
+
+// mustParseClock parses a clock constant, panicking on invalid input
+func mustParseClock(s string) Clock {
+	clock, err := ParseClock(s)
+	if err != nil {
+		panic(err)
+	}
+	return clock
+}
+
+// On returns the moment of this clock on the day of t, in t's location
+func (c Clock) On(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), c.Hour, c.Minute, 0, 0, t.Location())
+}
+
+// StartOfDay returns midnight of t's day in t's location
+// Unlike t.Truncate(24 * time.Hour), which works in UTC, this respects the timezone and DST
+func StartOfDay(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+}
+
+// String formats the clock as "HH:MM"
+func (c Clock) String() string {
+	return fmt.Sprintf("%02d:%02d", c.Hour, c.Minute)
+}
@@ -0,0 +1,5 @@
+// Package settings provides per-channel settings.
+// Cuisines, dinner time, timezone, vote threshold and minimum votes, RSVP deadline, voting mode, timeouts, how the cook is picked from the volunteers,
+// what happens when nobody volunteers, language and dietary rules are stored in the channel state,
+// and every service reads them from here instead of the global config.
+package settings
@@ -0,0 +1,517 @@
+package settings
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/korjavin/whatsfordinner/pkg/logger"
+	"github.com/korjavin/whatsfordinner/pkg/models"
+	"github.com/korjavin/whatsfordinner/pkg/poll"
+	"github.com/korjavin/whatsfordinner/pkg/storage"
+)
+
+// Defaults for channels that haven't changed a setting
+const (
+	DefaultDinnerTime      = "15:00"
+	DefaultCutoffTime      = "21:00"
+	DefaultVoteThreshold   = 2.0 / 3.0
+	DefaultVotingMode      = poll.ModePlurality
+	DefaultRSVPDeadline    = 15 * time.Minute
+	DefaultMinVotes        = 1
+	DefaultCookTimeout     = 15 * time.Minute
+	DefaultVolunteerWindow = 5 * time.Minute
+	DefaultCookPick        = CookPickFair
+	DefaultCookRetries     = 1
+	DefaultNoCookAction    = NoCookNoDinner
+	DefaultLanguage        = "en"
+)
+
+// How the cook is picked when several family members volunteer
+const (
+	CookPickRandom = "random" // Every volunteer has the same chance
+	CookPickFair   = "fair"   // Volunteers who cooked less lately have a better chance
+)
+
+// What happens when nobody volunteers to cook, even after asking again
+const (
+	NoCookNoDinner = "no_dinner" // Record that there's no dinner today
+	NoCookNewPoll  = "new_poll"  // Start a new poll, maybe someone wants to cook another dish
+	NoCookAssign   = "assign"    // Assign the cook whose turn it is in the rotation
+)
+
+// maxCookRetries is how many times the family may be asked again for a cook
+const maxCookRetries = 5
+
+// maxMinVotes is the highest number of votes a channel may require to close a poll
+const maxMinVotes = 20
+
+// Languages lists the supported language codes with their names as used in LLM prompts
+var Languages = map[string]string{
+	"en": "English",
+	"ru": "Russian",
+}
+
+// Settings holds the effective settings of a channel, with defaults filled in
+type Settings struct {
+	Cuisines        []string
+	DinnerTime      Clock
+	CutoffTime      Clock
+	Location        *time.Location
+	VoteThreshold   float64
+	VotingMode      string
+	RSVPDeadline    time.Duration // Zero means the poll starts without asking who's eating at home
+	MinVotes        int
+	CookTimeout     time.Duration
+	VolunteerWindow time.Duration // Zero means the first volunteer cooks
+	CookPick        string
+	CookRetries     int
+	NoCookAction    string
+	Language        string
+	DietaryRules    []string
+}
+
+// Now returns the current time in the channel's timezone
+func (s Settings) Now() time.Time {
+	return time.Now().In(s.Location)
+}
+
+// Today returns the start of the current day in the channel's timezone
+func (s Settings) Today() time.Time {
+	return StartOfDay(s.Now())
+}
+
+// LanguageName returns the name of the channel's language, e.g. "Russian"
+func (s Settings) LanguageName() string {
+	if name, ok := Languages[s.Language]; ok {
+		return name
+	}
+	return Languages[DefaultLanguage]
+}
+
+// Service provides settings functionality
+type Service struct {
+	store           *storage.Store
+	logger          *logger.Logger
+	defaultCuisines []string
+}
+
+// New creates a new settings service
+// The default cuisines come from the CUISINES environment variable
+func New(store *storage.Store, defaultCuisines []string) *Service {
+	return &Service{
+		store:           store,
+		logger:          logger.New(""),
+		defaultCuisines: defaultCuisines,
+	}
+}
+
+// Get returns the effective settings of a channel
+func (s *Service) Get(channelID int64) (Settings, error) {
+	channelKey := fmt.Sprintf("channel:%d", channelID)
+
+	var channelState models.ChannelState
+	if err := s.store.Get(channelKey, &channelState); err != nil {
+		// A channel without state simply uses the defaults
+		channelState = models.ChannelState{ChannelID: channelID}
+	}
+
+	return s.Resolve(channelState), nil
+}
+
+// Resolve fills in defaults for everything a channel state doesn't set
+// Invalid stored values fall back to the defaults as well
+func (s *Service) Resolve(channelState models.ChannelState) Settings {
+	settings := Settings{
+		Cuisines:        s.defaultCuisines,
+		DinnerTime:      mustParseClock(DefaultDinnerTime),
+		CutoffTime:      mustParseClock(DefaultCutoffTime),
+		Location:        time.Local,
+		VoteThreshold:   DefaultVoteThreshold,
+		VotingMode:      DefaultVotingMode,
+		RSVPDeadline:    DefaultRSVPDeadline,
+		MinVotes:        DefaultMinVotes,
+		CookTimeout:     DefaultCookTimeout,
+		VolunteerWindow: DefaultVolunteerWindow,
+		CookPick:        DefaultCookPick,
+		CookRetries:     DefaultCookRetries,
+		NoCookAction:    DefaultNoCookAction,
+		Language:        DefaultLanguage,
+		DietaryRules:    channelState.DietaryRules,
+	}
+
+	if len(channelState.Cuisines) > 0 {
+		settings.Cuisines = channelState.Cuisines
+	}
+	if clock, err := ParseClock(channelState.DinnerTime); err == nil {
+		settings.DinnerTime = clock
+	}
+	if clock, err := ParseClock(channelState.CutoffTime); err == nil {
+		settings.CutoffTime = clock
+	}
+	if channelState.Timezone != "" {
+		if location, err := time.LoadLocation(channelState.Timezone); err == nil {
+			settings.Location = location
+		} else {
+			s.logger.Warn("Invalid timezone %q for channel %d, using the server timezone", channelState.Timezone, channelState.ChannelID)
+		}
+	}
+	if channelState.VoteThreshold > 0 && channelState.VoteThreshold <= 1 {
+		settings.VoteThreshold = channelState.VoteThreshold
+	}
+	if isVotingMode(channelState.VotingMode) {
+		settings.VotingMode = channelState.VotingMode
+	}
+	if channelState.RSVPDeadline != nil && *channelState.RSVPDeadline >= 0 {
+		settings.RSVPDeadline = time.Duration(*channelState.RSVPDeadline) * time.Minute
+	}
+	if channelState.MinVotes > 0 && channelState.MinVotes <= maxMinVotes {
+		settings.MinVotes = channelState.MinVotes
+	}
+	if channelState.CookTimeoutMinutes > 0 {
+		settings.CookTimeout = time.Duration(channelState.CookTimeoutMinutes) * time.Minute
+	}
+	if channelState.VolunteerWindow != nil && *channelState.VolunteerWindow >= 0 {
+		settings.VolunteerWindow = time.Duration(*channelState.VolunteerWindow) * time.Minute
+	}
+	if channelState.CookPick == CookPickRandom || channelState.CookPick == CookPickFair {
+		settings.CookPick = channelState.CookPick
+	}
+	if channelState.CookRetries != nil && *channelState.CookRetries >= 0 && *channelState.CookRetries <= maxCookRetries {
+		settings.CookRetries = *channelState.CookRetries
+	}
+	if channelState.NoCookAction == NoCookNoDinner || channelState.NoCookAction == NoCookNewPoll || channelState.NoCookAction == NoCookAssign {
+		settings.NoCookAction = channelState.NoCookAction
+	}
+	if _, ok := Languages[channelState.Language]; ok {
+		settings.Language = channelState.Language
+	}
+
+	return settings
+}
+
+// Update loads the channel state, applies a change and saves it
+// The channel state is created if the channel doesn't have one yet
+func (s *Service) Update(channelID int64, change func(channelState *models.ChannelState) error) error {
+	channelKey := fmt.Sprintf("channel:%d", channelID)
+
+	var channelState models.ChannelState
+	if err := s.store.Get(channelKey, &channelState); err != nil {
+		channelState = models.ChannelState{
+			ChannelID:    channelID,
+			FridgeID:     fmt.Sprintf("fridge:%d", channelID),
+			LastActivity: time.Now(),
+		}
+	}
+
+	if err := change(&channelState); err != nil {
+		return err
+	}
+
+	channelState.LastActivity = time.Now()
+	return s.store.Set(channelKey, channelState)
+}
+
+// SetCuisines sets the preferred cuisines
+func (s *Service) SetCuisines(channelID int64, cuisines []string) error {
+	cuisines = cleanList(cuisines)
+	if len(cuisines) == 0 {
+		return fmt.Errorf("at least one cuisine is required")
+	}
+
+	s.logger.Info("Setting cuisines of channel %d to %v", channelID, cuisines)
+	return s.Update(channelID, func(channelState *models.ChannelState) error {
+		channelState.Cuisines = cuisines
+		return nil
+	})
+}
+
+// SetDinnerTime sets when the daily dinner poll starts, e.g. "18:30"
+func (s *Service) SetDinnerTime(channelID int64, value string) error {
+	clock, err := ParseClock(value)
+	if err != nil {
+		return err
+	}
+
+	s.logger.Info("Setting dinner time of channel %d to %s", channelID, clock)
+	return s.Update(channelID, func(channelState *models.ChannelState) error {
+		channelState.DinnerTime = clock.String()
+		return nil
+	})
+}
+
+// SetCutoffTime sets when unfinished dinner workflows are closed, e.g. "21:00"
+func (s *Service) SetCutoffTime(channelID int64, value string) error {
+	clock, err := ParseClock(value)
+	if err != nil {
+		return err
+	}
+
+	s.logger.Info("Setting cutoff time of channel %d to %s", channelID, clock)
+	return s.Update(channelID, func(channelState *models.ChannelState) error {
+		channelState.CutoffTime = clock.String()
+		return nil
+	})
+}
+
+// SetTimezone sets the channel's timezone by its IANA name, e.g. "Europe/Berlin"
+func (s *Service) SetTimezone(channelID int64, name string) error {
+	name = strings.TrimSpace(name)
+	if _, err := time.LoadLocation(name); err != nil || name == "" || name == "Local" {
+		return fmt.Errorf("unknown timezone: %q", name)
+	}
+
+	s.logger.Info("Setting timezone of channel %d to %s", channelID, name)
+	return s.Update(channelID, func(channelState *models.ChannelState) error {
+		channelState.Timezone = name
+		return nil
+	})
+}
+
+// SetVoteThreshold sets the share of members that must vote before a poll closes
+func (s *Service) SetVoteThreshold(channelID int64, threshold float64) error {
+	if threshold <= 0 || threshold > 1 {
+		return fmt.Errorf("vote threshold must be between 0 and 1, got %v", threshold)
+	}
+
+	s.logger.Info("Setting vote threshold of channel %d to %.2f", channelID, threshold)
+	return s.Update(channelID, func(channelState *models.ChannelState) error {
+		channelState.VoteThreshold = threshold
+		return nil
+	})
+}
+
+// SetVotingMode sets how dinner polls are decided: poll.ModePlurality, poll.ModeApproval or poll.ModeRanked
+func (s *Service) SetVotingMode(channelID int64, mode string) error {
+	mode = strings.ToLower(strings.TrimSpace(mode))
+	if !isVotingMode(mode) {
+		return fmt.Errorf("unknown voting mode: %q", mode)
+	}
+
+	s.logger.Info("Setting voting mode of channel %d to %s", channelID, mode)
+	return s.Update(channelID, func(channelState *models.ChannelState) error {
+		channelState.VotingMode = mode
+		return nil
+	})
+}
+
+// isVotingMode reports whether a voting mode is supported
+func isVotingMode(mode string) bool {
+	return mode == poll.ModePlurality || mode == poll.ModeApproval || mode == poll.ModeRanked
+}
+
+// SetRSVPDeadline sets how long the family is asked who's eating at home before the poll starts
+// A zero deadline starts the poll right away
+func (s *Service) SetRSVPDeadline(channelID int64, deadline time.Duration) error {
+	if deadline < 0 || deadline > 2*time.Hour {
+		return fmt.Errorf("RSVP deadline must be between 0 and 120 minutes, got %s", deadline)
+	}
+
+	minutes := int(deadline / time.Minute)
+	s.logger.Info("Setting RSVP deadline of channel %d to %d min", channelID, minutes)
+	return s.Update(channelID, func(channelState *models.ChannelState) error {
+		channelState.RSVPDeadline = &minutes
+		return nil
+	})
+}
+
+// SetMinVotes sets the fewest votes that close a poll, however few are eating at home
+func (s *Service) SetMinVotes(channelID int64, minVotes int) error {
+	if minVotes < 1 || minVotes > maxMinVotes {
+		return fmt.Errorf("minimum votes must be between 1 and %d, got %d", maxMinVotes, minVotes)
+	}
+
+	s.logger.Info("Setting minimum votes of channel %d to %d", channelID, minVotes)
+	return s.Update(channelID, func(channelState *models.ChannelState) error {
+		channelState.MinVotes = minVotes
+		return nil
+	})
+}
+
+// SetCookTimeout sets how long to wait for a cook volunteer before asking again
+func (s *Service) SetCookTimeout(channelID int64, timeout time.Duration) error {
+	if timeout < time.Minute {
+		return fmt.Errorf("cook timeout must be at least a minute, got %s", timeout)
+	}
+
+	s.logger.Info("Setting cook timeout of channel %d to %s", channelID, timeout)
+	return s.Update(channelID, func(channelState *models.ChannelState) error {
+		channelState.CookTimeoutMinutes = int(timeout / time.Minute)
+		return nil
+	})
+}
+
+// SetVolunteerWindow sets how long volunteers are gathered before the cook is picked
+// A zero window means the first volunteer cooks
+func (s *Service) SetVolunteerWindow(channelID int64, window time.Duration) error {
+	if window < 0 || window > time.Hour {
+		return fmt.Errorf("volunteer window must be between 0 and 60 minutes, got %s", window)
+	}
+
+	minutes := int(window / time.Minute)
+	s.logger.Info("Setting volunteer window of channel %d to %d min", channelID, minutes)
+	return s.Update(channelID, func(channelState *models.ChannelState) error {
+		channelState.VolunteerWindow = &minutes
+		return nil
+	})
+}
+
+// SetCookPick sets how the cook is picked from the volunteers, CookPickRandom or CookPickFair
+func (s *Service) SetCookPick(channelID int64, pick string) error {
+	pick = strings.ToLower(strings.TrimSpace(pick))
+	if pick != CookPickRandom && pick != CookPickFair {
+		return fmt.Errorf("unknown way to pick the cook: %q", pick)
+	}
+
+	s.logger.Info("Setting cook pick of channel %d to %s", channelID, pick)
+	return s.Update(channelID, func(channelState *models.ChannelState) error {
+		channelState.CookPick = pick
+		return nil
+	})
+}
+
+// SetCookRetries sets how many times to ask again for a cook when nobody volunteers
+func (s *Service) SetCookRetries(channelID int64, retries int) error {
+	if retries < 0 || retries > maxCookRetries {
+		return fmt.Errorf("cook retries must be between 0 and %d, got %d", maxCookRetries, retries)
+	}
+
+	s.logger.Info("Setting cook retries of channel %d to %d", channelID, retries)
+	return s.Update(channelID, func(channelState *models.ChannelState) error {
+		channelState.CookRetries = &retries
+		return nil
+	})
+}
+
+// SetNoCookAction sets what happens when still nobody cooks, NoCookNoDinner, NoCookNewPoll or NoCookAssign
+func (s *Service) SetNoCookAction(channelID int64, action string) error {
+	action = strings.ToLower(strings.TrimSpace(action))
+	if action != NoCookNoDinner && action != NoCookNewPoll && action != NoCookAssign {
+		return fmt.Errorf("unknown action when nobody cooks: %q", action)
+	}
+
+	s.logger.Info("Setting no cook action of channel %d to %s", channelID, action)
+	return s.Update(channelID, func(channelState *models.ChannelState) error {
+		channelState.NoCookAction = action
+		return nil
+	})
+}
+
+// SetLanguage sets the language used for suggestions
+func (s *Service) SetLanguage(channelID int64, language string) error {
+	language = strings.ToLower(strings.TrimSpace(language))
+	if _, ok := Languages[language]; !ok {
+		return fmt.Errorf("unsupported language: %q", language)
+	}
+
+	s.logger.Info("Setting language of channel %d to %s", channelID, language)
+	return s.Update(channelID, func(channelState *models.ChannelState) error {
+		channelState.Language = language
+		return nil
+	})
+}
+
+// SetDietaryRules sets the family-wide dietary rules; an empty list removes them
+func (s *Service) SetDietaryRules(channelID int64, rules []string) error {
+	rules = cleanList(rules)
+
+	s.logger.Info("Setting dietary rules of channel %d to %v", channelID, rules)
+	return s.Update(channelID, func(channelState *models.ChannelState) error {
+		channelState.DietaryRules = rules
+		return nil
+	})
+}
+
+// Reset puts all settings of a channel back to the defaults
+func (s *Service) Reset(channelID int64) error {
+	s.logger.Info("Resetting settings of channel %d", channelID)
+	return s.Update(channelID, func(channelState *models.ChannelState) error {
+		channelState.Cuisines = nil
+		channelState.DinnerTime = ""
+		channelState.CutoffTime = ""
+		channelState.Timezone = ""
+		channelState.VoteThreshold = 0
+		channelState.VotingMode = ""
+		channelState.RSVPDeadline = nil
+		channelState.MinVotes = 0
+		channelState.CookTimeoutMinutes = 0
+		channelState.VolunteerWindow = nil
+		channelState.CookPick = ""
+		channelState.CookRetries = nil
+		channelState.NoCookAction = ""
+		channelState.Language = ""
+		channelState.DietaryRules = nil
+		return nil
+	})
+}
+
+// ParseThreshold parses a vote threshold such as "2/3", "75%" or "0.5"
+func ParseThreshold(s string) (float64, error) {
+	s = strings.TrimSpace(s)
+
+	var threshold float64
+	var err error
+	if numerator, denominator, found := strings.Cut(s, "/"); found {
+		var n, d float64
+		n, err = strconv.ParseFloat(strings.TrimSpace(numerator), 64)
+		if err == nil {
+			d, err = strconv.ParseFloat(strings.TrimSpace(denominator), 64)
+		}
+		if err == nil && d != 0 {
+			threshold = n / d
+		}
+	} else if percent, found := strings.CutSuffix(s, "%"); found {
+		threshold, err = strconv.ParseFloat(strings.TrimSpace(percent), 64)
+		threshold /= 100
+	} else {
+		threshold, err = strconv.ParseFloat(s, 64)
+	}
+
+	if err != nil || threshold <= 0 || threshold > 1 {
+		return 0, fmt.Errorf("invalid vote threshold: %q", s)
+	}
+	return threshold, nil
+}
+
+// ParseTimeout parses a timeout given in minutes ("20") or as a duration ("20m", "1h")
+func ParseTimeout(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if minutes, err := strconv.Atoi(s); err == nil {
+		return time.Duration(minutes) * time.Minute, nil
+	}
+
+	timeout, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid timeout: %q", s)
+	}
+	return timeout, nil
+}
+
+// ParseList splits a comma-separated list as typed by a user
+// "none" or "-" yield an empty list
+func ParseList(s string) []string {
+	s = strings.TrimSpace(s)
+	if strings.EqualFold(s, "none") || s == "-" {
+		return nil
+	}
+
+	return cleanList(strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r == ';' || r == '\n'
+	}))
+}
+
+// cleanList trims entries and drops empty ones and duplicates
+func cleanList(items []string) []string {
+	seen := make(map[string]bool)
+	result := make([]string, 0, len(items))
+	for _, item := range items {
+		item = strings.TrimSpace(item)
+		if item == "" || seen[strings.ToLower(item)] {
+			continue
+		}
+		seen[strings.ToLower(item)] = true
+		result = append(result, item)
+	}
+	return result
+}
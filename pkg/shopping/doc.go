@@ -0,0 +1,4 @@
+// Package shopping provides functionality for managing the shopping list.
+// It collects ingredients missing for the winning dish, lets a family member volunteer to buy them,
+// and moves the bought items into the fridge once shopping is done.
+package shopping
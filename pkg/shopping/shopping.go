@@ -0,0 +1,258 @@
+package shopping
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/korjavin/whatsfordinner/pkg/dinner"
+	"github.com/korjavin/whatsfordinner/pkg/fridge"
+	"github.com/korjavin/whatsfordinner/pkg/ingredients"
+	"github.com/korjavin/whatsfordinner/pkg/logger"
+	"github.com/korjavin/whatsfordinner/pkg/models"
+	"github.com/korjavin/whatsfordinner/pkg/quantity"
+	"github.com/korjavin/whatsfordinner/pkg/storage"
+)
+
+var (
+	// ErrEmptyList is returned when there's nothing on the shopping list
+	ErrEmptyList = errors.New("shopping list is empty")
+	// ErrAlreadyClaimed is returned when someone else has already volunteered to shop
+	ErrAlreadyClaimed = errors.New("someone is already buying the shopping list")
+	// ErrNotBuyer is returned when someone other than the buyer tries to finish shopping
+	ErrNotBuyer = errors.New("only the buyer can finish shopping")
+)
+
+// Service provides shopping list functionality
+type Service struct {
+	store         *storage.Store
+	fridgeService *fridge.Service
+	logger        *logger.Logger
+}
+
+// New creates a new shopping service
+func New(store *storage.Store, fridgeService *fridge.Service) *Service {
+	return &Service{
+		store:         store,
+		fridgeService: fridgeService,
+		logger:        logger.New(""),
+	}
+}
+
+// GetList retrieves the shopping list for a channel
+func (s *Service) GetList(channelID int64) (*models.ShoppingList, error) {
+	listKey := fmt.Sprintf("shopping:%d", channelID)
+
+	var list models.ShoppingList
+	err := s.store.Get(listKey, &list)
+	if err != nil {
+		// If the list doesn't exist, create a new one
+		list = models.ShoppingList{
+			ID:          listKey,
+			ChannelID:   channelID,
+			Items:       make([]models.ShoppingItem, 0),
+			LastUpdated: time.Now(),
+		}
+
+		if err := s.store.Set(listKey, list); err != nil {
+			return nil, fmt.Errorf("failed to create shopping list: %w", err)
+		}
+	}
+
+	return &list, nil
+}
+
+// PlanForDish puts the ingredients of a dish that aren't in the fridge on the shopping list
+// Returns the missing ingredients as they're written in the recipe
+func (s *Service) PlanForDish(channelID int64, dish models.Dish) ([]string, error) {
+	fridgeIngredients, err := s.fridgeService.ListIngredients(channelID)
+	if err != nil {
+		return nil, err
+	}
+
+	fridgeNames := make([]string, len(fridgeIngredients))
+	for i, ingredient := range fridgeIngredients {
+		fridgeNames[i] = ingredient.Name
+	}
+
+	missing := dinner.CompareIngredients(dish.Ingredients, fridgeNames)
+	if len(missing) == 0 {
+		return nil, nil
+	}
+
+	if err := s.AddItems(channelID, dish.Name, missing); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Added %d missing ingredients for %s to the shopping list of channel %d", len(missing), dish.Name, channelID)
+	return missing, nil
+}
+
+// AddItems adds ingredient lines such as "500 g ground beef" to the shopping list
+// Items already on the list are merged, adding up compatible amounts
+func (s *Service) AddItems(channelID int64, dishName string, lines []string) error {
+	list, err := s.GetList(channelID)
+	if err != nil {
+		return err
+	}
+
+	for _, line := range lines {
+		name, needed, hasAmount := quantity.ParseIngredient(line)
+		name = ingredients.Canonicalize(name)
+		if name == "" {
+			continue
+		}
+
+		item := models.ShoppingItem{
+			Name:    name,
+			ForDish: dishName,
+			AddedAt: time.Now(),
+		}
+		if hasAmount {
+			item.Quantity = needed.Normalize().String()
+		}
+
+		if index := findItem(list, name); index >= 0 {
+			list.Items[index] = mergeItems(list.Items[index], item)
+			continue
+		}
+
+		list.Items = append(list.Items, item)
+	}
+
+	list.LastUpdated = time.Now()
+
+	return s.store.Set(list.ID, list)
+}
+
+// RemoveItem removes an item from the shopping list
+func (s *Service) RemoveItem(channelID int64, name string) error {
+	list, err := s.GetList(channelID)
+	if err != nil {
+		return err
+	}
+
+	index := findItem(list, ingredients.Canonicalize(name))
+	if index < 0 {
+		return fmt.Errorf("item not found: %s", name)
+	}
+
+	list.Items = append(list.Items[:index], list.Items[index+1:]...)
+	list.LastUpdated = time.Now()
+
+	return s.store.Set(list.ID, list)
+}
+
+// Claim records that a family member is going to buy everything on the list
+// The same buyer can claim the list again, e.g. after more items were added
+func (s *Service) Claim(channelID int64, userID, username string) (*models.ShoppingList, error) {
+	list, err := s.GetList(channelID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(list.Items) == 0 {
+		return nil, ErrEmptyList
+	}
+
+	if list.BuyerID != "" && list.BuyerID != userID {
+		return nil, ErrAlreadyClaimed
+	}
+
+	list.BuyerID = userID
+	list.BuyerName = username
+	list.ClaimedAt = time.Now()
+	list.LastUpdated = time.Now()
+
+	s.logger.Info("User %s is buying %d items for channel %d", username, len(list.Items), channelID)
+	if err := s.store.Set(list.ID, list); err != nil {
+		return nil, fmt.Errorf("failed to save shopping list: %w", err)
+	}
+
+	return list, nil
+}
+
+// Complete finishes the shopping trip: every item goes into the fridge and the list is cleared
+// Returns the items that were bought
+func (s *Service) Complete(channelID int64, userID string) ([]models.ShoppingItem, error) {
+	list, err := s.GetList(channelID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(list.Items) == 0 {
+		return nil, ErrEmptyList
+	}
+
+	if list.BuyerID != "" && list.BuyerID != userID {
+		return nil, ErrNotBuyer
+	}
+
+	bought := list.Items
+	for _, item := range bought {
+		if err := s.fridgeService.AddIngredient(channelID, item.Name, item.Quantity); err != nil {
+			s.logger.Error("Failed to add bought item %s to fridge %d: %v", item.Name, channelID, err)
+		}
+	}
+
+	if err := s.Clear(channelID); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Shopping done for channel %d, %d items added to the fridge", channelID, len(bought))
+	return bought, nil
+}
+
+// Clear empties the shopping list and releases the buyer
+func (s *Service) Clear(channelID int64) error {
+	listKey := fmt.Sprintf("shopping:%d", channelID)
+
+	list := models.ShoppingList{
+		ID:          listKey,
+		ChannelID:   channelID,
+		Items:       make([]models.ShoppingItem, 0),
+		LastUpdated: time.Now(),
+	}
+
+	return s.store.Set(listKey, list)
+}
+
+// findItem returns the index of an item on the list, or -1 if it isn't there
+func findItem(list *models.ShoppingList, name string) int {
+	for i, item := range list.Items {
+		if item.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// mergeItems combines two entries for the same ingredient
+// Amounts are added up when they can be compared; otherwise the existing amount is kept
+func mergeItems(existing, added models.ShoppingItem) models.ShoppingItem {
+	if existing.ForDish == "" {
+		existing.ForDish = added.ForDish
+	}
+
+	if added.Quantity == "" {
+		return existing
+	}
+	if existing.Quantity == "" {
+		existing.Quantity = added.Quantity
+		return existing
+	}
+
+	existingQuantity, err := quantity.Parse(existing.Quantity)
+	if err != nil {
+		return existing
+	}
+	addedQuantity, err := quantity.Parse(added.Quantity)
+	if err != nil {
+		return existing
+	}
+	if total, err := existingQuantity.Add(addedQuantity); err == nil {
+		existing.Quantity = total.Normalize().String()
+	}
+
+	return existing
+}
@@ -1,3 +1,4 @@
-// Package state provides functionality for tracking chat state.
-// It handles remembering which chats are in which state.
+// Package state provides functionality for tracking conversation state.
+// It remembers what each member of a chat is in the middle of, like adding ingredients or typing a recipe.
+// States are kept in storage with a TTL, so they survive restarts and expire when the member wanders off.
 package state
@@ -1,11 +1,15 @@
 package state
 
 import (
+	"fmt"
 	"sync"
 	"time"
+
+	"github.com/korjavin/whatsfordinner/pkg/logger"
+	"github.com/korjavin/whatsfordinner/pkg/storage"
 )
 
-// State represents the state of a chat
+// State represents the state of a conversation
 type State string
 
 const (
@@ -17,137 +21,174 @@ const (
 	StateAddingPhotos State = "adding_photos"
 	// StateSuggestingDish is the state when the user is suggesting a dish
 	StateSuggestingDish State = "suggesting_dish"
+	// StateEditingSettings is the state when the user is typing a new value for a setting
+	StateEditingSettings State = "editing_settings"
+	// StateEditingRecipe is the state when a user is typing the parts of a recipe for /recipe add or edit
+	StateEditingRecipe State = "editing_recipe"
 )
 
-// ChatState represents the state of a chat
+// DefaultTTL is how long a conversation state lasts without any activity
+const DefaultTTL = 10 * time.Minute
+
+// Description describes what a member in a state is doing, e.g. "adding ingredients"
+func (s State) Description() string {
+	switch s {
+	case StateAddingIngredients:
+		return "adding ingredients"
+	case StateAddingPhotos:
+		return "adding fridge photos"
+	case StateSuggestingDish:
+		return "suggesting a dish"
+	case StateEditingSettings:
+		return "changing a setting"
+	case StateEditingRecipe:
+		return "writing down a recipe"
+	default:
+		return ""
+	}
+}
+
+// ChatState represents the state of a member's conversation with the bot in a chat
 type ChatState struct {
-	State     State
-	Timestamp time.Time
-	Data      map[string]string
+	ChatID    int64             `json:"chat_id"`
+	UserID    int64             `json:"user_id"`
+	State     State             `json:"state"`
+	Timestamp time.Time         `json:"timestamp"`
+	Data      map[string]string `json:"data,omitempty"`
 }
 
-// Manager manages chat states
+// Manager manages conversation states
+// States are kept in storage per member of a chat, so they survive restarts and two members don't collide;
+// a state expires after the TTL without activity
 type Manager struct {
-	states map[int64]ChatState
-	mu     sync.RWMutex
+	store  *storage.Store
+	ttl    time.Duration
+	logger *logger.Logger
+	mu     sync.Mutex // Serializes changes, which read a state and write it back
 }
 
 // New creates a new state manager
-func New() *Manager {
+func New(store *storage.Store) *Manager {
 	return &Manager{
-		states: make(map[int64]ChatState),
+		store:  store,
+		ttl:    DefaultTTL,
+		logger: logger.New("state"),
 	}
 }
 
-// SetState sets the state for a chat
-func (m *Manager) SetState(chatID int64, state State) {
+// stateKey returns the store key of a member's state in a chat
+func stateKey(chatID, userID int64) string {
+	return fmt.Sprintf("state:%d:%d", chatID, userID)
+}
+
+// load returns a member's state in a chat, if they have one that hasn't expired
+func (m *Manager) load(chatID, userID int64) (ChatState, bool) {
+	var chatState ChatState
+	if err := m.store.Get(stateKey(chatID, userID), &chatState); err != nil {
+		return ChatState{}, false
+	}
+	if time.Since(chatState.Timestamp) > m.ttl {
+		return ChatState{}, false
+	}
+	return chatState, true
+}
+
+// save stores a member's state, restarting its TTL
+func (m *Manager) save(chatState ChatState) {
+	chatState.Timestamp = time.Now()
+	if err := m.store.SetWithTTL(stateKey(chatState.ChatID, chatState.UserID), chatState, m.ttl); err != nil {
+		m.logger.Error("Failed to save state of user %d in chat %d: %v", chatState.UserID, chatState.ChatID, err)
+	}
+}
+
+// SetState sets the state of a member in a chat, keeping its data
+func (m *Manager) SetState(chatID, userID int64, state State) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	// Check if the chat already has a state
-	if existingState, ok := m.states[chatID]; ok {
-		// Update the state but keep the data
-		existingState.State = state
-		existingState.Timestamp = time.Now()
-		m.states[chatID] = existingState
-	} else {
-		// Create a new state
-		m.states[chatID] = ChatState{
-			State:     state,
-			Timestamp: time.Now(),
-			Data:      make(map[string]string),
-		}
+	chatState, ok := m.load(chatID, userID)
+	if !ok {
+		chatState = ChatState{ChatID: chatID, UserID: userID, Data: make(map[string]string)}
 	}
+	chatState.State = state
+	m.save(chatState)
 }
 
-// GetState gets the state for a chat
-func (m *Manager) GetState(chatID int64) State {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
-	if state, ok := m.states[chatID]; ok {
-		// If the state is older than 10 minutes, reset it to normal
-		if time.Since(state.Timestamp) > 10*time.Minute {
-			m.mu.RUnlock()
-			m.mu.Lock()
-			delete(m.states, chatID)
-			m.mu.Unlock()
-			m.mu.RLock()
-			return StateNormal
-		}
-		return state.State
+// GetState gets the state of a member in a chat
+func (m *Manager) GetState(chatID, userID int64) State {
+	if chatState, ok := m.load(chatID, userID); ok {
+		return chatState.State
 	}
 	return StateNormal
 }
 
-// ClearState clears the state for a chat
-func (m *Manager) ClearState(chatID int64) {
+// ClearState clears the state of a member in a chat, with its data
+func (m *Manager) ClearState(chatID, userID int64) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	delete(m.states, chatID)
+
+	if err := m.store.Delete(stateKey(chatID, userID)); err != nil {
+		m.logger.Error("Failed to clear state of user %d in chat %d: %v", userID, chatID, err)
+	}
 }
 
-// SetData sets a data value for a chat
-func (m *Manager) SetData(chatID int64, key, value string) {
+// SetData sets a data value for a member in a chat
+func (m *Manager) SetData(chatID, userID int64, key, value string) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	// Check if the chat already has a state
-	if state, ok := m.states[chatID]; ok {
-		// Update the data
-		if state.Data == nil {
-			state.Data = make(map[string]string)
-		}
-		state.Data[key] = value
-		state.Timestamp = time.Now()
-		m.states[chatID] = state
-	} else {
-		// Create a new state with normal state
-		state = ChatState{
-			State:     StateNormal,
-			Timestamp: time.Now(),
-			Data:      make(map[string]string),
-		}
-		state.Data[key] = value
-		m.states[chatID] = state
+	chatState, ok := m.load(chatID, userID)
+	if !ok {
+		chatState = ChatState{ChatID: chatID, UserID: userID, State: StateNormal}
 	}
+	if chatState.Data == nil {
+		chatState.Data = make(map[string]string)
+	}
+	chatState.Data[key] = value
+	m.save(chatState)
 }
 
-// GetData gets a data value for a chat
-func (m *Manager) GetData(chatID int64, key string) (string, bool) {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
-
-	if state, ok := m.states[chatID]; ok {
-		// If the state is older than 10 minutes, reset it to normal
-		if time.Since(state.Timestamp) > 10*time.Minute {
-			m.mu.RUnlock()
-			m.mu.Lock()
-			delete(m.states, chatID)
-			m.mu.Unlock()
-			m.mu.RLock()
-			return "", false
-		}
-
-		if state.Data != nil {
-			if value, ok := state.Data[key]; ok {
-				return value, true
-			}
-		}
+// GetData gets a data value for a member in a chat
+func (m *Manager) GetData(chatID, userID int64, key string) (string, bool) {
+	chatState, ok := m.load(chatID, userID)
+	if !ok {
+		return "", false
 	}
-
-	return "", false
+	value, ok := chatState.Data[key]
+	return value, ok
 }
 
-// ClearData clears a data value for a chat
-func (m *Manager) ClearData(chatID int64, key string) {
+// ClearData clears a data value for a member in a chat
+func (m *Manager) ClearData(chatID, userID int64, key string) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	if state, ok := m.states[chatID]; ok {
-		if state.Data != nil {
-			delete(state.Data, key)
-			state.Timestamp = time.Now()
-			m.states[chatID] = state
+	chatState, ok := m.load(chatID, userID)
+	if !ok {
+		return
+	}
+	delete(chatState.Data, key)
+	m.save(chatState)
+}
+
+// Active returns the states of everyone in the middle of something, e.g. to pick up after a restart
+func (m *Manager) Active() ([]ChatState, error) {
+	keys, err := m.store.List("state:")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list states: %w", err)
+	}
+
+	var active []ChatState
+	for _, key := range keys {
+		var chatState ChatState
+		if err := m.store.Get(key, &chatState); err != nil {
+			m.logger.Error("Failed to get state %s: %v", key, err)
+			continue
+		}
+		if chatState.State == StateNormal || time.Since(chatState.Timestamp) > m.ttl {
+			continue
 		}
+		active = append(active, chatState)
 	}
+	return active, nil
 }
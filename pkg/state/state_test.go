@@ -0,0 +1,154 @@
+package state
+
+import (
+	"testing"
+	"time"
+
+	"github.com/korjavin/whatsfordinner/pkg/storage"
+)
+
+// newTestManager creates a state manager on a fresh store
+func newTestManager(t *testing.T) *Manager {
+	t.Helper()
+	store, err := storage.New(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to open store: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return New(store)
+}
+
+func TestExpiry(t *testing.T) {
+	tests := []struct {
+		name       string
+		age        time.Duration
+		wantState  State
+		wantData   bool
+		wantActive bool
+	}{
+		{name: "fresh", age: time.Minute, wantState: StateAddingIngredients, wantData: true, wantActive: true},
+		{name: "just before the TTL", age: DefaultTTL - time.Second, wantState: StateAddingIngredients, wantData: true, wantActive: true},
+		{name: "past the TTL", age: DefaultTTL + time.Second, wantState: StateNormal},
+		{name: "long gone", age: 24 * time.Hour, wantState: StateNormal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newTestManager(t)
+
+			// The store may still hold a state past its TTL, so the manager checks its age too
+			chatState := ChatState{
+				ChatID:    1,
+				UserID:    10,
+				State:     StateAddingIngredients,
+				Timestamp: time.Now().Add(-tt.age),
+				Data:      map[string]string{"item": "milk"},
+			}
+			if err := m.store.Set(stateKey(1, 10), chatState); err != nil {
+				t.Fatalf("failed to store state: %v", err)
+			}
+
+			if got := m.GetState(1, 10); got != tt.wantState {
+				t.Errorf("GetState() = %q, want %q", got, tt.wantState)
+			}
+			if _, ok := m.GetData(1, 10, "item"); ok != tt.wantData {
+				t.Errorf("GetData() found = %v, want %v", ok, tt.wantData)
+			}
+
+			active, err := m.Active()
+			if err != nil {
+				t.Fatalf("Active() error = %v", err)
+			}
+			if got := len(active) == 1; got != tt.wantActive {
+				t.Errorf("Active() = %+v, want active %v", active, tt.wantActive)
+			}
+		})
+	}
+}
+
+func TestExpiredStateStartsOver(t *testing.T) {
+	m := newTestManager(t)
+
+	stale := ChatState{
+		ChatID:    1,
+		UserID:    10,
+		State:     StateEditingRecipe,
+		Timestamp: time.Now().Add(-2 * DefaultTTL),
+		Data:      map[string]string{"recipe": "soup"},
+	}
+	if err := m.store.Set(stateKey(1, 10), stale); err != nil {
+		t.Fatalf("failed to store state: %v", err)
+	}
+
+	// A new state doesn't pick up the expired one's data
+	m.SetState(1, 10, StateAddingPhotos)
+	if got := m.GetState(1, 10); got != StateAddingPhotos {
+		t.Errorf("GetState() = %q, want %q", got, StateAddingPhotos)
+	}
+	if value, ok := m.GetData(1, 10, "recipe"); ok {
+		t.Errorf("GetData() = %q, want the expired data gone", value)
+	}
+}
+
+func TestScoping(t *testing.T) {
+	tests := []struct {
+		name        string
+		chatID      int64
+		userID      int64
+		otherChatID int64
+		otherUserID int64
+	}{
+		{name: "two members of a chat", chatID: 1, userID: 10, otherChatID: 1, otherUserID: 20},
+		{name: "a member in two chats", chatID: 1, userID: 10, otherChatID: 2, otherUserID: 10},
+		{name: "two members of two chats", chatID: 1, userID: 10, otherChatID: 2, otherUserID: 20},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newTestManager(t)
+
+			m.SetState(tt.chatID, tt.userID, StateAddingIngredients)
+			m.SetData(tt.chatID, tt.userID, "item", "milk")
+			m.SetState(tt.otherChatID, tt.otherUserID, StateSuggestingDish)
+			m.SetData(tt.otherChatID, tt.otherUserID, "item", "soup")
+
+			if got := m.GetState(tt.chatID, tt.userID); got != StateAddingIngredients {
+				t.Errorf("GetState() = %q, want %q", got, StateAddingIngredients)
+			}
+			if got := m.GetState(tt.otherChatID, tt.otherUserID); got != StateSuggestingDish {
+				t.Errorf("other GetState() = %q, want %q", got, StateSuggestingDish)
+			}
+			if got, _ := m.GetData(tt.chatID, tt.userID, "item"); got != "milk" {
+				t.Errorf("GetData() = %q, want %q", got, "milk")
+			}
+			if got, _ := m.GetData(tt.otherChatID, tt.otherUserID, "item"); got != "soup" {
+				t.Errorf("other GetData() = %q, want %q", got, "soup")
+			}
+
+			active, err := m.Active()
+			if err != nil {
+				t.Fatalf("Active() error = %v", err)
+			}
+			if len(active) != 2 {
+				t.Fatalf("Active() = %+v, want both states", active)
+			}
+
+			// Clearing one state leaves the other alone
+			m.ClearState(tt.chatID, tt.userID)
+			if got := m.GetState(tt.chatID, tt.userID); got != StateNormal {
+				t.Errorf("GetState() after clearing = %q, want %q", got, StateNormal)
+			}
+			if got := m.GetState(tt.otherChatID, tt.otherUserID); got != StateSuggestingDish {
+				t.Errorf("other GetState() after clearing = %q, want %q", got, StateSuggestingDish)
+			}
+
+			active, err = m.Active()
+			if err != nil {
+				t.Fatalf("Active() error = %v", err)
+			}
+			if len(active) != 1 || active[0].ChatID != tt.otherChatID || active[0].UserID != tt.otherUserID {
+				t.Errorf("Active() after clearing = %+v, want only chat %d user %d", active, tt.otherChatID, tt.otherUserID)
+			}
+		})
+	}
+}
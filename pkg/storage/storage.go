@@ -1,12 +1,15 @@
 package storage
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"path/filepath"
 	"time"
 
 	"github.com/dgraph-io/badger/v3"
+	"github.com/dgraph-io/badger/v3/pb"
 	"github.com/korjavin/whatsfordinner/pkg/logger"
 )
 
@@ -56,6 +59,18 @@ func (s *Store) Set(key string, value interface{}) error {
 	})
 }
 
+// SetWithTTL stores a value for a key that expires after the given time
+func (s *Store) SetWithTTL(key string, value interface{}, ttl time.Duration) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to marshal value: %w", err)
+	}
+
+	return s.db.Update(func(txn *badger.Txn) error {
+		return txn.SetEntry(badger.NewEntry([]byte(key), data).WithTTL(ttl))
+	})
+}
+
 // Get retrieves a value for a key
 func (s *Store) Get(key string, value interface{}) error {
 	var data []byte
@@ -136,3 +151,23 @@ func (s *Store) StartGCRoutine(interval time.Duration) {
 	}()
 	logger.Global.Info("Started BadgerDB GC routine with interval %v", interval)
 }
+
+// Watch calls fn with the key and new value of every change to the keys with one of the prefixes,
+// until the context is done; a deleted key comes with an empty value
+func (s *Store) Watch(ctx context.Context, fn func(key string, value []byte), prefixes ...string) error {
+	matches := make([]pb.Match, len(prefixes))
+	for i, prefix := range prefixes {
+		matches[i] = pb.Match{Prefix: []byte(prefix)}
+	}
+
+	err := s.db.Subscribe(ctx, func(list *badger.KVList) error {
+		for _, kv := range list.Kv {
+			fn(string(kv.Key), kv.Value)
+		}
+		return nil
+	}, matches)
+	if err != nil && !errors.Is(err, context.Canceled) {
+		return fmt.Errorf("failed to watch keys: %w", err)
+	}
+	return nil
+}
@@ -2,6 +2,10 @@ package telegram
 
 import (
 	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"github.com/korjavin/whatsfordinner/pkg/logger"
@@ -9,85 +13,75 @@ import (
 
 // Bot represents a Telegram bot instance
 type Bot struct {
-	api    *tgbotapi.BotAPI
-	logger *logger.Logger
+	api          *tgbotapi.BotAPI
+	fileEndpoint string // The URL format of files sent to chats, with the token and the file's path
+	logger       *logger.Logger
+	stop         chan struct{}
+	stopOnce     sync.Once
 }
 
-// HandlerFunc is a function that handles a Telegram update
-type HandlerFunc func(update tgbotapi.Update)
-
-// CommandHandler is a function that handles a Telegram command
-type CommandHandler func(message *tgbotapi.Message)
-
-// CallbackHandler is a function that handles a Telegram callback query
-type CallbackHandler func(callback *tgbotapi.CallbackQuery)
-
 // New creates a new Telegram bot instance
-func New(token string) (*Bot, error) {
-	api, err := tgbotapi.NewBotAPI(token)
+// The bot talks to the Bot API at apiURL, e.g. a local Bot API server or a fake one for testing;
+// empty for Telegram's own
+func New(token, apiURL string) (*Bot, error) {
+	apiEndpoint, fileEndpoint := tgbotapi.APIEndpoint, tgbotapi.FileEndpoint
+	if apiURL != "" {
+		apiURL = strings.TrimSuffix(apiURL, "/")
+		apiEndpoint = apiURL + "/bot%s/%s"
+		fileEndpoint = apiURL + "/file/bot%s/%s"
+	}
+
+	api, err := tgbotapi.NewBotAPIWithAPIEndpoint(token, apiEndpoint)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create Telegram bot: %w", err)
 	}
 
 	bot := &Bot{
-		api:    api,
-		logger: logger.New(""),
+		api:          api,
+		fileEndpoint: fileEndpoint,
+		logger:       logger.New(""),
+		stop:         make(chan struct{}),
 	}
 
 	bot.logger.Info("Telegram bot created: @%s", api.Self.UserName)
 	return bot, nil
 }
 
-// Start starts the bot and listens for updates
-func (b *Bot) Start(commandHandlers map[string]CommandHandler, callbackHandlers map[string]CallbackHandler, defaultHandler HandlerFunc) error {
+// Start starts the bot and hands its updates to the dispatcher until Stop is called
+func (b *Bot) Start(dispatcher *Dispatcher) error {
+	if err := b.removeWebhook(); err != nil {
+		return err
+	}
+	dispatcher.Start()
+
 	u := tgbotapi.NewUpdate(0)
 	u.Timeout = 60
 
 	updates := b.api.GetUpdatesChan(u)
-
-	for update := range updates {
-		// Create a channel-specific logger if we have a chat ID
-		var chatID int64
-		if update.Message != nil {
-			chatID = update.Message.Chat.ID
-		} else if update.CallbackQuery != nil {
-			chatID = update.CallbackQuery.Message.Chat.ID
-		}
-
-		if chatID != 0 {
-			b.logger = logger.New(fmt.Sprintf("%d", chatID))
-		}
-
-		// Handle commands
-		if update.Message != nil && update.Message.IsCommand() {
-			command := update.Message.Command()
-			if handler, ok := commandHandlers[command]; ok {
-				b.logger.Info("Handling command: %s from user %s", command, update.Message.From.UserName)
-				handler(update.Message)
-				continue
+	for {
+		select {
+		case update, ok := <-updates:
+			if !ok {
+				return nil
 			}
-		}
-
-		// Handle callback queries
-		if update.CallbackQuery != nil {
-			data := update.CallbackQuery.Data
-			for prefix, handler := range callbackHandlers {
-				if len(data) >= len(prefix) && data[:len(prefix)] == prefix {
-					b.logger.Info("Handling callback: %s from user %s", data, update.CallbackQuery.From.UserName)
-					handler(update.CallbackQuery)
-					break
-				}
+			if err := dispatcher.Dispatch(update); err != nil {
+				// The bot stopped fetching updates, so Telegram sends this one again after a restart
+				b.logger.Info("Not handling update %d: %v", update.UpdateID, err)
+				return nil
 			}
-			continue
-		}
-
-		// Use default handler for other updates
-		if defaultHandler != nil {
-			defaultHandler(update)
+		case <-b.stop:
+			return nil
 		}
 	}
+}
 
-	return nil
+// Stop stops fetching updates and makes Start return
+// Updates fetched but not dispatched yet aren't confirmed to Telegram, so they're sent again after a restart
+func (b *Bot) Stop() {
+	b.stopOnce.Do(func() {
+		b.api.StopReceivingUpdates()
+		close(b.stop)
+	})
 }
 
 // SendMessage sends a text message to a chat
@@ -104,9 +98,11 @@ func (b *Bot) SendMessageWithKeyboard(chatID int64, text string, keyboard tgbota
 }
 
 // CreatePoll creates a poll in a chat
-func (b *Bot) CreatePoll(chatID int64, question string, options []string) (tgbotapi.Message, error) {
+// With multipleAnswers, members can pick any number of the options
+func (b *Bot) CreatePoll(chatID int64, question string, options []string, multipleAnswers bool) (tgbotapi.Message, error) {
 	poll := tgbotapi.NewPoll(chatID, question, options...)
 	poll.IsAnonymous = false
+	poll.AllowsMultipleAnswers = multipleAnswers
 	return b.api.Send(poll)
 }
 
@@ -118,8 +114,12 @@ func (b *Bot) AnswerCallbackQuery(callbackID string, text string) error {
 }
 
 // EditMessage edits a message
-func (b *Bot) EditMessage(chatID int64, messageID int, text string) (tgbotapi.Message, error) {
+// The message gets the given inline keyboard; without one, its keyboard is removed
+func (b *Bot) EditMessage(chatID int64, messageID int, text string, keyboard ...tgbotapi.InlineKeyboardMarkup) (tgbotapi.Message, error) {
 	edit := tgbotapi.NewEditMessageText(chatID, messageID, text)
+	if len(keyboard) > 0 {
+		edit.ReplyMarkup = &keyboard[0]
+	}
 	return b.api.Send(edit)
 }
 
@@ -141,7 +141,37 @@ func (b *Bot) GetFileURL(fileID string) (string, error) {
 		return "", fmt.Errorf("failed to get file: %w", err)
 	}
 
-	return file.Link(b.api.Token), nil
+	return fmt.Sprintf(b.fileEndpoint, b.api.Token, file.FilePath), nil
+}
+
+// maxDownloadSize limits the size of files downloaded from chats; bots can't download bigger files anyway
+const maxDownloadSize = 20 << 20
+
+// DownloadFile downloads a file sent to a chat
+func (b *Bot) DownloadFile(fileID string) ([]byte, error) {
+	fileURL, err := b.GetFileURL(fileID)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := http.Get(fileURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download file: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download file: %s", resp.Status)
+	}
+
+	return io.ReadAll(io.LimitReader(resp.Body, maxDownloadSize))
+}
+
+// SendDocument sends a file to a chat
+func (b *Bot) SendDocument(chatID int64, name string, data []byte, caption string) (tgbotapi.Message, error) {
+	doc := tgbotapi.NewDocument(chatID, tgbotapi.FileBytes{Name: name, Bytes: data})
+	doc.Caption = caption
+	return b.api.Send(doc)
 }
 
 // GetChatMemberCount gets the number of members in a chat
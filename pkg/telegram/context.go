@@ -0,0 +1,135 @@
+package telegram
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/korjavin/whatsfordinner/pkg/logger"
+)
+
+// ErrBadPayload is returned when callback data doesn't have the fields its handler expects
+var ErrBadPayload = errors.New("malformed callback data")
+
+// Context is an update being handled, with the chat and the member it came from
+type Context struct {
+	Update     tgbotapi.Update
+	Bot        *Bot
+	Message    *tgbotapi.Message       // Commands and other messages
+	Callback   *tgbotapi.CallbackQuery // Button presses
+	PollAnswer *tgbotapi.PollAnswer    // Votes in Telegram polls
+	ChatID     int64                   // 0 for poll answers, which don't tell the chat
+	UserID     int64
+	Username   string         // The member's username, or their first name without one
+	Route      string         // What handles the update, e.g. "/dinner", "rate:" or "message"
+	Payload    Payload        // The callback data after its route
+	Logger     *logger.Logger // Logs with the chat's ID
+}
+
+// newContext returns the context of an update, looking up its chat and member
+func newContext(bot *Bot, update tgbotapi.Update) *Context {
+	ctx := &Context{
+		Update:     update,
+		Bot:        bot,
+		Message:    update.Message,
+		Callback:   update.CallbackQuery,
+		PollAnswer: update.PollAnswer,
+	}
+
+	var from *tgbotapi.User
+	switch {
+	case update.Message != nil:
+		ctx.ChatID = update.Message.Chat.ID
+		from = update.Message.From
+	case update.CallbackQuery != nil:
+		if update.CallbackQuery.Message != nil {
+			ctx.ChatID = update.CallbackQuery.Message.Chat.ID
+		}
+		from = update.CallbackQuery.From
+	case update.PollAnswer != nil:
+		from = &update.PollAnswer.User
+	}
+
+	if from != nil {
+		ctx.UserID = from.ID
+		ctx.Username = from.UserName
+		if ctx.Username == "" {
+			ctx.Username = from.FirstName
+		}
+	}
+
+	if ctx.ChatID != 0 {
+		ctx.Logger = logger.New(fmt.Sprintf("%d", ctx.ChatID))
+	} else {
+		ctx.Logger = logger.New("")
+	}
+	return ctx
+}
+
+// MemberID returns the member's user ID the way the bot stores it, e.g. as a voter or cook
+func (c *Context) MemberID() string {
+	return fmt.Sprintf("%d", c.UserID)
+}
+
+// Reply sends a message to the chat of the update
+func (c *Context) Reply(text string) (tgbotapi.Message, error) {
+	return c.Bot.SendMessage(c.ChatID, text)
+}
+
+// Answer answers a button press with a short notification; other updates have nothing to answer
+func (c *Context) Answer(text string) {
+	if c.Callback == nil {
+		return
+	}
+	if err := c.Bot.AnswerCallbackQuery(c.Callback.ID, text); err != nil {
+		c.Logger.Error("Failed to answer callback %s: %v", c.Route, err)
+	}
+}
+
+// Fail logs an error and tells the member something went wrong: a button press is answered, a message replied to
+func (c *Context) Fail(format string, v ...interface{}) {
+	c.Logger.Error(format, v...)
+	switch {
+	case c.Callback != nil:
+		c.Answer("Something went wrong. Please try again.")
+	case c.Message != nil:
+		c.Reply("😢 Sorry, something went wrong. Please try again.")
+	}
+}
+
+// Payload is the data of a callback after its route, e.g. "dinner:1:17:5" for "rate:dinner:1:17:5"
+type Payload string
+
+// String returns the payload as it is, e.g. a poll ID
+func (p Payload) String() string {
+	return string(p)
+}
+
+// Split splits the payload into n fields at colons; the last field keeps the rest, so it can be an ID with colons
+func (p Payload) Split(n int) ([]string, error) {
+	fields := strings.SplitN(string(p), ":", n)
+	if len(fields) < n || fields[0] == "" {
+		return nil, fmt.Errorf("%w: %q needs %d fields", ErrBadPayload, p, n)
+	}
+	return fields, nil
+}
+
+// SplitLast splits off the field after the last colon, e.g. the rating after a dinner ID
+func (p Payload) SplitLast() (string, string, error) {
+	i := strings.LastIndex(string(p), ":")
+	if i <= 0 {
+		return "", "", fmt.Errorf("%w: %q has no last field", ErrBadPayload, p)
+	}
+	return string(p[:i]), string(p[i+1:]), nil
+}
+
+// Int parses the payload as a number
+func (p Payload) Int() (int, error) {
+	n, err := strconv.Atoi(string(p))
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q isn't a number", ErrBadPayload, p)
+	}
+	return n, nil
+}
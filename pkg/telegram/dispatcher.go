@@ -0,0 +1,230 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/korjavin/whatsfordinner/pkg/logger"
+)
+
+// ErrDispatcherClosed is returned for updates dispatched after the dispatcher started shutting down
+var ErrDispatcherClosed = errors.New("dispatcher is shutting down")
+
+// queueKey identifies whose updates are kept in order: a chat's, or a member's when the update has no chat,
+// e.g. poll answers when the chat of their poll is unknown
+type queueKey struct {
+	chatID int64
+	userID int64
+}
+
+// chatQueue holds the updates of a chat that are waiting for a worker
+type chatQueue struct {
+	updates []tgbotapi.Update
+}
+
+// Dispatcher hands updates to a bounded pool of workers: the updates of different chats are handled
+// at the same time, the updates of one chat one after another, in the order they came
+// When too many updates are waiting, Dispatch blocks, so the bot stops fetching more until it caught up
+type Dispatcher struct {
+	router    *Router
+	workers   int
+	queueSize int
+	pollChat  func(pollID string) (int64, error)
+	logger    *logger.Logger
+
+	mu      sync.Mutex
+	changed *sync.Cond // Signalled when updates are taken from or put in the queues, or the dispatcher closes
+	queues  map[queueKey]*chatQueue
+	ready   chan queueKey // Chats with waiting updates and no worker on them
+	pending int           // Updates waiting in all the queues
+	busy    int           // Workers handling an update
+	closed  bool
+	started bool
+	done    chan struct{} // Closed once the workers stopped
+
+	drainOnce sync.Once // Lets the workers go once, however many times Shutdown is called
+}
+
+// NewDispatcher creates a dispatcher routing updates with the router, with the given number of workers
+// and at most queueSize updates waiting for them
+func NewDispatcher(router *Router, workers, queueSize int) *Dispatcher {
+	if workers < 1 {
+		workers = 1
+	}
+	if queueSize < 1 {
+		queueSize = 1
+	}
+
+	d := &Dispatcher{
+		router:    router,
+		workers:   workers,
+		queueSize: queueSize,
+		logger:    logger.New(""),
+		queues:    make(map[queueKey]*chatQueue),
+		ready:     make(chan queueKey, queueSize), // Every chat in it has a waiting update, so it never fills up
+		done:      make(chan struct{}),
+	}
+	d.changed = sync.NewCond(&d.mu)
+	return d
+}
+
+// PollChats tells the dispatcher how to find the chat of a poll, so that poll answers are kept in order
+// with the rest of their chat's updates; without it, they're kept in order per member
+// It's called for every poll answer before the answer is queued, so it must be a quick lookup
+func (d *Dispatcher) PollChats(resolve func(pollID string) (int64, error)) {
+	d.pollChat = resolve
+}
+
+// Start starts the workers
+func (d *Dispatcher) Start() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.started {
+		return
+	}
+	d.started = true
+
+	var wg sync.WaitGroup
+	for i := 0; i < d.workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			d.work()
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(d.done)
+	}()
+}
+
+// Dispatch queues an update for its chat's worker
+// It blocks while the queues are full, and fails once the dispatcher is shutting down
+func (d *Dispatcher) Dispatch(update tgbotapi.Update) error {
+	key := d.keyOf(update)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for d.pending >= d.queueSize && !d.closed {
+		d.changed.Wait()
+	}
+	if d.closed {
+		return ErrDispatcherClosed
+	}
+
+	queue, ok := d.queues[key]
+	if !ok {
+		// No worker is on this chat: queue it for the next free one
+		queue = &chatQueue{}
+		d.queues[key] = queue
+		d.ready <- key
+	}
+	queue.updates = append(queue.updates, update)
+	d.pending++
+	d.changed.Broadcast()
+	return nil
+}
+
+// Pending returns the number of updates waiting for a worker
+func (d *Dispatcher) Pending() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.pending
+}
+
+// Shutdown stops taking updates and waits for the waiting ones to be handled, or until the context is done
+// It may be called again, e.g. to wait longer
+func (d *Dispatcher) Shutdown(ctx context.Context) error {
+	d.mu.Lock()
+	d.closed = true
+	d.changed.Broadcast()
+	started := d.started
+	d.mu.Unlock()
+
+	if !started {
+		return nil
+	}
+
+	// Let the workers go once every queue is empty and no update is being handled
+	d.drainOnce.Do(func() {
+		go func() {
+			d.mu.Lock()
+			for d.pending > 0 || d.busy > 0 {
+				d.changed.Wait()
+			}
+			d.mu.Unlock()
+			close(d.ready)
+		}()
+	})
+
+	select {
+	case <-d.done:
+		return nil
+	case <-ctx.Done():
+		d.logger.Warn("Stopped waiting for %d updates to be handled", d.Pending())
+		return ctx.Err()
+	}
+}
+
+// work handles updates of the chats that are ready, one update at a time, until the dispatcher shuts down
+// After each update the chat goes to the back of the line, so a busy chat can't hold a worker forever
+func (d *Dispatcher) work() {
+	for key := range d.ready {
+		d.mu.Lock()
+		queue := d.queues[key]
+		update := queue.updates[0]
+		queue.updates = queue.updates[1:]
+		d.pending--
+		d.busy++
+		d.changed.Broadcast()
+		d.mu.Unlock()
+
+		d.router.Handle(update)
+
+		d.mu.Lock()
+		d.busy--
+		if len(queue.updates) == 0 {
+			delete(d.queues, key)
+		} else {
+			d.ready <- key
+		}
+		d.changed.Broadcast()
+		d.mu.Unlock()
+	}
+}
+
+// keyOf returns whose updates an update is kept in order with: its chat's, or its sender's when it has no chat
+// Only updates with neither, such as a poll's new results, share one queue
+func (d *Dispatcher) keyOf(update tgbotapi.Update) queueKey {
+	switch {
+	case update.CallbackQuery != nil && update.CallbackQuery.Message != nil:
+		return queueKey{chatID: update.CallbackQuery.Message.Chat.ID}
+	case update.CallbackQuery != nil:
+		// A button of an inline message has no chat
+		return queueKey{userID: update.CallbackQuery.From.ID}
+	case update.PollAnswer != nil:
+		if d.pollChat != nil {
+			if chatID, err := d.pollChat(update.PollAnswer.PollID); err == nil {
+				return queueKey{chatID: chatID}
+			}
+		}
+		return queueKey{userID: update.PollAnswer.User.ID}
+	case update.MyChatMember != nil:
+		return queueKey{chatID: update.MyChatMember.Chat.ID}
+	case update.ChatMember != nil:
+		return queueKey{chatID: update.ChatMember.Chat.ID}
+	case update.ChatJoinRequest != nil:
+		return queueKey{chatID: update.ChatJoinRequest.Chat.ID}
+	}
+
+	if chat := update.FromChat(); chat != nil {
+		return queueKey{chatID: chat.ID}
+	}
+	if user := update.SentFrom(); user != nil {
+		return queueKey{userID: user.ID}
+	}
+	return queueKey{}
+}
@@ -0,0 +1,300 @@
+package telegram
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// waitTimeout is how long a test waits for something that should happen right away
+const waitTimeout = 2 * time.Second
+
+// message makes a text message update in a chat, numbered by n
+func message(chatID int64, n int) tgbotapi.Update {
+	return tgbotapi.Update{
+		UpdateID: n,
+		Message: &tgbotapi.Message{
+			MessageID: n,
+			Chat:      &tgbotapi.Chat{ID: chatID},
+			Text:      "hello",
+		},
+	}
+}
+
+// dispatcherWith makes a started dispatcher handing every message to the handler
+func dispatcherWith(t *testing.T, workers, queueSize int, handler Handler) *Dispatcher {
+	t.Helper()
+	router := NewRouter(nil)
+	router.Message(handler)
+	dispatcher := NewDispatcher(router, workers, queueSize)
+	dispatcher.Start()
+	return dispatcher
+}
+
+// shutdown shuts a dispatcher down, failing the test if its updates aren't handled in time
+func shutdown(t *testing.T, dispatcher *Dispatcher) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), waitTimeout)
+	defer cancel()
+	if err := dispatcher.Shutdown(ctx); err != nil {
+		t.Fatalf("shutdown: %v", err)
+	}
+}
+
+func TestDispatcherKeepsChatOrder(t *testing.T) {
+	var mu sync.Mutex
+	handled := make(map[int64][]int)
+	dispatcher := dispatcherWith(t, 4, 8, func(ctx *Context) {
+		time.Sleep(time.Duration(ctx.Message.MessageID%3) * time.Millisecond)
+		mu.Lock()
+		handled[ctx.ChatID] = append(handled[ctx.ChatID], ctx.Message.MessageID)
+		mu.Unlock()
+	})
+
+	const chats, perChat = 5, 40
+	for n := 0; n < perChat; n++ {
+		for chatID := int64(1); chatID <= chats; chatID++ {
+			if err := dispatcher.Dispatch(message(chatID, n)); err != nil {
+				t.Fatalf("dispatch: %v", err)
+			}
+		}
+	}
+	shutdown(t, dispatcher)
+
+	for chatID := int64(1); chatID <= chats; chatID++ {
+		got := handled[chatID]
+		if len(got) != perChat {
+			t.Fatalf("chat %d: handled %d updates, want %d", chatID, len(got), perChat)
+		}
+		for i, n := range got {
+			if n != i {
+				t.Fatalf("chat %d: handled %v, want them in order", chatID, got)
+			}
+		}
+	}
+}
+
+func TestDispatcherRunsChatsConcurrently(t *testing.T) {
+	release := make(chan struct{})
+	blocked := make(chan struct{})
+	handledB := make(chan struct{})
+	dispatcher := dispatcherWith(t, 2, 8, func(ctx *Context) {
+		switch ctx.ChatID {
+		case 1:
+			close(blocked)
+			<-release
+		case 2:
+			close(handledB)
+		}
+	})
+	defer shutdown(t, dispatcher)
+	defer close(release)
+
+	if err := dispatcher.Dispatch(message(1, 1)); err != nil {
+		t.Fatalf("dispatch: %v", err)
+	}
+	<-blocked
+	if err := dispatcher.Dispatch(message(2, 2)); err != nil {
+		t.Fatalf("dispatch: %v", err)
+	}
+
+	select {
+	case <-handledB:
+	case <-time.After(waitTimeout):
+		t.Fatal("chat 2 waited for the blocked handler of chat 1")
+	}
+}
+
+func TestDispatchBlocksWhenQueueFull(t *testing.T) {
+	release := make(chan struct{})
+	blocked := make(chan struct{}, 1)
+	dispatcher := dispatcherWith(t, 1, 2, func(ctx *Context) {
+		if ctx.Message.MessageID == 0 {
+			blocked <- struct{}{}
+			<-release
+		}
+	})
+
+	// The first update keeps the only worker busy, the next two fill the queue
+	for n := 0; n < 3; n++ {
+		if err := dispatcher.Dispatch(message(1, n)); err != nil {
+			t.Fatalf("dispatch: %v", err)
+		}
+		if n == 0 {
+			<-blocked
+		}
+	}
+	if pending := dispatcher.Pending(); pending != 2 {
+		t.Fatalf("pending = %d, want 2", pending)
+	}
+
+	dispatched := make(chan error, 1)
+	go func() {
+		dispatched <- dispatcher.Dispatch(message(2, 3))
+	}()
+	select {
+	case err := <-dispatched:
+		t.Fatalf("dispatch returned %v with the queue full", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(release)
+	select {
+	case err := <-dispatched:
+		if err != nil {
+			t.Fatalf("dispatch: %v", err)
+		}
+	case <-time.After(waitTimeout):
+		t.Fatal("dispatch still blocked after the queue emptied")
+	}
+
+	shutdown(t, dispatcher)
+	if err := dispatcher.Dispatch(message(1, 4)); !errors.Is(err, ErrDispatcherClosed) {
+		t.Fatalf("dispatch after shutdown = %v, want %v", err, ErrDispatcherClosed)
+	}
+}
+
+func TestShutdownReleasesBlockedDispatch(t *testing.T) {
+	release := make(chan struct{})
+	blocked := make(chan struct{}, 1)
+	dispatcher := dispatcherWith(t, 1, 1, func(ctx *Context) {
+		if ctx.Message.MessageID == 0 {
+			blocked <- struct{}{}
+			<-release
+		}
+	})
+
+	if err := dispatcher.Dispatch(message(1, 0)); err != nil {
+		t.Fatalf("dispatch: %v", err)
+	}
+	<-blocked
+	if err := dispatcher.Dispatch(message(1, 1)); err != nil {
+		t.Fatalf("dispatch: %v", err)
+	}
+
+	dispatched := make(chan error, 1)
+	go func() {
+		dispatched <- dispatcher.Dispatch(message(1, 2))
+	}()
+	stopped := make(chan error, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), waitTimeout)
+		defer cancel()
+		stopped <- dispatcher.Shutdown(ctx)
+	}()
+
+	select {
+	case err := <-dispatched:
+		if !errors.Is(err, ErrDispatcherClosed) {
+			t.Fatalf("blocked dispatch = %v, want %v", err, ErrDispatcherClosed)
+		}
+	case <-time.After(waitTimeout):
+		t.Fatal("dispatch still blocked after shutdown")
+	}
+
+	close(release)
+	if err := <-stopped; err != nil {
+		t.Fatalf("shutdown: %v", err)
+	}
+}
+
+func TestShutdownWaitsForHandlers(t *testing.T) {
+	release := make(chan struct{})
+	var mu sync.Mutex
+	var handled []int
+	dispatcher := dispatcherWith(t, 1, 8, func(ctx *Context) {
+		<-release
+		mu.Lock()
+		handled = append(handled, ctx.Message.MessageID)
+		mu.Unlock()
+	})
+
+	for n := 0; n < 3; n++ {
+		if err := dispatcher.Dispatch(message(1, n)); err != nil {
+			t.Fatalf("dispatch: %v", err)
+		}
+	}
+
+	stopped := make(chan error, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), waitTimeout)
+		defer cancel()
+		stopped <- dispatcher.Shutdown(ctx)
+	}()
+	select {
+	case err := <-stopped:
+		t.Fatalf("shutdown returned %v while a handler was running", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(release)
+	if err := <-stopped; err != nil {
+		t.Fatalf("shutdown: %v", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(handled) != 3 {
+		t.Fatalf("handled %v before shutdown returned, want all 3 updates", handled)
+	}
+}
+
+func TestShutdownHonoursDeadline(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	dispatcher := dispatcherWith(t, 1, 8, func(ctx *Context) {
+		<-release
+	})
+	if err := dispatcher.Dispatch(message(1, 0)); err != nil {
+		t.Fatalf("dispatch: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := dispatcher.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("shutdown = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if waited := time.Since(start); waited > waitTimeout {
+		t.Fatalf("shutdown waited %s past its deadline", waited)
+	}
+}
+
+func TestKeyOf(t *testing.T) {
+	chat := &tgbotapi.Chat{ID: 7}
+	user := &tgbotapi.User{ID: 42}
+	dispatcher := NewDispatcher(NewRouter(nil), 1, 1)
+	dispatcher.PollChats(func(pollID string) (int64, error) {
+		if pollID == "mapped" {
+			return 7, nil
+		}
+		return 0, errors.New("no mapping")
+	})
+
+	tests := []struct {
+		name   string
+		update tgbotapi.Update
+		want   queueKey
+	}{
+		{"message", tgbotapi.Update{Message: &tgbotapi.Message{Chat: chat}}, queueKey{chatID: 7}},
+		{"edited message", tgbotapi.Update{EditedMessage: &tgbotapi.Message{Chat: chat}}, queueKey{chatID: 7}},
+		{"channel post", tgbotapi.Update{ChannelPost: &tgbotapi.Message{Chat: chat}}, queueKey{chatID: 7}},
+		{"callback", tgbotapi.Update{CallbackQuery: &tgbotapi.CallbackQuery{From: user, Message: &tgbotapi.Message{Chat: chat}}}, queueKey{chatID: 7}},
+		{"inline callback", tgbotapi.Update{CallbackQuery: &tgbotapi.CallbackQuery{From: user}}, queueKey{userID: 42}},
+		{"my chat member", tgbotapi.Update{MyChatMember: &tgbotapi.ChatMemberUpdated{Chat: *chat, From: *user}}, queueKey{chatID: 7}},
+		{"chat member", tgbotapi.Update{ChatMember: &tgbotapi.ChatMemberUpdated{Chat: *chat, From: *user}}, queueKey{chatID: 7}},
+		{"mapped poll answer", tgbotapi.Update{PollAnswer: &tgbotapi.PollAnswer{PollID: "mapped", User: *user}}, queueKey{chatID: 7}},
+		{"unmapped poll answer", tgbotapi.Update{PollAnswer: &tgbotapi.PollAnswer{PollID: "other", User: *user}}, queueKey{userID: 42}},
+		{"inline query", tgbotapi.Update{InlineQuery: &tgbotapi.InlineQuery{From: user}}, queueKey{userID: 42}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := dispatcher.keyOf(tt.update); got != tt.want {
+				t.Errorf("keyOf = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
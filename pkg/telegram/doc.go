@@ -1,3 +1,8 @@
 // Package telegram provides a thin wrapper around the Telegram Bot API.
-// It handles bot initialization, message handling, and command routing.
+// It handles bot initialization, fetches updates or receives them on a webhook, and dispatches them to
+// a pool of workers, concurrently across chats and in order within a chat. The router sends them to
+// their handlers: commands by name, buttons by their exact callback data or a prefix whose rest is the
+// handler's payload, and poll answers and other messages to their own handlers. Middleware wraps every
+// routed update, e.g. to recover from panics, log with the chat, allow only some chats, limit how fast
+// members send updates, or count them.
 package telegram
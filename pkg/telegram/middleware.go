@@ -0,0 +1,202 @@
+package telegram
+
+import (
+	"fmt"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Recover keeps a panicking handler from taking the bot down; the member is told something went wrong
+func Recover() Middleware {
+	return func(next Handler) Handler {
+		return func(ctx *Context) {
+			defer func() {
+				if r := recover(); r != nil {
+					ctx.Fail("Panic handling %s: %v\n%s", ctx.Route, r, debug.Stack())
+				}
+			}()
+			next(ctx)
+		}
+	}
+}
+
+// Logging logs every update with the chat's logger: what handles it, who sent it and how long it took
+func Logging() Middleware {
+	return func(next Handler) Handler {
+		return func(ctx *Context) {
+			started := time.Now()
+			ctx.Logger.Info("Handling %s from user %s", ctx.Route, ctx.Username)
+			next(ctx)
+			ctx.Logger.Debug("Handled %s in %s", ctx.Route, time.Since(started))
+		}
+	}
+}
+
+// AllowChats turns away the updates from chats that aren't in the list; with an empty list, every chat is allowed
+// Poll answers don't tell their chat, and only count for polls the bot started in an allowed chat anyway
+func AllowChats(chatIDs []int64) Middleware {
+	allowed := make(map[int64]bool)
+	for _, chatID := range chatIDs {
+		allowed[chatID] = true
+	}
+
+	return func(next Handler) Handler {
+		return func(ctx *Context) {
+			if len(allowed) > 0 && ctx.ChatID != 0 && !allowed[ctx.ChatID] {
+				ctx.Logger.Warn("Ignoring %s from user %s, the chat isn't allowed", ctx.Route, ctx.Username)
+				ctx.Answer("Sorry, I'm not available in this chat.")
+				return
+			}
+			next(ctx)
+		}
+	}
+}
+
+// bucket is a member's token bucket for the rate limit
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// RateLimit lets each member send at most limit updates per interval, with bursts of up to limit
+// Votes in Telegram polls aren't limited, so a vote is never lost
+func RateLimit(limit int, interval time.Duration) Middleware {
+	var mu sync.Mutex
+	buckets := make(map[int64]*bucket)
+	rate := float64(limit) / interval.Seconds()
+
+	allow := func(userID int64, now time.Time) bool {
+		mu.Lock()
+		defer mu.Unlock()
+
+		b, ok := buckets[userID]
+		if !ok {
+			b = &bucket{tokens: float64(limit), last: now}
+			buckets[userID] = b
+		}
+
+		b.tokens += now.Sub(b.last).Seconds() * rate
+		if b.tokens > float64(limit) {
+			b.tokens = float64(limit)
+		}
+		b.last = now
+
+		if b.tokens < 1 {
+			return false
+		}
+		b.tokens--
+		return true
+	}
+
+	return func(next Handler) Handler {
+		return func(ctx *Context) {
+			if ctx.PollAnswer == nil && ctx.UserID != 0 && !allow(ctx.UserID, time.Now()) {
+				ctx.Logger.Warn("Rate limiting %s from user %s", ctx.Route, ctx.Username)
+				ctx.Answer("Slow down a little, please try again in a moment.")
+				return
+			}
+			next(ctx)
+		}
+	}
+}
+
+// RouteMetrics are the numbers of a route: how many updates it handled, how many panicked and how long they took
+type RouteMetrics struct {
+	Count  int
+	Panics int
+	Total  time.Duration
+	Max    time.Duration
+}
+
+// Metrics counts the updates of each route, as middleware
+type Metrics struct {
+	mu     sync.Mutex
+	routes map[string]*RouteMetrics
+}
+
+// NewMetrics creates empty metrics
+func NewMetrics() *Metrics {
+	return &Metrics{routes: make(map[string]*RouteMetrics)}
+}
+
+// Middleware returns the middleware that records the metrics of every update
+func (m *Metrics) Middleware() Middleware {
+	return func(next Handler) Handler {
+		return func(ctx *Context) {
+			started := time.Now()
+			defer func() {
+				r := recover()
+				m.record(ctx.Route, time.Since(started), r != nil)
+				if r != nil {
+					panic(r)
+				}
+			}()
+			next(ctx)
+		}
+	}
+}
+
+// record adds an update to the metrics of its route
+func (m *Metrics) record(route string, took time.Duration, panicked bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	metrics, ok := m.routes[route]
+	if !ok {
+		metrics = &RouteMetrics{}
+		m.routes[route] = metrics
+	}
+	metrics.Count++
+	metrics.Total += took
+	if took > metrics.Max {
+		metrics.Max = took
+	}
+	if panicked {
+		metrics.Panics++
+	}
+}
+
+// Snapshot returns a copy of the metrics of every route
+func (m *Metrics) Snapshot() map[string]RouteMetrics {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	snapshot := make(map[string]RouteMetrics, len(m.routes))
+	for route, metrics := range m.routes {
+		snapshot[route] = *metrics
+	}
+	return snapshot
+}
+
+// Summary describes the metrics, busiest route first, e.g. "/dinner: 3 updates, avg 1.2s, max 2s"
+func (m *Metrics) Summary() string {
+	snapshot := m.Snapshot()
+	routes := make([]string, 0, len(snapshot))
+	for route := range snapshot {
+		routes = append(routes, route)
+	}
+	sort.Slice(routes, func(i, j int) bool {
+		if snapshot[routes[i]].Count != snapshot[routes[j]].Count {
+			return snapshot[routes[i]].Count > snapshot[routes[j]].Count
+		}
+		return routes[i] < routes[j]
+	})
+
+	var lines []string
+	for _, route := range routes {
+		metrics := snapshot[route]
+		line := fmt.Sprintf("%s: %d updates, avg %s, max %s", route, metrics.Count,
+			(metrics.Total / time.Duration(metrics.Count)).Round(time.Millisecond), metrics.Max.Round(time.Millisecond))
+		if metrics.Panics > 0 {
+			line += fmt.Sprintf(", %d panics", metrics.Panics)
+		}
+		lines = append(lines, line)
+	}
+	if len(lines) == 0 {
+		return "no updates yet"
+	}
+	return strings.Join(lines, "; ")
+}
@@ -0,0 +1,140 @@
+package telegram
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Handler handles an update
+type Handler func(ctx *Context)
+
+// Middleware wraps a handler, e.g. to log, recover from panics or turn updates away
+type Middleware func(next Handler) Handler
+
+// prefixRoute is a callback route matching every callback data that starts with its prefix
+type prefixRoute struct {
+	prefix  string
+	handler Handler
+}
+
+// Router routes updates to their handlers: commands by name, callbacks by their exact data or else
+// the longest matching prefix, and poll answers and other messages to their own handlers
+// Every routed update goes through the middleware, in the order it was added
+type Router struct {
+	bot        *Bot
+	commands   map[string]Handler
+	callbacks  map[string]Handler
+	prefixes   []prefixRoute // Longest first, so overlapping prefixes always resolve the same way
+	pollAnswer Handler
+	message    Handler
+	middleware []Middleware
+}
+
+// NewRouter creates a router for the bot's updates
+func NewRouter(bot *Bot) *Router {
+	return &Router{
+		bot:       bot,
+		commands:  make(map[string]Handler),
+		callbacks: make(map[string]Handler),
+	}
+}
+
+// Use adds middleware that wraps every routed update
+func (r *Router) Use(middleware ...Middleware) {
+	r.middleware = append(r.middleware, middleware...)
+}
+
+// Command routes a command, e.g. "dinner" for /dinner
+func (r *Router) Command(name string, handler Handler) {
+	if _, ok := r.commands[name]; ok {
+		panic(fmt.Sprintf("telegram: command %q is already routed", name))
+	}
+	r.commands[name] = handler
+}
+
+// Callback routes the callbacks whose data is exactly the given data, e.g. "shopping_claim"
+func (r *Router) Callback(data string, handler Handler) {
+	if _, ok := r.callbacks[data]; ok {
+		panic(fmt.Sprintf("telegram: callback %q is already routed", data))
+	}
+	r.callbacks[data] = handler
+}
+
+// CallbackPrefix routes the callbacks whose data starts with the prefix, e.g. "rate:"
+// The handler gets the rest of the data as the payload
+func (r *Router) CallbackPrefix(prefix string, handler Handler) {
+	for _, route := range r.prefixes {
+		if route.prefix == prefix {
+			panic(fmt.Sprintf("telegram: callback prefix %q is already routed", prefix))
+		}
+	}
+
+	r.prefixes = append(r.prefixes, prefixRoute{prefix: prefix, handler: handler})
+	sort.SliceStable(r.prefixes, func(i, j int) bool {
+		return len(r.prefixes[i].prefix) > len(r.prefixes[j].prefix)
+	})
+}
+
+// PollAnswer routes the answers to Telegram polls
+func (r *Router) PollAnswer(handler Handler) {
+	r.pollAnswer = handler
+}
+
+// Message routes the messages that aren't commands, e.g. text typed in a conversation or photos
+func (r *Router) Message(handler Handler) {
+	r.message = handler
+}
+
+// Handle routes an update through the middleware to its handler; updates without a route are dropped
+func (r *Router) Handle(update tgbotapi.Update) {
+	ctx := newContext(r.bot, update)
+	handler := r.route(ctx)
+	if handler == nil {
+		return
+	}
+
+	for i := len(r.middleware) - 1; i >= 0; i-- {
+		handler = r.middleware[i](handler)
+	}
+	handler(ctx)
+}
+
+// route finds the handler of an update and records its route and payload in the context
+func (r *Router) route(ctx *Context) Handler {
+	switch {
+	case ctx.Message != nil && ctx.Message.IsCommand():
+		command := ctx.Message.Command()
+		ctx.Route = "/" + command
+		return r.commands[command]
+
+	case ctx.Callback != nil:
+		data := ctx.Callback.Data
+		if handler, ok := r.callbacks[data]; ok {
+			ctx.Route = data
+			return handler
+		}
+		for _, route := range r.prefixes {
+			if strings.HasPrefix(data, route.prefix) {
+				ctx.Route = route.prefix
+				ctx.Payload = Payload(strings.TrimPrefix(data, route.prefix))
+				return route.handler
+			}
+		}
+		// Answer anyway, or the member's client keeps showing the button as loading
+		ctx.Logger.Warn("No route for callback %s", data)
+		ctx.Answer("")
+		return nil
+
+	case ctx.PollAnswer != nil:
+		ctx.Route = "poll_answer"
+		return r.pollAnswer
+
+	case ctx.Message != nil:
+		ctx.Route = "message"
+		return r.message
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+package telegram
+
+import (
+	"testing"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestRouterAnswersUnroutedCallbacks(t *testing.T) {
+	api := newFakeAPI(t)
+	bot, err := New(testToken, api.server.URL)
+	if err != nil {
+		t.Fatalf("new bot: %v", err)
+	}
+	router := NewRouter(bot)
+	router.CallbackPrefix("rate:", func(ctx *Context) {
+		t.Fatalf("routed %q to the rate: handler", ctx.Callback.Data)
+	})
+
+	router.Handle(tgbotapi.Update{CallbackQuery: &tgbotapi.CallbackQuery{
+		ID:      "cb1",
+		From:    &tgbotapi.User{ID: 42},
+		Message: &tgbotapi.Message{Chat: &tgbotapi.Chat{ID: 7}},
+		Data:    "stale:button",
+	}})
+
+	answers := api.called("answerCallbackQuery")
+	if len(answers) != 1 {
+		t.Fatalf("answered the callback %d times, want once", len(answers))
+	}
+	if got := answers[0].Get("callback_query_id"); got != "cb1" {
+		t.Errorf("answered callback %q, want cb1", got)
+	}
+	if got := answers[0].Get("text"); got != "" {
+		t.Errorf("answered with %q, want no notification", got)
+	}
+}
@@ -0,0 +1,212 @@
+package telegram
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// secretHeader is the header Telegram sends the webhook's secret token in
+const secretHeader = "X-Telegram-Bot-Api-Secret-Token"
+
+// maxUpdateSize limits the size of an update posted to the webhook; updates are small JSON objects
+const maxUpdateSize = 1 << 20
+
+// webhookShutdownTimeout is how long a stopping webhook waits for the updates being posted to it,
+// and a failed one for the updates it took to be handled
+const webhookShutdownTimeout = 5 * time.Second
+
+// validSecret matches the secret tokens Telegram accepts
+var validSecret = regexp.MustCompile(`^[A-Za-z0-9_-]{1,256}$`)
+
+// WebhookConfig is how Telegram reaches the bot's webhook
+// With a certificate and key the bot serves HTTPS itself; without, a reverse proxy in front of it terminates TLS
+type WebhookConfig struct {
+	URL        string // The public HTTPS URL Telegram posts updates to, e.g. https://bot.example.com/telegram
+	ListenAddr string // The address the webhook server listens on, e.g. ":8080"
+	Secret     string // The token Telegram sends with every update; a random one is made if empty
+	CertFile   string // The TLS certificate, for serving HTTPS without a reverse proxy
+	KeyFile    string // The TLS certificate's private key
+	SelfSigned bool   // Upload the certificate to Telegram, so it trusts a self-signed one
+}
+
+// StartWebhook registers the webhook with Telegram and hands the updates Telegram posts to it to the dispatcher
+// until Stop is called; then the webhook is deleted, so updates wait at Telegram until the bot is back
+// If the webhook fails, the dispatcher is shut down before the error is returned
+func (b *Bot) StartWebhook(dispatcher *Dispatcher, config WebhookConfig) error {
+	webhookURL, err := url.Parse(config.URL)
+	if err != nil || webhookURL.Scheme != "https" || webhookURL.Host == "" {
+		return fmt.Errorf("webhook URL must be an https URL, got %q", config.URL)
+	}
+	if (config.CertFile == "") != (config.KeyFile == "") {
+		return fmt.Errorf("webhook needs both a certificate and a key to serve TLS")
+	}
+	if config.SelfSigned && config.CertFile == "" {
+		return fmt.Errorf("webhook needs a certificate to upload as self-signed")
+	}
+	if config.Secret == "" {
+		if config.Secret, err = randomSecret(); err != nil {
+			return err
+		}
+	} else if !validSecret.MatchString(config.Secret) {
+		return fmt.Errorf("webhook secret may only have 1-256 letters, digits, _ and -")
+	}
+
+	path := webhookURL.Path
+	if path == "" {
+		path = "/"
+	}
+	mux := http.NewServeMux()
+	mux.Handle(path, b.webhookHandler(dispatcher, config.Secret))
+	server := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	// Listen before registering the webhook, since Telegram posts the waiting updates right away
+	listener, err := net.Listen("tcp", config.ListenAddr)
+	if err != nil {
+		return fmt.Errorf("failed to listen for the webhook: %w", err)
+	}
+
+	served := make(chan error, 1)
+	go func() {
+		if config.CertFile != "" {
+			served <- server.ServeTLS(listener, config.CertFile, config.KeyFile)
+		} else {
+			served <- server.Serve(listener)
+		}
+	}()
+
+	// Updates posted before the workers start wait in the dispatcher's queues
+	if err := b.setWebhook(webhookURL, config); err != nil {
+		server.Close()
+		return err
+	}
+	dispatcher.Start()
+	b.logger.Info("Receiving updates on %s at %s", listener.Addr(), webhookURL.Redacted())
+
+	select {
+	case err := <-served:
+		b.deleteWebhook()
+		b.shutdownDispatcher(dispatcher)
+		return fmt.Errorf("webhook server stopped: %w", err)
+	case <-b.stop:
+	}
+
+	// Stop taking updates before the server goes, so Telegram keeps the rest until the bot is back
+	b.deleteWebhook()
+	ctx, cancel := context.WithTimeout(context.Background(), webhookShutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		return fmt.Errorf("failed to stop the webhook server: %w", err)
+	}
+	return nil
+}
+
+// shutdownDispatcher lets the dispatcher finish the updates it has when the webhook fails
+func (b *Bot) shutdownDispatcher(dispatcher *Dispatcher) {
+	ctx, cancel := context.WithTimeout(context.Background(), webhookShutdownTimeout)
+	defer cancel()
+	if err := dispatcher.Shutdown(ctx); err != nil {
+		b.logger.Error("Failed to finish handling updates: %v", err)
+	}
+}
+
+// webhookHandler hands the updates Telegram posts to the dispatcher
+// Telegram posts an update again until it gets a 2xx, so an update the bot can't take now isn't lost
+func (b *Bot) webhookHandler(dispatcher *Dispatcher, secret string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get(secretHeader)), []byte(secret)) != 1 {
+			b.logger.Warn("Rejected a webhook request from %s with a wrong secret token", r.RemoteAddr)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		var update tgbotapi.Update
+		if err := json.NewDecoder(io.LimitReader(r.Body, maxUpdateSize)).Decode(&update); err != nil {
+			b.logger.Error("Failed to decode webhook update: %v", err)
+			http.Error(w, "bad update", http.StatusBadRequest)
+			return
+		}
+
+		// Dispatching blocks while the workers are behind, which makes Telegram slow down too
+		if err := dispatcher.Dispatch(update); err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+// setWebhook tells Telegram to post updates to the webhook, with the secret token
+func (b *Bot) setWebhook(webhookURL *url.URL, config WebhookConfig) error {
+	params := tgbotapi.Params{
+		"url":          webhookURL.String(),
+		"secret_token": config.Secret,
+	}
+
+	var err error
+	if config.SelfSigned {
+		_, err = b.api.UploadFiles("setWebhook", params, []tgbotapi.RequestFile{{
+			Name: "certificate",
+			Data: tgbotapi.FilePath(config.CertFile),
+		}})
+	} else {
+		_, err = b.api.MakeRequest("setWebhook", params)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to set webhook: %w", err)
+	}
+	return nil
+}
+
+// deleteWebhook tells Telegram to stop posting updates; the updates it has are kept for the next start
+func (b *Bot) deleteWebhook() {
+	if _, err := b.api.Request(tgbotapi.DeleteWebhookConfig{}); err != nil {
+		b.logger.Error("Failed to delete webhook: %v", err)
+	}
+}
+
+// removeWebhook deletes a webhook left from running in webhook mode, since Telegram doesn't let a bot
+// with a webhook fetch its updates
+func (b *Bot) removeWebhook() error {
+	info, err := b.api.GetWebhookInfo()
+	if err != nil {
+		return fmt.Errorf("failed to get webhook info: %w", err)
+	}
+	if info.URL == "" {
+		return nil
+	}
+
+	b.logger.Info("Deleting the webhook to fetch updates instead")
+	if _, err := b.api.Request(tgbotapi.DeleteWebhookConfig{}); err != nil {
+		return fmt.Errorf("failed to delete webhook: %w", err)
+	}
+	return nil
+}
+
+// randomSecret makes a secret token for a webhook that wasn't given one
+func randomSecret() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", fmt.Errorf("failed to make a webhook secret: %w", err)
+	}
+	return hex.EncodeToString(secret), nil
+}
@@ -0,0 +1,271 @@
+package telegram
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const testToken = "123:secret-token"
+
+// fakeAPI is a fake Telegram Bot API recording the methods called on it and their parameters
+type fakeAPI struct {
+	server *httptest.Server
+	failOn string // A method that fails
+
+	mu    sync.Mutex
+	calls map[string][]url.Values
+	seen  chan string // Gets every method called
+}
+
+func newFakeAPI(t *testing.T) *fakeAPI {
+	t.Helper()
+	api := &fakeAPI{
+		calls: make(map[string][]url.Values),
+		seen:  make(chan string, 16),
+	}
+	api.server = httptest.NewServer(http.HandlerFunc(api.serve))
+	t.Cleanup(api.server.Close)
+	return api
+}
+
+func (a *fakeAPI) serve(w http.ResponseWriter, r *http.Request) {
+	method, ok := strings.CutPrefix(r.URL.Path, "/bot"+testToken+"/")
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	a.mu.Lock()
+	a.calls[method] = append(a.calls[method], r.Form)
+	a.mu.Unlock()
+	a.seen <- method
+
+	w.Header().Set("Content-Type", "application/json")
+	switch {
+	case method == a.failOn:
+		w.Write([]byte(`{"ok":false,"error_code":400,"description":"Bad Request: bad webhook"}`))
+	case method == "getMe":
+		w.Write([]byte(`{"ok":true,"result":{"id":123,"is_bot":true,"first_name":"Dinner","username":"dinner_bot"}}`))
+	default:
+		w.Write([]byte(`{"ok":true,"result":true}`))
+	}
+}
+
+// called returns the parameters of each call of a method
+func (a *fakeAPI) called(method string) []url.Values {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.calls[method]
+}
+
+// waitFor waits until a method is called
+func (a *fakeAPI) waitFor(t *testing.T, method string) {
+	t.Helper()
+	timeout := time.After(waitTimeout)
+	for {
+		select {
+		case seen := <-a.seen:
+			if seen == method {
+				return
+			}
+		case <-timeout:
+			t.Fatalf("%s wasn't called", method)
+		}
+	}
+}
+
+func TestWebhookHandler(t *testing.T) {
+	const secret = "s3cret"
+	update := `{"update_id":1,"message":{"message_id":1,"chat":{"id":7,"type":"group"},"text":"hello"}}`
+
+	tests := []struct {
+		name   string
+		method string
+		secret string
+		body   string
+		status int
+	}{
+		{"valid update", http.MethodPost, secret, update, http.StatusOK},
+		{"wrong secret", http.MethodPost, "guess", update, http.StatusUnauthorized},
+		{"missing secret", http.MethodPost, "", update, http.StatusUnauthorized},
+		{"not a POST", http.MethodGet, secret, "", http.StatusMethodNotAllowed},
+		{"bad JSON", http.MethodPost, secret, `{"update_id":`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handled := make(chan int64, 1)
+			dispatcher := dispatcherWith(t, 1, 8, func(ctx *Context) {
+				handled <- ctx.ChatID
+			})
+			defer shutdown(t, dispatcher)
+
+			bot := &Bot{logger: dispatcher.logger}
+			request := httptest.NewRequest(tt.method, "/telegram", strings.NewReader(tt.body))
+			if tt.secret != "" {
+				request.Header.Set(secretHeader, tt.secret)
+			}
+			recorder := httptest.NewRecorder()
+			bot.webhookHandler(dispatcher, secret).ServeHTTP(recorder, request)
+
+			if recorder.Code != tt.status {
+				t.Fatalf("status = %d, want %d", recorder.Code, tt.status)
+			}
+			if tt.status != http.StatusOK {
+				if pending := dispatcher.Pending(); pending != 0 {
+					t.Fatalf("a rejected request queued %d updates", pending)
+				}
+				return
+			}
+			select {
+			case chatID := <-handled:
+				if chatID != 7 {
+					t.Fatalf("handled an update of chat %d, want 7", chatID)
+				}
+			case <-time.After(waitTimeout):
+				t.Fatal("the update didn't reach the dispatcher")
+			}
+		})
+	}
+}
+
+func TestWebhookHandlerAfterShutdown(t *testing.T) {
+	dispatcher := dispatcherWith(t, 1, 8, func(ctx *Context) {})
+	shutdown(t, dispatcher)
+
+	bot := &Bot{logger: dispatcher.logger}
+	request := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"update_id":1}`))
+	request.Header.Set(secretHeader, "s3cret")
+	recorder := httptest.NewRecorder()
+	bot.webhookHandler(dispatcher, "s3cret").ServeHTTP(recorder, request)
+
+	if recorder.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status = %d, want %d so Telegram posts the update again", recorder.Code, http.StatusServiceUnavailable)
+	}
+}
+
+func TestStartWebhook(t *testing.T) {
+	api := newFakeAPI(t)
+	bot, err := New(testToken, api.server.URL)
+	if err != nil {
+		t.Fatalf("new bot: %v", err)
+	}
+	dispatcher := NewDispatcher(NewRouter(bot), 1, 8)
+
+	started := make(chan error, 1)
+	go func() {
+		started <- bot.StartWebhook(dispatcher, WebhookConfig{
+			URL:        "https://bot.example.com/telegram",
+			ListenAddr: "127.0.0.1:0",
+			Secret:     "s3cret",
+		})
+	}()
+
+	api.waitFor(t, "setWebhook")
+	params := api.called("setWebhook")[0]
+	if got := params.Get("url"); got != "https://bot.example.com/telegram" {
+		t.Errorf("setWebhook url = %q", got)
+	}
+	if got := params.Get("secret_token"); got != "s3cret" {
+		t.Errorf("setWebhook secret_token = %q", got)
+	}
+	if len(api.called("deleteWebhook")) != 0 {
+		t.Fatal("deleteWebhook was called before Stop")
+	}
+
+	bot.Stop()
+	select {
+	case err := <-started:
+		if err != nil {
+			t.Fatalf("StartWebhook: %v", err)
+		}
+	case <-time.After(waitTimeout):
+		t.Fatal("StartWebhook didn't return after Stop")
+	}
+	if len(api.called("deleteWebhook")) != 1 {
+		t.Fatalf("deleteWebhook was called %d times on Stop, want once", len(api.called("deleteWebhook")))
+	}
+	shutdown(t, dispatcher)
+}
+
+func TestStartWebhookMakesSecret(t *testing.T) {
+	api := newFakeAPI(t)
+	bot, err := New(testToken, api.server.URL)
+	if err != nil {
+		t.Fatalf("new bot: %v", err)
+	}
+	dispatcher := NewDispatcher(NewRouter(bot), 1, 8)
+
+	started := make(chan error, 1)
+	go func() {
+		started <- bot.StartWebhook(dispatcher, WebhookConfig{URL: "https://bot.example.com/", ListenAddr: "127.0.0.1:0"})
+	}()
+	api.waitFor(t, "setWebhook")
+	bot.Stop()
+	if err := <-started; err != nil {
+		t.Fatalf("StartWebhook: %v", err)
+	}
+
+	secret := api.called("setWebhook")[0].Get("secret_token")
+	if !validSecret.MatchString(secret) || len(secret) < 32 {
+		t.Fatalf("made secret %q, want a long random one Telegram accepts", secret)
+	}
+}
+
+func TestStartWebhookFailsToSet(t *testing.T) {
+	api := newFakeAPI(t)
+	api.failOn = "setWebhook"
+	bot, err := New(testToken, api.server.URL)
+	if err != nil {
+		t.Fatalf("new bot: %v", err)
+	}
+	dispatcher := NewDispatcher(NewRouter(bot), 1, 8)
+
+	err = bot.StartWebhook(dispatcher, WebhookConfig{
+		URL:        "https://bot.example.com/telegram",
+		ListenAddr: "127.0.0.1:0",
+		Secret:     "s3cret",
+	})
+	if err == nil || !strings.Contains(err.Error(), "bad webhook") {
+		t.Fatalf("StartWebhook = %v, want Telegram's error", err)
+	}
+
+	dispatcher.mu.Lock()
+	started := dispatcher.started
+	dispatcher.mu.Unlock()
+	if started {
+		t.Fatal("the dispatcher's workers were left running")
+	}
+}
+
+func TestStartWebhookChecksConfig(t *testing.T) {
+	bot := &Bot{stop: make(chan struct{})}
+	dispatcher := NewDispatcher(NewRouter(bot), 1, 8)
+
+	tests := []struct {
+		name   string
+		config WebhookConfig
+	}{
+		{"plain HTTP", WebhookConfig{URL: "http://bot.example.com/"}},
+		{"no host", WebhookConfig{URL: "https:///telegram"}},
+		{"certificate without key", WebhookConfig{URL: "https://bot.example.com/", CertFile: "cert.pem"}},
+		{"self-signed without certificate", WebhookConfig{URL: "https://bot.example.com/", SelfSigned: true}},
+		{"invalid secret", WebhookConfig{URL: "https://bot.example.com/", Secret: "not allowed!"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := bot.StartWebhook(dispatcher, tt.config); err == nil {
+				t.Fatal("StartWebhook accepted the config")
+			}
+		})
+	}
+}
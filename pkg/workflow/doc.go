@@ -0,0 +1,9 @@
+// Package workflow provides the lifecycle of each dinner as a finite-state machine.
+// A dinner goes from proposing (asking who's home and suggesting dishes) to voting, awaiting a cook,
+// cooking, served and rating, and ends closed; until it's served, it can be cancelled instead.
+// Only the transitions in the table are allowed, so callbacks can't rate an unfinished dinner or
+// start cooking twice. Every state has a timeout after which the workflow moves on by itself,
+// and every transition is kept in the workflow's history, one workflow per dinner.
+// Workflows that haven't ended are indexed with their deadline, and dinners with their workflow,
+// so neither the timeouts nor a dinner's buttons go through the channel's whole history.
+package workflow
@@ -0,0 +1,456 @@
+package workflow
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/korjavin/whatsfordinner/pkg/logger"
+	"github.com/korjavin/whatsfordinner/pkg/models"
+	"github.com/korjavin/whatsfordinner/pkg/storage"
+)
+
+// State is a step in the lifecycle of a dinner
+type State string
+
+const (
+	// Proposing is while the family is asked who's eating at home and dishes are suggested
+	Proposing State = "proposing"
+	// Voting is while the family votes on the dish
+	Voting State = "voting"
+	// AwaitingCook is from the end of the vote until the cook starts cooking
+	AwaitingCook State = "awaiting_cook"
+	// Cooking is while the cook is cooking
+	Cooking State = "cooking"
+	// Served is once the cook said dinner is ready
+	Served State = "served"
+	// Rating is while the family rates the dinner
+	Rating State = "rating"
+	// Closed is the end of a dinner that was served
+	Closed State = "closed"
+	// Cancelled is the end of a dinner that never was served
+	Cancelled State = "cancelled"
+)
+
+var (
+	// ErrNoWorkflow is returned when a channel or dinner has no workflow, e.g. one from before workflows were kept
+	ErrNoWorkflow = errors.New("no dinner workflow")
+	// ErrIllegalTransition is returned when a workflow can't move from its state to the requested one
+	ErrIllegalTransition = errors.New("illegal workflow transition")
+	// ErrWrongState is returned when a dinner isn't at the step an action needs
+	ErrWrongState = errors.New("the dinner isn't at that step")
+)
+
+// transitions lists the states each state may move to; closed and cancelled are final
+// Staying in voting replaces the poll, e.g. with a new suggestion; staying in awaiting_cook asks for a cook again
+var transitions = map[State][]State{
+	Proposing:    {Voting, AwaitingCook, Cancelled}, // Planned dinners skip the vote
+	Voting:       {Voting, AwaitingCook, Cancelled},
+	AwaitingCook: {AwaitingCook, Cooking, Cancelled},
+	Cooking:      {Served, Cancelled},
+	Served:       {Rating, Closed},
+	Rating:       {Closed},
+}
+
+// Timeout is how long a workflow may stay in a state, and the state it moves to after that
+type Timeout struct {
+	After time.Duration
+	To    State
+}
+
+// Timeouts are the timeouts of the states
+// The evening cutoff usually moves a workflow on well before, so they only catch workflows left behind;
+// the scheduler then ends what the family sees of the dinner with it, as at the cutoff
+var Timeouts = map[State]Timeout{
+	Proposing:    {After: 3 * time.Hour, To: Cancelled},
+	Voting:       {After: 12 * time.Hour, To: Cancelled},
+	AwaitingCook: {After: 12 * time.Hour, To: Cancelled},
+	Cooking:      {After: 12 * time.Hour, To: Served}, // The cook forgot to say dinner is ready, as at the cutoff
+	Served:       {After: time.Hour, To: Closed},
+	Rating:       {After: 24 * time.Hour, To: Closed},
+}
+
+// Service keeps the workflow of each dinner and moves it from state to state
+type Service struct {
+	store  *storage.Store
+	logger *logger.Logger
+	mu     sync.Mutex // Serializes transitions, which read a workflow and write it back
+}
+
+// New creates a new workflow service
+func New(store *storage.Store) *Service {
+	return &Service{
+		store:  store,
+		logger: logger.New("workflow"),
+	}
+}
+
+// openWorkflow is an entry of the index of workflows that haven't ended, with when their state times out
+type openWorkflow struct {
+	ID       string    `json:"id"`
+	Deadline time.Time `json:"deadline"`
+}
+
+// currentKey returns the store key of the ID of a channel's latest workflow
+func currentKey(channelID int64) string {
+	return fmt.Sprintf("workflow_current:%d", channelID)
+}
+
+// openKey returns the store key of a workflow's entry in the index of workflows that haven't ended,
+// e.g. workflow_open:<channel>:<started>
+func openKey(workflow *models.Workflow) string {
+	return "workflow_open:" + strings.TrimPrefix(workflow.ID, "workflow:")
+}
+
+// dinnerKey returns the store key of the ID of the workflow a dinner was cooked in
+func dinnerKey(channelID int64, dinnerID string) string {
+	return fmt.Sprintf("workflow_dinner:%d:%s", channelID, dinnerID)
+}
+
+// Begin starts a new dinner workflow in a channel, proposing dishes
+// A workflow that's still proposing is kept, e.g. when the poll starts after asking who's eating at home;
+// one that's voting, waiting for a cook or cooking is cancelled, since the family starts over
+func (s *Service) Begin(channelID int64, reason string) (*models.Workflow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, err := s.current(channelID)
+	switch {
+	case err == nil && State(current.State) == Proposing:
+		return current, nil
+	case err == nil && IsUnfinished(current):
+		if err := s.move(current, Cancelled, "started over: "+reason); err != nil {
+			return nil, err
+		}
+	case err != nil && !errors.Is(err, ErrNoWorkflow):
+		return nil, err
+	}
+
+	now := time.Now()
+	workflow := &models.Workflow{
+		ID:         fmt.Sprintf("workflow:%d:%d", channelID, now.UnixNano()),
+		ChannelID:  channelID,
+		State:      string(Proposing),
+		StateSince: now,
+		StartedAt:  now,
+		History:    []models.WorkflowTransition{{To: string(Proposing), At: now, Reason: reason}},
+	}
+	if err := s.save(workflow); err != nil {
+		return nil, err
+	}
+	if err := s.store.Set(currentKey(channelID), workflow.ID); err != nil {
+		return nil, fmt.Errorf("failed to save current workflow: %w", err)
+	}
+
+	s.logger.Info("Began workflow %s: %s", workflow.ID, reason)
+	return workflow, nil
+}
+
+// Current returns a channel's latest workflow, which may have ended
+func (s *Service) Current(channelID int64) (*models.Workflow, error) {
+	return s.current(channelID)
+}
+
+// current returns a channel's latest workflow; the caller holds the lock when it changes it
+func (s *Service) current(channelID int64) (*models.Workflow, error) {
+	var id string
+	if err := s.store.Get(currentKey(channelID), &id); err != nil {
+		return nil, ErrNoWorkflow
+	}
+
+	var workflow models.Workflow
+	if err := s.store.Get(id, &workflow); err != nil {
+		return nil, fmt.Errorf("failed to get workflow %s: %w", id, err)
+	}
+	return &workflow, nil
+}
+
+// ForDinner returns the workflow a dinner was cooked in
+func (s *Service) ForDinner(channelID int64, dinnerID string) (*models.Workflow, error) {
+	var id string
+	if err := s.store.Get(dinnerKey(channelID, dinnerID), &id); err != nil {
+		return nil, ErrNoWorkflow
+	}
+
+	var workflow models.Workflow
+	if err := s.store.Get(id, &workflow); err != nil {
+		return nil, fmt.Errorf("failed to get workflow %s: %w", id, err)
+	}
+	return &workflow, nil
+}
+
+// Advance moves a channel's latest workflow to a state
+func (s *Service) Advance(channelID int64, to State, reason string) (*models.Workflow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	workflow, err := s.current(channelID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.move(workflow, to, reason); err != nil {
+		return nil, err
+	}
+	return workflow, nil
+}
+
+// AdvanceDinner moves the workflow a dinner was cooked in to a state
+func (s *Service) AdvanceDinner(channelID int64, dinnerID string, to State, reason string) (*models.Workflow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	workflow, err := s.ForDinner(channelID, dinnerID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.move(workflow, to, reason); err != nil {
+		return nil, err
+	}
+	return workflow, nil
+}
+
+// Link records the vote and the dinner of a channel's latest workflow; empty IDs are left as they are
+func (s *Service) Link(channelID int64, pollID, dinnerID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	workflow, err := s.current(channelID)
+	if err != nil {
+		return err
+	}
+	if pollID != "" {
+		workflow.PollID = pollID
+	}
+	if dinnerID != "" {
+		workflow.DinnerID = dinnerID
+		if err := s.store.Set(dinnerKey(channelID, dinnerID), workflow.ID); err != nil {
+			return fmt.Errorf("failed to save the workflow of dinner %s: %w", dinnerID, err)
+		}
+	}
+
+	if err := s.store.Set(workflow.ID, workflow); err != nil {
+		return fmt.Errorf("failed to save workflow: %w", err)
+	}
+	return nil
+}
+
+// Require returns ErrWrongState unless a channel's latest workflow is in the given state
+func (s *Service) Require(channelID int64, state State) error {
+	workflow, err := s.current(channelID)
+	if err != nil {
+		return err
+	}
+	return require(workflow, state)
+}
+
+// RequireVote returns ErrWrongState unless a channel's latest workflow is in the given state with the given vote,
+// so buttons of a vote that was replaced or decided are turned down
+func (s *Service) RequireVote(channelID int64, pollID string, state State) error {
+	workflow, err := s.current(channelID)
+	if err != nil {
+		return err
+	}
+	if workflow.PollID != pollID {
+		return fmt.Errorf("%w: vote %s isn't the running one", ErrWrongState, pollID)
+	}
+	return require(workflow, state)
+}
+
+// RequireDinner returns ErrWrongState unless the workflow a dinner was cooked in is in the given state
+func (s *Service) RequireDinner(channelID int64, dinnerID string, state State) error {
+	workflow, err := s.ForDinner(channelID, dinnerID)
+	if err != nil {
+		return err
+	}
+	return require(workflow, state)
+}
+
+// require returns ErrWrongState unless a workflow is in the given state
+func require(workflow *models.Workflow, state State) error {
+	if State(workflow.State) != state {
+		return fmt.Errorf("%w: it's %s, not %s", ErrWrongState, workflow.State, state)
+	}
+	return nil
+}
+
+// Due returns the workflows that stayed in their state past the state's timeout
+// Only the index of workflows that haven't ended is scanned, so ended ones cost nothing
+func (s *Service) Due(now time.Time) ([]models.Workflow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	keys, err := s.store.List("workflow_open:")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list open workflows: %w", err)
+	}
+
+	var due []models.Workflow
+	for _, key := range keys {
+		var open openWorkflow
+		if err := s.store.Get(key, &open); err != nil {
+			s.logger.Error("Failed to get open workflow %s: %v", key, err)
+			continue
+		}
+		if now.Before(open.Deadline) {
+			continue
+		}
+
+		var workflow models.Workflow
+		if err := s.store.Get(open.ID, &workflow); err != nil {
+			s.logger.Error("Failed to get workflow %s: %v", open.ID, err)
+			continue
+		}
+
+		if _, ok := Timeouts[State(workflow.State)]; !ok {
+			// The workflow ended without leaving the index
+			if err := s.store.Delete(key); err != nil {
+				s.logger.Error("Failed to remove workflow %s from the open ones: %v", workflow.ID, err)
+			}
+			continue
+		}
+		if timedOut(&workflow, now) {
+			due = append(due, workflow)
+		}
+	}
+	return due, nil
+}
+
+// Expire moves a workflow whose state timed out on to the timeout's state
+// A workflow that moved on since it was due isn't touched and gets ErrWrongState
+func (s *Service) Expire(id string, now time.Time) (*models.Workflow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var workflow models.Workflow
+	if err := s.store.Get(id, &workflow); err != nil {
+		return nil, fmt.Errorf("failed to get workflow %s: %w", id, err)
+	}
+	if !timedOut(&workflow, now) {
+		return nil, fmt.Errorf("%w: %s hasn't timed out", ErrWrongState, workflow.State)
+	}
+
+	timeout := Timeouts[State(workflow.State)]
+	if err := s.move(&workflow, timeout.To, TimeoutReason(timeout)); err != nil {
+		return nil, err
+	}
+	return &workflow, nil
+}
+
+// TimeoutReason describes a timeout in a workflow's history
+func TimeoutReason(timeout Timeout) string {
+	return fmt.Sprintf("timed out after %s", timeout.After)
+}
+
+// timedOut reports whether a workflow stayed in a state that times out past its timeout
+func timedOut(workflow *models.Workflow, now time.Time) bool {
+	timeout, ok := Timeouts[State(workflow.State)]
+	return ok && now.Sub(workflow.StateSince) >= timeout.After
+}
+
+// move moves a workflow to a state if its guard allows it, recording the transition
+func (s *Service) move(workflow *models.Workflow, to State, reason string) error {
+	if err := guard(workflow, to); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	workflow.History = append(workflow.History, models.WorkflowTransition{
+		From:   workflow.State,
+		To:     string(to),
+		At:     now,
+		Reason: reason,
+	})
+	from := workflow.State
+	workflow.State = string(to)
+	workflow.StateSince = now
+
+	if err := s.save(workflow); err != nil {
+		return err
+	}
+
+	s.logger.Info("Workflow %s: %s -> %s (%s)", workflow.ID, from, to, reason)
+	return nil
+}
+
+// save stores a workflow and keeps its entry in the index of workflows that haven't ended:
+// the entry moves its deadline with each state, and goes once the workflow is closed or cancelled
+func (s *Service) save(workflow *models.Workflow) error {
+	if err := s.store.Set(workflow.ID, workflow); err != nil {
+		return fmt.Errorf("failed to save workflow: %w", err)
+	}
+
+	timeout, ok := Timeouts[State(workflow.State)]
+	if !ok {
+		if err := s.store.Delete(openKey(workflow)); err != nil {
+			return fmt.Errorf("failed to remove workflow from the open ones: %w", err)
+		}
+		return nil
+	}
+
+	open := openWorkflow{ID: workflow.ID, Deadline: workflow.StateSince.Add(timeout.After)}
+	if err := s.store.Set(openKey(workflow), open); err != nil {
+		return fmt.Errorf("failed to save open workflow: %w", err)
+	}
+	return nil
+}
+
+// guard checks that a workflow may move to a state: the table allows the transition,
+// and a dinner can only be served or rated once it's known which dinner it is
+func guard(workflow *models.Workflow, to State) error {
+	if !CanTransition(State(workflow.State), to) {
+		return fmt.Errorf("%w: %s to %s", ErrIllegalTransition, workflow.State, to)
+	}
+	if (to == Served || to == Rating) && workflow.DinnerID == "" {
+		return fmt.Errorf("%w: %s without a dinner", ErrIllegalTransition, to)
+	}
+	return nil
+}
+
+// CanTransition reports whether the table allows moving from one state to another
+func CanTransition(from, to State) bool {
+	for _, next := range transitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// IsUnfinished reports whether a workflow hasn't got to serving dinner yet, nor was cancelled
+func IsUnfinished(workflow *models.Workflow) bool {
+	if workflow == nil {
+		return false
+	}
+	switch State(workflow.State) {
+	case Proposing, Voting, AwaitingCook, Cooking:
+		return true
+	}
+	return false
+}
+
+// IsEnded reports whether a workflow was closed or cancelled, so it can't move on anymore
+func IsEnded(workflow *models.Workflow) bool {
+	return len(transitions[State(workflow.State)]) == 0
+}
+
+// Description describes where a dinner in a state is at, e.g. "the dinner vote is open"
+func (s State) Description() string {
+	switch s {
+	case Proposing:
+		return "the dinner is being planned"
+	case Voting:
+		return "the dinner vote is open"
+	case AwaitingCook:
+		return "the dinner is waiting for a cook"
+	case Cooking:
+		return "the dinner is being cooked"
+	case Served:
+		return "the dinner is served"
+	case Rating:
+		return "the dinner can be rated"
+	default:
+		return ""
+	}
+}
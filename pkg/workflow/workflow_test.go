@@ -0,0 +1,338 @@
+package workflow
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/korjavin/whatsfordinner/pkg/storage"
+)
+
+// newTestService creates a workflow service on a fresh store
+func newTestService(t *testing.T) *Service {
+	t.Helper()
+	store, err := storage.New(t.TempDir())
+	if err != nil {
+		t.Fatalf("failed to open store: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return New(store)
+}
+
+func TestCanTransition(t *testing.T) {
+	tests := []struct {
+		from, to State
+		want     bool
+	}{
+		{Proposing, Voting, true},
+		{Proposing, AwaitingCook, true},
+		{Voting, Voting, true},
+		{Voting, AwaitingCook, true},
+		{AwaitingCook, AwaitingCook, true},
+		{AwaitingCook, Cooking, true},
+		{Cooking, Served, true},
+		{Served, Rating, true},
+		{Rating, Closed, true},
+		{Voting, Cancelled, true},
+		{Cooking, Cancelled, true},
+		{Proposing, Cooking, false},
+		{Voting, Served, false},
+		{Cooking, Cooking, false},
+		{Cooking, Rating, false},
+		{Served, Cancelled, false},
+		{Rating, Rating, false},
+		{Closed, Voting, false},
+		{Cancelled, Proposing, false},
+	}
+
+	for _, tt := range tests {
+		if got := CanTransition(tt.from, tt.to); got != tt.want {
+			t.Errorf("CanTransition(%s, %s) = %v, want %v", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
+
+func TestAdvance(t *testing.T) {
+	// step moves the channel's workflow, or links its vote or dinner when to is empty
+	type step struct {
+		to      State
+		pollID  string
+		dinner  string
+		wantErr error
+	}
+
+	tests := []struct {
+		name  string
+		steps []step
+		want  State
+	}{
+		{
+			name: "a dinner from proposing to closed",
+			steps: []step{
+				{to: Voting}, {pollID: "p1"}, {to: AwaitingCook}, {to: Cooking}, {dinner: "d1"},
+				{to: Served}, {to: Rating}, {to: Closed},
+			},
+			want: Closed,
+		},
+		{
+			name:  "a planned dinner skips the vote",
+			steps: []step{{pollID: "plan"}, {to: AwaitingCook}, {to: Cooking}},
+			want:  Cooking,
+		},
+		{
+			name: "rating a dinner that isn't served",
+			steps: []step{
+				{to: Voting}, {to: AwaitingCook}, {to: Cooking}, {dinner: "d1"},
+				{to: Rating, wantErr: ErrIllegalTransition},
+			},
+			want: Cooking,
+		},
+		{
+			name: "volunteering twice",
+			steps: []step{
+				{to: Voting}, {to: AwaitingCook}, {to: Cooking},
+				{to: Cooking, wantErr: ErrIllegalTransition},
+			},
+			want: Cooking,
+		},
+		{
+			name:  "serving without a dinner",
+			steps: []step{{to: AwaitingCook}, {to: Cooking}, {to: Served, wantErr: ErrIllegalTransition}},
+			want:  Cooking,
+		},
+		{
+			name:  "calling off a served dinner",
+			steps: []step{{to: AwaitingCook}, {to: Cooking}, {dinner: "d1"}, {to: Served}, {to: Cancelled, wantErr: ErrIllegalTransition}},
+			want:  Served,
+		},
+		{
+			name:  "a cancelled dinner stays cancelled",
+			steps: []step{{to: Voting}, {to: Cancelled}, {to: Voting, wantErr: ErrIllegalTransition}},
+			want:  Cancelled,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestService(t)
+			if _, err := s.Begin(1, "test"); err != nil {
+				t.Fatalf("Begin: %v", err)
+			}
+
+			for i, step := range tt.steps {
+				var err error
+				if step.to == "" {
+					err = s.Link(1, step.pollID, step.dinner)
+				} else {
+					_, err = s.Advance(1, step.to, "test")
+				}
+				if !errors.Is(err, step.wantErr) {
+					t.Fatalf("step %d to %q: error = %v, want %v", i+1, step.to, err, step.wantErr)
+				}
+			}
+
+			current, err := s.Current(1)
+			if err != nil {
+				t.Fatalf("Current: %v", err)
+			}
+			if State(current.State) != tt.want {
+				t.Fatalf("state = %s, want %s", current.State, tt.want)
+			}
+		})
+	}
+}
+
+func TestAdvanceWithoutWorkflow(t *testing.T) {
+	s := newTestService(t)
+	if _, err := s.Advance(1, Voting, "test"); !errors.Is(err, ErrNoWorkflow) {
+		t.Fatalf("Advance = %v, want %v", err, ErrNoWorkflow)
+	}
+	if _, err := s.AdvanceDinner(1, "d1", Served, "test"); !errors.Is(err, ErrNoWorkflow) {
+		t.Fatalf("AdvanceDinner = %v, want %v", err, ErrNoWorkflow)
+	}
+}
+
+func TestBeginStartsOver(t *testing.T) {
+	s := newTestService(t)
+	first, err := s.Begin(1, "test")
+	if err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+
+	// Still proposing: the same workflow goes on
+	if again, err := s.Begin(1, "test"); err != nil || again.ID != first.ID {
+		t.Fatalf("Begin while proposing = %v, %v; want workflow %s", again, err, first.ID)
+	}
+
+	if _, err := s.Advance(1, Voting, "test"); err != nil {
+		t.Fatalf("Advance: %v", err)
+	}
+	time.Sleep(time.Millisecond) // Workflow IDs are made of the time they begin
+	second, err := s.Begin(1, "test")
+	if err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+	if second.ID == first.ID {
+		t.Fatal("Begin while voting kept the workflow")
+	}
+
+	var old struct{ State string }
+	if err := s.store.Get(first.ID, &old); err != nil {
+		t.Fatalf("get old workflow: %v", err)
+	}
+	if State(old.State) != Cancelled {
+		t.Fatalf("old workflow is %s, want %s", old.State, Cancelled)
+	}
+}
+
+func TestRequire(t *testing.T) {
+	s := newTestService(t)
+	if _, err := s.Begin(1, "test"); err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+	if err := s.Link(1, "p1", ""); err != nil {
+		t.Fatalf("Link: %v", err)
+	}
+	if _, err := s.Advance(1, Voting, "test"); err != nil {
+		t.Fatalf("Advance: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		check func() error
+		want  error
+	}{
+		{"the running vote", func() error { return s.RequireVote(1, "p1", Voting) }, nil},
+		{"a replaced vote", func() error { return s.RequireVote(1, "p0", Voting) }, ErrWrongState},
+		{"volunteering during the vote", func() error { return s.RequireVote(1, "p1", AwaitingCook) }, ErrWrongState},
+		{"another chat", func() error { return s.RequireVote(2, "p1", Voting) }, ErrNoWorkflow},
+		{"a dinner without a workflow", func() error { return s.RequireDinner(1, "d1", Rating) }, ErrNoWorkflow},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.check(); !errors.Is(err, tt.want) {
+				t.Fatalf("error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	// Once served, the dinner's workflow takes ratings, also after the chat began the next one
+	for _, to := range []State{AwaitingCook, Cooking} {
+		if _, err := s.Advance(1, to, "test"); err != nil {
+			t.Fatalf("Advance to %s: %v", to, err)
+		}
+	}
+	if err := s.Link(1, "", "d1"); err != nil {
+		t.Fatalf("Link: %v", err)
+	}
+	if err := s.RequireDinner(1, "d1", Rating); !errors.Is(err, ErrWrongState) {
+		t.Fatalf("rating while cooking = %v, want %v", err, ErrWrongState)
+	}
+	for _, to := range []State{Served, Rating} {
+		if _, err := s.AdvanceDinner(1, "d1", to, "test"); err != nil {
+			t.Fatalf("AdvanceDinner to %s: %v", to, err)
+		}
+	}
+	time.Sleep(time.Millisecond)
+	if _, err := s.Begin(1, "next day"); err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+	if err := s.RequireDinner(1, "d1", Rating); err != nil {
+		t.Fatalf("rating a served dinner = %v", err)
+	}
+}
+
+func TestDueAndExpire(t *testing.T) {
+	tests := []struct {
+		name    string
+		states  []State // Where the workflow is moved after beginning
+		dinner  bool
+		after   time.Duration
+		wantDue bool
+		want    State // The state it expires to
+	}{
+		{"proposing for too long", nil, false, 3*time.Hour + time.Minute, true, Cancelled},
+		{"proposing for a while", nil, false, 2 * time.Hour, false, Proposing},
+		{"voting for too long", []State{Voting}, false, 13 * time.Hour, true, Cancelled},
+		{"waiting for a cook for too long", []State{AwaitingCook}, false, 13 * time.Hour, true, Cancelled},
+		{"cooking for too long", []State{AwaitingCook, Cooking}, true, 13 * time.Hour, true, Served},
+		{"served", []State{AwaitingCook, Cooking, Served}, true, 2 * time.Hour, true, Closed},
+		{"rating for a while", []State{AwaitingCook, Cooking, Served, Rating}, true, 2 * time.Hour, false, Rating},
+		{"rating for too long", []State{AwaitingCook, Cooking, Served, Rating}, true, 25 * time.Hour, true, Closed},
+		{"cancelled", []State{Cancelled}, false, 48 * time.Hour, false, Cancelled},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestService(t)
+			if _, err := s.Begin(1, "test"); err != nil {
+				t.Fatalf("Begin: %v", err)
+			}
+			if tt.dinner {
+				if err := s.Link(1, "", "d1"); err != nil {
+					t.Fatalf("Link: %v", err)
+				}
+			}
+			for _, to := range tt.states {
+				if _, err := s.Advance(1, to, "test"); err != nil {
+					t.Fatalf("Advance to %s: %v", to, err)
+				}
+			}
+
+			now := time.Now().Add(tt.after)
+			due, err := s.Due(now)
+			if err != nil {
+				t.Fatalf("Due: %v", err)
+			}
+			if (len(due) == 1) != tt.wantDue {
+				t.Fatalf("Due = %d workflows, want due %v", len(due), tt.wantDue)
+			}
+			if !tt.wantDue {
+				return
+			}
+
+			expired, err := s.Expire(due[0].ID, now)
+			if err != nil {
+				t.Fatalf("Expire: %v", err)
+			}
+			if State(expired.State) != tt.want {
+				t.Fatalf("expired to %s, want %s", expired.State, tt.want)
+			}
+			if last := expired.History[len(expired.History)-1]; last.Reason != TimeoutReason(Timeouts[State(last.From)]) {
+				t.Errorf("history reason = %q", last.Reason)
+			}
+
+			// Its new state's timeout starts over
+			again, err := s.Due(time.Now())
+			if err != nil {
+				t.Fatalf("Due: %v", err)
+			}
+			if len(again) != 0 {
+				t.Fatalf("still due after expiring: %+v", again)
+			}
+		})
+	}
+}
+
+func TestExpireAfterMovingOn(t *testing.T) {
+	s := newTestService(t)
+	if _, err := s.Begin(1, "test"); err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+	now := time.Now().Add(4 * time.Hour)
+	due, err := s.Due(now)
+	if err != nil || len(due) != 1 {
+		t.Fatalf("Due = %v, %v; want the proposing workflow", due, err)
+	}
+
+	// The family started voting meanwhile, so the workflow isn't cancelled under them
+	if _, err := s.Advance(1, Voting, "test"); err != nil {
+		t.Fatalf("Advance: %v", err)
+	}
+	if _, err := s.Expire(due[0].ID, time.Now()); !errors.Is(err, ErrWrongState) {
+		t.Fatalf("Expire = %v, want %v", err, ErrWrongState)
+	}
+	if err := s.Require(1, Voting); err != nil {
+		t.Fatalf("workflow moved: %v", err)
+	}
+}